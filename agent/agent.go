@@ -8,9 +8,11 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/engigu/baihu-panel/internal/constant"
@@ -35,6 +37,25 @@ const (
 	WSTypeExecute       = constant.WSTypeExecute
 	WSTypeTaskHeartbeat = constant.WSTypeTaskHeartbeat
 	WSTypeStop          = constant.WSTypeStop
+
+	WSTypeTerminalOpen   = constant.WSTypeTerminalOpen
+	WSTypeTerminalInput  = constant.WSTypeTerminalInput
+	WSTypeTerminalResize = constant.WSTypeTerminalResize
+	WSTypeTerminalClose  = constant.WSTypeTerminalClose
+	WSTypeTerminalOpened = constant.WSTypeTerminalOpened
+	WSTypeTerminalOutput = constant.WSTypeTerminalOutput
+	WSTypeTerminalClosed = constant.WSTypeTerminalClosed
+
+	WSTypeScriptSync       = constant.WSTypeScriptSync
+	WSTypeScriptSyncResult = constant.WSTypeScriptSyncResult
+
+	WSTypeCommand       = constant.WSTypeCommand
+	WSTypeCommandCancel = constant.WSTypeCommandCancel
+	WSTypeCommandOutput = constant.WSTypeCommandOutput
+	WSTypeCommandResult = constant.WSTypeCommandResult
+
+	WSTypeCertRotate   = constant.WSTypeCertRotate
+	WSTypeUpdateStatus = constant.WSTypeUpdateStatus
 )
 
 type WSMessage struct {
@@ -55,6 +76,8 @@ type AgentTask struct {
 	RandomRange int                 `json:"random_range"`
 	Secrets     []string            `json:"secrets"`
 	Enabled     bool                `json:"enabled"`
+	// FailoverDelay 与面板失联超过该秒数后面板会接管调度，0 表示不会接管
+	FailoverDelay int `json:"failover_delay"`
 }
 
 func (t *AgentTask) GetID() string {
@@ -66,7 +89,7 @@ func (t *AgentTask) GetName() string {
 }
 
 func (t *AgentTask) GetCommand() string {
-	return t.Command
+	return resolveScriptsPath(t.Command)
 }
 
 func (t *AgentTask) GetTimeout() int {
@@ -74,7 +97,7 @@ func (t *AgentTask) GetTimeout() int {
 }
 
 func (t *AgentTask) GetWorkDir() string {
-	return t.WorkDir
+	return resolveScriptsPath(t.WorkDir)
 }
 
 func (t *AgentTask) GetEnvs() string {
@@ -93,12 +116,13 @@ func (t *AgentTask) GetLanguages() []map[string]string {
 	return t.Languages
 }
 
+// GetUseMise 任务指定了运行语言时经本机 mise 执行（需在 Agent 主机上安装 mise 及对应版本）
 func (t *AgentTask) GetUseMise() bool {
-	return false
+	return len(t.Languages) > 0
 }
 
 func (t *AgentTask) UseMise() bool {
-	return false
+	return t.GetUseMise()
 }
 
 func (t *AgentTask) GetSchedule() string {
@@ -139,9 +163,23 @@ type Agent struct {
 	wsConn        *websocket.Conn
 	wsMu          sync.Mutex
 	stopCh        chan struct{}
-	wsStopCh      chan struct{}       // 用于停止当前 WebSocket 相关的 goroutine
-	taskLogs      map[string][]string // 记录最近的日志行，用于失败显示
-	logMu         sync.Mutex          // taskLogs 的锁
+	wsStopCh      chan struct{}               // 用于停止当前 WebSocket 相关的 goroutine
+	taskLogs      map[string][]string         // 记录最近的日志行，用于失败显示
+	logMu         sync.Mutex                  // taskLogs 的锁
+	outbox        *Outbox                     // 断线期间暂存的任务结果与日志
+	terminals     map[string]*terminalSession // 会话 ID -> 远程终端
+	terminalMu    sync.Mutex
+	scriptSyncMu  sync.Mutex           // 脚本同步串行执行
+	commands      map[string]*exec.Cmd // 请求 ID -> 运行环境管理命令
+	commandMu     sync.Mutex
+	cert          *clientCert // mTLS 客户端证书，nil 表示使用 Token 认证
+	certLoaded    bool
+	enrollAfter   time.Time // 申请 / 轮换证书失败后的下次重试时间
+	tlsMu         sync.Mutex
+	updating      atomic.Bool  // 自更新进行中
+	pendingUpdate *updateState // 待健康检查确认或待上报回滚的更新
+	updateMu      sync.Mutex
+	lastContact   atomic.Int64 // 最后一次收到面板消息的时间（UnixNano）
 }
 
 func NewAgent(config *Config, configFile string) *Agent {
@@ -154,6 +192,9 @@ func NewAgent(config *Config, configFile string) *Agent {
 		stopCh:        make(chan struct{}),
 		lastTaskCount: -1,
 		taskLogs:      make(map[string][]string),
+		outbox:        NewOutbox(getOutboxFile()),
+		terminals:     make(map[string]*terminalSession),
+		commands:      make(map[string]*exec.Cmd),
 	}
 
 	// 初始化调度器
@@ -168,6 +209,7 @@ func NewAgent(config *Config, configFile string) *Agent {
 	a.scheduler.SetLogger(logger.NewSchedulerLogger())
 	a.cronManager = executor.NewCronManager(a.scheduler)
 	a.cronManager.SetLogger(logger.NewSchedulerLogger())
+	a.cronManager.SetSkipCheck(a.failoverSkipReason)
 
 	return a
 }
@@ -184,6 +226,8 @@ func (h *AgentHandler) OnTaskExecuting(req *executor.ExecutionRequest) (io.Write
 		writer := &RealTimeLogWriter{agent: h.agent, logID: req.LogID}
 		return writer, writer, nil
 	}
+	// 本地定时触发的执行由 Agent 生成日志 ID，服务端据此对补传的结果去重
+	req.LogID = utils.GenerateID()
 	return nil, nil, nil
 }
 
@@ -227,7 +271,7 @@ func (h *AgentHandler) OnTaskCompleted(req *executor.ExecutionRequest, result *e
 func (h *AgentHandler) OnTaskFailed(req *executor.ExecutionRequest, err error) {
 	errMsg := fmt.Sprintf("任务执行失败: %v", err)
 	// 先发送日志，确保服务端能收到错误信息
-	h.agent.sendWSMessage(WSTypeTaskLog, map[string]interface{}{
+	h.agent.deliver(WSTypeTaskLog, map[string]interface{}{
 		"log_id":  req.LogID,
 		"content": errMsg,
 	})
@@ -257,6 +301,7 @@ func (a *Agent) Start() error {
 	}
 
 	logger.Infof("机器识别码: %s", a.machineID[:16]+"...")
+	a.checkPendingUpdate()
 	a.scheduler.Start()
 	a.cronManager.Start()
 
@@ -297,21 +342,33 @@ func (a *Agent) wsLoop() {
 }
 
 func (a *Agent) connectWS() error {
-	serverURL := a.config.ServerURL
+	a.ensureClientCert()
+	serverURL, _, cert := a.apiTarget()
 	wsURL := strings.Replace(serverURL, "http://", "ws://", 1)
 	wsURL = strings.Replace(wsURL, "https://", "wss://", 1)
-	wsURL = fmt.Sprintf("%s/api/agent/ws?token=%s&machine_id=%s", wsURL, url.QueryEscape(a.config.Token), url.QueryEscape(a.machineID))
-
-	logger.Infof("正在连接 WebSocket: %s", wsURL)
-	logger.Infof("Token: %s..., MachineID: %s...", a.config.Token[:8], a.machineID[:16])
 
 	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	if cert != nil {
+		// 持有客户端证书时经 TLS 端口以证书认证，不再携带 Token
+		wsURL = fmt.Sprintf("%s/api/agent/ws?machine_id=%s", wsURL, url.QueryEscape(a.machineID))
+		dialer.TLSClientConfig = cert.tlsConfig
+		logger.Infof("正在连接 WebSocket (mTLS): %s", wsURL)
+	} else {
+		wsURL = fmt.Sprintf("%s/api/agent/ws?token=%s&machine_id=%s", wsURL, url.QueryEscape(a.config.Token), url.QueryEscape(a.machineID))
+		logger.Infof("正在连接 WebSocket: %s", wsURL)
+		logger.Infof("Token: %s..., MachineID: %s...", a.config.Token[:8], a.machineID[:16])
+	}
+
 	conn, resp, err := dialer.Dial(wsURL, nil)
 	if err != nil {
 		if resp != nil {
 			bodyBytes, _ := io.ReadAll(resp.Body)
 			logger.Errorf("WebSocket 握手失败: HTTP %d, Body: %s", resp.StatusCode, string(bodyBytes))
 			resp.Body.Close()
+			// 证书被吊销、过期或面板已不认识该证书时丢弃，下次连接重新申请
+			if cert != nil && resp.StatusCode == http.StatusUnauthorized && certRejected(resp.Header.Get(constant.AgentCertErrorHeader)) {
+				a.removeClientCert(string(bodyBytes))
+			}
 		} else {
 			logger.Errorf("WebSocket 连接失败: %v", err)
 		}
@@ -322,9 +379,11 @@ func (a *Agent) connectWS() error {
 	a.wsConn = conn
 	a.wsStopCh = make(chan struct{})
 	a.wsMu.Unlock()
+	a.lastContact.Store(time.Now().UnixNano())
 
 	logger.Info("WebSocket 已连接")
 	a.sendHeartbeat()
+	go a.flushOutbox()
 	go a.heartbeatLoop()
 
 	return nil
@@ -347,6 +406,8 @@ func (a *Agent) readWS() {
 	defer func() {
 		logger.Info("readWS 退出，准备关闭连接")
 		a.closeWS()
+		// 远程终端依附于当前连接，断线后无法继续交互
+		a.closeAllTerminals()
 	}()
 
 	for {
@@ -364,6 +425,7 @@ func (a *Agent) readWS() {
 			logger.Warnf("WebSocket 读取错误: %v", err)
 			return
 		}
+		a.lastContact.Store(time.Now().UnixNano())
 
 		var msg WSMessage
 		if err := json.Unmarshal(message, &msg); err != nil {
@@ -395,6 +457,22 @@ func (a *Agent) handleWSMessage(msg *WSMessage) {
 		a.handleExecute(msg.Data)
 	case WSTypeStop:
 		a.handleStop(msg.Data)
+	case WSTypeTerminalOpen:
+		a.handleTerminalOpen(msg.Data)
+	case WSTypeTerminalInput:
+		a.handleTerminalInput(msg.Data)
+	case WSTypeTerminalResize:
+		a.handleTerminalResize(msg.Data)
+	case WSTypeTerminalClose:
+		a.handleTerminalClose(msg.Data)
+	case WSTypeScriptSync:
+		a.handleScriptSync(msg.Data)
+	case WSTypeCommand:
+		a.handleCommand(msg.Data)
+	case WSTypeCommandCancel:
+		a.handleCommandCancel(msg.Data)
+	case WSTypeCertRotate:
+		a.handleCertRotate()
 	}
 }
 
@@ -412,6 +490,8 @@ func (a *Agent) handleConnected(data json.RawMessage) {
 		IsNewAgent      bool                   `json:"is_new_agent"`
 		MachineID       string                 `json:"machine_id"`
 		SchedulerConfig map[string]interface{} `json:"scheduler_config"`
+		MTLSMode        string                 `json:"mtls_mode"`
+		CertEnroll      bool                   `json:"cert_enroll"`
 	}
 	json.Unmarshal(data, &resp)
 
@@ -426,6 +506,11 @@ func (a *Agent) handleConnected(data json.RawMessage) {
 		a.updateSchedulerConfig(resp.SchedulerConfig)
 	}
 
+	// 面板开启 mTLS 后，仅凭 Token 连接的 Agent 申请证书并改用 TLS 端口
+	if resp.MTLSMode != "" && resp.MTLSMode != "off" && resp.CertEnroll && a.currentCert() == nil {
+		go a.reconnectWithCert(false)
+	}
+
 	a.fetchTasks()
 }
 
@@ -473,6 +558,9 @@ func (a *Agent) handleHeartbeatAck(data json.RawMessage) {
 	}
 	json.Unmarshal(data, &resp)
 
+	// 能收到心跳响应说明已连上面板，更新后的健康检查通过
+	a.confirmPendingUpdate()
+
 	if resp.NeedUpdate && (a.config.AutoUpdate || resp.ForceUpdate) {
 		logger.Infof("发现新版本 %s，开始更新...", resp.LatestVersion)
 		go a.selfUpdate()
@@ -496,10 +584,11 @@ func (a *Agent) handleTasks(data json.RawMessage) {
 
 func (a *Agent) handleExecute(data json.RawMessage) {
 	var req struct {
-		TaskID  string   `json:"task_id"`
-		LogID   string   `json:"log_id"`
-		Envs    string   `json:"envs"`
-		Secrets []string `json:"secrets"`
+		TaskID  string     `json:"task_id"`
+		LogID   string     `json:"log_id"`
+		Envs    string     `json:"envs"`
+		Secrets []string   `json:"secrets"`
+		Task    *AgentTask `json:"task"` // 服务端附带的任务定义（按标签调度的任务不在本地列表中）
 	}
 	if err := json.Unmarshal(data, &req); err != nil {
 		logger.Errorf("解析立即执行请求失败: %v", err)
@@ -511,6 +600,10 @@ func (a *Agent) handleExecute(data json.RawMessage) {
 	task, exists := a.tasks[req.TaskID]
 	a.mu.RUnlock()
 
+	if !exists && req.Task != nil && req.Task.ID == req.TaskID {
+		task, exists = req.Task, true
+	}
+
 	if !exists {
 		logger.Warnf("任务 #%s 不存在，无法执行", req.TaskID)
 		return
@@ -527,8 +620,8 @@ func (a *Agent) handleExecute(data json.RawMessage) {
 		TaskID:    task.ID,
 		LogID:     req.LogID,
 		Name:      task.Name,
-		Command:   task.Command,
-		WorkDir:   task.WorkDir,
+		Command:   task.GetCommand(),
+		WorkDir:   task.GetWorkDir(),
 		Envs:      executor.ParseEnvVars(envs),
 		Secrets:   req.Secrets,
 		Timeout:   task.Timeout,
@@ -578,33 +671,59 @@ func (w *RealTimeLogWriter) Write(p []byte) (n int, err error) {
 		"content": string(p),
 	}
 
-	// 发送消息
-	if err := w.agent.sendWSMessage(WSTypeTaskLog, msg); err != nil {
-		// 如果发送失败，不阻塞程序执行，只记录日志
-		return len(p), nil
-	}
+	// 发送消息（断线时写入 outbox，不阻塞程序执行）
+	w.agent.deliver(WSTypeTaskLog, msg)
 
 	return len(p), nil
 }
 
 func (a *Agent) sendWSMessage(msgType string, data interface{}) error {
+	dataBytes, _ := json.Marshal(data)
+	msg := WSMessage{Type: msgType, Data: dataBytes}
+	msgBytes, _ := json.Marshal(msg)
+
+	if err := a.writeWS(msgBytes); err != nil {
+		if err != errWSNotConnected {
+			logger.Warnf("发送消息失败 (%s): %v", msgType, err)
+		}
+		return err
+	}
+	return nil
+}
+
+var errWSNotConnected = fmt.Errorf("WebSocket 未连接")
+
+// writeWS 发送已编码的消息
+func (a *Agent) writeWS(msgBytes []byte) error {
 	a.wsMu.Lock()
 	defer a.wsMu.Unlock()
 
 	if a.wsConn == nil {
-		return fmt.Errorf("WebSocket 未连接")
+		return errWSNotConnected
 	}
 
-	dataBytes, _ := json.Marshal(data)
-	msg := WSMessage{Type: msgType, Data: dataBytes}
-	msgBytes, _ := json.Marshal(msg)
-
 	a.wsConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-	if err := a.wsConn.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
-		logger.Warnf("发送消息失败 (%s): %v", msgType, err)
-		return err
+	return a.wsConn.WriteMessage(websocket.TextMessage, msgBytes)
+}
+
+// deliver 发送任务日志/结果，连接断开时写入 outbox，重连后按顺序补传
+func (a *Agent) deliver(msgType string, data interface{}) {
+	a.outbox.Deliver(msgType, data, a.writeWS)
+}
+
+// flushOutbox 补传断线期间积压的任务结果与日志
+func (a *Agent) flushOutbox() {
+	pending := a.outbox.Len()
+	if pending == 0 {
+		return
 	}
-	return nil
+	logger.Infof("开始补传断线期间积压的 %d 条消息", pending)
+	sent, err := a.outbox.Flush(a.writeWS)
+	if err != nil {
+		logger.Warnf("补传中断: %v (已补传 %d 条，剩余 %d 条)", err, sent, a.outbox.Len())
+		return
+	}
+	logger.Infof("积压消息补传完成，共 %d 条", sent)
 }
 
 func (a *Agent) heartbeatLoop() {
@@ -633,6 +752,8 @@ func (a *Agent) heartbeatLoop() {
 				return
 			}
 			a.sendHeartbeat()
+			a.flushOutbox()
+			a.checkCertRotation()
 		}
 	}
 }
@@ -646,6 +767,7 @@ func (a *Agent) sendHeartbeat() {
 		"os":          runtime.GOOS,
 		"arch":        runtime.GOARCH,
 		"auto_update": a.config.AutoUpdate,
+		"metrics":     a.collectMetrics(),
 	}
 	if err := a.sendWSMessage(WSTypeHeartbeat, data); err != nil {
 		logger.Warnf("发送心跳失败: %v", err)
@@ -653,19 +775,7 @@ func (a *Agent) sendHeartbeat() {
 }
 
 func (a *Agent) sendTaskResult(result *TaskResult) {
-	if err := a.sendWSMessage(WSTypeTaskResult, result); err != nil {
-		logger.Warnf("发送任务结果失败: %v，尝试 HTTP 上报", err)
-		a.reportResultHTTP(result)
-	}
-}
-
-func (a *Agent) reportResultHTTP(result *TaskResult) error {
-	resp, err := a.doRequest("POST", "/api/agent/report", result)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	a.deliver(WSTypeTaskResult, result)
 }
 
 func (a *Agent) updateTasks(tasks []AgentTask) {
@@ -692,7 +802,7 @@ func (a *Agent) updateTasks(tasks []AgentTask) {
 		if !exists || oldTask.Schedule != task.Schedule || oldTask.Command != task.Command ||
 			oldTask.Enabled != task.Enabled || oldTask.Timeout != task.Timeout ||
 			oldTask.WorkDir != task.WorkDir || oldTask.Envs != task.Envs ||
-			oldTask.RandomRange != task.RandomRange {
+			oldTask.RandomRange != task.RandomRange || oldTask.FailoverDelay != task.FailoverDelay {
 			if task.Enabled {
 				err := a.cronManager.AddTask(task)
 				if err != nil {
@@ -709,6 +819,22 @@ func (a *Agent) updateTasks(tasks []AgentTask) {
 	}
 }
 
+// failoverSkipReason 与面板失联达到接管时长的一半后，跳过配置了故障转移的任务的本地定时执行。
+// 此时面板即将（或已经）按故障转移策略接管，继续执行会在网络分区时与面板重复执行同一次调度
+func (a *Agent) failoverSkipReason(taskID string) string {
+	a.mu.RLock()
+	task := a.tasks[taskID]
+	a.mu.RUnlock()
+	if task == nil || task.FailoverDelay <= 0 {
+		return ""
+	}
+	lost := time.Since(time.Unix(0, a.lastContact.Load()))
+	if lost < time.Duration(task.FailoverDelay)*time.Second/2 {
+		return ""
+	}
+	return fmt.Sprintf("已与面板失联 %v，由面板按故障转移策略接管执行", lost.Round(time.Second))
+}
+
 func (a *Agent) clearAllTasks() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -779,14 +905,17 @@ func (a *Agent) doRequest(method, path string, body interface{}) (*http.Response
 		bodyReader = bytes.NewReader(data)
 	}
 
-	req, err := http.NewRequest(method, a.config.ServerURL+path, bodyReader)
+	baseURL, client, cert := a.apiTarget()
+	req, err := http.NewRequest(method, baseURL+path, bodyReader)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+a.config.Token)
+	if cert == nil {
+		req.Header.Set("Authorization", "Bearer "+a.config.Token)
+	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Machine-ID", a.machineID)
 
-	return a.client.Do(req)
+	return client.Do(req)
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// 与面板失联达到接管时长的一半后，配置了故障转移的任务跳过本地定时执行
+func TestFailoverSkipReason(t *testing.T) {
+	a := &Agent{tasks: map[string]*AgentTask{
+		"f": {ID: "f", FailoverDelay: 300},
+		"n": {ID: "n"},
+	}}
+
+	a.lastContact.Store(time.Now().Add(-time.Minute).UnixNano())
+	if reason := a.failoverSkipReason("f"); reason != "" {
+		t.Fatalf("skipped before half of the takeover delay: %s", reason)
+	}
+
+	a.lastContact.Store(time.Now().Add(-3 * time.Minute).UnixNano())
+	if a.failoverSkipReason("f") == "" {
+		t.Fatal("task still runs after losing contact for half of the takeover delay")
+	}
+	if reason := a.failoverSkipReason("n"); reason != "" {
+		t.Fatalf("task without failover skipped: %s", reason)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// defaultCommandTimeout 面板未指定超时时间时的上限
+const defaultCommandTimeout = 30 * time.Minute
+
+// commandRequest 面板下发的运行环境管理命令（mise / 依赖安装），Args 直接执行，Command 经 Shell 执行
+type commandRequest struct {
+	RequestID string   `json:"request_id"`
+	Args      []string `json:"args"`
+	Command   string   `json:"command"`
+	Env       []string `json:"env"`
+	Timeout   int      `json:"timeout"` // 秒
+}
+
+// handleCommand 执行面板下发的命令，输出实时回传，结束后回报退出码
+func (a *Agent) handleCommand(data json.RawMessage) {
+	var req commandRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.RequestID == "" {
+		logger.Errorf("解析命令请求失败: %v", err)
+		return
+	}
+	if !a.config.AllowCommands {
+		logger.Warnf("拒绝执行面板下发的命令 #%s：未开启 allow_commands", req.RequestID)
+		a.sendCommandResult(req.RequestID, -1, "Agent 未开启运行环境管理（配置 allow_commands = true）")
+		return
+	}
+
+	var cmd *exec.Cmd
+	switch {
+	case len(req.Args) > 0:
+		cmd = exec.Command(req.Args[0], req.Args[1:]...)
+	case req.Command != "":
+		cmd = utils.NewShellCommandCmd(req.Command)
+	default:
+		a.sendCommandResult(req.RequestID, -1, "命令为空")
+		return
+	}
+	cmd.Env = append(os.Environ(), req.Env...)
+	if home, err := os.UserHomeDir(); err == nil {
+		cmd.Dir = home
+	}
+
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
+	if err := cmd.Start(); err != nil {
+		a.sendCommandResult(req.RequestID, -1, "启动命令失败: "+err.Error())
+		return
+	}
+
+	a.commandMu.Lock()
+	a.commands[req.RequestID] = cmd
+	a.commandMu.Unlock()
+	logger.Infof("[Command] 开始执行 #%s", req.RequestID)
+
+	timeout := defaultCommandTimeout
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+
+	go func() {
+		timer := time.AfterFunc(timeout, func() {
+			logger.Warnf("[Command] #%s 执行超时，已终止", req.RequestID)
+			cmd.Process.Kill()
+		})
+		defer timer.Stop()
+
+		var wg sync.WaitGroup
+		for stream, r := range map[string]io.Reader{"stdout": stdout, "stderr": stderr} {
+			wg.Add(1)
+			go func(stream string, r io.Reader) {
+				defer wg.Done()
+				buf := make([]byte, 4096)
+				for {
+					n, err := r.Read(buf)
+					if n > 0 {
+						a.sendWSMessage(WSTypeCommandOutput, map[string]interface{}{
+							"request_id": req.RequestID,
+							"stream":     stream,
+							"data":       buf[:n],
+						})
+					}
+					if err != nil {
+						return
+					}
+				}
+			}(stream, r)
+		}
+		wg.Wait()
+		err := cmd.Wait()
+
+		a.commandMu.Lock()
+		delete(a.commands, req.RequestID)
+		a.commandMu.Unlock()
+
+		exitCode, errMsg := 0, ""
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = exitErr.ExitCode()
+			} else {
+				exitCode, errMsg = -1, err.Error()
+			}
+		}
+		logger.Infof("[Command] #%s 执行结束，退出码 %d", req.RequestID, exitCode)
+		a.sendCommandResult(req.RequestID, exitCode, errMsg)
+	}()
+}
+
+// handleCommandCancel 面板放弃等待时终止命令
+func (a *Agent) handleCommandCancel(data json.RawMessage) {
+	var req commandRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return
+	}
+	a.commandMu.Lock()
+	cmd := a.commands[req.RequestID]
+	a.commandMu.Unlock()
+	if cmd != nil && cmd.Process != nil {
+		logger.Infof("[Command] 面板取消 #%s", req.RequestID)
+		cmd.Process.Kill()
+	}
+}
+
+func (a *Agent) sendCommandResult(requestID string, exitCode int, errMsg string) {
+	a.sendWSMessage(WSTypeCommandResult, map[string]interface{}{
+		"request_id": requestID,
+		"exit_code":  exitCode,
+		"error":      errMsg,
+	})
+}
@@ -15,6 +15,14 @@ type Config struct {
 	Token      string
 	Interval   int
 	AutoUpdate bool
+	// AllowTerminal 允许面板管理员打开本机的远程终端（默认关闭）
+	AllowTerminal bool
+	// AllowCommands 允许面板在本机执行运行环境管理命令（mise、依赖安装，默认关闭）
+	AllowCommands bool
+	// TLSURL 面板 Agent mTLS 端口地址，为空时申请证书后由 server_url 与面板返回的端口推导
+	TLSURL string
+	// UpdatePublicKey 信任的更新包签名公钥（base64），为空时使用发布时内置的公钥
+	UpdatePublicKey string
 }
 
 func loadConfigFile(path string, config *Config) error {
@@ -41,6 +49,18 @@ func loadConfigFile(path string, config *Config) error {
 	if v := section.Key("auto_update").String(); v != "" {
 		config.AutoUpdate = v == "true" || v == "1"
 	}
+	if v := section.Key("allow_terminal").String(); v != "" {
+		config.AllowTerminal = v == "true" || v == "1"
+	}
+	if v := section.Key("allow_commands").String(); v != "" {
+		config.AllowCommands = v == "true" || v == "1"
+	}
+	if v := section.Key("tls_url").String(); v != "" {
+		config.TLSURL = v
+	}
+	if v := section.Key("update_public_key").String(); v != "" {
+		config.UpdatePublicKey = v
+	}
 	return nil
 }
 
@@ -61,6 +81,22 @@ func saveConfigFile(path string, config *Config) error {
 	} else {
 		section.Key("auto_update").SetValue("false")
 	}
+	if config.AllowTerminal {
+		section.Key("allow_terminal").SetValue("true")
+	} else {
+		section.Key("allow_terminal").SetValue("false")
+	}
+	if config.AllowCommands {
+		section.Key("allow_commands").SetValue("true")
+	} else {
+		section.Key("allow_commands").SetValue("false")
+	}
+	if config.TLSURL != "" {
+		section.Key("tls_url").SetValue(config.TLSURL)
+	}
+	if config.UpdatePublicKey != "" {
+		section.Key("update_public_key").SetValue(config.UpdatePublicKey)
+	}
 
 	return cfg.SaveTo(path)
 }
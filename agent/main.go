@@ -25,6 +25,8 @@ const ServiceDesc = "Baihu Agent Service"
 var (
 	Version   = "dev"
 	BuildTime = ""
+	// UpdatePublicKey 发布时内置的更新包签名公钥（base64），配置 update_public_key 可覆盖
+	UpdatePublicKey = ""
 )
 
 // 全局配置
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math"
+	"os"
+
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// collectMetrics 采集主机指标，随心跳上报（采集失败的项为 0）
+func (a *Agent) collectMetrics() models.AgentMetrics {
+	m := models.AgentMetrics{RunningTasks: a.scheduler.GetRunningTaskCount()}
+
+	// 间隔为 0 时返回距上次调用（即上一次心跳）的平均使用率
+	if percents, err := cpu.Percent(0, false); err == nil && len(percents) > 0 {
+		m.CPUPercent = round1(percents[0])
+	}
+	if vm, err := mem.VirtualMemory(); err == nil {
+		m.MemPercent = round1(vm.UsedPercent)
+		m.MemUsed = vm.Used
+		m.MemTotal = vm.Total
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if usage, err := disk.Usage(wd); err == nil {
+			m.DiskPercent = round1(usage.UsedPercent)
+			m.DiskUsed = usage.Used
+			m.DiskTotal = usage.Total
+		}
+	}
+	if avg, err := load.Avg(); err == nil {
+		m.Load1 = round1(avg.Load1)
+		m.Load5 = round1(avg.Load5)
+		m.Load15 = round1(avg.Load15)
+	}
+	return m
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/logger"
+)
+
+// certEnrollRetry 申请证书失败（如面板未开启 mTLS）后的重试间隔
+const certEnrollRetry = 10 * time.Minute
+
+// 客户端证书文件，私钥只保存在本机
+func tlsDir() string      { return filepath.Join(dataDir, "tls") }
+func tlsKeyFile() string  { return filepath.Join(tlsDir(), "agent.key") }
+func tlsCertFile() string { return filepath.Join(tlsDir(), "agent.crt") }
+func tlsCAFile() string   { return filepath.Join(tlsDir(), "ca.crt") }
+
+// clientCert 已加载的客户端证书及经 TLS 端口访问面板的 HTTP 客户端
+type clientCert struct {
+	leaf      *x509.Certificate
+	tlsConfig *tls.Config
+	client    *http.Client
+}
+
+// needRotate 证书剩余有效期不足三分之一时需要轮换
+func (c *clientCert) needRotate() bool {
+	lifetime := c.leaf.NotAfter.Sub(c.leaf.NotBefore)
+	return time.Until(c.leaf.NotAfter) < lifetime/3
+}
+
+// loadClientCert 从 data/tls 加载客户端证书，不存在时返回 nil
+func loadClientCert() (*clientCert, error) {
+	certPEM, err := os.ReadFile(tlsCertFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(tlsKeyFile())
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := os.ReadFile(tlsCAFile())
+	if err != nil {
+		return nil, err
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(leaf.NotAfter) {
+		return nil, fmt.Errorf("客户端证书已于 %s 过期", leaf.NotAfter.Format("2006-01-02 15:04:05"))
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("CA 证书格式错误")
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	return &clientCert{
+		leaf:      leaf,
+		tlsConfig: tlsConfig,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+// certRejected 判断面板返回的证书错误码是否表示本地证书已不可用
+func certRejected(code string) bool {
+	switch code {
+	case constant.AgentCertErrUnknown, constant.AgentCertErrRevoked, constant.AgentCertErrExpired:
+		return true
+	}
+	return false
+}
+
+// removeClientCert 删除本地证书（证书被吊销或过期后重新申请）
+func (a *Agent) removeClientCert(reason string) {
+	a.tlsMu.Lock()
+	defer a.tlsMu.Unlock()
+	logger.Warnf("[mTLS] 丢弃本地客户端证书: %s", reason)
+	os.Remove(tlsCertFile())
+	os.Remove(tlsKeyFile())
+	a.cert = nil
+	a.enrollAfter = time.Time{}
+}
+
+// currentCert 返回已加载的客户端证书，首次调用时从磁盘加载
+func (a *Agent) currentCert() *clientCert {
+	a.tlsMu.Lock()
+	defer a.tlsMu.Unlock()
+	if !a.certLoaded {
+		a.certLoaded = true
+		cert, err := loadClientCert()
+		if err != nil {
+			logger.Warnf("[mTLS] 加载客户端证书失败: %v", err)
+		}
+		a.cert = cert
+	}
+	return a.cert
+}
+
+// apiTarget 返回访问面板 Agent 接口的基础地址与客户端：持有证书时走 TLS 端口，否则走 server_url 并携带 Token
+func (a *Agent) apiTarget() (string, *http.Client, *clientCert) {
+	if cert := a.currentCert(); cert != nil && a.config.TLSURL != "" {
+		return a.config.TLSURL, cert.client, cert
+	}
+	return a.config.ServerURL, a.client, nil
+}
+
+// ensureClientCert 连接前检查客户端证书：没有证书时凭 Token 申请，临近过期时轮换
+// 失败后 certEnrollRetry 内不再重试
+func (a *Agent) ensureClientCert() {
+	cert := a.currentCert()
+	if cert != nil && !cert.needRotate() {
+		return
+	}
+
+	a.tlsMu.Lock()
+	wait := time.Now().Before(a.enrollAfter)
+	a.tlsMu.Unlock()
+	if wait {
+		return
+	}
+
+	var err error
+	if cert != nil {
+		if err = a.requestCert(true); err != nil {
+			logger.Warnf("[mTLS] 轮换客户端证书失败，继续使用当前证书: %v", err)
+		}
+	} else if err = a.requestCert(false); err != nil {
+		logger.Infof("[mTLS] 未获取客户端证书，使用 Token 连接: %v", err)
+	}
+	if err != nil {
+		a.tlsMu.Lock()
+		a.enrollAfter = time.Now().Add(certEnrollRetry)
+		a.tlsMu.Unlock()
+	}
+}
+
+// checkCertRotation 心跳时检查证书有效期，需要轮换时轮换后重连
+func (a *Agent) checkCertRotation() {
+	cert := a.currentCert()
+	if cert == nil || !cert.needRotate() {
+		return
+	}
+	a.ensureClientCert()
+	if a.currentCert() != cert {
+		logger.Info("[mTLS] 客户端证书已轮换，使用新证书重新连接")
+		a.closeWS()
+	}
+}
+
+// requestCert 生成私钥与 CSR 向面板申请证书；rotate 为 true 时经 TLS 端口用当前证书认证
+func (a *Agent) requestCert(rotate bool) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: a.config.Name},
+	}, key)
+	if err != nil {
+		return err
+	}
+	body, _ := json.Marshal(map[string]string{
+		"csr": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})),
+	})
+
+	baseURL, client := a.config.ServerURL, a.client
+	if rotate {
+		var cert *clientCert
+		if baseURL, client, cert = a.apiTarget(); cert == nil {
+			return fmt.Errorf("没有可用于轮换的证书")
+		}
+	}
+	req, err := http.NewRequest("POST", baseURL+"/api/agent/cert", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Machine-ID", a.machineID)
+	if !rotate {
+		req.Header.Set("Authorization", "Bearer "+a.config.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			AgentID       string `json:"agent_id"`
+			Certificate   string `json:"certificate"`
+			CACertificate string `json:"ca_certificate"`
+			TLSPort       int    `json:"tls_port"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("解析响应失败: HTTP %d", resp.StatusCode)
+	}
+	if apiResp.Code != 200 {
+		return fmt.Errorf("%s", apiResp.Msg)
+	}
+
+	tlsURL := a.config.TLSURL
+	if tlsURL == "" {
+		if tlsURL, err = deriveTLSURL(a.config.ServerURL, apiResp.Data.TLSPort); err != nil {
+			return err
+		}
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tlsDir(), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(tlsKeyFile(), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(tlsCertFile(), []byte(apiResp.Data.Certificate), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(tlsCAFile(), []byte(apiResp.Data.CACertificate), 0644); err != nil {
+		return err
+	}
+
+	if a.config.TLSURL == "" {
+		a.config.TLSURL = tlsURL
+		if a.configFile != "" {
+			if err := saveConfigFile(a.configFile, a.config); err != nil {
+				logger.Warnf("[mTLS] 保存 tls_url 失败: %v", err)
+			}
+		}
+	}
+
+	cert, err := loadClientCert()
+	if err != nil {
+		return err
+	}
+	a.tlsMu.Lock()
+	a.cert = cert
+	a.certLoaded = true
+	a.tlsMu.Unlock()
+	logger.Infof("[mTLS] 已获取客户端证书（Agent #%s，有效期至 %s），面板地址: %s",
+		apiResp.Data.AgentID, cert.leaf.NotAfter.Local().Format("2006-01-02"), a.config.TLSURL)
+	return nil
+}
+
+// deriveTLSURL 由 server_url 推导面板 Agent TLS 端口地址（保留路径前缀）
+func deriveTLSURL(serverURL string, port int) (string, error) {
+	if port <= 0 {
+		return "", fmt.Errorf("面板未返回 TLS 端口，请在配置中设置 tls_url")
+	}
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = "https"
+	u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(port))
+	return u.String(), nil
+}
+
+// reconnectWithCert 证书获取或轮换成功后断开当前连接，由 wsLoop 使用新证书重连
+func (a *Agent) reconnectWithCert(rotate bool) {
+	if err := a.requestCert(rotate); err != nil {
+		logger.Warnf("[mTLS] 申请客户端证书失败: %v", err)
+		return
+	}
+	logger.Info("[mTLS] 使用新证书重新连接")
+	a.closeWS()
+}
+
+// handleCertRotate 面板要求立即轮换证书
+func (a *Agent) handleCertRotate() {
+	if a.currentCert() == nil {
+		logger.Warn("[mTLS] 收到轮换指令，但本机尚无客户端证书")
+		return
+	}
+	logger.Info("[mTLS] 面板要求轮换客户端证书")
+	go a.reconnectWithCert(true)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/engigu/baihu-panel/internal/logger"
+)
+
+// outboxMaxSize 积压超过该大小后丢弃新的日志片段（执行结果始终保留）
+const outboxMaxSize = 64 << 20
+
+func getOutboxFile() string {
+	return filepath.Join(dataDir, "outbox.jsonl")
+}
+
+// Outbox 断线期间将任务结果与日志按顺序落盘（每行一条 WSMessage），重连后补传
+type Outbox struct {
+	mu      sync.Mutex
+	path    string
+	count   int
+	size    int64
+	dropped bool
+}
+
+func NewOutbox(path string) *Outbox {
+	o := &Outbox{path: path}
+	o.count, o.size = countOutbox(path)
+	if o.count > 0 {
+		logger.Infof("发现 %d 条待补传的消息，将在连接后补传", o.count)
+	}
+	return o
+}
+
+// countOutbox 统计积压文件中的消息条数与文件大小
+func countOutbox(path string) (int, int64) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+
+	count := 0
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 1 {
+			count++
+		}
+		if err != nil {
+			break
+		}
+	}
+	info, _ := f.Stat()
+	if info == nil {
+		return count, 0
+	}
+	return count, info.Size()
+}
+
+// Len 当前积压的消息条数
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.count
+}
+
+// Deliver 无积压时直接发送；发送失败或已有积压时追加到末尾，保证补传顺序
+func (o *Outbox) Deliver(msgType string, data interface{}, send func([]byte) error) {
+	dataBytes, _ := json.Marshal(data)
+	line, _ := json.Marshal(WSMessage{Type: msgType, Data: dataBytes})
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.count == 0 && send(line) == nil {
+		return
+	}
+
+	if msgType == WSTypeTaskLog && o.size >= outboxMaxSize {
+		if !o.dropped {
+			logger.Warnf("积压消息已超过 %d MB，后续日志片段将被丢弃（执行结果仍会保留）", outboxMaxSize>>20)
+			o.dropped = true
+		}
+		return
+	}
+
+	if err := o.append(line); err != nil {
+		logger.Errorf("写入积压消息失败 (%s): %v", msgType, err)
+	}
+}
+
+func (o *Outbox) append(line []byte) error {
+	os.MkdirAll(filepath.Dir(o.path), 0755)
+	f, err := os.OpenFile(o.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	o.count++
+	o.size += int64(len(line) + 1)
+	return nil
+}
+
+// Flush 按顺序补传积压消息，遇到发送失败即停止并保留剩余部分
+func (o *Outbox) Flush(send func([]byte) error) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.count == 0 {
+		return 0, nil
+	}
+
+	f, err := os.Open(o.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			o.count, o.size = 0, 0
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	sent := 0
+	r := bufio.NewReader(f)
+	for {
+		line, readErr := r.ReadBytes('\n')
+		if len(line) > 1 {
+			if sendErr := send(bytes.TrimSuffix(line, []byte("\n"))); sendErr != nil {
+				err := o.writeRemaining(line, r)
+				f.Close()
+				if err == nil {
+					err = o.replaceWithRemaining()
+				}
+				if err != nil {
+					return sent, err
+				}
+				return sent, sendErr
+			}
+			sent++
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			f.Close()
+			return sent, readErr
+		}
+	}
+	f.Close()
+
+	o.count, o.size, o.dropped = 0, 0, false
+	return sent, os.Remove(o.path)
+}
+
+// writeRemaining 将未发送的消息（从 first 开始）写入临时文件
+func (o *Outbox) writeRemaining(first []byte, rest io.Reader) error {
+	tmp, err := os.OpenFile(o.path+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(first); err == nil {
+		_, err = io.Copy(tmp, rest)
+	}
+	tmp.Close()
+	if err != nil {
+		os.Remove(o.path + ".tmp")
+	}
+	return err
+}
+
+// replaceWithRemaining 用临时文件替换积压文件
+func (o *Outbox) replaceWithRemaining() error {
+	if err := os.Rename(o.path+".tmp", o.path); err != nil {
+		return err
+	}
+	o.count, o.size = countOutbox(o.path)
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var errOffline = errors.New("offline")
+
+// recorder 记录发送的消息，fail 为 true 时模拟断线
+type recorder struct {
+	sent []WSMessage
+	fail bool
+}
+
+func (r *recorder) send(line []byte) error {
+	if r.fail {
+		return errOffline
+	}
+	var msg WSMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return err
+	}
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func TestOutboxDeliverDirect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	o := NewOutbox(path)
+	r := &recorder{}
+
+	o.Deliver(WSTypeTaskResult, map[string]string{"log_id": "1"}, r.send)
+
+	if len(r.sent) != 1 || o.Len() != 0 {
+		t.Fatalf("expected direct send, sent=%d backlog=%d", len(r.sent), o.Len())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("outbox file should not be created, err=%v", err)
+	}
+}
+
+func TestOutboxDeliverKeepsOrderAndFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	o := NewOutbox(path)
+	r := &recorder{fail: true}
+
+	o.Deliver(WSTypeTaskLog, map[string]string{"content": "a"}, r.send)
+	// 已有积压时即使连接恢复也要追加到末尾，保证补传顺序
+	r.fail = false
+	o.Deliver(WSTypeTaskResult, map[string]string{"log_id": "1"}, r.send)
+
+	if len(r.sent) != 0 || o.Len() != 2 {
+		t.Fatalf("expected 2 queued messages, sent=%d backlog=%d", len(r.sent), o.Len())
+	}
+
+	sent, err := o.Flush(r.send)
+	if err != nil || sent != 2 {
+		t.Fatalf("Flush = %d, %v", sent, err)
+	}
+	if r.sent[0].Type != WSTypeTaskLog || r.sent[1].Type != WSTypeTaskResult {
+		t.Fatalf("unexpected order: %s, %s", r.sent[0].Type, r.sent[1].Type)
+	}
+	if o.Len() != 0 {
+		t.Fatalf("backlog should be empty, got %d", o.Len())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("outbox file should be removed, err=%v", err)
+	}
+}
+
+func TestOutboxFlushStopsOnFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	o := NewOutbox(path)
+	offline := &recorder{fail: true}
+	for _, id := range []string{"1", "2", "3"} {
+		o.Deliver(WSTypeTaskResult, map[string]string{"log_id": id}, offline.send)
+	}
+
+	// 第二条发送时断线，剩余两条保留
+	calls := 0
+	r := &recorder{}
+	sent, err := o.Flush(func(line []byte) error {
+		calls++
+		if calls == 2 {
+			return errOffline
+		}
+		return r.send(line)
+	})
+	if !errors.Is(err, errOffline) || sent != 1 {
+		t.Fatalf("Flush = %d, %v", sent, err)
+	}
+	if o.Len() != 2 {
+		t.Fatalf("expected 2 remaining, got %d", o.Len())
+	}
+
+	// 重新加载时从文件中恢复计数
+	if count, size := countOutbox(path); count != 2 || size == 0 {
+		t.Fatalf("countOutbox = %d, %d", count, size)
+	}
+	reloaded := NewOutbox(path)
+	sent, err = reloaded.Flush(r.send)
+	if err != nil || sent != 2 {
+		t.Fatalf("Flush after reload = %d, %v", sent, err)
+	}
+	var ids []string
+	for _, msg := range r.sent {
+		var data map[string]string
+		_ = json.Unmarshal(msg.Data, &data)
+		ids = append(ids, data["log_id"])
+	}
+	if len(ids) != 3 || ids[0] != "1" || ids[1] != "2" || ids[2] != "3" {
+		t.Fatalf("unexpected delivery order: %v", ids)
+	}
+}
+
+func TestOutboxDropsLogsWhenFull(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	o := NewOutbox(path)
+	r := &recorder{fail: true}
+	o.Deliver(WSTypeTaskLog, map[string]string{"content": "a"}, r.send)
+
+	o.size = outboxMaxSize
+	o.Deliver(WSTypeTaskLog, map[string]string{"content": "b"}, r.send)
+	o.Deliver(WSTypeTaskResult, map[string]string{"log_id": "1"}, r.send)
+
+	// 日志片段被丢弃，执行结果仍然保留
+	if o.Len() != 2 {
+		t.Fatalf("expected 2 queued messages, got %d", o.Len())
+	}
+}
+
+func TestCountOutboxMissingFile(t *testing.T) {
+	if count, size := countOutbox(filepath.Join(t.TempDir(), "missing.jsonl")); count != 0 || size != 0 {
+		t.Fatalf("countOutbox = %d, %d", count, size)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+// scriptsDirPlaceholder 任务命令与工作目录中的脚本目录代号，在 Agent 上解析为本地同步目录
+const scriptsDirPlaceholder = "$SCRIPTS_DIR$"
+
+// getScriptsDir 面板同步过来的脚本所在目录
+func getScriptsDir() string {
+	dir, err := filepath.Abs(filepath.Join(dataDir, "scripts"))
+	if err != nil {
+		return filepath.Join(dataDir, "scripts")
+	}
+	return dir
+}
+
+// resolveScriptsPath 将 $SCRIPTS_DIR$ 替换为本地脚本目录
+func resolveScriptsPath(s string) string {
+	if !strings.Contains(s, scriptsDirPlaceholder) {
+		return s
+	}
+	return strings.ReplaceAll(s, scriptsDirPlaceholder, getScriptsDir())
+}
+
+type scriptSyncRequest struct {
+	RequestID string                `json:"request_id"`
+	SyncID    string                `json:"sync_id"`
+	Path      string                `json:"path"`
+	Manifest  models.ScriptManifest `json:"manifest"`
+}
+
+// handleScriptSync 按面板下发的清单同步脚本目录，完成后回报结果
+func (a *Agent) handleScriptSync(data json.RawMessage) {
+	var req scriptSyncRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		logger.Errorf("解析脚本同步请求失败: %v", err)
+		return
+	}
+
+	go func() {
+		a.scriptSyncMu.Lock()
+		defer a.scriptSyncMu.Unlock()
+
+		result := models.ScriptSyncResult{RequestID: req.RequestID, SyncID: req.SyncID, Hash: req.Manifest.Hash}
+		downloaded, removed, err := a.syncScriptDir(&req)
+		result.Downloaded, result.Removed = downloaded, removed
+		if err != nil {
+			result.Error = err.Error()
+			logger.Warnf("[ScriptSync] 同步目录 %s 失败: %v", req.Path, err)
+		} else if downloaded > 0 || removed > 0 {
+			logger.Infof("[ScriptSync] 已同步目录 %s: 更新 %d 个文件，删除 %d 个文件", req.Path, downloaded, removed)
+		}
+		a.sendWSMessage(WSTypeScriptSyncResult, result)
+	}()
+}
+
+// syncScriptDir 对比本地文件摘要，仅下载有变化的文件并逐个原位替换
+// 只会删除此前由本规则同步过来、但已不在清单中的文件；Agent 本地自行创建的文件（包括 .git）不受影响，
+// 也不会替换整个目录，运行中任务的工作目录始终有效
+func (a *Agent) syncScriptDir(req *scriptSyncRequest) (int, int, error) {
+	rel := path.Clean(strings.ReplaceAll(req.Path, "\\", "/"))
+	if rel == ".." || strings.HasPrefix(rel, "../") || strings.HasPrefix(rel, "/") {
+		return 0, 0, fmt.Errorf("无效的同步目录: %s", req.Path)
+	}
+	target := filepath.Join(getScriptsDir(), filepath.FromSlash(rel))
+
+	state := loadScriptSyncState(scriptSyncStateFile())
+	tracked := state[req.SyncID]
+	if tracked != nil && tracked.Path != rel {
+		// 规则改了目录，旧目录中的文件不再由本规则管理
+		tracked = nil
+	}
+	var trackedFiles []string
+	if tracked != nil {
+		trackedFiles = tracked.Files
+	}
+
+	downloaded, removed, err := syncScriptFiles(target, req.Manifest.Files, trackedFiles, func(f models.ScriptManifestFile, dst string) error {
+		return a.downloadScriptFile(req.SyncID, f, dst)
+	})
+	if err != nil {
+		return downloaded, removed, err
+	}
+
+	files := make([]string, 0, len(req.Manifest.Files))
+	for _, f := range req.Manifest.Files {
+		files = append(files, f.Path)
+	}
+	state[req.SyncID] = &scriptSyncTracked{Path: rel, Files: files}
+	if err := saveScriptSyncState(scriptSyncStateFile(), state); err != nil {
+		logger.Warnf("[ScriptSync] 保存同步记录失败: %v", err)
+	}
+	return downloaded, removed, nil
+}
+
+// syncScriptFiles 按清单更新 target 下的文件，tracked 为上次同步的文件列表
+// 先把有变化的文件全部下载到同目录的临时文件，全部成功后再逐个重命名替换，下载失败时本地文件保持原样；
+// 删除只针对 tracked 中已不在清单里的文件，空目录保留
+func syncScriptFiles(target string, files []models.ScriptManifestFile, tracked []string, fetch func(f models.ScriptManifestFile, dst string) error) (int, int, error) {
+	wanted := make(map[string]struct{}, len(files))
+	var download []models.ScriptManifestFile
+	var chmod []models.ScriptManifestFile
+	for _, f := range files {
+		if !isSafeScriptPath(f.Path) {
+			return 0, 0, fmt.Errorf("无效的文件路径: %s", f.Path)
+		}
+		wanted[f.Path] = struct{}{}
+		dst := filepath.Join(target, filepath.FromSlash(f.Path))
+		info, err := os.Lstat(dst)
+		if err != nil || !info.Mode().IsRegular() || info.Size() != f.Size || hashLocalFile(dst) != f.Hash {
+			download = append(download, f)
+		} else if runtime.GOOS != "windows" && uint32(info.Mode().Perm()) != f.Mode {
+			chmod = append(chmod, f)
+		}
+	}
+
+	var stale []string
+	for _, p := range tracked {
+		if _, ok := wanted[p]; !ok && isSafeScriptPath(p) {
+			stale = append(stale, p)
+		}
+	}
+	if len(download) == 0 && len(chmod) == 0 && len(stale) == 0 {
+		return 0, 0, nil
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return 0, 0, err
+	}
+	root, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tmps := make([]string, 0, len(download))
+	defer func() {
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}()
+	for _, f := range download {
+		dst := filepath.Join(target, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return 0, 0, err
+		}
+		// 本地的符号链接目录不能把同步文件带到脚本目录之外
+		if !insideDir(root, filepath.Dir(dst)) {
+			return 0, 0, fmt.Errorf("文件 %s 的上级目录指向同步目录之外", f.Path)
+		}
+		tmp := dst + ".sync-tmp"
+		tmps = append(tmps, tmp)
+		if err := fetch(f, tmp); err != nil {
+			return 0, 0, err
+		}
+		os.Chmod(tmp, os.FileMode(f.Mode))
+	}
+	for i, f := range download {
+		dst := filepath.Join(target, filepath.FromSlash(f.Path))
+		if err := os.Rename(tmps[i], dst); err != nil {
+			return i, 0, fmt.Errorf("替换 %s 失败: %v", f.Path, err)
+		}
+	}
+	for _, f := range chmod {
+		os.Chmod(filepath.Join(target, filepath.FromSlash(f.Path)), os.FileMode(f.Mode))
+	}
+
+	removed := 0
+	for _, p := range stale {
+		file := filepath.Join(target, filepath.FromSlash(p))
+		if !insideDir(root, filepath.Dir(file)) {
+			continue
+		}
+		if info, err := os.Lstat(file); err != nil || info.IsDir() {
+			continue
+		}
+		if err := os.Remove(file); err == nil {
+			removed++
+		}
+	}
+	return len(download), removed, nil
+}
+
+// insideDir 判断 dir 解析符号链接后是否仍位于 root 内
+func insideDir(root, dir string) bool {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, real)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// scriptSyncTracked 一条同步规则上次同步到本地的目录与文件列表
+type scriptSyncTracked struct {
+	Path  string   `json:"path"`
+	Files []string `json:"files"`
+}
+
+// scriptSyncStateFile 记录各同步规则已同步文件的位置，键为规则 ID
+func scriptSyncStateFile() string { return filepath.Join(dataDir, "script_sync_state.json") }
+
+func loadScriptSyncState(file string) map[string]*scriptSyncTracked {
+	state := make(map[string]*scriptSyncTracked)
+	if data, err := os.ReadFile(file); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+func saveScriptSyncState(file string, state map[string]*scriptSyncTracked) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// downloadScriptFile 从面板下载文件并校验摘要
+func (a *Agent) downloadScriptFile(syncID string, f models.ScriptManifestFile, dst string) error {
+	resp, err := a.doRequest("GET", "/api/agent/scripts/file?sync_id="+url.QueryEscape(syncID)+"&path="+url.QueryEscape(f.Path), nil)
+	if err != nil {
+		return fmt.Errorf("下载 %s 失败: %v", f.Path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载 %s 失败: HTTP %d", f.Path, resp.StatusCode)
+	}
+	// 面板的错误响应为 JSON（文件本身以 http.ServeFile 返回，不带 charset）
+	if resp.Header.Get("Content-Type") == "application/json; charset=utf-8" {
+		var apiResp struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiResp) == nil && apiResp.Code != 200 {
+			return fmt.Errorf("下载 %s 失败: %s", f.Path, apiResp.Msg)
+		}
+		return fmt.Errorf("下载 %s 失败: 响应异常", f.Path)
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, h), resp.Body)
+	out.Close()
+	if err != nil {
+		return fmt.Errorf("下载 %s 失败: %v", f.Path, err)
+	}
+	if hex.EncodeToString(h.Sum(nil)) != f.Hash {
+		return fmt.Errorf("文件 %s 校验失败（下载期间可能被修改）", f.Path)
+	}
+	return nil
+}
+
+func isSafeScriptPath(p string) bool {
+	clean := path.Clean(p)
+	return clean == p && clean != "." && clean != ".." && !strings.HasPrefix(clean, "../") && !strings.HasPrefix(clean, "/")
+}
+
+func hashLocalFile(file string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+func manifestFile(p, content string) models.ScriptManifestFile {
+	sum := sha256.Sum256([]byte(content))
+	return models.ScriptManifestFile{Path: p, Size: int64(len(content)), Hash: hex.EncodeToString(sum[:]), Mode: 0644}
+}
+
+func TestSyncScriptFilesKeepsUntrackedFiles(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "tool")
+	contents := map[string]string{"run.sh": "echo v1", "lib/a.py": "print(1)"}
+	fetch := func(f models.ScriptManifestFile, dst string) error {
+		return os.WriteFile(dst, []byte(contents[f.Path]), 0644)
+	}
+
+	files := []models.ScriptManifestFile{manifestFile("run.sh", "echo v1"), manifestFile("lib/a.py", "print(1)")}
+	if n, _, err := syncScriptFiles(target, files, nil, fetch); err != nil || n != 2 {
+		t.Fatalf("first sync: downloaded=%d err=%v", n, err)
+	}
+
+	// Agent 本地自行创建的文件与 .git
+	os.MkdirAll(filepath.Join(target, ".git"), 0755)
+	os.WriteFile(filepath.Join(target, ".git", "HEAD"), []byte("ref"), 0644)
+	os.WriteFile(filepath.Join(target, "local.log"), []byte("x"), 0644)
+	// 正在运行的任务以 lib 为工作目录
+	before, _ := os.Stat(filepath.Join(target, "lib"))
+
+	contents["run.sh"] = "echo v2"
+	next := []models.ScriptManifestFile{manifestFile("run.sh", "echo v2")}
+	downloaded, removed, err := syncScriptFiles(target, next, []string{"run.sh", "lib/a.py"}, fetch)
+	if err != nil || downloaded != 1 || removed != 1 {
+		t.Fatalf("second sync: downloaded=%d removed=%d err=%v", downloaded, removed, err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(target, "run.sh")); string(data) != "echo v2" {
+		t.Fatalf("run.sh = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(target, "lib", "a.py")); !os.IsNotExist(err) {
+		t.Fatalf("removed file still exists: %v", err)
+	}
+	for _, p := range []string{".git/HEAD", "local.log"} {
+		if _, err := os.Stat(filepath.Join(target, filepath.FromSlash(p))); err != nil {
+			t.Fatalf("untracked %s was touched: %v", p, err)
+		}
+	}
+	after, err := os.Stat(filepath.Join(target, "lib"))
+	if err != nil || !os.SameFile(before, after) {
+		t.Fatalf("working directory lib was replaced: %v", err)
+	}
+}
+
+func TestSyncScriptFilesFailedDownloadKeepsOldFiles(t *testing.T) {
+	target := t.TempDir()
+	os.WriteFile(filepath.Join(target, "a.sh"), []byte("old"), 0644)
+
+	files := []models.ScriptManifestFile{manifestFile("a.sh", "new"), manifestFile("b.sh", "b")}
+	fetch := func(f models.ScriptManifestFile, dst string) error {
+		if f.Path == "b.sh" {
+			return os.ErrDeadlineExceeded
+		}
+		return os.WriteFile(dst, []byte("new"), 0644)
+	}
+	if _, _, err := syncScriptFiles(target, files, []string{"a.sh"}, fetch); err == nil {
+		t.Fatal("expected download error")
+	}
+	if data, _ := os.ReadFile(filepath.Join(target, "a.sh")); string(data) != "old" {
+		t.Fatalf("a.sh = %q, want old content kept", data)
+	}
+	entries, _ := os.ReadDir(target)
+	if len(entries) != 1 {
+		t.Fatalf("temporary files left behind: %v", entries)
+	}
+}
@@ -98,6 +98,8 @@ func cmdStop() {
 }
 
 func cmdStatus() {
+	defer printOutboxStatus()
+
 	pid := readPidFile()
 	if pid == 0 {
 		fmt.Println("状态: 未运行")
@@ -113,6 +115,16 @@ func cmdStatus() {
 	fmt.Printf("状态: 运行中 (PID: %d)\n", pid)
 }
 
+// printOutboxStatus 显示断线期间积压、尚未补传的消息
+func printOutboxStatus() {
+	count, size := countOutbox(getOutboxFile())
+	if count == 0 {
+		fmt.Println("积压消息: 无")
+		return
+	}
+	fmt.Printf("积压消息: %d 条 (%.1f KB)，连接服务端后自动补传\n", count, float64(size)/1024)
+}
+
 func isProcessRunning(pid int) bool {
 	process, err := os.FindProcess(pid)
 	if err != nil {
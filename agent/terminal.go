@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/utils"
+
+	"github.com/creack/pty"
+)
+
+// terminalSession 面板打开的一路远程终端
+type terminalSession struct {
+	id     string
+	cmd    *exec.Cmd
+	ptmx   *os.File       // PTY 模式
+	stdin  io.WriteCloser // pipe 模式（Windows）
+	closed bool
+	mu     sync.Mutex
+}
+
+func (t *terminalSession) write(data []byte) error {
+	if t.ptmx != nil {
+		_, err := t.ptmx.Write(data)
+		return err
+	}
+	_, err := t.stdin.Write(data)
+	return err
+}
+
+// kill 结束 Shell，读取协程随之退出
+func (t *terminalSession) kill() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	t.closed = true
+	if t.cmd.Process != nil {
+		t.cmd.Process.Kill()
+	}
+	if t.ptmx != nil {
+		t.ptmx.Close()
+	}
+	if t.stdin != nil {
+		t.stdin.Close()
+	}
+}
+
+type terminalRequest struct {
+	SessionID string `json:"session_id"`
+	Data      []byte `json:"data"`
+	Rows      int    `json:"rows"`
+	Cols      int    `json:"cols"`
+}
+
+func parseTerminalRequest(data json.RawMessage) *terminalRequest {
+	var req terminalRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.SessionID == "" {
+		return nil
+	}
+	return &req
+}
+
+func (a *Agent) sendTerminalClosed(sessionID, reason string) {
+	a.sendWSMessage(WSTypeTerminalClosed, map[string]interface{}{
+		"session_id": sessionID,
+		"reason":     reason,
+	})
+}
+
+func (a *Agent) getTerminal(sessionID string) *terminalSession {
+	a.terminalMu.Lock()
+	defer a.terminalMu.Unlock()
+	return a.terminals[sessionID]
+}
+
+// handleTerminalOpen 启动 Shell 并开始转发输出
+func (a *Agent) handleTerminalOpen(data json.RawMessage) {
+	req := parseTerminalRequest(data)
+	if req == nil {
+		return
+	}
+	if !a.config.AllowTerminal {
+		logger.Warnf("[Terminal] 拒绝远程终端 #%s：未开启 allow_terminal", req.SessionID)
+		a.sendTerminalClosed(req.SessionID, "Agent 未开启远程终端（配置 allow_terminal = true）")
+		return
+	}
+
+	t := &terminalSession{id: req.SessionID, cmd: utils.NewShellCmd()}
+	if home, err := os.UserHomeDir(); err == nil {
+		t.cmd.Dir = home
+	}
+
+	var readers []io.Reader
+	mode := "pty"
+	if runtime.GOOS == "windows" {
+		mode = "pipe"
+		t.cmd.Env = os.Environ()
+		stdin, err := t.cmd.StdinPipe()
+		if err != nil {
+			a.sendTerminalClosed(req.SessionID, "启动 Shell 失败: "+err.Error())
+			return
+		}
+		stdout, _ := t.cmd.StdoutPipe()
+		stderr, _ := t.cmd.StderrPipe()
+		if err := t.cmd.Start(); err != nil {
+			a.sendTerminalClosed(req.SessionID, "启动 Shell 失败: "+err.Error())
+			return
+		}
+		t.stdin = stdin
+		readers = []io.Reader{stdout, stderr}
+	} else {
+		t.cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+		ptmx, err := pty.Start(t.cmd)
+		if err != nil {
+			a.sendTerminalClosed(req.SessionID, "启动 Shell 失败: "+err.Error())
+			return
+		}
+		if req.Rows > 0 && req.Cols > 0 {
+			pty.Setsize(ptmx, &pty.Winsize{Rows: uint16(req.Rows), Cols: uint16(req.Cols)})
+		}
+		t.ptmx = ptmx
+		readers = []io.Reader{ptmx}
+	}
+
+	a.terminalMu.Lock()
+	a.terminals[t.id] = t
+	a.terminalMu.Unlock()
+	logger.Infof("[Terminal] 远程终端 #%s 已打开 (%s)", t.id, mode)
+
+	a.sendWSMessage(WSTypeTerminalOpened, map[string]interface{}{
+		"session_id": t.id,
+		"mode":       mode,
+	})
+
+	var wg sync.WaitGroup
+	for _, r := range readers {
+		wg.Add(1)
+		go func(r io.Reader) {
+			defer wg.Done()
+			buf := make([]byte, 4096)
+			for {
+				n, err := r.Read(buf)
+				if n > 0 {
+					a.sendWSMessage(WSTypeTerminalOutput, map[string]interface{}{
+						"session_id": t.id,
+						"data":       buf[:n],
+					})
+				}
+				if err != nil {
+					return
+				}
+			}
+		}(r)
+	}
+
+	go func() {
+		wg.Wait()
+		t.cmd.Wait()
+		t.kill()
+
+		a.terminalMu.Lock()
+		delete(a.terminals, t.id)
+		a.terminalMu.Unlock()
+		logger.Infof("[Terminal] 远程终端 #%s 已结束", t.id)
+		a.sendTerminalClosed(t.id, "Shell 已退出")
+	}()
+}
+
+func (a *Agent) handleTerminalInput(data json.RawMessage) {
+	req := parseTerminalRequest(data)
+	if req == nil {
+		return
+	}
+	if t := a.getTerminal(req.SessionID); t != nil {
+		if err := t.write(req.Data); err != nil {
+			t.kill()
+		}
+	}
+}
+
+func (a *Agent) handleTerminalResize(data json.RawMessage) {
+	req := parseTerminalRequest(data)
+	if req == nil || req.Rows <= 0 || req.Cols <= 0 {
+		return
+	}
+	// pipe 模式不支持调整尺寸
+	if t := a.getTerminal(req.SessionID); t != nil && t.ptmx != nil {
+		pty.Setsize(t.ptmx, &pty.Winsize{Rows: uint16(req.Rows), Cols: uint16(req.Cols)})
+	}
+}
+
+func (a *Agent) handleTerminalClose(data json.RawMessage) {
+	req := parseTerminalRequest(data)
+	if req == nil {
+		return
+	}
+	if t := a.getTerminal(req.SessionID); t != nil {
+		t.kill()
+	}
+}
+
+// closeAllTerminals 与面板断开时结束全部远程终端
+func (a *Agent) closeAllTerminals() {
+	a.terminalMu.Lock()
+	defer a.terminalMu.Unlock()
+	for _, t := range a.terminals {
+		t.kill()
+	}
+}
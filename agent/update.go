@@ -2,7 +2,15 @@ package main
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -12,88 +20,253 @@ import (
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/models"
 )
 
-// selfUpdate 自动更新
-func (a *Agent) selfUpdate() {
-	// 获取当前可执行文件路径
-	exePath, err := os.Executable()
+const (
+	// updateHealthTimeout 新版本启动后需在该时间内连上面板，否则回滚
+	updateHealthTimeout = 2 * time.Minute
+	// updateMaxAttempts 新版本启动次数上限，反复崩溃重启超过该次数直接回滚
+	updateMaxAttempts = 3
+)
+
+func updateStateFile() string { return filepath.Join(dataDir, "update_state.json") }
+
+// updateState 自更新状态，替换程序后写入，新版本启动时据此做健康检查与回滚
+type updateState struct {
+	FromVersion string `json:"from_version"`
+	ToVersion   string `json:"to_version"`
+	Binary      string `json:"binary"` // 新版本程序路径
+	Backup      string `json:"backup"` // 旧版本备份路径
+	StartedAt   int64  `json:"started_at"`
+	Attempts    int    `json:"attempts"` // 新版本已启动次数
+	RolledBack  bool   `json:"rolled_back"`
+	Message     string `json:"message"`
+}
+
+func loadUpdateState() *updateState {
+	data, err := os.ReadFile(updateStateFile())
 	if err != nil {
-		log.Errorf("获取可执行文件路径失败: %v", err)
+		return nil
+	}
+	var state updateState
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Warnf("更新状态文件损坏，已忽略: %v", err)
+		os.Remove(updateStateFile())
+		return nil
+	}
+	return &state
+}
+
+func saveUpdateState(state *updateState) error {
+	data, _ := json.MarshalIndent(state, "", "  ")
+	return os.WriteFile(updateStateFile(), data, 0644)
+}
+
+// reportUpdate 向面板上报自更新进度
+func (a *Agent) reportUpdate(status, fromVersion, toVersion, message string) {
+	a.sendWSMessage(WSTypeUpdateStatus, map[string]string{
+		"status":       status,
+		"from_version": fromVersion,
+		"to_version":   toVersion,
+		"message":      message,
+	})
+}
+
+// selfUpdate 自动更新：校验签名与 SHA-256 后替换程序并重启，新版本启动后做健康检查
+func (a *Agent) selfUpdate() {
+	if !a.updating.CompareAndSwap(false, true) {
 		return
 	}
-	exePath, _ = filepath.Abs(exePath)
+	defer a.updating.Store(false)
 
-	// 下载新版本 tar.gz
-	downloadURL := a.config.ServerURL + "/api/agent/download?os=" + runtime.GOOS + "&arch=" + runtime.GOARCH
-	req, err := http.NewRequest("GET", downloadURL, nil)
-	if err != nil {
-		log.Errorf("创建下载请求失败: %v", err)
+	a.updateMu.Lock()
+	pending := a.pendingUpdate
+	a.updateMu.Unlock()
+	if pending != nil {
+		log.Infof("上次更新（%s）尚未确认，跳过本次更新", pending.ToVersion)
 		return
 	}
-	req.Header.Set("Authorization", "Bearer "+a.config.Token)
 
-	client := &http.Client{Timeout: 5 * time.Minute}
-	resp, err := client.Do(req)
+	manifest, err := a.fetchUpdateManifest()
 	if err != nil {
-		log.Errorf("下载新版本失败: %v", err)
+		log.Errorf("获取更新清单失败: %v", err)
+		a.reportUpdate(constant.AgentUpdateFailed, Version, "", err.Error())
 		return
 	}
+	if err := a.installUpdate(manifest); err != nil {
+		log.Errorf("更新到 %s 失败: %v", manifest.Version, err)
+		a.reportUpdate(constant.AgentUpdateFailed, Version, manifest.Version, err.Error())
+		return
+	}
+
+	log.Infof("已更新到 %s，正在重启...", manifest.Version)
+	a.reportUpdate(constant.AgentUpdateUpdating, Version, manifest.Version, "")
+	a.restart()
+}
+
+// fetchUpdateManifest 获取更新包清单，未配置信任的签名公钥时不更新
+func (a *Agent) fetchUpdateManifest() (*models.AgentUpdateManifest, error) {
+	if _, err := a.updatePublicKey(); err != nil {
+		return nil, err
+	}
+	resp, err := a.doRequest("GET", "/api/agent/update/manifest?os="+runtime.GOOS+"&arch="+runtime.GOARCH, nil)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
+	var apiResp struct {
+		Code int                        `json:"code"`
+		Msg  string                     `json:"msg"`
+		Data models.AgentUpdateManifest `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: HTTP %d（面板版本过旧时不支持签名更新）", resp.StatusCode)
+	}
+	if apiResp.Code != 200 {
+		return nil, fmt.Errorf("%s", apiResp.Msg)
+	}
+	m := &apiResp.Data
+	if m.OS != runtime.GOOS || m.Arch != runtime.GOARCH {
+		return nil, fmt.Errorf("更新清单平台 %s/%s 与本机 %s/%s 不符", m.OS, m.Arch, runtime.GOOS, runtime.GOARCH)
+	}
+	if m.Signature == "" {
+		return nil, fmt.Errorf("更新清单缺少签名")
+	}
+	return m, nil
+}
+
+// updatePublicKey 返回信任的更新包签名公钥：配置 update_public_key 优先，其次为发布时内置的公钥。
+// 公钥只来自本地，不接受面板下发，两者都没有时拒绝自动更新
+func (a *Agent) updatePublicKey() (ed25519.PublicKey, error) {
+	key, source := strings.TrimSpace(a.config.UpdatePublicKey), "配置 update_public_key"
+	if key == "" {
+		key, source = strings.TrimSpace(UpdatePublicKey), "内置签名公钥"
+	}
+	if key == "" {
+		return nil, fmt.Errorf("未配置更新包签名公钥（update_public_key），拒绝自动更新")
+	}
+	pub, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%s 格式错误", source)
+	}
+	return ed25519.PublicKey(pub), nil
+}
+
+// downloadUpdate 下载更新包，比对大小与 SHA-256 并校验发布签名
+func (a *Agent) downloadUpdate(m *models.AgentUpdateManifest) ([]byte, error) {
+	pub, err := a.updatePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	baseURL, client, cert := a.apiTarget()
+	req, err := http.NewRequest("GET", baseURL+"/api/agent/download?os="+runtime.GOOS+"&arch="+runtime.GOARCH, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		req.Header.Set("Authorization", "Bearer "+a.config.Token)
+	}
+
+	// 复用 mTLS 传输层，仅放宽超时
+	downloadClient := &http.Client{Timeout: 5 * time.Minute, Transport: client.Transport}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		log.Errorf("下载新版本失败: HTTP %d", resp.StatusCode)
-		return
+		return nil, fmt.Errorf("下载失败: HTTP %d", resp.StatusCode)
 	}
 
-	// 读取 tar.gz 内容
-	gzReader, err := gzip.NewReader(resp.Body)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, m.Size+1))
 	if err != nil {
-		log.Errorf("解压 gzip 失败: %v", err)
-		return
+		return nil, err
 	}
-	defer gzReader.Close()
+	if err := verifyUpdateBundle(pub, m, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
 
-	tarReader := tar.NewReader(gzReader)
+// verifyUpdateBundle 比对更新包大小与 SHA-256，并用信任的公钥校验对更新包内容的分离签名
+func verifyUpdateBundle(pub ed25519.PublicKey, m *models.AgentUpdateManifest, data []byte) error {
+	if int64(len(data)) != m.Size {
+		return fmt.Errorf("更新包大小不符: %d，期望 %d", len(data), m.Size)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != m.SHA256 {
+		return fmt.Errorf("更新包 SHA-256 校验失败")
+	}
+	sig, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil || !ed25519.Verify(pub, data, sig) {
+		return fmt.Errorf("更新包签名校验失败")
+	}
+	return nil
+}
 
-	// 解压并找到二进制文件
-	var newBinary []byte
-	binaryName := "baihu-agent"
-	if runtime.GOOS == "windows" {
-		binaryName = "baihu-agent.exe"
+// extractBinary 从 tar.gz 中取出 Agent 程序
+func extractBinary(data []byte, binaryName string) ([]byte, error) {
+	gzReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解压 gzip 失败: %v", err)
 	}
+	defer gzReader.Close()
 
+	tarReader := tar.NewReader(gzReader)
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			log.Errorf("读取 tar 失败: %v", err)
-			return
+			return nil, fmt.Errorf("读取 tar 失败: %v", err)
 		}
-
 		if header.Typeflag == tar.TypeReg && header.Name == binaryName {
-			newBinary, err = io.ReadAll(tarReader)
-			if err != nil {
-				log.Errorf("读取二进制文件失败: %v", err)
-				return
-			}
-			break
+			return io.ReadAll(tarReader)
 		}
 	}
+	return nil, fmt.Errorf("tar.gz 中未找到 %s", binaryName)
+}
 
-	if newBinary == nil {
-		log.Errorf("tar.gz 中未找到 %s", binaryName)
-		return
+// installUpdate 下载并校验更新包，预检新程序后替换当前程序，旧程序保留为 .bak 供回滚
+func (a *Agent) installUpdate(m *models.AgentUpdateManifest) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取可执行文件路径失败: %v", err)
+	}
+	exePath, _ = filepath.Abs(exePath)
+
+	data, err := a.downloadUpdate(m)
+	if err != nil {
+		return err
+	}
+	binaryName := "baihu-agent"
+	if runtime.GOOS == "windows" {
+		binaryName = "baihu-agent.exe"
+	}
+	newBinary, err := extractBinary(data, binaryName)
+	if err != nil {
+		return err
 	}
 
 	// 保存到临时文件（放到 data 目录）
 	os.MkdirAll(dataDir, 0755)
 	tmpFile := filepath.Join(dataDir, binaryName+".new")
 	if err := os.WriteFile(tmpFile, newBinary, 0755); err != nil {
-		log.Errorf("保存新版本失败: %v", err)
-		return
+		return fmt.Errorf("保存新版本失败: %v", err)
+	}
+
+	// 预检：新程序能在本机运行且版本与清单一致
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	out, err := exec.CommandContext(ctx, tmpFile, "version").Output()
+	cancel()
+	if err != nil || !strings.Contains(string(out), "v"+m.Version) {
+		os.Remove(tmpFile)
+		return fmt.Errorf("新版本预检失败: %v %s", err, strings.TrimSpace(string(out)))
 	}
 
 	// 计算基础路径（去掉所有 .bak 后缀）
@@ -103,35 +276,125 @@ func (a *Agent) selfUpdate() {
 	}
 	backupFile := basePath + ".bak"
 
-	// 如果当前运行的就是 .bak 文件，直接删除它（更新后会用新版本）
-	// 否则需要备份当前文件
+	// 当前运行的就是 .bak 文件时它本身即为备份，否则备份当前文件
 	if exePath != backupFile {
 		os.Remove(backupFile)
 		if err := os.Rename(exePath, backupFile); err != nil {
-			log.Errorf("备份旧版本失败: %v", err)
 			os.Remove(tmpFile)
-			return
+			return fmt.Errorf("备份旧版本失败: %v", err)
 		}
 	}
 
 	// 替换为新版本（放到 basePath，即不带 .bak 的路径）
 	if err := os.Rename(tmpFile, basePath); err != nil {
-		log.Errorf("替换新版本失败: %v", err)
 		if exePath != backupFile {
 			os.Rename(backupFile, exePath) // 恢复旧版本
 		}
+		return fmt.Errorf("替换新版本失败: %v", err)
+	}
+
+	state := &updateState{
+		FromVersion: Version,
+		ToVersion:   m.Version,
+		Binary:      basePath,
+		Backup:      backupFile,
+		StartedAt:   time.Now().Unix(),
+	}
+	if err := saveUpdateState(state); err != nil {
+		// 没有状态文件新版本无法回滚，恢复旧版本
+		os.Rename(basePath, tmpFile)
+		os.Rename(backupFile, exePath)
+		os.Remove(tmpFile)
+		return fmt.Errorf("保存更新状态失败: %v", err)
+	}
+	return nil
+}
+
+// checkPendingUpdate 启动时检查上次更新：新版本开始健康检查，反复启动失败时回滚；已回滚的待连上面板后上报
+func (a *Agent) checkPendingUpdate() {
+	state := loadUpdateState()
+	if state == nil {
+		return
+	}
+	if !state.RolledBack {
+		if Version != state.ToVersion {
+			log.Warnf("当前版本 %s 与待确认的更新版本 %s 不符，放弃健康检查", Version, state.ToVersion)
+			os.Remove(updateStateFile())
+			return
+		}
+		state.Attempts++
+		if state.Attempts > updateMaxAttempts {
+			a.rollbackUpdate(state, fmt.Sprintf("新版本连续 %d 次启动未通过健康检查", updateMaxAttempts))
+			return
+		}
+		saveUpdateState(state)
+		log.Infof("已更新到 %s，等待连接面板确认（第 %d 次启动，%s 内未连上将回滚到 %s）",
+			state.ToVersion, state.Attempts, updateHealthTimeout, state.FromVersion)
+		go a.watchUpdateHealth(state)
+	}
+
+	a.updateMu.Lock()
+	a.pendingUpdate = state
+	a.updateMu.Unlock()
+}
+
+// watchUpdateHealth 新版本未在 updateHealthTimeout 内连上面板时回滚
+func (a *Agent) watchUpdateHealth(state *updateState) {
+	select {
+	case <-a.stopCh:
 		return
+	case <-time.After(updateHealthTimeout):
 	}
 
-	// 如果之前运行的是 .bak 文件，现在可以删除它了
-	if exePath == backupFile {
-		os.Remove(exePath)
+	a.updateMu.Lock()
+	pending := a.pendingUpdate == state
+	a.updateMu.Unlock()
+	if pending {
+		a.rollbackUpdate(state, fmt.Sprintf("新版本 %s 内未能连接面板", updateHealthTimeout))
 	}
+}
 
-	log.Info("更新完成，正在重启...")
+// confirmPendingUpdate 收到面板心跳响应时确认更新成功，或上报此前的回滚
+func (a *Agent) confirmPendingUpdate() {
+	a.updateMu.Lock()
+	state := a.pendingUpdate
+	a.pendingUpdate = nil
+	a.updateMu.Unlock()
+	if state == nil {
+		return
+	}
 
-	// 重启服务
-	a.restart()
+	if state.RolledBack {
+		log.Warnf("已回滚到 %s: %s", Version, state.Message)
+		a.reportUpdate(constant.AgentUpdateRolledBack, state.FromVersion, state.ToVersion, state.Message)
+	} else {
+		log.Infof("已确认更新到 %s", state.ToVersion)
+		a.reportUpdate(constant.AgentUpdateSucceeded, state.FromVersion, state.ToVersion, "")
+	}
+	os.Remove(updateStateFile())
+}
+
+// rollbackUpdate 恢复旧版本程序并重启，失败的新程序保留为 .failed 便于排查
+func (a *Agent) rollbackUpdate(state *updateState, reason string) {
+	log.Errorf("更新到 %s 失败，回滚到 %s: %s", state.ToVersion, state.FromVersion, reason)
+
+	failedFile := state.Binary + ".failed"
+	os.Remove(failedFile)
+	if err := os.Rename(state.Binary, failedFile); err != nil {
+		log.Errorf("回滚失败，无法移走新版本: %v", err)
+		return
+	}
+	if err := os.Rename(state.Backup, state.Binary); err != nil {
+		log.Errorf("回滚失败，无法恢复旧版本: %v", err)
+		os.Rename(failedFile, state.Binary)
+		os.Remove(updateStateFile())
+		return
+	}
+
+	state.RolledBack = true
+	state.Message = reason
+	saveUpdateState(state)
+	a.execBinary(state.Binary)
 }
 
 // restart 重启服务
@@ -143,19 +406,23 @@ func (a *Agent) restart() {
 	for strings.HasSuffix(basePath, ".bak") {
 		basePath = strings.TrimSuffix(basePath, ".bak")
 	}
+	a.execBinary(basePath)
+}
 
+// execBinary 以指定程序替换当前进程
+func (a *Agent) execBinary(path string) {
 	// 删除 PID 文件，避免新进程检测到旧 PID 而拒绝启动
 	removePidFile()
 
 	if runtime.GOOS == "windows" {
 		// Windows: 启动新进程后退出
-		cmd := exec.Command(basePath, "start")
+		cmd := exec.Command(path, "start")
 		cmd.Start()
 		os.Exit(0)
 	} else {
 		// Linux/macOS: 使用 exec 替换当前进程，直接运行（不需要 daemon）
 		// 因为 syscall.Exec 会替换当前进程，当前进程本身就是 daemon
 		// --restart 标记告诉新进程这是重启，只输出到文件
-		syscall.Exec(basePath, []string{basePath, "run", "--restart"}, os.Environ())
+		syscall.Exec(path, []string{path, "run", "--restart"}, os.Environ())
 	}
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+func TestVerifyUpdateBundle(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	data := []byte("bundle")
+	sum := sha256.Sum256(data)
+	manifest := func(signer ed25519.PrivateKey) *models.AgentUpdateManifest {
+		return &models.AgentUpdateManifest{
+			SHA256:    hex.EncodeToString(sum[:]),
+			Size:      int64(len(data)),
+			Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(signer, data)),
+		}
+	}
+
+	if err := verifyUpdateBundle(pub, manifest(key), data); err != nil {
+		t.Fatalf("valid bundle rejected: %v", err)
+	}
+	// 面板自行签名（非发布私钥）的更新包不能通过
+	if err := verifyUpdateBundle(pub, manifest(otherKey), data); err == nil {
+		t.Fatal("bundle signed by another key accepted")
+	}
+	// 面板同时替换更新包与摘要也不能通过
+	tampered := []byte("bundlx")
+	m := manifest(key)
+	tamperedSum := sha256.Sum256(tampered)
+	m.SHA256 = hex.EncodeToString(tamperedSum[:])
+	if err := verifyUpdateBundle(pub, m, tampered); err == nil {
+		t.Fatal("tampered bundle accepted")
+	}
+}
+
+func TestUpdatePublicKeyIsLocalOnly(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	encoded := base64.StdEncoding.EncodeToString(pub)
+
+	saved := UpdatePublicKey
+	t.Cleanup(func() { UpdatePublicKey = saved })
+
+	UpdatePublicKey = ""
+	a := &Agent{config: &Config{}}
+	if _, err := a.updatePublicKey(); err == nil {
+		t.Fatal("update allowed without a pinned public key")
+	}
+
+	UpdatePublicKey = encoded
+	if got, err := a.updatePublicKey(); err != nil || !got.Equal(pub) {
+		t.Fatalf("built-in key not used: %v", err)
+	}
+
+	a.config.UpdatePublicKey = "invalid"
+	if _, err := a.updatePublicKey(); err == nil {
+		t.Fatal("invalid configured key accepted")
+	}
+}
@@ -16,10 +16,21 @@ if (!TOKEN || !CHANNEL) {
     throw new Error(`缺少必要的环境变量以使用 baihu 模块: ${missing.join(", ")}。请在白虎面板的任务设置中配置这些 Key。`);
 }
 
+/**
+ * 可选的富文本字段，渠道不支持的格式会由面板自动降级为纯文本
+ */
+const RICH_FIELDS = ['markdown', 'html', 'url', 'image_url', 'at_mobiles', 'at_user_ids', 'at_all'];
+
 /**
  * 发送通知的辅助函数 (仅使用 Node.js 标准库)
+ * extra 支持: markdown, html, url, image_url, at_mobiles, at_user_ids, at_all
+ * 兼容 notify(title, text, extra) 写法
  */
-function notify(title, text, channelId) {
+function notify(title, text, channelId, extra) {
+    if (channelId && typeof channelId === 'object') {
+        extra = channelId;
+        channelId = undefined;
+    }
     const notifyUrl = process.env.BHPKG_NOTIFY_URL || 'http://localhost:8052/api/v1/notify/send';
     const cid = channelId || CHANNEL;
 
@@ -29,11 +40,17 @@ function notify(title, text, channelId) {
         const parsedUrl = new URL(notifyUrl);
         const protocol = parsedUrl.protocol === 'https:' ? https : http;
         
-        const data = JSON.stringify({
+        const body = {
             channel_id: cid,
             title: title || '系统通知',
             text: text
-        });
+        };
+        for (const key of RICH_FIELDS) {
+            if (extra && extra[key] !== undefined && extra[key] !== null) {
+                body[key] = extra[key];
+            }
+        }
+        const data = JSON.stringify(body);
 
         const options = {
             hostname: parsedUrl.hostname,
@@ -1,10 +1,11 @@
 import os
 from .notify import notify as _notify
 
-def notify(title, text):
+def notify(title, text, **options):
     """
     发送内建通知。
     会在调用时校验环境变量：BHPKG_NOTIFY_TOKEN, BHPKG_NOTIFY_CHANNEL
+    可选参数：markdown, html, url, image_url, at_mobiles, at_user_ids, at_all, channel_id
     """
     _TOKEN = os.environ.get("BHPKG_NOTIFY_TOKEN")
     _CHANNEL = os.environ.get("BHPKG_NOTIFY_CHANNEL")
@@ -17,6 +18,7 @@ def notify(title, text):
         error_msg = f"缺少必要的环境变量以使用 baihu 模块: {', '.join(missing)}。请在白虎面板的任务设置中配置指定的 Key。"
         raise RuntimeError(error_msg)
     
-    return _notify(title, text)
+    channel_id = options.pop("channel_id", None)
+    return _notify(title, text, channel_id, **options)
 
 __all__ = ['notify']
@@ -2,10 +2,18 @@ import os
 import json
 import urllib.request
 
-def notify(title, text, channel_id=None):
+# 可选的富文本字段，渠道不支持的格式会由面板自动降级为纯文本
+RICH_FIELDS = ("markdown", "html", "url", "image_url", "at_mobiles", "at_user_ids", "at_all")
+
+def notify(title, text, channel_id=None, **options):
     """
     发送内建通知。
+    options 支持: markdown, html, url, image_url, at_mobiles, at_user_ids, at_all
     """
+    unknown = [k for k in options if k not in RICH_FIELDS]
+    if unknown:
+        raise TypeError(f"不支持的通知参数: {', '.join(unknown)}")
+
     token = os.environ.get("BHPKG_NOTIFY_TOKEN")
     url = os.environ.get("BHPKG_NOTIFY_URL", "http://localhost:8052/api/v1/notify/send")
     default_channel = os.environ.get("BHPKG_NOTIFY_CHANNEL")
@@ -20,6 +28,9 @@ def notify(title, text, channel_id=None):
         "title": title,
         "text": text
     }
+    for key, value in options.items():
+        if value is not None:
+            payload[key] = value
     
     data = json.dumps(payload).encode('utf-8')
     req = urllib.request.Request(url, data=data, method='POST')
@@ -1,14 +1,21 @@
 package bootstrap
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/engigu/baihu-panel/internal/constant"
 	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/eventbus"
 	"github.com/engigu/baihu-panel/internal/logger"
 	"github.com/engigu/baihu-panel/internal/router"
 	"github.com/engigu/baihu-panel/internal/services"
@@ -17,6 +24,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout 优雅停止时各阶段的最长等待时间
+const shutdownTimeout = 10 * time.Second
+
 type App struct {
 	Config *services.AppConfig
 	Router *gin.Engine
@@ -125,5 +135,70 @@ func (a *App) initRouter() {
 func (a *App) Run() {
 	addr := fmt.Sprintf("%s:%d", a.Config.Server.Host, a.Config.Server.Port)
 	logger.Infof("Starting server on %s", addr)
-	a.Router.Run(addr)
+
+	srv := &http.Server{Addr: addr, Handler: a.Router}
+	// 停止时关闭 SSE 事件流，否则长连接会一直阻塞 Shutdown
+	srv.RegisterOnShutdown(services.GetEventStreamService().Close)
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+	agentSrv := a.startAgentTLSServer()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Infof("[System] 正在停止服务...")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Warnf("[System] HTTP 服务停止超时: %v", err)
+	}
+	if agentSrv != nil {
+		agentSrv.Shutdown(ctx)
+	}
+
+	// 等待事件总线中剩余的事件处理完毕（如通知发送、日志写入）
+	if !eventbus.DefaultBus.Close(shutdownTimeout) {
+		logger.Warnf("[System] 事件总线未能在 %v 内处理完剩余事件", shutdownTimeout)
+	}
+	logger.Infof("[System] 服务已停止")
+}
+
+// startAgentTLSServer 开启 Agent mTLS 端口（配置了 agent_tls_port 时）
+// 服务器证书由面板 CA 签发，只开放 /api/agent/ 下的接口
+func (a *App) startAgentTLSServer() *http.Server {
+	port := a.Config.Server.AgentTLSPort
+	if port <= 0 {
+		return nil
+	}
+	tlsConfig, err := services.GetAgentPKI().ServerTLSConfig(port, a.Config.Server.AgentTLSHosts)
+	if err != nil {
+		logger.Errorf("[AgentPKI] 初始化 Agent TLS 端口失败: %v", err)
+		return nil
+	}
+
+	agentPrefix := strings.TrimSuffix(a.Config.Server.URLPrefix, "/") + "/api/agent/"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(path.Clean(r.URL.Path), agentPrefix) {
+			http.NotFound(w, r)
+			return
+		}
+		a.Router.ServeHTTP(w, r)
+	})
+	srv := &http.Server{
+		Addr:      fmt.Sprintf("%s:%d", a.Config.Server.Host, port),
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}
+	logger.Infof("Starting agent TLS server on %s", srv.Addr)
+	go func() {
+		if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			logger.Fatalf("Failed to start agent TLS server: %v", err)
+		}
+	}()
+	return srv
 }
@@ -25,6 +25,12 @@ const (
 	// ScriptsWorkDir 脚本工作目录
 	ScriptsWorkDir = "./data/scripts"
 
+	// TerminalRecordDir Agent 远程终端会话录像目录
+	TerminalRecordDir = "./data/terminal_records"
+
+	// AgentPKIDir 面板 CA（签发 Agent 客户端证书）所在目录
+	AgentPKIDir = "./data/agent_pki"
+
 	// CookieName Cookie 名称
 	CookieName = "BHToken"
 
@@ -37,6 +43,8 @@ const (
 	SectionScheduler = "scheduler"
 	SectionSecurity  = "security"
 	SectionNotify    = "notify"
+	SectionEventBus  = "eventbus"
+	SectionAgent     = "agent"
 
 	// Site Settings Key 常量
 	KeyTitle        = "title"
@@ -61,16 +69,44 @@ const (
 	KeyLoginLogDays         = "login_log_days"
 	KeyLoginLogMaxCount     = "login_log_max_count"
 
+	// 数据目录剩余空间低于该百分比时发布 disk_low 事件，0 表示不检查
+	KeyDiskLowThreshold = "disk_low_threshold"
+
 	// Scheduler Settings Key 常量
 	KeyWorkerCount  = "worker_count"
 	KeyQueueSize    = "queue_size"
 	KeyRateInterval = "rate_interval"
 
+	// EventBus Settings Key 常量（worker_count/queue_size 与调度器共用键名）
+	KeyEventPersist     = "persist"
+	KeyEventPersistDays = "persist_days"
+
+	// Agent 负载上限（百分比，0 表示不限制），超过后不再向其派发任务并发布 agent_overloaded 事件
+	KeyAgentMaxCPUPercent  = "max_cpu_percent"
+	KeyAgentMaxMemPercent  = "max_mem_percent"
+	KeyAgentMaxDiskPercent = "max_disk_percent"
+
+	// Agent mTLS 模式，取值见 AgentMTLSOff / AgentMTLSOptional / AgentMTLSRequired（需配置 agent_tls_port）
+	KeyAgentMTLSMode = "mtls_mode"
+
+	// Agent 最后一次心跳超过该秒数后，面板才接管其配置了故障转移的定时任务
+	KeyAgentFailoverDelay = "failover_delay"
+
 	// Notify Settings Key 常量
-	KeyNotifyChannels = "channels"
-	KeyNotifyEvents   = "events"
-	KeyNotifyToken    = "notify_token"
-	KeyNotifyPrefix   = "notify_prefix"
+	KeyNotifyChannels  = "channels"
+	KeyNotifyEvents    = "events"
+	KeyNotifyToken     = "notify_token"
+	KeyNotifyPrefix    = "notify_prefix"
+	KeyNotifyPublicURL = "public_url" // 面板对外访问地址，用于生成告警确认链接
+
+	// Web Push（VAPID 密钥首次使用时自动生成）
+	KeyWebPushPublicKey  = "webpush_public_key"
+	KeyWebPushPrivateKey = "webpush_private_key"
+	KeyWebPushSubject    = "webpush_subject"
+
+	// Telegram Bot 命令（复用已配置的 Telegram 渠道的 Bot Token）
+	KeyTelegramBotWayID   = "telegram_bot_way_id"   // 接收命令的 Telegram 渠道ID，为空表示不启用
+	KeyTelegramBotChatIDs = "telegram_bot_chat_ids" // 允许发送命令的 chat_id 白名单，逗号分隔
 
 	// Notify Templates Keys
 	KeyNotifyTemplateUserLoginTitle       = "notify_template_user_login_title"
@@ -85,10 +121,33 @@ const (
 	KeyNotifyTemplateTaskFailedText       = "notify_template_task_failed_text"
 	KeyNotifyTemplateTaskTimeoutTitle     = "notify_template_task_timeout_title"
 	KeyNotifyTemplateTaskTimeoutText      = "notify_template_task_timeout_text"
+	KeyNotifyTemplateTaskRecoveredTitle   = "notify_template_task_recovered_title"
+	KeyNotifyTemplateTaskRecoveredText    = "notify_template_task_recovered_text"
+	KeyNotifyTemplateCheckDownTitle       = "notify_template_check_down_title"
+	KeyNotifyTemplateCheckDownText        = "notify_template_check_down_text"
+	KeyNotifyTemplateCheckUpTitle         = "notify_template_check_up_title"
+	KeyNotifyTemplateCheckUpText          = "notify_template_check_up_text"
+	KeyNotifyTemplateAgentOnlineTitle     = "notify_template_agent_online_title"
+	KeyNotifyTemplateAgentOnlineText      = "notify_template_agent_online_text"
+	KeyNotifyTemplateAgentOfflineTitle    = "notify_template_agent_offline_title"
+	KeyNotifyTemplateAgentOfflineText     = "notify_template_agent_offline_text"
+	KeyNotifyTemplateAgentOverloadedTitle = "notify_template_agent_overloaded_title"
+	KeyNotifyTemplateAgentOverloadedText  = "notify_template_agent_overloaded_text"
+	KeyNotifyTemplateDiskLowTitle         = "notify_template_disk_low_title"
+	KeyNotifyTemplateDiskLowText          = "notify_template_disk_low_text"
+	KeyNotifyTemplateBackupSuccessTitle   = "notify_template_backup_success_title"
+	KeyNotifyTemplateBackupSuccessText    = "notify_template_backup_success_text"
+	KeyNotifyTemplateBackupFailedTitle    = "notify_template_backup_failed_title"
+	KeyNotifyTemplateBackupFailedText     = "notify_template_backup_failed_text"
+	KeyNotifyTemplateDependencyFailedTitle = "notify_template_dependency_failed_title"
+	KeyNotifyTemplateDependencyFailedText  = "notify_template_dependency_failed_text"
+	KeyNotifyTemplatePanelStartedTitle    = "notify_template_panel_started_title"
+	KeyNotifyTemplatePanelStartedText     = "notify_template_panel_started_text"
 
 	// 事件绑定类型
 	BindingTypeSystem = "system"
 	BindingTypeTask   = "task"
+	BindingTypeCheck  = "check"
 
 	// 系统事件类型
 	EventUserLogin       = "user_login"
@@ -96,9 +155,28 @@ const (
 	EventPasswordChanged = "password_changed"
 
 	// 任务事件类型
-	EventTaskSuccess = "task_success"
-	EventTaskFailed  = "task_failed"
-	EventTaskTimeout = "task_timeout"
+	EventTaskScheduled = "task_scheduled"
+	EventTaskStarted   = "task_started"
+	EventTaskSuccess   = "task_success"
+	EventTaskFailed    = "task_failed"
+	EventTaskTimeout   = "task_timeout"
+	EventTaskRecovered = "task_recovered"
+
+	// Agent 事件类型
+	EventAgentOnline     = "agent_online"
+	EventAgentOffline    = "agent_offline"
+	EventAgentOverloaded = "agent_overloaded"
+
+	// 心跳检测事件类型
+	EventCheckDown = "check_down"
+	EventCheckUp   = "check_up"
+
+	// 面板运行事件类型
+	EventDiskLow          = "disk_low"
+	EventBackupSuccess    = "backup_success"
+	EventBackupFailed     = "backup_failed"
+	EventDependencyFailed = "dependency_failed"
+	EventPanelStarted     = "panel_started"
 
 	// 其他事件类型
 	EventSystemNotice = "system_notice"
@@ -120,6 +198,35 @@ const (
 	WSTypeTaskHeartbeat = "task_heartbeat"
 	WSTypeStop          = "stop"
 
+	// 远程终端消息类型（open/input/resize/close 由面板发往 Agent，opened/output/closed 由 Agent 发往面板）
+	WSTypeTerminalOpen   = "terminal_open"
+	WSTypeTerminalInput  = "terminal_input"
+	WSTypeTerminalResize = "terminal_resize"
+	WSTypeTerminalClose  = "terminal_close"
+	WSTypeTerminalOpened = "terminal_opened"
+	WSTypeTerminalOutput = "terminal_output"
+	WSTypeTerminalClosed = "terminal_closed"
+
+	// 脚本同步消息类型（sync 由面板发往 Agent，sync_result 由 Agent 回报）
+	WSTypeScriptSync       = "script_sync"
+	WSTypeScriptSyncResult = "script_sync_result"
+
+	// 运行环境管理命令消息类型（command/cancel 由面板发往 Agent，output/result 由 Agent 回报）
+	WSTypeCommand       = "command"
+	WSTypeCommandCancel = "command_cancel"
+	WSTypeCommandOutput = "command_output"
+	WSTypeCommandResult = "command_result"
+
+	// 面板要求 Agent 立即轮换客户端证书
+	WSTypeCertRotate = "cert_rotate"
+
+	// Agent 上报自更新进度（更新中 / 成功 / 已回滚 / 失败）
+	WSTypeUpdateStatus = "update_status"
+
+	// 远程终端会话状态
+	TerminalStatusActive = "active"
+	TerminalStatusClosed = "closed"
+
 	// 任务状态
 	TaskStatusSuccess   = "success"
 	TaskStatusFailed    = "failed"
@@ -141,15 +248,92 @@ const (
 	TriggerTypeCron         = "cron"
 	TriggerTypeBaihuStartup = "baihu_startup"
 
+	// Agent 调度模式：单个 Agent 执行 / 广播到多个 Agent 执行
+	AgentModeSingle    = "single"
+	AgentModeBroadcast = "broadcast"
+
+	// 广播执行的成功判定：全部成功 / 任一成功
+	BroadcastPolicyAll = "all"
+	BroadcastPolicyAny = "any"
+
+	// Agent 不可用时的故障转移策略：改为本机执行 / 改派备用 Agent / 等待 Agent 恢复
+	FailoverModeLocal = "local"
+	FailoverModeAgent = "agent"
+	FailoverModeWait  = "wait"
+
+	// 心跳检测状态
+	CheckStatusNew     = "new"
+	CheckStatusUp      = "up"
+	CheckStatusDown    = "down"
+	CheckStatusStarted = "started"
+	CheckStatusPaused  = "paused"
+
+	// 心跳检测 ping 类型
+	CheckPingSuccess = "success"
+	CheckPingStart   = "start"
+	CheckPingFail    = "fail"
+
+	// 持久化事件状态
+	EventRecordPending = "pending"
+	EventRecordHandled = "handled"
+	EventRecordFailed  = "failed"
+
+	// 通知路由规则中表示本地执行的 Agent 标识
+	NotifyRouteAgentLocal = "local"
+
+	// 通知级别
+	NotifySeverityNormal   = "normal"
+	NotifySeverityCritical = "critical"
+
+	// 紧急通知确认状态
+	NotifyAckPending   = "pending"
+	NotifyAckAcked     = "acked"
+	NotifyAckEscalated = "escalated"
+
+	// Webhook 投递状态
+	WebhookStatusPending = "pending"
+	WebhookStatusSuccess = "success"
+	WebhookStatusFailed  = "failed"
+
 	// Agent 状态
 	AgentStatusOnline  = "online"
 	AgentStatusOffline = "offline"
 
+	// Agent mTLS 模式：off 不签发证书；optional 签发并校验证书，仍允许仅凭 Token 连接；required 连接必须使用客户端证书
+	AgentMTLSOff      = "off"
+	AgentMTLSOptional = "optional"
+	AgentMTLSRequired = "required"
+
+	// Agent 客户端证书状态
+	AgentCertStatusActive  = "active"
+	AgentCertStatusRevoked = "revoked"
+
+	// Agent 客户端证书校验失败时通过响应头返回的错误码，Agent 据此丢弃本地证书
+	AgentCertErrorHeader = "X-Agent-Cert-Error"
+	AgentCertErrUnknown  = "cert_unknown"
+	AgentCertErrRevoked  = "cert_revoked"
+	AgentCertErrExpired  = "cert_expired"
+
+	// Agent 分批发布状态
+	RolloutStatusRunning   = "running"
+	RolloutStatusPaused    = "paused"
+	RolloutStatusCompleted = "completed"
+	RolloutStatusCancelled = "cancelled"
+
+	// 单个 Agent 的更新状态
+	AgentUpdatePending    = "pending"     // 已进入发布批次，等待下次心跳下发
+	AgentUpdateUpdating   = "updating"    // 已下发，等待 Agent 以新版本上线
+	AgentUpdateSucceeded  = "succeeded"   // 新版本已通过健康检查
+	AgentUpdateRolledBack = "rolled_back" // 新版本未能连接面板，Agent 已回滚到旧版本
+	AgentUpdateFailed     = "failed"      // 校验失败、下载失败或超时
+
 	// AppLog 分类
 	LogCategoryDefault      = "default"
 	LogCategorySystemNotice = "system_notice"
 	LogCategoryPushLog      = "push_log"
 	LogCategoryLoginLog     = "login_log"
+	LogCategoryBotLog       = "bot_log"     // Bot 命令审计记录
+	LogCategoryDeadLetter   = "dead_letter" // 重试后仍发送失败的通知
 
 	// AppLog 级别
 	LogLevelInfo    = "info"
@@ -217,6 +401,19 @@ var DefaultSettings = map[string]map[string]string{
 		KeyQueueSize:    "100",
 		KeyRateInterval: "200",
 	},
+	SectionAgent: {
+		KeyAgentMaxCPUPercent:  "0",
+		KeyAgentMaxMemPercent:  "0",
+		KeyAgentMaxDiskPercent: "0",
+		KeyAgentMTLSMode:       AgentMTLSOff,
+		KeyAgentFailoverDelay:  "300",
+	},
+	SectionEventBus: {
+		KeyWorkerCount:      "4",
+		KeyQueueSize:        "1000",
+		KeyEventPersist:     "false",
+		KeyEventPersistDays: "7",
+	},
 	SectionNotify: {
 		KeyNotifyPrefix: "[白虎面板]",
 		// Login
@@ -227,11 +424,36 @@ var DefaultSettings = map[string]map[string]string{
 		KeyNotifyTemplatePasswordChangedTitle: "账户安全通知",
 		KeyNotifyTemplatePasswordChangedText:  "用户 {{username}} 刚刚修改了密码",
 		// Task
-		KeyNotifyTemplateTaskSuccessTitle: "任务[{{task_name}}] 成功",
-		KeyNotifyTemplateTaskSuccessText:  "任务 #{{task_id}} {{task_name}}\n状态: 成功\n耗时: {{duration}}ms\n执行结果: {{output}}",
-		KeyNotifyTemplateTaskFailedTitle:  "任务[{{task_name}}] 失败",
-		KeyNotifyTemplateTaskFailedText:   "任务 #{{task_id}} {{task_name}}\n状态: 失败\n执行时间: {{start_time}}\n原因: {{error}}\n最后输出: {{output}}",
-		KeyNotifyTemplateTaskTimeoutTitle: "任务[{{task_name}}] 超时",
-		KeyNotifyTemplateTaskTimeoutText:  "任务 #{{task_id}} {{task_name}}\n状态: 超时\n耗时: {{duration}}ms\n最后输出: {{output}}",
+		KeyNotifyTemplateTaskSuccessTitle:   "任务[{{task_name}}] 成功",
+		KeyNotifyTemplateTaskSuccessText:    "任务 #{{task_id}} {{task_name}}\n状态: 成功\n耗时: {{duration}}ms\n执行结果: {{output}}",
+		KeyNotifyTemplateTaskFailedTitle:    "任务[{{task_name}}] 失败",
+		KeyNotifyTemplateTaskFailedText:     "任务 #{{task_id}} {{task_name}}\n状态: 失败\n执行时间: {{start_time}}\n原因: {{error}}\n最后输出: {{output}}",
+		KeyNotifyTemplateTaskTimeoutTitle:   "任务[{{task_name}}] 超时",
+		KeyNotifyTemplateTaskTimeoutText:    "任务 #{{task_id}} {{task_name}}\n状态: 超时\n耗时: {{duration}}ms\n最后输出: {{output}}",
+		KeyNotifyTemplateTaskRecoveredTitle: "任务[{{task_name}}] 已恢复",
+		KeyNotifyTemplateTaskRecoveredText:  "任务 #{{task_id}} {{task_name}}\n状态: 已恢复（此前连续失败 {{previous_failures}} 次）\n耗时: {{duration}}ms",
+		// Check
+		KeyNotifyTemplateCheckDownTitle: "心跳[{{check_name}}] 异常",
+		KeyNotifyTemplateCheckDownText:  "心跳检测 {{check_name}}\n状态: 异常\n原因: {{reason}}\n最后上报: {{last_ping}}",
+		KeyNotifyTemplateCheckUpTitle:   "心跳[{{check_name}}] 恢复",
+		KeyNotifyTemplateCheckUpText:    "心跳检测 {{check_name}}\n状态: 已恢复\n最后上报: {{last_ping}}",
+		// Agent
+		KeyNotifyTemplateAgentOnlineTitle:  "Agent[{{agent_name}}] 上线",
+		KeyNotifyTemplateAgentOnlineText:   "Agent {{agent_name}} (#{{agent_id}}) 已上线\nIP: {{ip}}",
+		KeyNotifyTemplateAgentOfflineTitle: "Agent[{{agent_name}}] 离线",
+		KeyNotifyTemplateAgentOfflineText:  "Agent {{agent_name}} (#{{agent_id}}) 已离线\nIP: {{ip}}\n原因: {{reason}}",
+		KeyNotifyTemplateAgentOverloadedTitle: "Agent[{{agent_name}}] 负载过高",
+		KeyNotifyTemplateAgentOverloadedText:  "Agent {{agent_name}} (#{{agent_id}}) {{reason}}，暂停派发任务\nCPU: {{cpu_percent}}%\n内存: {{mem_percent}}%\n磁盘: {{disk_percent}}%\n负载: {{load1}}",
+		// System
+		KeyNotifyTemplateDiskLowTitle:          "磁盘空间不足",
+		KeyNotifyTemplateDiskLowText:           "数据目录 {{path}} 剩余空间 {{free}}（{{free_percent}}%），低于阈值 {{threshold}}%\n总容量: {{total}}",
+		KeyNotifyTemplateBackupSuccessTitle:    "备份完成",
+		KeyNotifyTemplateBackupSuccessText:     "备份文件: {{file}}\n大小: {{size}}\n耗时: {{duration}}ms",
+		KeyNotifyTemplateBackupFailedTitle:     "备份失败",
+		KeyNotifyTemplateBackupFailedText:      "面板数据备份失败\n错误: {{error}}",
+		KeyNotifyTemplateDependencyFailedTitle: "依赖[{{name}}] 安装失败",
+		KeyNotifyTemplateDependencyFailedText:  "语言: {{language}} {{lang_version}}\n依赖: {{name}} {{version}}\n错误: {{error}}",
+		KeyNotifyTemplatePanelStartedTitle:     "面板已启动",
+		KeyNotifyTemplatePanelStartedText:      "白虎面板 {{version}} 已启动\n主机: {{hostname}}\n启动时间: {{time}}",
 	},
 }
@@ -2,7 +2,10 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -25,24 +28,133 @@ var agentUpgrader = websocket.Upgrader{
 
 // AgentController Agent 控制器
 type AgentController struct {
-	agentService    *services.AgentService
-	wsManager       *services.AgentWSManager
-	settingsService *services.SettingsService
+	agentService      *services.AgentService
+	wsManager         *services.AgentWSManager
+	settingsService   *services.SettingsService
+	executorService   *tasks.ExecutorService
+	scriptSyncService *services.ScriptSyncService
 }
 
 // NewAgentController 创建 Agent 控制器
-func NewAgentController(settingsService *services.SettingsService) *AgentController {
+func NewAgentController(settingsService *services.SettingsService, executorService *tasks.ExecutorService) *AgentController {
 	return &AgentController{
-		agentService:    services.NewAgentService(),
-		wsManager:       services.GetAgentWSManager(),
-		settingsService: settingsService,
+		agentService:      services.NewAgentService(),
+		wsManager:         services.GetAgentWSManager(),
+		settingsService:   settingsService,
+		executorService:   executorService,
+		scriptSyncService: services.NewScriptSyncService(),
 	}
 }
 
 // List 获取 Agent 列表
 func (c *AgentController) List(ctx *gin.Context) {
 	agents := c.agentService.List()
-	utils.Success(ctx, vo.ToAgentVOListFromModels(agents))
+	vos := vo.ToAgentVOListFromModels(agents)
+	for _, v := range vos {
+		v.Metrics = c.wsManager.GetMetrics(v.ID)
+	}
+	utils.Success(ctx, vos)
+}
+
+// Metrics 获取 Agent 最近的主机指标采样
+func (c *AgentController) Metrics(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if c.agentService.GetByID(id) == nil {
+		utils.NotFound(ctx, "Agent 不存在")
+		return
+	}
+	utils.Success(ctx, c.wsManager.GetMetricsHistory(id))
+}
+
+// ListTerminalSessions 远程终端会话审计记录
+func (c *AgentController) ListTerminalSessions(ctx *gin.Context) {
+	p := utils.ParsePagination(ctx)
+	sessions, total := c.agentService.ListTerminalSessions(ctx.Query("agent_id"), p.Page, p.PageSize)
+	utils.PaginatedResponse(ctx, sessions, total, p)
+}
+
+// DownloadTerminalRecord 下载远程终端会话录像（asciicast v2，可用 asciinema play 回放）
+func (c *AgentController) DownloadTerminalRecord(ctx *gin.Context) {
+	session := c.agentService.GetTerminalSession(ctx.Param("id"))
+	if session == nil {
+		utils.NotFound(ctx, "会话不存在")
+		return
+	}
+	if _, err := os.Stat(session.RecordFile); err != nil {
+		utils.NotFound(ctx, "录像文件不存在")
+		return
+	}
+	ctx.Header("Content-Disposition", "attachment; filename="+filepath.Base(session.RecordFile))
+	ctx.Header("Content-Type", "application/x-asciicast")
+	ctx.File(session.RecordFile)
+}
+
+// ListScriptSyncs 获取脚本同步规则列表
+func (c *AgentController) ListScriptSyncs(ctx *gin.Context) {
+	utils.Success(ctx, c.scriptSyncService.List())
+}
+
+// SaveScriptSync 创建或更新脚本同步规则
+func (c *AgentController) SaveScriptSync(ctx *gin.Context) {
+	var rule models.ScriptSync
+	if err := ctx.ShouldBindJSON(&rule); err != nil {
+		utils.BadRequest(ctx, "参数错误")
+		return
+	}
+	if err := c.scriptSyncService.Save(&rule); err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, rule)
+}
+
+// DeleteScriptSync 删除脚本同步规则
+func (c *AgentController) DeleteScriptSync(ctx *gin.Context) {
+	if err := c.scriptSyncService.Delete(ctx.Param("id")); err != nil {
+		utils.ServerError(ctx, err.Error())
+		return
+	}
+	utils.SuccessMsg(ctx, "删除成功")
+}
+
+// ScriptSyncStatus 获取各 Agent 的脚本同步状态
+func (c *AgentController) ScriptSyncStatus(ctx *gin.Context) {
+	utils.Success(ctx, c.wsManager.GetScriptSyncStates())
+}
+
+// TriggerScriptSync 立即向全部在线 Agent 推送脚本同步
+func (c *AgentController) TriggerScriptSync(ctx *gin.Context) {
+	c.wsManager.TriggerScriptSync()
+	utils.SuccessMsg(ctx, "已触发同步")
+}
+
+// ScriptFile 供 Agent 按同步清单下载脚本文件
+func (c *AgentController) ScriptFile(ctx *gin.Context) {
+	agent := c.authAgent(ctx)
+	if agent == nil {
+		return
+	}
+
+	rule := c.scriptSyncService.GetByID(ctx.Query("sync_id"))
+	if rule == nil || !utils.DerefBool(rule.Enabled, true) || !rule.MatchAgent(agent) {
+		utils.NotFound(ctx, "同步规则不存在")
+		return
+	}
+	file, err := services.ResolveScriptSyncFile(rule, ctx.Query("path"))
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() {
+		utils.NotFound(ctx, "文件不存在")
+		return
+	}
+	ctx.File(file)
+}
+
+// ListLabels 获取全部 Agent 标签
+func (c *AgentController) ListLabels(ctx *gin.Context) {
+	utils.Success(ctx, c.agentService.ListLabels())
 }
 
 // Update 更新 Agent
@@ -56,6 +168,7 @@ func (c *AgentController) Update(ctx *gin.Context) {
 	var req struct {
 		Name        string `json:"name" binding:"required"`
 		Description string `json:"description"`
+		Labels      string `json:"labels"`
 		Enabled     bool   `json:"enabled"`
 	}
 
@@ -72,7 +185,7 @@ func (c *AgentController) Update(ctx *gin.Context) {
 	}
 	wasEnabled := utils.DerefBool(oldAgent.Enabled, true)
 
-	if err := c.agentService.Update(id, req.Name, req.Description, req.Enabled); err != nil {
+	if err := c.agentService.Update(id, req.Name, req.Description, req.Labels, req.Enabled); err != nil {
 		utils.ServerError(ctx, err.Error())
 		return
 	}
@@ -161,9 +274,13 @@ func (c *AgentController) Register(ctx *gin.Context) {
 
 // Heartbeat Agent 心跳
 func (c *AgentController) Heartbeat(ctx *gin.Context) {
+	certAgent, _, err := c.agentFromCert(ctx)
+	if err != nil {
+		utils.Unauthorized(ctx, err.Error())
+		return
+	}
 	token := c.getAgentToken(ctx)
-	if token == "" {
-		utils.Unauthorized(ctx, "缺少认证 Token")
+	if certAgent == nil && !c.checkTokenAllowed(ctx, token) {
 		return
 	}
 
@@ -178,21 +295,20 @@ func (c *AgentController) Heartbeat(ctx *gin.Context) {
 	ctx.ShouldBindJSON(&req)
 
 	ip := ctx.ClientIP()
-	agent, err := c.agentService.Heartbeat(token, ip, req.Version, req.BuildTime, req.Hostname, req.OS, req.Arch)
+	var agent *models.Agent
+	if certAgent != nil {
+		agent, err = c.agentService.HeartbeatAgent(certAgent, ip, req.Version, req.BuildTime, req.Hostname, req.OS, req.Arch)
+	} else {
+		agent, err = c.agentService.Heartbeat(token, ip, req.Version, req.BuildTime, req.Hostname, req.OS, req.Arch)
+	}
 	if err != nil {
 		utils.Unauthorized(ctx, err.Error())
 		return
 	}
 
-	// 检查是否需要更新
+	// 检查是否需要更新（强制更新、分批发布与自动更新）
 	latestVersion := c.agentService.GetLatestVersion()
-	needUpdate := c.agentService.CheckNeedUpdate(req.Version, req.BuildTime)
-	forceUpdate := agent.ForceUpdate
-
-	// 如果强制更新已触发，重置标志
-	if forceUpdate && needUpdate {
-		c.agentService.ClearForceUpdate(agent.ID)
-	}
+	needUpdate, forceUpdate := c.agentService.UpdateDecision(agent.ID, req.Version, req.BuildTime)
 
 	utils.Success(ctx, gin.H{
 		"agent_id":       agent.ID,
@@ -205,14 +321,19 @@ func (c *AgentController) Heartbeat(ctx *gin.Context) {
 
 // GetTasks Agent 获取任务列表
 func (c *AgentController) GetTasks(ctx *gin.Context) {
-	token := c.getAgentToken(ctx)
-	if token == "" {
-		utils.Unauthorized(ctx, "缺少认证 Token")
+	agent, _, err := c.agentFromCert(ctx)
+	if err != nil {
+		utils.Unauthorized(ctx, err.Error())
 		return
 	}
-
-	// 先尝试通过 token 查找 Agent
-	agent := c.agentService.GetByToken(token)
+	token := c.getAgentToken(ctx)
+	if agent == nil {
+		if !c.checkTokenAllowed(ctx, token) {
+			return
+		}
+		// 先尝试通过 token 查找 Agent
+		agent = c.agentService.GetByToken(token)
+	}
 
 	// 如果找不到，尝试验证令牌并通过 machine_id 查找
 	if agent == nil {
@@ -245,20 +366,8 @@ func (c *AgentController) GetTasks(ctx *gin.Context) {
 
 // ReportResult Agent 上报执行结果
 func (c *AgentController) ReportResult(ctx *gin.Context) {
-	token := c.getAgentToken(ctx)
-	if token == "" {
-		utils.Unauthorized(ctx, "缺少认证 Token")
-		return
-	}
-
-	agent := c.agentService.GetByToken(token)
+	agent := c.authAgent(ctx)
 	if agent == nil {
-		utils.Unauthorized(ctx, "无效的 Token")
-		return
-	}
-
-	if !utils.DerefBool(agent.Enabled, true) {
-		utils.Forbidden(ctx, "Agent 已禁用")
 		return
 	}
 
@@ -270,7 +379,7 @@ func (c *AgentController) ReportResult(ctx *gin.Context) {
 
 	result.AgentID = agent.ID
 
-	if err := c.agentService.ReportResult(&result); err != nil {
+	if err := c.reportResult(&result); err != nil {
 		utils.ServerError(ctx, err.Error())
 		return
 	}
@@ -292,6 +401,133 @@ func (c *AgentController) getAgentToken(ctx *gin.Context) string {
 	return auth
 }
 
+// agentFromCert 通过 mTLS 客户端证书识别 Agent，请求未携带客户端证书时返回 nil, nil, nil
+func (c *AgentController) agentFromCert(ctx *gin.Context) (*models.Agent, *models.AgentCert, error) {
+	state := ctx.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return nil, nil, nil
+	}
+	record, err := c.agentService.VerifyAgentCert(state.PeerCertificates[0])
+	if err != nil {
+		// 通过响应头告知 Agent 证书失效的原因，避免依赖错误文案
+		var svcErr *services.ServiceError
+		if errors.As(err, &svcErr) && svcErr.Code != "" {
+			ctx.Header(constant.AgentCertErrorHeader, svcErr.Code)
+		}
+		return nil, nil, err
+	}
+	agent := c.agentService.GetByID(record.AgentID)
+	if agent == nil {
+		return nil, nil, &services.ServiceError{Message: "Agent 不存在"}
+	}
+	return agent, record, nil
+}
+
+// checkTokenAllowed 检查是否允许仅凭 Token 认证（mTLS required 模式下必须使用客户端证书）
+func (c *AgentController) checkTokenAllowed(ctx *gin.Context, token string) bool {
+	if services.AgentMTLSMode() == constant.AgentMTLSRequired {
+		utils.Unauthorized(ctx, "面板要求使用 mTLS 客户端证书")
+		return false
+	}
+	if token == "" {
+		utils.Unauthorized(ctx, "缺少认证 Token")
+		return false
+	}
+	return true
+}
+
+// authAgent 认证 Agent API 请求：优先使用 mTLS 客户端证书，其次使用 Token，失败时已写入响应并返回 nil
+func (c *AgentController) authAgent(ctx *gin.Context) *models.Agent {
+	agent, _, err := c.agentFromCert(ctx)
+	if err != nil {
+		utils.Unauthorized(ctx, err.Error())
+		return nil
+	}
+	if agent == nil {
+		token := c.getAgentToken(ctx)
+		if !c.checkTokenAllowed(ctx, token) {
+			return nil
+		}
+		if agent = c.agentService.GetByToken(token); agent == nil {
+			utils.Unauthorized(ctx, "无效的 Token")
+			return nil
+		}
+	}
+	if !utils.DerefBool(agent.Enabled, true) {
+		utils.Forbidden(ctx, "Agent 已禁用")
+		return nil
+	}
+	return agent
+}
+
+// IssueCert Agent 申请或轮换 mTLS 客户端证书
+// 持有有效证书时经 TLS 端口用当前证书轮换；否则凭 Token 与 Machine ID 申请，每个 Agent 仅在首次注册或管理员吊销并允许重新申请后可用
+func (c *AgentController) IssueCert(ctx *gin.Context) {
+	if services.AgentMTLSMode() == constant.AgentMTLSOff {
+		utils.BadRequest(ctx, "面板未开启 Agent mTLS")
+		return
+	}
+	var req struct {
+		CSR string `json:"csr"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.CSR == "" {
+		utils.BadRequest(ctx, "缺少 CSR")
+		return
+	}
+
+	agent, _, err := c.agentFromCert(ctx)
+	if err != nil {
+		utils.Unauthorized(ctx, err.Error())
+		return
+	}
+	if agent == nil {
+		token := c.getAgentToken(ctx)
+		machineID := ctx.GetHeader("X-Machine-ID")
+		if token == "" || machineID == "" {
+			utils.Unauthorized(ctx, "缺少认证 Token 或 Machine ID")
+			return
+		}
+		if agent = c.agentService.GetByMachineID(machineID); agent == nil {
+			if agent, _, err = c.agentService.RegisterByToken(token, machineID, ctx.ClientIP()); err != nil {
+				utils.Unauthorized(ctx, err.Error())
+				return
+			}
+		} else if agent.Token != token {
+			if _, err := c.agentService.ValidateToken(token); err != nil {
+				utils.Unauthorized(ctx, "无效的 Token")
+				return
+			}
+		}
+		if !utils.DerefBool(agent.CertEnroll, true) {
+			utils.Forbidden(ctx, "该 Agent 已签发过证书，请使用现有证书轮换，或由管理员吊销后允许重新申请")
+			return
+		}
+	}
+	if !utils.DerefBool(agent.Enabled, true) {
+		utils.Forbidden(ctx, "Agent 已禁用")
+		return
+	}
+
+	certPEM, record, err := c.agentService.IssueAgentCert(agent, req.CSR)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	caPEM, err := services.GetAgentPKI().CACertPEM()
+	if err != nil {
+		utils.ServerError(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, gin.H{
+		"agent_id":       agent.ID,
+		"certificate":    certPEM,
+		"ca_certificate": string(caPEM),
+		"fingerprint":    record.Fingerprint,
+		"not_after":      record.NotAfter,
+		"tls_port":       services.GetAgentPKI().TLSPort(),
+	})
+}
+
 // Download 下载 Agent 程序
 func (c *AgentController) Download(ctx *gin.Context) {
 	osType := ctx.DefaultQuery("os", "linux")
@@ -309,6 +545,38 @@ func (c *AgentController) Download(ctx *gin.Context) {
 	ctx.Data(200, "application/gzip", data)
 }
 
+// UpdateDownload Agent 下载更新包，只提供带发布签名的更新包
+func (c *AgentController) UpdateDownload(ctx *gin.Context) {
+	osType := ctx.DefaultQuery("os", "linux")
+	arch := ctx.DefaultQuery("arch", "amd64")
+
+	data, _, err := c.agentService.GetSignedAgentBinary(osType, arch)
+	if err != nil {
+		utils.NotFound(ctx, err.Error())
+		return
+	}
+
+	ctx.Header("Content-Length", strconv.Itoa(len(data)))
+	ctx.Data(200, "application/gzip", data)
+}
+
+// UpdateManifest 获取更新包签名清单，Agent 校验签名与 SHA-256 后才会替换程序
+func (c *AgentController) UpdateManifest(ctx *gin.Context) {
+	osType := ctx.DefaultQuery("os", "linux")
+	arch := ctx.DefaultQuery("arch", "amd64")
+
+	manifest, err := c.agentService.GetUpdateManifest(osType, arch)
+	if err != nil {
+		if _, ok := err.(*services.ServiceError); ok {
+			utils.NotFound(ctx, err.Error())
+		} else {
+			utils.ServerError(ctx, err.Error())
+		}
+		return
+	}
+	utils.Success(ctx, manifest)
+}
+
 // GetVersion 获取 Agent 最新版本信息
 func (c *AgentController) GetVersion(ctx *gin.Context) {
 	version := c.agentService.GetLatestVersion()
@@ -336,6 +604,145 @@ func (c *AgentController) ForceUpdate(ctx *gin.Context) {
 	utils.SuccessMsg(ctx, "已标记强制更新，Agent 下次心跳时将自动更新")
 }
 
+// ListUpdates 获取 Agent 的更新记录
+func (c *AgentController) ListUpdates(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if c.agentService.GetByID(id) == nil {
+		utils.NotFound(ctx, "Agent 不存在")
+		return
+	}
+	utils.Success(ctx, c.agentService.ListAgentUpdates(id))
+}
+
+// ListRollouts 获取分批发布列表
+func (c *AgentController) ListRollouts(ctx *gin.Context) {
+	utils.Success(ctx, c.agentService.ListRollouts())
+}
+
+// CreateRollout 创建分批发布，按批次逐步更新 Agent 到最新版本
+func (c *AgentController) CreateRollout(ctx *gin.Context) {
+	var req struct {
+		Waves          models.RolloutWaves `json:"waves"`
+		MaxFailures    int                 `json:"max_failures"`
+		MinWaveMinutes int                 `json:"min_wave_minutes"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "参数错误")
+		return
+	}
+	rollout, err := c.agentService.CreateRollout(req.Waves, req.MaxFailures, req.MinWaveMinutes)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, rollout)
+}
+
+// GetRollout 获取分批发布详情及各 Agent 的更新记录
+func (c *AgentController) GetRollout(ctx *gin.Context) {
+	rollout, updates := c.agentService.GetRollout(ctx.Param("id"))
+	if rollout == nil {
+		utils.NotFound(ctx, "分批发布不存在")
+		return
+	}
+	utils.Success(ctx, gin.H{
+		"rollout": rollout,
+		"updates": updates,
+	})
+}
+
+// PauseRollout 暂停分批发布
+func (c *AgentController) PauseRollout(ctx *gin.Context) {
+	if err := c.agentService.PauseRollout(ctx.Param("id")); err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.SuccessMsg(ctx, "已暂停")
+}
+
+// ResumeRollout 继续分批发布
+func (c *AgentController) ResumeRollout(ctx *gin.Context) {
+	if err := c.agentService.ResumeRollout(ctx.Param("id")); err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.SuccessMsg(ctx, "已继续")
+}
+
+// CancelRollout 取消分批发布
+func (c *AgentController) CancelRollout(ctx *gin.Context) {
+	if err := c.agentService.CancelRollout(ctx.Param("id")); err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.SuccessMsg(ctx, "已取消")
+}
+
+// ListCerts 获取 Agent 的客户端证书签发记录
+func (c *AgentController) ListCerts(ctx *gin.Context) {
+	agent := c.agentService.GetByID(ctx.Param("id"))
+	if agent == nil {
+		utils.NotFound(ctx, "Agent 不存在")
+		return
+	}
+	utils.Success(ctx, gin.H{
+		"mtls_mode":   services.AgentMTLSMode(),
+		"tls_port":    services.GetAgentPKI().TLSPort(),
+		"cert_enroll": utils.DerefBool(agent.CertEnroll, true),
+		"certs":       c.agentService.ListAgentCerts(agent.ID),
+	})
+}
+
+// RevokeCert 吊销客户端证书并断开使用该证书的连接
+func (c *AgentController) RevokeCert(ctx *gin.Context) {
+	var req struct {
+		Reason        string `json:"reason"`
+		AllowReenroll bool   `json:"allow_reenroll"`
+	}
+	ctx.ShouldBindJSON(&req)
+
+	record, err := c.agentService.RevokeAgentCert(ctx.Param("id"), req.Reason, req.AllowReenroll)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, record)
+}
+
+// RotateCert 通知在线 Agent 立即轮换客户端证书
+func (c *AgentController) RotateCert(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if !checkAgentOnline(ctx, id) {
+		return
+	}
+	if services.AgentMTLSMode() == constant.AgentMTLSOff {
+		utils.BadRequest(ctx, "面板未开启 Agent mTLS")
+		return
+	}
+	c.wsManager.SendToAgent(id, services.WSTypeCertRotate, nil)
+	utils.SuccessMsg(ctx, "已通知 Agent 轮换证书")
+}
+
+// AllowCertEnroll 允许 Agent 再次凭 Token 申请客户端证书
+func (c *AgentController) AllowCertEnroll(ctx *gin.Context) {
+	if err := c.agentService.AllowCertEnroll(ctx.Param("id")); err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.SuccessMsg(ctx, "已允许重新申请证书")
+}
+
+// DownloadCA 下载面板 CA 证书，可预先分发给 Agent（ca_file）以校验 TLS 端口
+func (c *AgentController) DownloadCA(ctx *gin.Context) {
+	caPEM, err := services.GetAgentPKI().CACertPEM()
+	if err != nil {
+		utils.ServerError(ctx, err.Error())
+		return
+	}
+	ctx.Header("Content-Disposition", "attachment; filename=baihu-agent-ca.crt")
+	ctx.Data(http.StatusOK, "application/x-pem-file", caPEM)
+}
+
 // ========== WebSocket ==========
 
 // WSConnect Agent WebSocket 连接
@@ -360,22 +767,41 @@ func (c *AgentController) WSConnect(ctx *gin.Context) {
 		return
 	}
 
-	token := ctx.Query("token")
-	if token == "" {
+	machineID := ctx.Query("machine_id")
+	isNewAgent := false
+
+	// 携带客户端证书时以证书识别 Agent，不再使用 Token
+	agent, certRecord, err := c.agentFromCert(ctx)
+	if err != nil {
 		c.wsManager.RecordConnectFail(ip)
-		logger.Warnf("[AgentWS] 连接失败: 缺少 token, IP=%s", ip)
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "缺少 token"})
+		logger.Warnf("[AgentWS] 客户端证书校验失败: %v, IP=%s", err, ip)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "code": ctx.Writer.Header().Get(constant.AgentCertErrorHeader)})
 		return
 	}
+	certFP := ""
+	token := ctx.Query("token")
+	if agent != nil {
+		certFP = certRecord.Fingerprint
+		logger.Infof("[AgentWS] 客户端证书认证: Agent #%s, 指纹 %s...", agent.ID, certFP[:16])
+	} else {
+		if services.AgentMTLSMode() == constant.AgentMTLSRequired {
+			c.wsManager.RecordConnectFail(ip)
+			logger.Warnf("[AgentWS] 连接失败: 面板要求客户端证书, IP=%s", ip)
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "面板要求使用 mTLS 客户端证书"})
+			return
+		}
+		if token == "" {
+			c.wsManager.RecordConnectFail(ip)
+			logger.Warnf("[AgentWS] 连接失败: 缺少 token, IP=%s", ip)
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "缺少 token"})
+			return
+		}
+		logger.Infof("[AgentWS] Token: %s..., MachineID: %s...", token[:8], machineID[:16])
 
-	machineID := ctx.Query("machine_id")
-	logger.Infof("[AgentWS] Token: %s..., MachineID: %s...", token[:8], machineID[:16])
-
-	isNewAgent := false
-
-	// 先尝试用 token 查找已有 Agent
-	agent := c.agentService.GetByToken(token)
-	logger.Infof("[AgentWS] GetByToken 结果: agent=%v", agent != nil)
+		// 先尝试用 token 查找已有 Agent
+		agent = c.agentService.GetByToken(token)
+		logger.Infof("[AgentWS] GetByToken 结果: agent=%v", agent != nil)
+	}
 
 	// 如果没找到，尝试用令牌注册（会检查 machine_id 是否已存在）
 	if agent == nil {
@@ -410,10 +836,11 @@ func (c *AgentController) WSConnect(ctx *gin.Context) {
 	c.wsManager.RecordConnectSuccess(ip)
 
 	// 注册连接
-	ac := c.wsManager.Register(agent.ID, conn, ip)
+	ac := c.wsManager.Register(agent.ID, conn, ip, certFP)
 
-	// 更新 Agent 状态
-	c.agentService.Heartbeat(token, ip, "", "", "", "", "")
+	// 更新 Agent 状态（传入副本，避免清空读协程使用的版本等信息）
+	current := *agent
+	c.agentService.HeartbeatAgent(&current, ip, "", "", "", "", "")
 
 	// 获取调度配置
 	workerCount := getIntSetting(c.settingsService, constant.SectionScheduler, constant.KeyWorkerCount, 4)
@@ -426,6 +853,8 @@ func (c *AgentController) WSConnect(ctx *gin.Context) {
 		"name":         agent.Name,
 		"is_new_agent": isNewAgent,
 		"machine_id":   machineID,
+		"mtls_mode":    services.AgentMTLSMode(),
+		"cert_enroll":  certFP == "" && utils.DerefBool(agent.CertEnroll, true),
 		"scheduler_config": map[string]interface{}{
 			"worker_count":  workerCount,
 			"queue_size":    queueSize,
@@ -442,6 +871,8 @@ func (c *AgentController) WSConnect(ctx *gin.Context) {
 
 	// 主动推送任务列表
 	go c.wsManager.BroadcastTasks(agent.ID)
+	// 校验并推送脚本同步目录
+	go c.wsManager.ResyncScripts(agent.ID)
 }
 
 // wsReadPump 读取消息
@@ -539,6 +970,18 @@ func (c *AgentController) handleWSMessage(ac *services.AgentConnection, agent *m
 
 	case services.WSTypeTaskHeartbeat: // 任务心跳
 		c.handleTaskHeartbeat(agent, msg.Data)
+
+	case services.WSTypeTerminalOpened, services.WSTypeTerminalOutput, services.WSTypeTerminalClosed: // 远程终端
+		c.wsManager.HandleTerminalMessage(agent.ID, msg.Type, msg.Data)
+
+	case services.WSTypeScriptSyncResult: // 脚本同步结果
+		c.wsManager.HandleScriptSyncResult(agent.ID, msg.Data)
+
+	case services.WSTypeCommandOutput, services.WSTypeCommandResult: // 运行环境管理命令
+		c.wsManager.HandleCommandMessage(agent.ID, msg.Type, msg.Data)
+
+	case services.WSTypeUpdateStatus: // 自更新进度
+		c.agentService.HandleUpdateStatus(agent, msg.Data)
 	}
 }
 
@@ -570,12 +1013,13 @@ func (c *AgentController) handleFetchTasks(agent *models.Agent) {
 // handleHeartbeat 处理心跳
 func (c *AgentController) handleHeartbeat(ac *services.AgentConnection, agent *models.Agent, data json.RawMessage) {
 	var req struct {
-		Version    string `json:"version"`
-		BuildTime  string `json:"build_time"`
-		Hostname   string `json:"hostname"`
-		OS         string `json:"os"`
-		Arch       string `json:"arch"`
-		AutoUpdate bool   `json:"auto_update"`
+		Version    string               `json:"version"`
+		BuildTime  string               `json:"build_time"`
+		Hostname   string               `json:"hostname"`
+		OS         string               `json:"os"`
+		Arch       string               `json:"arch"`
+		AutoUpdate bool                 `json:"auto_update"`
+		Metrics    *models.AgentMetrics `json:"metrics"` // 旧版本 Agent 不上报
 	}
 	json.Unmarshal(data, &req)
 
@@ -583,15 +1027,13 @@ func (c *AgentController) handleHeartbeat(ac *services.AgentConnection, agent *m
 
 	// 更新 Agent 信息（使用连接时保存的 IP）
 	c.agentService.Heartbeat(agent.Token, ac.IP, req.Version, req.BuildTime, req.Hostname, req.OS, req.Arch)
+	if req.Metrics != nil {
+		c.agentService.RecordMetrics(agent, ac.IP, *req.Metrics)
+	}
 
-	// 检查是否需要更新
+	// 检查是否需要更新（强制更新、分批发布与自动更新）
 	latestVersion := c.agentService.GetLatestVersion()
-	needUpdate := c.agentService.CheckNeedUpdate(req.Version, req.BuildTime)
-	forceUpdate := agent.ForceUpdate
-
-	if forceUpdate && needUpdate {
-		c.agentService.ClearForceUpdate(agent.ID)
-	}
+	needUpdate, forceUpdate := c.agentService.UpdateDecision(agent.ID, req.Version, req.BuildTime)
 
 	// 发送心跳响应
 	response := map[string]interface{}{
@@ -612,7 +1054,19 @@ func (c *AgentController) handleTaskResult(agent *models.Agent, data json.RawMes
 	}
 
 	result.AgentID = agent.ID
-	c.agentService.ReportResult(&result)
+	if err := c.reportResult(&result); err != nil {
+		logger.Errorf("[AgentWS] 处理任务结果失败: LogID=%s, %v", result.LogID, err)
+	}
+}
+
+// reportResult 优先通知正在等待结果的执行协程，否则直接入库
+func (c *AgentController) reportResult(result *models.AgentTaskResult) error {
+	if c.wsManager.NotifyRemoteResult(result) {
+		logger.Infof("[Agent] 已通知正在等待任务 #%s 结果的 goroutine", result.TaskID)
+		return nil
+	}
+	logger.Infof("[Agent] 没有找到等待任务 #%s 结果的 goroutine，直接处理结果", result.TaskID)
+	return c.executorService.HandleAgentResult(result)
 }
 
 // handleTaskLog 处理 Agent 发送的实时日志
@@ -629,8 +1083,11 @@ func (c *AgentController) handleTaskLog(agent *models.Agent, data json.RawMessag
 	tl := tasks.GetActiveLog(logMsg.LogID)
 	if tl != nil {
 		tl.Write([]byte(logMsg.Content))
-	} else {
-		logger.Warnf("[AgentWS] 收到任务日志 but could not find active TinyLog: LogID=%s, ContentSize=%d", logMsg.LogID, len(logMsg.Content))
+		return
+	}
+	// 断线补传的日志片段没有活动的 TinyLog，追加到已保存的执行记录中
+	if err := c.executorService.HandleAgentLog(logMsg.LogID, logMsg.Content); err != nil {
+		logger.Errorf("[AgentWS] 追加任务日志失败: LogID=%s, %v", logMsg.LogID, err)
 	}
 }
 
@@ -707,3 +1164,31 @@ func getIntSetting(s *services.SettingsService, section, key string, defaultVal
 	}
 	return defaultVal
 }
+
+// checkAgentOnline 校验运行环境管理的目标 Agent 存在且在线（agentID 为空表示面板本机）
+func checkAgentOnline(ctx *gin.Context, agentID string) bool {
+	if agentID == "" {
+		return true
+	}
+	agent := services.NewAgentService().GetByID(agentID)
+	if agent == nil {
+		utils.NotFound(ctx, "Agent 不存在")
+		return false
+	}
+	if !services.GetAgentWSManager().IsAgentOnline(agentID) {
+		utils.BadRequest(ctx, "Agent "+agent.Name+" 不在线")
+		return false
+	}
+	return true
+}
+
+// agentCommandResponse 返回供终端执行的命令；指定 Agent 时同时登记该命令，
+// 浏览器凭 command_id 连接终端 WebSocket（/terminal/ws?agent_id=&command_id=）在 Agent 上执行并查看实时输出
+func agentCommandResponse(ctx *gin.Context, cmd, agentID string) {
+	if agentID == "" {
+		utils.Success(ctx, gin.H{"command": cmd})
+		return
+	}
+	id := services.GetAgentWSManager().PrepareAgentCommand(agentID, services.AgentCommand{Command: cmd})
+	utils.Success(ctx, gin.H{"command": cmd, "agent_id": agentID, "command_id": id})
+}
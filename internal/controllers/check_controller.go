@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"io"
+	"strings"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/models/vo"
+	"github.com/engigu/baihu-panel/internal/services"
+	"github.com/engigu/baihu-panel/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CheckController struct {
+	checkService *services.CheckService
+}
+
+func NewCheckController(checkService *services.CheckService) *CheckController {
+	return &CheckController{checkService: checkService}
+}
+
+func checkURLPrefix() string {
+	return strings.TrimSuffix(services.GetConfig().Server.URLPrefix, "/")
+}
+
+// List 获取心跳检测列表
+func (cc *CheckController) List(c *gin.Context) {
+	p := utils.ParsePagination(c)
+	name := c.DefaultQuery("name", "")
+	status := c.DefaultQuery("status", "")
+
+	checks, total := cc.checkService.List(name, status, p.Page, p.PageSize)
+	utils.PaginatedResponse(c, vo.ToCheckVOListFromModels(checks, checkURLPrefix()), total, p)
+}
+
+// Get 获取心跳检测详情
+func (cc *CheckController) Get(c *gin.Context) {
+	check := cc.checkService.GetByID(c.Param("id"))
+	if check == nil {
+		utils.NotFound(c, "心跳检测不存在")
+		return
+	}
+	utils.Success(c, vo.ToCheckVO(check, checkURLPrefix()))
+}
+
+type checkRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Remark  string `json:"remark"`
+	Tags    string `json:"tags"`
+	Period  int    `json:"period"`
+	Grace   int    `json:"grace"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// Create 创建心跳检测
+func (cc *CheckController) Create(c *gin.Context) {
+	var req checkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	check := &models.Check{
+		Name:    req.Name,
+		Remark:  req.Remark,
+		Tags:    req.Tags,
+		Period:  req.Period,
+		Grace:   req.Grace,
+		Enabled: req.Enabled,
+	}
+	if err := cc.checkService.Create(check); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.Success(c, vo.ToCheckVO(check, checkURLPrefix()))
+}
+
+// Update 更新心跳检测
+func (cc *CheckController) Update(c *gin.Context) {
+	var req checkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	check, err := cc.checkService.Update(c.Param("id"), req.Name, req.Remark, req.Tags, req.Period, req.Grace, req.Enabled)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.Success(c, vo.ToCheckVO(check, checkURLPrefix()))
+}
+
+// Delete 删除心跳检测
+func (cc *CheckController) Delete(c *gin.Context) {
+	if err := cc.checkService.Delete(c.Param("id")); err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+	utils.SuccessMsg(c, "删除成功")
+}
+
+// RegenerateKey 重新生成 ping 地址
+func (cc *CheckController) RegenerateKey(c *gin.Context) {
+	check, err := cc.checkService.RegeneratePingKey(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.Success(c, vo.ToCheckVO(check, checkURLPrefix()))
+}
+
+// GetPings 获取心跳检测的 ping 记录
+func (cc *CheckController) GetPings(c *gin.Context) {
+	p := utils.ParsePagination(c)
+	pings, total := cc.checkService.GetPings(c.Param("id"), p.Page, p.PageSize)
+	utils.PaginatedResponse(c, pings, total, p)
+}
+
+// Ping 成功上报 (GET/POST/HEAD /ping/:key)
+func (cc *CheckController) Ping(c *gin.Context) {
+	cc.handlePing(c, constant.CheckPingSuccess)
+}
+
+// PingStart 开始执行上报 (/ping/:key/start)
+func (cc *CheckController) PingStart(c *gin.Context) {
+	cc.handlePing(c, constant.CheckPingStart)
+}
+
+// PingFail 执行失败上报 (/ping/:key/fail)
+func (cc *CheckController) PingFail(c *gin.Context) {
+	cc.handlePing(c, constant.CheckPingFail)
+}
+
+// handlePing ping 接口面向 curl/wget 等脚本，直接返回纯文本
+func (cc *CheckController) handlePing(c *gin.Context, kind string) {
+	var body string
+	if c.Request.Body != nil {
+		data, _ := io.ReadAll(io.LimitReader(c.Request.Body, 10*1024))
+		body = string(data)
+	}
+
+	if err := cc.checkService.Ping(c.Param("key"), kind, c.ClientIP(), c.Request.UserAgent(), body); err != nil {
+		c.String(404, "not found")
+		return
+	}
+	c.String(200, "OK")
+}
@@ -30,6 +30,8 @@ type StatsResponse struct {
 	Logs       int64 `json:"logs"`
 	Scheduled  int   `json:"scheduled"`
 	Running    int   `json:"running"`
+	Checks     int64 `json:"checks"`      // 心跳检测总数
+	ChecksDown int64 `json:"checks_down"` // 异常的心跳检测数
 }
 
 func (dc *DashboardController) GetStats(c *gin.Context) {
@@ -59,6 +61,11 @@ func (dc *DashboardController) GetStats(c *gin.Context) {
 	// Agent 端的运行状态需要通过心跳上报（未来优化）
 	running := dc.executorService.GetRunningCount()
 
+	// 心跳检测统计
+	var checkCount, checkDown int64
+	database.DB.Model(&models.Check{}).Count(&checkCount)
+	database.DB.Model(&models.Check{}).Where("status = ?", constant.CheckStatusDown).Count(&checkDown)
+
 	stats := StatsResponse{
 		Tasks:      taskCount,
 		TodayExecs: todayExecs,
@@ -66,6 +73,8 @@ func (dc *DashboardController) GetStats(c *gin.Context) {
 		Logs:       logCount,
 		Scheduled:  totalScheduled,
 		Running:    running,
+		Checks:     checkCount,
+		ChecksDown: checkDown,
 	}
 
 	utils.Success(c, stats)
@@ -141,6 +150,7 @@ func (dc *DashboardController) GetSendStats(c *gin.Context) {
 type TaskStats struct {
 	TaskID   string `json:"task_id"`
 	TaskName string `json:"task_name"`
+	Type     string `json:"type"` // task 或 check（外部心跳检测）
 	Count    int    `json:"count"`
 }
 
@@ -184,16 +194,33 @@ func (dc *DashboardController) GetTaskStats(c *gin.Context) {
 		taskNameMap[t.ID] = t.Name
 	}
 
+	// 心跳检测与任务共用 SendStats 统计，同样展示在占比中
+	var checks []models.Check
+	if len(taskIDs) > 0 {
+		database.DB.Where("id IN ?", taskIDs).Find(&checks)
+	}
+	checkNameMap := make(map[string]string)
+	for _, ck := range checks {
+		checkNameMap[ck.ID] = ck.Name
+	}
+
 	// 构建结果
 	stats := make([]TaskStats, 0, len(results))
 	for _, r := range results {
+		itemType := constant.BindingTypeTask
 		name := taskNameMap[r.TaskID]
 		if name == "" {
-			name = "未知任务"
+			if checkName, ok := checkNameMap[r.TaskID]; ok {
+				itemType = constant.BindingTypeCheck
+				name = checkName
+			} else {
+				name = "未知任务"
+			}
 		}
 		stats = append(stats, TaskStats{
 			TaskID:   r.TaskID,
 			TaskName: name,
+			Type:     itemType,
 			Count:    r.Total,
 		})
 	}
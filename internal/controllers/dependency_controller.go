@@ -25,7 +25,7 @@ func NewDependencyController() *DependencyController {
 func (c *DependencyController) List(ctx *gin.Context) {
 	language := ctx.Query("language")
 	langVersion := ctx.Query("lang_version")
-	deps, err := c.service.List(language, langVersion)
+	deps, err := c.service.List(language, langVersion, ctx.Query("agent_id"))
 	if err != nil {
 		utils.ServerError(ctx, "获取依赖列表失败")
 		return
@@ -41,6 +41,7 @@ func (c *DependencyController) Create(ctx *gin.Context) {
 		Version     string `json:"version"`
 		Language    string `json:"language" binding:"required"`
 		LangVersion string `json:"lang_version"`
+		AgentID     string `json:"agent_id"`
 		Remark      string `json:"remark"`
 	}
 
@@ -54,6 +55,7 @@ func (c *DependencyController) Create(ctx *gin.Context) {
 		Version:     req.Version,
 		Language:    req.Language,
 		LangVersion: req.LangVersion,
+		AgentID:     req.AgentID,
 		Remark:      req.Remark,
 	}
 
@@ -87,6 +89,7 @@ func (c *DependencyController) Install(ctx *gin.Context) {
 		Version     string `json:"version"`
 		Language    string `json:"language"`
 		LangVersion string `json:"lang_version"`
+		AgentID     string `json:"agent_id"`
 		Remark      string `json:"remark"`
 	}
 
@@ -103,12 +106,20 @@ func (c *DependencyController) Install(ctx *gin.Context) {
 	if langVersion == "" {
 		langVersion = ctx.Query("lang_version")
 	}
+	agentID := req.AgentID
+	if agentID == "" {
+		agentID = ctx.Query("agent_id")
+	}
+	if !checkAgentOnline(ctx, agentID) {
+		return
+	}
 
 	dep := &models.Dependency{
 		Name:        req.Name,
 		Version:     req.Version,
 		Language:    language,
 		LangVersion: langVersion,
+		AgentID:     agentID,
 		Remark:      req.Remark,
 	}
 
@@ -130,6 +141,7 @@ func (c *DependencyController) GetInstallCommand(ctx *gin.Context) {
 		Version     string `json:"version"`
 		Language    string `json:"language"`
 		LangVersion string `json:"lang_version"`
+		AgentID     string `json:"agent_id"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -145,12 +157,20 @@ func (c *DependencyController) GetInstallCommand(ctx *gin.Context) {
 	if langVersion == "" {
 		langVersion = ctx.Query("lang_version")
 	}
+	agentID := req.AgentID
+	if agentID == "" {
+		agentID = ctx.Query("agent_id")
+	}
+	if !checkAgentOnline(ctx, agentID) {
+		return
+	}
 
 	dep := &models.Dependency{
 		Name:        req.Name,
 		Version:     req.Version,
 		Language:    language,
 		LangVersion: langVersion,
+		AgentID:     agentID,
 	}
 
 	cmd, err := c.service.GetInstallCommand(dep)
@@ -159,7 +179,7 @@ func (c *DependencyController) GetInstallCommand(ctx *gin.Context) {
 		return
 	}
 
-	utils.Success(ctx, gin.H{"command": cmd})
+	agentCommandResponse(ctx, cmd, agentID)
 }
 
 // GetReinstallAllCommand 获取全部重装命令
@@ -170,14 +190,18 @@ func (c *DependencyController) GetReinstallAllCommand(ctx *gin.Context) {
 		utils.BadRequest(ctx, "缺少 language 参数")
 		return
 	}
+	agentID := ctx.Query("agent_id")
+	if !checkAgentOnline(ctx, agentID) {
+		return
+	}
 
-	cmd, err := c.service.GetReinstallAllCommand(language, langVersion)
+	cmd, err := c.service.GetReinstallAllCommand(language, langVersion, agentID)
 	if err != nil {
 		utils.ServerError(ctx, err.Error())
 		return
 	}
 
-	utils.Success(ctx, gin.H{"command": cmd})
+	agentCommandResponse(ctx, cmd, agentID)
 }
 
 // Uninstall 卸载依赖
@@ -191,15 +215,7 @@ func (c *DependencyController) Uninstall(ctx *gin.Context) {
 	force := ctx.Query("force") == "true"
 
 	// 获取依赖信息
-	deps, _ := c.service.List("", "")
-	var dep *models.Dependency
-	for i := range deps {
-		if deps[i].ID == id {
-			dep = &deps[i]
-			break
-		}
-	}
-
+	dep := c.service.GetByID(id)
 	if dep == nil {
 		utils.NotFound(ctx, "依赖不存在")
 		return
@@ -227,19 +243,14 @@ func (c *DependencyController) Reinstall(ctx *gin.Context) {
 	}
 
 	// 获取依赖信息
-	deps, _ := c.service.List("", "")
-	var dep *models.Dependency
-	for i := range deps {
-		if deps[i].ID == id {
-			dep = &deps[i]
-			break
-		}
-	}
-
+	dep := c.service.GetByID(id)
 	if dep == nil {
 		utils.NotFound(ctx, "依赖不存在")
 		return
 	}
+	if !checkAgentOnline(ctx, dep.AgentID) {
+		return
+	}
 
 	err := c.service.Install(dep)
 	// 无论成功失败，都同步记录日志
@@ -260,8 +271,12 @@ func (c *DependencyController) ReinstallAll(ctx *gin.Context) {
 		utils.BadRequest(ctx, "缺少 language 参数")
 		return
 	}
+	agentID := ctx.Query("agent_id")
+	if !checkAgentOnline(ctx, agentID) {
+		return
+	}
 
-	deps, err := c.service.List(language, langVersion)
+	deps, err := c.service.List(language, langVersion, agentID)
 	if err != nil {
 		utils.ServerError(ctx, "获取依赖列表失败")
 		return
@@ -294,8 +309,12 @@ func (c *DependencyController) GetInstalled(ctx *gin.Context) {
 		utils.BadRequest(ctx, "缺少 language 参数")
 		return
 	}
+	agentID := ctx.Query("agent_id")
+	if !checkAgentOnline(ctx, agentID) {
+		return
+	}
 
-	packages, err := c.service.GetInstalledPackages(language, langVersion)
+	packages, err := c.service.GetInstalledPackages(language, langVersion, agentID)
 	if err != nil {
 		utils.ServerError(ctx, "获取已安装包失败: "+err.Error())
 		return
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/services"
+	"github.com/engigu/baihu-panel/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventStreamKeepAlive SSE 保活注释的发送间隔，防止代理断开空闲连接
+const eventStreamKeepAlive = 15 * time.Second
+
+type EventController struct {
+	eventStreamService *services.EventStreamService
+	eventStoreService  *services.EventStoreService
+}
+
+func NewEventController(eventStreamService *services.EventStreamService, eventStoreService *services.EventStoreService) *EventController {
+	return &EventController{
+		eventStreamService: eventStreamService,
+		eventStoreService:  eventStoreService,
+	}
+}
+
+// Stream 以 SSE 方式实时推送事件总线上的事件
+// @Summary 订阅事件流
+// @Description 以 Server-Sent Events 推送任务入队/开始/完成、Agent 上下线、通知发送等事件
+// @Tags 事件
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param types query string false "事件类型，逗号分隔 (如 task_started,task_success)"
+// @Param task_id query string false "任务ID，逗号分隔"
+// @Success 200 {object} services.StreamEvent
+// @Router /events/stream [get]
+func (ec *EventController) Stream(c *gin.Context) {
+	ec.stream(c, services.NewEventStreamFilter(c.Query("types"), c.Query("task_id")))
+}
+
+// OpenStream 开放接口的事件流，只推送任务与 Agent 事件
+// @Summary 订阅任务与 Agent 事件流
+// @Description 以 Server-Sent Events 推送任务调度/开始/完成与 Agent 上下线事件，不包含登录、通知等敏感事件
+// @Tags 事件
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param types query string false "事件类型，逗号分隔 (如 task_started,task_success)"
+// @Param task_id query string false "任务ID，逗号分隔"
+// @Success 200 {object} services.StreamEvent
+// @Router /open2api/v1/events/stream [get]
+func (ec *EventController) OpenStream(c *gin.Context) {
+	filter := services.NewEventStreamFilter(c.Query("types"), c.Query("task_id"))
+	filter.Allowed = services.OpenAPIStreamEvents
+	ec.stream(c, filter)
+}
+
+func (ec *EventController) stream(c *gin.Context, filter services.EventStreamFilter) {
+	events, cancel := ec.eventStreamService.Subscribe(filter)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// 先发送一条注释，让客户端尽快确认连接已建立
+	fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(eventStreamKeepAlive)
+	defer ticker.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Event, data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// GetStats 获取事件总线运行状态
+func (ec *EventController) GetStats(c *gin.Context) {
+	utils.Success(c, gin.H{
+		"bus":     eventbus.DefaultBus.Stats(),
+		"streams": ec.eventStreamService.ClientCount(),
+	})
+}
+
+// GetRecords 获取持久化的事件记录
+func (ec *EventController) GetRecords(c *gin.Context) {
+	p := utils.ParsePagination(c)
+	records, total := ec.eventStoreService.List(c.Query("type"), c.Query("status"), p.Page, p.PageSize)
+	utils.PaginatedResponse(c, records, total, p)
+}
+
+// Replay 重放单个事件
+func (ec *EventController) Replay(c *gin.Context) {
+	if err := ec.eventStoreService.Replay(c.Param("id")); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.SuccessMsg(c, "已重新分发")
+}
+
+// ReplayByStatus 批量重放未完成或失败的事件
+func (ec *EventController) ReplayByStatus(c *gin.Context) {
+	var req struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	count, err := ec.eventStoreService.ReplayByStatus(req.Status)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"count": count})
+}
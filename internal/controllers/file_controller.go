@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/engigu/baihu-panel/internal/services"
 	"github.com/engigu/baihu-panel/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -18,7 +19,8 @@ var (
 )
 
 type FileController struct {
-	workDir string
+	workDir   string
+	wsManager *services.AgentWSManager
 }
 
 func NewFileController(workDir string) *FileController {
@@ -27,7 +29,7 @@ func NewFileController(workDir string) *FileController {
 	if err != nil {
 		absPath = workDir
 	}
-	return &FileController{workDir: absPath}
+	return &FileController{workDir: absPath, wsManager: services.GetAgentWSManager()}
 }
 
 type FileNode struct {
@@ -145,6 +147,8 @@ func (fc *FileController) GetFileContent(c *gin.Context) {
 }
 
 func (fc *FileController) SaveFileContent(c *gin.Context) {
+	defer fc.notifyChanged()
+
 	var req struct {
 		Path    string `json:"path" binding:"required"`
 		Content string `json:"content"`
@@ -172,6 +176,8 @@ func (fc *FileController) SaveFileContent(c *gin.Context) {
 }
 
 func (fc *FileController) CreateFile(c *gin.Context) {
+	defer fc.notifyChanged()
+
 	var req struct {
 		Path  string `json:"path" binding:"required"`
 		IsDir bool   `json:"isDir"`
@@ -205,6 +211,8 @@ func (fc *FileController) CreateFile(c *gin.Context) {
 }
 
 func (fc *FileController) DeleteFile(c *gin.Context) {
+	defer fc.notifyChanged()
+
 	var req struct {
 		Path string `json:"path" binding:"required"`
 	}
@@ -229,6 +237,8 @@ func (fc *FileController) DeleteFile(c *gin.Context) {
 }
 
 func (fc *FileController) MoveFile(c *gin.Context) {
+	defer fc.notifyChanged()
+
 	var req struct {
 		OldPath string `json:"oldPath" binding:"required"`
 		NewPath string `json:"newPath" binding:"required"`
@@ -270,6 +280,8 @@ func (fc *FileController) MoveFile(c *gin.Context) {
 }
 
 func (fc *FileController) CopyFile(c *gin.Context) {
+	defer fc.notifyChanged()
+
 	var req struct {
 		SourcePath string `json:"sourcePath" binding:"required"`
 		TargetPath string `json:"targetPath" binding:"required"`
@@ -318,6 +330,8 @@ func (fc *FileController) CopyFile(c *gin.Context) {
 }
 
 func (fc *FileController) RenameFile(c *gin.Context) {
+	defer fc.notifyChanged()
+
 	var req struct {
 		OldPath string `json:"oldPath" binding:"required"`
 		NewPath string `json:"newPath" binding:"required"`
@@ -363,6 +377,8 @@ func (fc *FileController) RenameFile(c *gin.Context) {
 
 // UploadArchive 处理归档文件的上传和解压
 func (fc *FileController) UploadArchive(c *gin.Context) {
+	defer fc.notifyChanged()
+
 	targetDir := c.PostForm("path")
 
 	file, err := c.FormFile("file")
@@ -416,6 +432,8 @@ func (fc *FileController) UploadArchive(c *gin.Context) {
 
 // UploadFiles 处理多个文件的上传
 func (fc *FileController) UploadFiles(c *gin.Context) {
+	defer fc.notifyChanged()
+
 	targetDir := c.PostForm("path")
 
 	// 确定目标目录
@@ -492,3 +510,10 @@ func (fc *FileController) DownloadFile(c *gin.Context) {
 	c.Header("Content-Type", "application/octet-stream")
 	c.File(fullPath)
 }
+
+// notifyChanged 脚本文件变更后触发向 Agent 的同步（内容未变化的目录不会重复推送）
+func (fc *FileController) notifyChanged() {
+	if fc.wsManager != nil {
+		fc.wsManager.TriggerScriptSync()
+	}
+}
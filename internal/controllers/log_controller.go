@@ -26,6 +26,7 @@ func NewLogController() *LogController {
 // @Param task_id query string false "任务 ID"
 // @Param task_name query string false "任务名称"
 // @Param status query string false "状态"
+// @Param parent_id query string false "父执行记录 ID（查询广播子日志）"
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
 // @Success 200 {object} utils.Response{data=utils.PaginationData{data=[]vo.TaskLogVO}}
@@ -35,11 +36,13 @@ func (lc *LogController) GetLogs(c *gin.Context) {
 	taskID := c.DefaultQuery("task_id", "")
 	taskName := c.DefaultQuery("task_name", "")
 	status := c.DefaultQuery("status", "")
+	parentID := c.DefaultQuery("parent_id", "")
 
 	var logs []models.TaskLog
 	var total int64
 
-	query := database.DB.Model(&models.TaskLog{})
+	// 默认只列出顶层记录，指定 parent_id 时列出广播执行的子日志
+	query := database.DB.Model(&models.TaskLog{}).Where("parent_id = ?", parentID)
 	if taskID != "" {
 		query = query.Where("task_id = ?", taskID)
 	}
@@ -87,6 +90,7 @@ func (lc *LogController) GetLogs(c *gin.Context) {
 			TaskName:  task.Name,
 			TaskType:  taskType,
 			AgentID:   log.AgentID,
+			ParentID:  log.ParentID,
 			Command:   string(log.Command),
 			Status:    log.Status,
 			Duration:  log.Duration,
@@ -161,7 +165,7 @@ func (lc *LogController) DeleteLog(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Where("id = ?", id).Delete(&models.TaskLog{}).Error; err != nil {
+	if err := database.DB.Where("id = ? OR parent_id = ?", id, id).Delete(&models.TaskLog{}).Error; err != nil {
 		utils.ServerError(c, "删除日志失败")
 		return
 	}
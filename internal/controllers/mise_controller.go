@@ -17,9 +17,24 @@ func NewMiseController(service *services.MiseService) *MiseController {
 	}
 }
 
+// target 按 agent_id 参数选择管理的主机（为空表示面板本机），Agent 不可用时已写入响应
+func (c *MiseController) target(ctx *gin.Context, agentID string) (*services.MiseService, bool) {
+	if agentID == "" {
+		agentID = ctx.Query("agent_id")
+	}
+	if !checkAgentOnline(ctx, agentID) {
+		return nil, false
+	}
+	return c.service.ForAgent(agentID), true
+}
+
 // List 获取语言列表
 func (c *MiseController) List(ctx *gin.Context) {
-	langs, err := c.service.List()
+	svc, ok := c.target(ctx, "")
+	if !ok {
+		return
+	}
+	langs, err := svc.List()
 	if err != nil {
 		utils.ServerError(ctx, "获取语言列表失败: "+err.Error())
 		return
@@ -29,7 +44,11 @@ func (c *MiseController) List(ctx *gin.Context) {
 
 // Sync 同步本地环境到数据库
 func (c *MiseController) Sync(ctx *gin.Context) {
-	if err := c.service.Sync(); err != nil {
+	svc, ok := c.target(ctx, "")
+	if !ok {
+		return
+	}
+	if err := svc.Sync(); err != nil {
 		utils.ServerError(ctx, "同步本地环境失败: "+err.Error())
 		return
 	}
@@ -53,7 +72,11 @@ func (c *MiseController) Versions(ctx *gin.Context) {
 		utils.BadRequest(ctx, "参数 plugin 不能为空")
 		return
 	}
-	versions, err := c.service.Versions(plugin)
+	svc, ok := c.target(ctx, "")
+	if !ok {
+		return
+	}
+	versions, err := svc.Versions(plugin)
 	if err != nil {
 		utils.ServerError(ctx, "获取版本列表失败: "+err.Error())
 		return
@@ -69,18 +92,23 @@ func (c *MiseController) VerifyCommand(ctx *gin.Context) {
 		utils.BadRequest(ctx, "参数 plugin 不能为空")
 		return
 	}
+	agentID := ctx.Query("agent_id")
+	if !checkAgentOnline(ctx, agentID) {
+		return
+	}
 	cmd, err := c.service.GetVerifyCommand(plugin, version)
 	if err != nil {
 		utils.ServerError(ctx, "获取验证命令失败: "+err.Error())
 		return
 	}
-	utils.Success(ctx, gin.H{"command": cmd})
+	agentCommandResponse(ctx, cmd, agentID)
 }
 // UseGlobal 设置全局默认版本
 func (c *MiseController) UseGlobal(ctx *gin.Context) {
 	var req struct {
 		Plugin  string `json:"plugin"`
 		Version string `json:"version"`
+		AgentID string `json:"agent_id"`
 	}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(ctx, "参数错误: "+err.Error())
@@ -90,7 +118,11 @@ func (c *MiseController) UseGlobal(ctx *gin.Context) {
 		utils.BadRequest(ctx, "参数 plugin 和 version 不能为空")
 		return
 	}
-	if err := c.service.UseGlobal(req.Plugin, req.Version); err != nil {
+	svc, ok := c.target(ctx, req.AgentID)
+	if !ok {
+		return
+	}
+	if err := svc.UseGlobal(req.Plugin, req.Version); err != nil {
 		utils.ServerError(ctx, "设置全局版本失败: "+err.Error())
 		return
 	}
@@ -102,6 +134,7 @@ func (c *MiseController) UnsetGlobal(ctx *gin.Context) {
 	var req struct {
 		Plugin  string `json:"plugin"`
 		Version string `json:"version"`
+		AgentID string `json:"agent_id"`
 	}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(ctx, "参数错误: "+err.Error())
@@ -111,7 +144,11 @@ func (c *MiseController) UnsetGlobal(ctx *gin.Context) {
 		utils.BadRequest(ctx, "参数 plugin 不能为空")
 		return
 	}
-	if err := c.service.UnsetGlobal(req.Plugin, req.Version); err != nil {
+	svc, ok := c.target(ctx, req.AgentID)
+	if !ok {
+		return
+	}
+	if err := svc.UnsetGlobal(req.Plugin, req.Version); err != nil {
 		utils.ServerError(ctx, "取消全局版本失败: "+err.Error())
 		return
 	}
@@ -120,7 +157,11 @@ func (c *MiseController) UnsetGlobal(ctx *gin.Context) {
 
 // Envs 获取全局环境变量
 func (c *MiseController) Envs(ctx *gin.Context) {
-	envs, err := c.service.Envs()
+	svc, ok := c.target(ctx, "")
+	if !ok {
+		return
+	}
+	envs, err := svc.Envs()
 	if err != nil {
 		utils.ServerError(ctx, "获取全局环境变量失败: "+err.Error())
 		return
@@ -131,8 +172,9 @@ func (c *MiseController) Envs(ctx *gin.Context) {
 // SetEnv 设置全局环境变量
 func (c *MiseController) SetEnv(ctx *gin.Context) {
 	var req struct {
-		Key   string `json:"key"`
-		Value string `json:"value"`
+		Key     string `json:"key"`
+		Value   string `json:"value"`
+		AgentID string `json:"agent_id"`
 	}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(ctx, "参数错误: "+err.Error())
@@ -142,7 +184,11 @@ func (c *MiseController) SetEnv(ctx *gin.Context) {
 		utils.BadRequest(ctx, "参数 key 不能为空")
 		return
 	}
-	if err := c.service.SetEnv(req.Key, req.Value); err != nil {
+	svc, ok := c.target(ctx, req.AgentID)
+	if !ok {
+		return
+	}
+	if err := svc.SetEnv(req.Key, req.Value); err != nil {
 		utils.ServerError(ctx, "设置环境变量失败: "+err.Error())
 		return
 	}
@@ -156,7 +202,11 @@ func (c *MiseController) UnsetEnv(ctx *gin.Context) {
 		utils.BadRequest(ctx, "参数 key 不能为空")
 		return
 	}
-	if err := c.service.UnsetEnv(key); err != nil {
+	svc, ok := c.target(ctx, "")
+	if !ok {
+		return
+	}
+	if err := svc.UnsetEnv(key); err != nil {
 		utils.ServerError(ctx, "取消环境变量失败: "+err.Error())
 		return
 	}
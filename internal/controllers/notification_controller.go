@@ -1,12 +1,20 @@
 package controllers
 
 import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
 	"github.com/engigu/baihu-panel/internal/models"
 	"github.com/engigu/baihu-panel/internal/services"
 	"github.com/engigu/baihu-panel/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// notifySendWait 开放接口发送通知时最长等待时间，超时后发送在后台继续
+const notifySendWait = 10 * time.Second
+
 type NotificationController struct {
 	notifyService *services.NotificationService
 }
@@ -68,6 +76,15 @@ func (nc *NotificationController) DeleteChannel(c *gin.Context) {
 	utils.SuccessMsg(c, "删除成功")
 }
 
+// ResetChannelStats 清空渠道发送统计
+func (nc *NotificationController) ResetChannelStats(c *gin.Context) {
+	if err := nc.notifyService.ResetChannelStats(c.Param("id")); err != nil {
+		utils.ServerError(c, err.Error())
+		return
+	}
+	utils.SuccessMsg(c, "已重置")
+}
+
 // TestChannel 测试渠道
 func (nc *NotificationController) TestChannel(c *gin.Context) {
 	var req services.NotifyChannel
@@ -76,7 +93,7 @@ func (nc *NotificationController) TestChannel(c *gin.Context) {
 		return
 	}
 
-	result := nc.notifyService.SendToChannel(req, &services.NotifyMessage{
+	result := nc.notifyService.TestChannel(req, &services.NotifyMessage{
 		Title: "🔔 白虎面板测试通知",
 		Text:  "如果你看到这条消息，说明通知渠道配置正确！",
 	})
@@ -168,12 +185,188 @@ func (nc *NotificationController) BatchSaveBindings(c *gin.Context) {
 	utils.SuccessMsg(c, "保存成功")
 }
 
+// GetRoutes 获取通知路由规则列表
+func (nc *NotificationController) GetRoutes(c *gin.Context) {
+	utils.Success(c, nc.notifyService.GetRoutes())
+}
+
+// SaveRoute 保存通知路由规则
+func (nc *NotificationController) SaveRoute(c *gin.Context) {
+	var route models.NotifyRoute
+	if err := c.ShouldBindJSON(&route); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	if err := nc.notifyService.SaveRoute(&route); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, route)
+}
+
+// DeleteRoute 删除通知路由规则
+func (nc *NotificationController) DeleteRoute(c *gin.Context) {
+	if err := nc.notifyService.DeleteRoute(c.Param("id")); err != nil {
+		utils.ServerError(c, err.Error())
+		return
+	}
+	utils.SuccessMsg(c, "删除成功")
+}
+
+// PreviewRoute 预览路由规则当前会匹配到的任务
+func (nc *NotificationController) PreviewRoute(c *gin.Context) {
+	var route models.NotifyRoute
+	if err := c.ShouldBindJSON(&route); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	utils.Success(c, nc.notifyService.GetRouteMatchedTasks(&route))
+}
+
+// GetAcks 获取紧急通知确认记录
+func (nc *NotificationController) GetAcks(c *gin.Context) {
+	p := utils.ParsePagination(c)
+	acks, total := nc.notifyService.GetAcks(c.Query("status"), p.Page, p.PageSize)
+	utils.PaginatedResponse(c, acks, total, p)
+}
+
+// AckAlert 在面板中确认紧急通知
+func (nc *NotificationController) AckAlert(c *gin.Context) {
+	ack, err := nc.notifyService.AckByID(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.Success(c, ack)
+}
+
+// ackPageTmpl 告警确认页面；链接预览、邮件安全扫描等会自动访问消息中的链接，
+// 因此打开页面（GET）只展示告警，由用户点击按钮（POST）后才确认
+var ackPageTmpl = template.Must(template.New("ack").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>告警确认</title>
+<style>
+body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",sans-serif;max-width:560px;margin:48px auto;padding:0 16px;color:#1e293b}
+pre{white-space:pre-wrap;background:#f1f5f9;padding:12px;border-radius:6px;font-size:13px}
+button{padding:8px 20px;border:0;border-radius:6px;background:#2563eb;color:#fff;font-size:15px;cursor:pointer}
+.muted{color:#64748b}
+</style>
+</head>
+<body>
+<h2>{{.Title}}</h2>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+{{with .Ack}}<pre>{{.Content}}</pre>
+{{if $.AckedAt}}<p class="muted">已于 {{$.AckedAt}} 确认</p>
+{{else}}<form method="post"><button type="submit">确认告警</button></form>{{end}}{{end}}
+</body>
+</html>`))
+
+type ackPageData struct {
+	Title   string
+	Message string
+	Ack     *models.NotifyAck
+	AckedAt string // 已确认时的确认时间
+}
+
+func renderAckPage(c *gin.Context, status int, data ackPageData) {
+	if data.Ack != nil && data.Ack.AckedAt != nil {
+		data.AckedAt = data.Ack.AckedAt.Time().Format("2006-01-02 15:04:05")
+	}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	_ = ackPageTmpl.Execute(c.Writer, data)
+}
+
+// AckPage 展示通知中确认链接对应的告警（无需登录，不会确认告警）
+func (nc *NotificationController) AckPage(c *gin.Context) {
+	ack, err := nc.notifyService.GetAckByToken(c.Param("token"))
+	if err != nil {
+		renderAckPage(c, http.StatusNotFound, ackPageData{Title: "告警确认", Message: err.Error()})
+		return
+	}
+	var message string
+	switch ack.Status {
+	case constant.NotifyAckPending:
+		message = "请确认已知悉该告警，确认后将不再升级通知。"
+	case constant.NotifyAckEscalated:
+		message = "该告警已升级通知，仍可确认以记录处理情况。"
+	}
+	renderAckPage(c, http.StatusOK, ackPageData{Title: ack.Title, Message: message, Ack: ack})
+}
+
+// AckByToken 在确认页面提交后确认告警（无需登录，凭令牌确认）
+func (nc *NotificationController) AckByToken(c *gin.Context) {
+	ack, err := nc.notifyService.AckByToken(c.Param("token"))
+	if err != nil {
+		renderAckPage(c, http.StatusNotFound, ackPageData{Title: "告警确认", Message: err.Error()})
+		return
+	}
+	renderAckPage(c, http.StatusOK, ackPageData{Title: ack.Title, Message: "告警已确认", Ack: ack})
+}
+
+// GetTemplateFuncs 获取模板可用函数与各事件示例数据
+func (nc *NotificationController) GetTemplateFuncs(c *gin.Context) {
+	utils.Success(c, gin.H{
+		"funcs":   services.NotifyTemplateFuncs,
+		"samples": services.NotifyTemplateSamples,
+	})
+}
+
+// PreviewTemplate 使用示例数据（或自定义数据）渲染模板，用于预览与校验
+func (nc *NotificationController) PreviewTemplate(c *gin.Context) {
+	var req struct {
+		Event    string                 `json:"event"`
+		Title    string                 `json:"title"`
+		Text     string                 `json:"text"`
+		Markdown string                 `json:"markdown"`
+		Payload  map[string]interface{} `json:"payload"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	payload := req.Payload
+	if payload == nil {
+		payload = services.NotifyTemplateSamples[req.Event]
+	}
+
+	title, err := services.RenderNotifyTemplate(req.Title, payload)
+	if err != nil {
+		utils.BadRequest(c, "标题模板错误: "+err.Error())
+		return
+	}
+	text, err := services.RenderNotifyTemplate(req.Text, payload)
+	if err != nil {
+		utils.BadRequest(c, "内容模板错误: "+err.Error())
+		return
+	}
+
+	markdown, err := services.RenderNotifyTemplate(req.Markdown, payload)
+	if err != nil {
+		utils.BadRequest(c, "Markdown 模板错误: "+err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"title":    title,
+		"text":     text,
+		"markdown": markdown,
+		"payload":  payload,
+	})
+}
+
 // SendNotification API 发送通知（供脚本调用）
 func (nc *NotificationController) SendNotification(c *gin.Context) {
 	var req struct {
 		ChannelID string `json:"channel_id"`
-		Title     string `json:"title"`
-		Text      string `json:"text"`
+		services.NotifyMessage
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(c, "参数错误")
@@ -185,10 +378,8 @@ func (nc *NotificationController) SendNotification(c *gin.Context) {
 		return
 	}
 
-	result := nc.notifyService.SendByChannelID(req.ChannelID, &services.NotifyMessage{
-		Title: req.Title,
-		Text:  req.Text,
-	})
+	// 渠道失败重试可能持续数分钟，不让脚本的请求一直挂起
+	result := nc.notifyService.SendByChannelIDWithin(req.ChannelID, &req.NotifyMessage, notifySendWait)
 
 	utils.Success(c, result)
 }
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAckLinkRequiresPost(t *testing.T) {
+	dir := t.TempDir()
+	if err := database.Init(&database.Config{Type: "sqlite", Path: filepath.Join(dir, "test.db")}); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.AutoMigrate(&models.NotifyAck{}); err != nil {
+		t.Fatal(err)
+	}
+	ack := &models.NotifyAck{ID: "ack1", Token: "tok", Title: "任务失败", Content: "backup 执行失败", Status: constant.NotifyAckPending}
+	if err := database.DB.Create(ack).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	nc := NewNotificationController()
+	r := gin.New()
+	r.GET("/notify/ack/:token", nc.AckPage)
+	r.POST("/notify/ack/:token", nc.AckByToken)
+
+	status := func() string {
+		var got models.NotifyAck
+		database.DB.Where("id = ?", ack.ID).First(&got)
+		return got.Status
+	}
+
+	// 链接预览、邮件扫描等只会 GET，不能因此确认告警
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notify/ack/tok", nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<form method="post">`) {
+			t.Fatalf("GET = %d, body %q", w.Code, w.Body.String())
+		}
+	}
+	if got := status(); got != constant.NotifyAckPending {
+		t.Fatalf("status after GET = %q, want %q", got, constant.NotifyAckPending)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/notify/ack/tok", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST = %d", w.Code)
+	}
+	if got := status(); got != constant.NotifyAckAcked {
+		t.Fatalf("status after POST = %q, want %q", got, constant.NotifyAckAcked)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notify/ack/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("GET unknown token = %d", w.Code)
+	}
+}
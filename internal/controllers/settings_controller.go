@@ -296,7 +296,7 @@ func (sc *SettingsController) GetAbout(c *gin.Context) {
 	memUsage := "N/A"
 	if p, err := process.NewProcess(int32(os.Getpid())); err == nil {
 		if memInfo, err := p.MemoryInfo(); err == nil {
-			memUsage = formatBytes(memInfo.RSS)
+			memUsage = utils.FormatBytes(memInfo.RSS)
 		}
 	}
 
@@ -343,20 +343,6 @@ func (sc *SettingsController) GetChangelog(c *gin.Context) {
 	utils.Success(c, string(content))
 }
 
-// formatBytes 格式化字节数
-func formatBytes(bytes uint64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := uint64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
-}
-
 // formatDuration 格式化时间间隔
 func formatDuration(d time.Duration) string {
 	days := int(d.Hours()) / 24
@@ -519,6 +505,19 @@ func (sc *SettingsController) UpdateSectionSettings(c *gin.Context) {
 		return
 	}
 
+	// 通知模板保存前校验语法
+	if section == constant.SectionNotify {
+		for key, value := range values {
+			if !strings.HasPrefix(key, "notify_template_") {
+				continue
+			}
+			if err := services.ValidateNotifyTemplate(value); err != nil {
+				utils.BadRequest(c, fmt.Sprintf("模板 %s 语法错误: %v", key, err))
+				return
+			}
+		}
+	}
+
 	if err := sc.settingsService.SetSection(section, values); err != nil {
 		utils.ServerError(c, "更新失败")
 		return
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -29,6 +30,58 @@ func NewTaskController(taskService *tasks.TaskService, executorService *tasks.Ex
 	}
 }
 
+// normalizePlacement 规范化并校验任务的 Agent 调度配置，固定 Agent 时忽略标签与广播配置
+func normalizePlacement(agentID *string, p *models.TaskPlacement) error {
+	fixed := agentID != nil && *agentID != ""
+	if fixed {
+		p.AgentSelector, p.AgentMode, p.AgentIDs, p.BroadcastPolicy = "", "", "", ""
+	} else {
+		p.AgentSelector = strings.Join(models.SplitLabels(p.AgentSelector), ",")
+	}
+
+	// 故障转移仅适用于单个 Agent 执行的任务
+	if p.IsBroadcast() || (!fixed && p.AgentSelector == "") {
+		p.FailoverMode = ""
+	}
+	switch p.FailoverMode {
+	case "", constant.FailoverModeLocal:
+		p.FailoverAgentID, p.FailoverWait = "", 0
+	case constant.FailoverModeAgent:
+		p.FailoverWait = 0
+		if p.FailoverAgentID == "" {
+			return fmt.Errorf("请选择备用 Agent")
+		}
+		if fixed && p.FailoverAgentID == *agentID {
+			return fmt.Errorf("备用 Agent 不能与执行 Agent 相同")
+		}
+	case constant.FailoverModeWait:
+		p.FailoverAgentID = ""
+		if p.FailoverWait < 1 || p.FailoverWait > 1440 {
+			return fmt.Errorf("等待 Agent 恢复的时间需在 1-1440 分钟之间")
+		}
+	default:
+		return fmt.Errorf("无效的故障转移策略: %s", p.FailoverMode)
+	}
+
+	if !p.IsBroadcast() {
+		p.AgentMode, p.AgentIDs, p.BroadcastPolicy = "", "", ""
+		return nil
+	}
+
+	p.AgentIDs = strings.Join(models.SplitLabels(p.AgentIDs), ",")
+	if p.AgentSelector == "" && p.AgentIDs == "" {
+		return fmt.Errorf("广播模式需指定 Agent 标签选择器或 Agent 列表")
+	}
+	switch p.BroadcastPolicy {
+	case "":
+		p.BroadcastPolicy = constant.BroadcastPolicyAll
+	case constant.BroadcastPolicyAll, constant.BroadcastPolicyAny:
+	default:
+		return fmt.Errorf("无效的广播成功判定: %s", p.BroadcastPolicy)
+	}
+	return nil
+}
+
 // resolveWorkDir 将相对路径转换为绝对路径
 func resolveWorkDir(workDir string) string {
 	if workDir == "" {
@@ -69,6 +122,7 @@ func (tc *TaskController) CreateTask(c *gin.Context) {
 		Envs        string              `json:"envs"`
 		Languages   models.TaskLanguages `json:"languages"`
 		AgentID       *string             `json:"agent_id"`
+		models.TaskPlacement
 		TriggerType   string              `json:"trigger_type"`
 		RetryCount    int                 `json:"retry_count"`
 		RetryInterval int                 `json:"retry_interval"`
@@ -94,9 +148,14 @@ func (tc *TaskController) CreateTask(c *gin.Context) {
 		}
 	}
 
+	if err := normalizePlacement(req.AgentID, &req.TaskPlacement); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
 	// 转换为绝对路径（Agent 任务保持原样）
 	workDir := req.WorkDir
-	if req.AgentID == nil || *req.AgentID == "" {
+	if (req.AgentID == nil || *req.AgentID == "") && req.AgentSelector == "" && !req.IsBroadcast() {
 		workDir = resolveWorkDir(req.WorkDir)
 	}
 
@@ -117,17 +176,18 @@ func (tc *TaskController) CreateTask(c *gin.Context) {
 	if sourceID != "" {
 		task = tc.taskService.GetTaskBySourceID(sourceID)
 		if task != nil {
-			task = tc.taskService.UpdateTask(task.ID, req.Name, req.Command, req.Schedule, req.Timeout, workDir, req.CleanConfig, req.Envs, true, req.Type, req.Config, req.AgentID, req.Languages, req.TriggerType, req.Tags, req.RetryCount, req.RetryInterval, req.RandomRange, sourceID, req.PinType)
+			task = tc.taskService.UpdateTask(task.ID, req.Name, req.Command, req.Schedule, req.Timeout, workDir, req.CleanConfig, req.Envs, true, req.Type, req.Config, req.AgentID, req.TaskPlacement, req.Languages, req.TriggerType, req.Tags, req.RetryCount, req.RetryInterval, req.RandomRange, sourceID, req.PinType)
 		}
 	}
 
 	if task == nil {
-		task = tc.taskService.CreateTask(req.Name, req.Command, req.Schedule, req.Timeout, workDir, req.CleanConfig, req.Envs, req.Type, req.Config, req.AgentID, req.Languages, req.TriggerType, req.Tags, req.RetryCount, req.RetryInterval, req.RandomRange, sourceID, req.PinType)
+		task = tc.taskService.CreateTask(req.Name, req.Command, req.Schedule, req.Timeout, workDir, req.CleanConfig, req.Envs, req.Type, req.Config, req.AgentID, req.TaskPlacement, req.Languages, req.TriggerType, req.Tags, req.RetryCount, req.RetryInterval, req.RandomRange, sourceID, req.PinType)
 	}
 
 	// 如果是 Agent 任务，通知 Agent；否则添加到本地 cron
 	if task.AgentID != nil && *task.AgentID != "" {
 		tc.agentWSManager.BroadcastTasks(*task.AgentID)
+		tc.executorService.SyncAgentTaskCron(task)
 	} else {
 		tc.executorService.AddCronTask(task)
 	}
@@ -234,6 +294,7 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 		Enabled     bool                `json:"enabled"`
 		Languages   models.TaskLanguages `json:"languages"`
 		AgentID       *string             `json:"agent_id"`
+		models.TaskPlacement
 		TriggerType   string              `json:"trigger_type"`
 		RetryCount    int                 `json:"retry_count"`
 		RetryInterval int                 `json:"retry_interval"`
@@ -253,9 +314,14 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 		}
 	}
 
+	if err := normalizePlacement(req.AgentID, &req.TaskPlacement); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
 	// 转换为绝对路径（Agent 任务保持原样）
 	workDir := req.WorkDir
-	if req.AgentID == nil || *req.AgentID == "" {
+	if (req.AgentID == nil || *req.AgentID == "") && req.AgentSelector == "" && !req.IsBroadcast() {
 		workDir = resolveWorkDir(req.WorkDir)
 	}
 
@@ -272,7 +338,7 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 		sourceID = oldTask.SourceID
 	}
 
-	task := tc.taskService.UpdateTask(id, req.Name, req.Command, req.Schedule, req.Timeout, workDir, req.CleanConfig, req.Envs, req.Enabled, req.Type, req.Config, req.AgentID, req.Languages, req.TriggerType, req.Tags, req.RetryCount, req.RetryInterval, req.RandomRange, sourceID, req.PinType)
+	task := tc.taskService.UpdateTask(id, req.Name, req.Command, req.Schedule, req.Timeout, workDir, req.CleanConfig, req.Envs, req.Enabled, req.Type, req.Config, req.AgentID, req.TaskPlacement, req.Languages, req.TriggerType, req.Tags, req.RetryCount, req.RetryInterval, req.RandomRange, sourceID, req.PinType)
 	if task == nil {
 		utils.NotFound(c, "任务不存在")
 		return
@@ -280,8 +346,8 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 
 	// 处理任务调度
 	if task.AgentID != nil && *task.AgentID != "" {
-		// Agent 任务：从本地 cron 移除，通知 Agent
-		tc.executorService.RemoveCronTask(task.ID)
+		// Agent 任务：从本地 cron 移除（Agent 离线且配置了故障转移时由面板接管），通知 Agent
+		tc.executorService.SyncAgentTaskCron(task)
 		tc.agentWSManager.BroadcastTasks(*task.AgentID)
 		// 如果 agent 变更了，也通知旧 agent
 		if oldAgentID != nil && *oldAgentID != "" && *oldAgentID != *task.AgentID {
@@ -3,6 +3,7 @@ package controllers
 import (
 	"bufio"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -25,12 +26,16 @@ import (
 
 
 type TerminalController struct {
-	envService *services.EnvService
+	envService   *services.EnvService
+	agentService *services.AgentService
+	wsManager    *services.AgentWSManager
 }
 
 func NewTerminalController(envService *services.EnvService) *TerminalController {
 	return &TerminalController{
-		envService: envService,
+		envService:   envService,
+		agentService: services.NewAgentService(),
+		wsManager:    services.GetAgentWSManager(),
 	}
 }
 
@@ -89,6 +94,16 @@ func (tc *TerminalController) HandleWebSocket(c *gin.Context) {
 	if userID == "" {
 		userID = "1" // 兜底
 	}
+	// 指定 agent_id 时经由 Agent WebSocket 打开远程终端
+	if agentID := c.Query("agent_id"); agentID != "" {
+		// 带 command_id 时仅执行运行环境管理接口生成的命令
+		if commandID := c.Query("command_id"); commandID != "" {
+			tc.handleAgentCommand(conn, agentID, commandID)
+			return
+		}
+		tc.handleAgentMode(conn, c, agentID, userID)
+		return
+	}
 	if runtime.GOOS == "windows" {
 		tc.handlePipeMode(conn, userID)
 	} else {
@@ -324,6 +339,191 @@ func (tc *TerminalController) handlePipeMode(conn *websocket.Conn, userID string
 	wg.Wait()
 }
 
+// handleAgentMode 远程终端：浏览器协议与本地终端一致，输入输出经 Agent WebSocket 转发并录像
+func (tc *TerminalController) handleAgentMode(conn *websocket.Conn, c *gin.Context, agentID, userID string) {
+	conn.SetReadLimit(constant.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(constant.PongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(constant.PongWait))
+		return nil
+	})
+
+	writeError := func(msg string) {
+		conn.WriteMessage(websocket.TextMessage, []byte("\r\n\033[1;31m"+msg+"\033[0m\r\n"))
+	}
+
+	agent := tc.agentService.GetByID(agentID)
+	if agent == nil {
+		writeError("Agent 不存在")
+		return
+	}
+	if !utils.DerefBool(agent.Enabled, true) {
+		writeError("Agent 已禁用")
+		return
+	}
+
+	term, err := tc.wsManager.OpenTerminal(agent, userID, c.GetString("username"), c.ClientIP(), 24, 80)
+	if err != nil {
+		writeError(err.Error())
+		return
+	}
+	reason := "用户关闭"
+	defer func() { term.Close(reason) }()
+
+	// 等待 Agent 启动 Shell
+	select {
+	case ev := <-term.Events:
+		if ev.Type != services.WSTypeTerminalOpened {
+			writeError("Agent 响应异常")
+			return
+		}
+		if ev.Mode == "pipe" {
+			conn.WriteMessage(websocket.TextMessage, []byte("__PIPE_MODE__"))
+		} else {
+			conn.WriteMessage(websocket.TextMessage, []byte("__PTY_MODE__"))
+		}
+	case <-term.Done():
+		reason = term.Reason()
+		writeError(reason)
+		return
+	case <-time.After(10 * time.Second):
+		reason = "等待 Agent 响应超时"
+		writeError(reason)
+		return
+	}
+
+	var connMu sync.Mutex
+	writeMessage := func(data []byte) {
+		connMu.Lock()
+		defer connMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	// 转发 Agent 输出；会话被 Agent 结束时关闭浏览器连接以结束读循环
+	pumpDone := make(chan struct{})
+	go func() {
+		defer close(pumpDone)
+		for {
+			select {
+			case ev := <-term.Events:
+				writeMessage([]byte(ev.Text))
+			case <-term.Done():
+				writeMessage([]byte("\r\n\033[1;33m[会话已结束] " + term.Reason() + "\033[0m\r\n"))
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	// 启动 ping 协程
+	pingDone := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(constant.PingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				connMu.Lock()
+				conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					connMu.Unlock()
+					return
+				}
+				connMu.Unlock()
+			case <-pingDone:
+				return
+			}
+		}
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		// 处理调整窗口大小的消息
+		if len(message) > 0 && message[0] == '{' {
+			var resizeMsg struct {
+				Type string `json:"type"`
+				Rows int    `json:"rows"`
+				Cols int    `json:"cols"`
+			}
+			if err := json.Unmarshal(message, &resizeMsg); err == nil && resizeMsg.Type == "resize" {
+				term.Resize(resizeMsg.Rows, resizeMsg.Cols)
+				continue
+			}
+		}
+
+		term.Input(message)
+	}
+
+	close(pingDone)
+	select {
+	case <-term.Done():
+		reason = term.Reason()
+	default:
+	}
+	term.Close(reason)
+	<-pumpDone
+}
+
+// handleAgentCommand 在 Agent 上执行预先登记的命令（依赖安装、环境验证等），以 pipe 模式实时输出
+// 浏览器断开不会中断命令，命令结束后关闭连接
+func (tc *TerminalController) handleAgentCommand(conn *websocket.Conn, agentID, commandID string) {
+	var connMu sync.Mutex
+	writeMessage := func(data []byte) {
+		connMu.Lock()
+		defer connMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	pending := tc.wsManager.TakeAgentCommand(commandID, agentID)
+	if pending == nil {
+		writeMessage([]byte("\r\n\033[1;31m命令不存在或已过期\033[0m\r\n"))
+		return
+	}
+	writeMessage([]byte("__PIPE_MODE__"))
+	writeMessage([]byte("\033[1;36m$ " + pending.Command.Command + "\033[0m\r\n"))
+
+	// 读取控制帧（pong / close），浏览器输入一律忽略
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	pingDone := make(chan struct{})
+	defer close(pingDone)
+	go func() {
+		ticker := time.NewTicker(constant.PingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				connMu.Lock()
+				conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				conn.WriteMessage(websocket.PingMessage, nil)
+				connMu.Unlock()
+			case <-pingDone:
+				return
+			}
+		}
+	}()
+
+	result, err := tc.wsManager.RunAgentCommand(agentID, pending.Command, func(data []byte) {
+		writeMessage([]byte(toUTF8(data)))
+	})
+	if err != nil && (result == nil || result.ExitCode == -1) {
+		writeMessage([]byte("\r\n\033[1;31m" + err.Error() + "\033[0m\r\n"))
+		return
+	}
+	writeMessage([]byte(fmt.Sprintf("\r\n\033[1;33m[命令已结束] 退出码 %d\033[0m\r\n", result.ExitCode)))
+}
+
 // ExecuteShellCommand 执行单个命令并返回结果
 func (tc *TerminalController) ExecuteShellCommand(c *gin.Context) {
 	// 演示模式下禁止执行命令
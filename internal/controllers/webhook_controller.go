@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/services"
+	"github.com/engigu/baihu-panel/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookController(webhookService *services.WebhookService) *WebhookController {
+	return &WebhookController{webhookService: webhookService}
+}
+
+// GetEvents 获取可订阅的事件类型
+func (wc *WebhookController) GetEvents(c *gin.Context) {
+	utils.Success(c, services.WebhookEvents)
+}
+
+// List 获取 Webhook 列表（签名密钥脱敏）
+func (wc *WebhookController) List(c *gin.Context) {
+	hooks := wc.webhookService.List()
+	for i := range hooks {
+		hooks[i].Secret = utils.MaskString(hooks[i].Secret)
+	}
+	utils.Success(c, hooks)
+}
+
+// Save 创建/更新 Webhook
+func (wc *WebhookController) Save(c *gin.Context) {
+	var req struct {
+		ID         string `json:"id"`
+		Name       string `json:"name" binding:"required"`
+		URL        string `json:"url" binding:"required"`
+		Secret     string `json:"secret"`
+		Events     string `json:"events"`
+		MaxRetries int    `json:"max_retries"`
+		Timeout    int    `json:"timeout"`
+		Enabled    *bool  `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	hook := &models.Webhook{
+		ID:         req.ID,
+		Name:       req.Name,
+		URL:        req.URL,
+		Secret:     req.Secret,
+		Events:     req.Events,
+		MaxRetries: req.MaxRetries,
+		Timeout:    req.Timeout,
+		Enabled:    req.Enabled,
+	}
+	if err := wc.webhookService.Save(hook); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	// 新建时返回完整密钥供接收端配置，之后只返回脱敏值
+	if req.ID != "" {
+		hook.Secret = utils.MaskString(hook.Secret)
+	}
+	utils.Success(c, hook)
+}
+
+// Delete 删除 Webhook
+func (wc *WebhookController) Delete(c *gin.Context) {
+	if err := wc.webhookService.Delete(c.Param("id")); err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+	utils.SuccessMsg(c, "删除成功")
+}
+
+// Test 发送测试事件
+func (wc *WebhookController) Test(c *gin.Context) {
+	delivery, err := wc.webhookService.Test(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.Success(c, delivery)
+}
+
+// GetDeliveries 获取投递记录
+func (wc *WebhookController) GetDeliveries(c *gin.Context) {
+	p := utils.ParsePagination(c)
+	status := c.DefaultQuery("status", "")
+	deliveries, total := wc.webhookService.ListDeliveries(c.Param("id"), status, p.Page, p.PageSize)
+	utils.PaginatedResponse(c, deliveries, total, p)
+}
+
+// GetDelivery 获取投递详情
+func (wc *WebhookController) GetDelivery(c *gin.Context) {
+	delivery := wc.webhookService.GetDelivery(c.Param("id"))
+	if delivery == nil {
+		utils.NotFound(c, "投递记录不存在")
+		return
+	}
+	utils.Success(c, delivery)
+}
+
+// Redeliver 手动重新投递
+func (wc *WebhookController) Redeliver(c *gin.Context) {
+	delivery, err := wc.webhookService.Redeliver(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.Success(c, delivery)
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"github.com/engigu/baihu-panel/internal/sdk/messenger"
+	"github.com/engigu/baihu-panel/internal/services"
+	"github.com/engigu/baihu-panel/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebPushController struct {
+	webPushService *services.WebPushService
+}
+
+func NewWebPushController(webPushService *services.WebPushService) *WebPushController {
+	return &WebPushController{webPushService: webPushService}
+}
+
+// GetPublicKey 获取 VAPID 公钥（浏览器订阅时作为 applicationServerKey）
+func (wc *WebPushController) GetPublicKey(c *gin.Context) {
+	key, err := wc.webPushService.GetPublicKey()
+	if err != nil {
+		utils.ServerError(c, err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"public_key": key})
+}
+
+// Subscribe 保存当前浏览器的推送订阅（PushSubscription.toJSON() 的结果）
+func (wc *WebPushController) Subscribe(c *gin.Context) {
+	var req struct {
+		Endpoint string `json:"endpoint" binding:"required"`
+		Keys     struct {
+			P256dh string `json:"p256dh" binding:"required"`
+			Auth   string `json:"auth" binding:"required"`
+		} `json:"keys"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	sub, err := wc.webPushService.Subscribe(c.GetString("userID"), req.Endpoint, req.Keys.P256dh, req.Keys.Auth, c.Request.UserAgent())
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.Success(c, sub)
+}
+
+// Unsubscribe 取消当前浏览器的推送订阅
+func (wc *WebPushController) Unsubscribe(c *gin.Context) {
+	var req struct {
+		Endpoint string `json:"endpoint" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	if err := wc.webPushService.Unsubscribe(req.Endpoint); err != nil {
+		utils.ServerError(c, err.Error())
+		return
+	}
+	utils.SuccessMsg(c, "已取消订阅")
+}
+
+// Test 向当前用户的浏览器发送测试推送
+func (wc *WebPushController) Test(c *gin.Context) {
+	delivered, err := wc.webPushService.Send(c.GetString("userID"), &messenger.Message{
+		Title: "🔔 白虎面板测试通知",
+		Text:  "如果你看到这条消息，说明浏览器推送配置正确！",
+	}, 0, "")
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"delivered": delivered})
+}
+
+// GetSubscriptions 获取全部浏览器订阅
+func (wc *WebPushController) GetSubscriptions(c *gin.Context) {
+	utils.Success(c, wc.webPushService.ListSubscriptions(c.Query("user_id")))
+}
+
+// DeleteSubscription 删除浏览器订阅
+func (wc *WebPushController) DeleteSubscription(c *gin.Context) {
+	if err := wc.webPushService.DeleteSubscription(c.Param("id")); err != nil {
+		utils.ServerError(c, err.Error())
+		return
+	}
+	utils.SuccessMsg(c, "删除成功")
+}
@@ -33,7 +33,10 @@ func Init(cfg *Config) error {
 	DBConfig = cfg
 	// 设置东八区时区
 	loc := systime.CST
-	time.Local = loc
+	// 重复初始化时不再写 time.Local，避免与正在读取时间的后台协程产生数据竞争
+	if time.Local != loc {
+		time.Local = loc
+	}
 
 	var dialector gorm.Dialector
 
@@ -26,6 +26,21 @@ var allModels = []interface{}{
 	&models.Language{},
 	&models.NotifyWay{},
 	&models.NotifyBinding{},
+	&models.Check{},
+	&models.CheckPing{},
+	&models.Webhook{},
+	&models.WebhookDelivery{},
+	&models.EventRecord{},
+	&models.NotifyRoute{},
+	&models.NotifyAck{},
+	&models.NotifyQuietItem{},
+	&models.WebPushSubscription{},
+	&models.NotifyChannelStat{},
+	&models.TerminalSession{},
+	&models.ScriptSync{},
+	&models.AgentCert{},
+	&models.AgentRollout{},
+	&models.AgentUpdate{},
 }
 
 func Migrate() error {
@@ -81,20 +96,28 @@ func getModelSignature(models []interface{}) string {
 			t = t.Elem()
 		}
 		sb.WriteString(t.Name())
-		for i := 0; i < t.NumField(); i++ {
-			f := t.Field(i)
-			if f.Anonymous {
-				continue
-			}
-			sb.WriteString(f.Name)
-			sb.WriteString(f.Type.String())
-			sb.WriteString(f.Tag.Get("gorm"))
-		}
+		writeFieldSignature(&sb, t)
 	}
 	hash := md5.Sum([]byte(sb.String()))
 	return hex.EncodeToString(hash[:])
 }
 
+// writeFieldSignature 写入结构体字段的指纹，嵌入结构体的字段同样会映射为表字段，需一并计入
+func writeFieldSignature(sb *strings.Builder, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			if f.Type.Kind() == reflect.Struct {
+				writeFieldSignature(sb, f.Type)
+			}
+			continue
+		}
+		sb.WriteString(f.Name)
+		sb.WriteString(f.Type.String())
+		sb.WriteString(f.Tag.Get("gorm"))
+	}
+}
+
 // hasGormTypeText 检查 gorm tag 中是否包含 type:text
 func hasGormTypeText(gormTag string) bool {
 	for _, part := range strings.Split(gormTag, ";") {
@@ -1,49 +1,425 @@
 package eventbus
 
-import "sync"
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // Event 统一定义的事件体内包含的数据
 type Event struct {
+	ID      string // 持久化后的事件ID，未开启持久化时为空
 	Type    string
 	Payload interface{}
+	Time    time.Time // 发布时间，Publish 时自动填充
+	Replay  bool      // 是否为重放的历史事件
+	// Handlers 重放时只分发给这些句柄（通常是上次分发中出错的），为空表示全部句柄
+	Handlers []string
 }
 
+// AllEvents 通配事件类型，订阅后可收到总线上的全部事件
+const AllEvents = "*"
+
 // Handler 事件具体的执行句柄
 type Handler func(event Event)
 
+// ErrorHandler 可返回错误的事件句柄，返回的错误会交给 ErrorReporter 记录
+type ErrorHandler func(event Event) error
+
+// ErrorReporter 处理句柄出错（返回错误或 panic）时的回调，handler 为订阅时指定的名称
+type ErrorReporter func(event Event, handler string, err error)
+
+// Store 事件持久化接口，用于事件重放
+type Store interface {
+	// Save 保存事件，需为 event.ID 赋值；返回错误时事件仍会正常分发
+	Save(event *Event) error
+	// MarkHandled 事件的所有句柄执行完毕后回调，failed 为出错的句柄名称
+	MarkHandled(event Event, failed []string)
+}
+
 // Subscriber 事件订阅者接口，各业务 Service 若关注系统总线可实现此接口
 type Subscriber interface {
 	SubscribeEvents(bus *EventBus)
 }
 
+// Options 总线运行参数
+type Options struct {
+	Workers        int           // 工作协程数
+	QueueSize      int           // 每个工作协程的队列长度，也是待分发事件的队列长度
+	EnqueueTimeout time.Duration // 工作协程队列已满时分发协程最长等待时间，超时后丢弃
+}
+
+// DefaultOptions 默认运行参数
+var DefaultOptions = Options{
+	Workers:        4,
+	QueueSize:      1000,
+	EnqueueTimeout: 5 * time.Second,
+}
+
+// ErrClosed 总线已关闭
+var ErrClosed = errors.New("event bus closed")
+
+// ErrQueueFull 待分发事件过多，Publish 不等待，直接丢弃
+var ErrQueueFull = errors.New("event bus queue full")
+
+type subscription struct {
+	seq     int
+	name    string
+	handler ErrorHandler
+}
+
+// dispatch 一次事件分发，所有句柄执行完毕后回调 Store.MarkHandled
+type dispatch struct {
+	event     Event
+	remaining int32
+	mu        sync.Mutex
+	failed    []string
+}
+
+type job struct {
+	dispatch *dispatch
+	sub      *subscription
+}
+
+// EventBus 事件总线
+//
+// Publish 只把事件放入待分发队列，不会阻塞调用方（队列满时丢弃并计数）；
+// 由单个分发协程按发布顺序持久化事件并投递到工作协程。
+// 事件按句柄固定分配到工作协程（同一句柄总在同一协程上执行），
+// 因此每个句柄看到的事件顺序与发布顺序一致，协程数量与队列长度均有上限。
 type EventBus struct {
-	handlers map[string][]Handler
+	handlers map[string][]*subscription
+	subSeq   int
 	mu       sync.RWMutex
+
+	opts      Options
+	inbox     chan Event
+	queues    []chan job
+	startOnce sync.Once
+	closed    bool
+	closeMu   sync.RWMutex
+	pending   sync.WaitGroup
+	workers   sync.WaitGroup
+
+	store    Store
+	reporter ErrorReporter
+	dropped  uint64
 }
 
 func New() *EventBus {
 	return &EventBus{
-		handlers: make(map[string][]Handler),
+		handlers: make(map[string][]*subscription),
 	}
 }
 
-// Subscribe 注册订阅事件
-func (bus *EventBus) Subscribe(eventType string, handler Handler) {
+// Start 按指定参数启动工作协程，只在第一次调用时生效
+// 未调用 Start 时，首次发布事件会以默认参数自动启动
+func (bus *EventBus) Start(opts Options) {
+	bus.startOnce.Do(func() {
+		if opts.Workers <= 0 {
+			opts.Workers = DefaultOptions.Workers
+		}
+		if opts.QueueSize <= 0 {
+			opts.QueueSize = DefaultOptions.QueueSize
+		}
+		if opts.EnqueueTimeout <= 0 {
+			opts.EnqueueTimeout = DefaultOptions.EnqueueTimeout
+		}
+		bus.opts = opts
+		bus.inbox = make(chan Event, opts.QueueSize)
+		bus.workers.Add(1)
+		go bus.dispatcher()
+		bus.queues = make([]chan job, opts.Workers)
+		for i := range bus.queues {
+			bus.queues[i] = make(chan job, opts.QueueSize)
+			bus.workers.Add(1)
+			go bus.worker(bus.queues[i])
+		}
+	})
+}
+
+// SetStore 设置事件持久化存储，传入 nil 关闭持久化
+func (bus *EventBus) SetStore(store Store) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	bus.store = store
+}
+
+// SetErrorReporter 设置句柄出错时的回调
+func (bus *EventBus) SetErrorReporter(reporter ErrorReporter) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	bus.reporter = reporter
+}
+
+// Subscribe 注册订阅事件，eventType 为 AllEvents 时订阅全部事件
+//
+// name 是句柄的稳定名称（如 "webhook"、"notify.task"），出错记录和重放筛选都按它匹配，
+// 因此不能依赖函数名等随构建变化的值；同一事件类型下名称不可重复。
+func (bus *EventBus) Subscribe(eventType, name string, handler Handler) {
+	bus.subscribe(eventType, name, func(event Event) error {
+		handler(event)
+		return nil
+	})
+}
+
+// SubscribeWithError 注册可返回错误的订阅句柄，name 的要求同 Subscribe
+func (bus *EventBus) SubscribeWithError(eventType, name string, handler ErrorHandler) {
+	bus.subscribe(eventType, name, handler)
+}
+
+func (bus *EventBus) subscribe(eventType, name string, handler ErrorHandler) {
+	if name == "" {
+		panic("eventbus: subscriber name is required")
+	}
 	bus.mu.Lock()
 	defer bus.mu.Unlock()
-	bus.handlers[eventType] = append(bus.handlers[eventType], handler)
+	for _, sub := range bus.handlers[eventType] {
+		if sub.name == name {
+			panic(fmt.Sprintf("eventbus: duplicate subscriber %q for event %q", name, eventType))
+		}
+	}
+	bus.subSeq++
+	bus.handlers[eventType] = append(bus.handlers[eventType], &subscription{
+		seq:     bus.subSeq,
+		name:    name,
+		handler: handler,
+	})
 }
 
-// Publish 异步抛出事件
+// Publish 发布事件，持久化与句柄执行都在后台完成，调用方不会被阻塞
+// 可以在句柄内部发布事件；待分发队列已满时事件被丢弃并计入 Stats().Dropped
 func (bus *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Replay = false
+	bus.Start(DefaultOptions)
+
+	bus.closeMu.RLock()
+	defer bus.closeMu.RUnlock()
+	if bus.closed {
+		atomic.AddUint64(&bus.dropped, 1)
+		bus.report(event, "bus", ErrClosed)
+		return
+	}
+	bus.pending.Add(1)
+	select {
+	case bus.inbox <- event:
+	default:
+		bus.pending.Done()
+		atomic.AddUint64(&bus.dropped, 1)
+		bus.report(event, "bus", ErrQueueFull)
+	}
+}
+
+// Replay 重新分发一个历史事件（不会再次持久化）
+// event.Handlers 不为空时只分发给这些句柄，避免已成功的通知、Webhook 等副作用重复执行
+func (bus *EventBus) Replay(event Event) {
+	event.Replay = true
+	bus.Start(DefaultOptions)
+
+	bus.closeMu.RLock()
+	if bus.closed {
+		bus.closeMu.RUnlock()
+		atomic.AddUint64(&bus.dropped, 1)
+		bus.report(event, "bus", ErrClosed)
+		return
+	}
+	bus.pending.Add(1)
+	bus.closeMu.RUnlock()
+
+	defer bus.pending.Done()
+	bus.dispatch(event, bus.getStore())
+}
+
+// dispatcher 按发布顺序持久化事件并投递到各句柄的工作协程
+func (bus *EventBus) dispatcher() {
+	defer bus.workers.Done()
+	for event := range bus.inbox {
+		store := bus.getStore()
+		if store != nil {
+			if err := store.Save(&event); err != nil {
+				bus.report(event, "store", err)
+			}
+		}
+		bus.dispatch(event, store)
+		bus.pending.Done()
+	}
+}
+
+func (bus *EventBus) getStore() Store {
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	return bus.store
+}
+
+// dispatch 将事件投递到订阅句柄，调用方需已为本次分发计入 pending
+func (bus *EventBus) dispatch(event Event, store Store) {
+	bus.mu.RLock()
+	subs := make([]*subscription, 0, len(bus.handlers[event.Type])+len(bus.handlers[AllEvents]))
+	subs = append(subs, bus.handlers[event.Type]...)
+	if event.Type != AllEvents {
+		subs = append(subs, bus.handlers[AllEvents]...)
+	}
+	bus.mu.RUnlock()
+	if event.Replay && len(event.Handlers) > 0 {
+		subs = filterSubscriptions(subs, event.Handlers)
+	}
+
+	d := &dispatch{event: event, remaining: int32(len(subs))}
+	if len(subs) == 0 {
+		if store != nil && event.ID != "" {
+			store.MarkHandled(event, nil)
+		}
+		return
+	}
+
+	for _, sub := range subs {
+		bus.pending.Add(1)
+		if !bus.enqueue(job{dispatch: d, sub: sub}) {
+			bus.pending.Done()
+			atomic.AddUint64(&bus.dropped, 1)
+			bus.finish(d, sub, fmt.Errorf("队列已满，事件被丢弃"))
+		}
+	}
+}
+
+func (bus *EventBus) enqueue(j job) bool {
+	queue := bus.queues[j.sub.seq%len(bus.queues)]
+	select {
+	case queue <- j:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(bus.opts.EnqueueTimeout)
+	defer timer.Stop()
+	select {
+	case queue <- j:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (bus *EventBus) worker(queue chan job) {
+	defer bus.workers.Done()
+	for j := range queue {
+		err := bus.invoke(j)
+		bus.finish(j.dispatch, j.sub, err)
+		bus.pending.Done()
+	}
+}
+
+// invoke 执行句柄并捕获 panic
+func (bus *EventBus) invoke(j job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return j.sub.handler(j.dispatch.event)
+}
+
+func (bus *EventBus) finish(d *dispatch, sub *subscription, err error) {
+	if err != nil {
+		d.mu.Lock()
+		d.failed = append(d.failed, sub.name)
+		d.mu.Unlock()
+		bus.report(d.event, sub.name, err)
+	}
+	if atomic.AddInt32(&d.remaining, -1) == 0 && d.event.ID != "" {
+		if store := bus.getStore(); store != nil {
+			d.mu.Lock()
+			failed := d.failed
+			d.mu.Unlock()
+			store.MarkHandled(d.event, failed)
+		}
+	}
+}
+
+// filterSubscriptions 按句柄名称筛选订阅
+func filterSubscriptions(subs []*subscription, names []string) []*subscription {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	filtered := subs[:0]
+	for _, sub := range subs {
+		if wanted[sub.name] {
+			filtered = append(filtered, sub)
+		}
+	}
+	return filtered
+}
+
+func (bus *EventBus) report(event Event, handler string, err error) {
 	bus.mu.RLock()
-	handlers := bus.handlers[event.Type]
+	reporter := bus.reporter
 	bus.mu.RUnlock()
+	if reporter == nil {
+		return
+	}
+	// 回调自身出错不能影响总线
+	defer func() { _ = recover() }()
+	reporter(event, handler, err)
+}
+
+// Close 停止接收新事件，并等待队列中的事件处理完毕（最长 timeout）
+// 返回 false 表示超时，仍有事件未处理完
+func (bus *EventBus) Close(timeout time.Duration) bool {
+	// 从未发布过事件的总线也需要先初始化队列，才能正常关闭
+	bus.Start(DefaultOptions)
+
+	bus.closeMu.Lock()
+	if bus.closed {
+		bus.closeMu.Unlock()
+		return true
+	}
+	bus.closed = true
+	bus.closeMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		bus.pending.Wait()
+		close(done)
+	}()
 
-	for _, handler := range handlers {
-		// 采用 Goroutine 异步不阻塞核心主线
-		go handler(event)
+	select {
+	case <-done:
+		close(bus.inbox)
+		for _, queue := range bus.queues {
+			close(queue)
+		}
+		bus.workers.Wait()
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Stats 总线运行状态
+type Stats struct {
+	Workers int    `json:"workers"`
+	Queued  int    `json:"queued"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// Stats 获取总线运行状态
+func (bus *EventBus) Stats() Stats {
+	stats := Stats{
+		Workers: len(bus.queues),
+		Queued:  len(bus.inbox),
+		Dropped: atomic.LoadUint64(&bus.dropped),
+	}
+	for _, queue := range bus.queues {
+		stats.Queued += len(queue)
 	}
+	return stats
 }
 
 // 全局唯一的事件总线实例
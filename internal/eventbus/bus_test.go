@@ -0,0 +1,204 @@
+package eventbus
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	mu      sync.Mutex
+	saved   int
+	handled map[string][]string
+}
+
+func (s *memStore) Save(event *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved++
+	event.ID = event.Type
+	return nil
+}
+
+func (s *memStore) MarkHandled(event Event, failed []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handled[event.ID] = failed
+}
+
+func TestEventBusOrdering(t *testing.T) {
+	bus := New()
+	bus.Start(Options{Workers: 2, QueueSize: 100})
+
+	var got []int
+	bus.Subscribe("test", "collect", func(e Event) {
+		got = append(got, e.Payload.(int))
+	})
+
+	for i := 0; i < 100; i++ {
+		bus.Publish(Event{Type: "test", Payload: i})
+	}
+	if !bus.Close(5 * time.Second) {
+		t.Fatal("close timed out")
+	}
+
+	if len(got) != 100 {
+		t.Fatalf("expected 100 events, got %d", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("event %d out of order: %d", i, v)
+		}
+	}
+}
+
+func TestEventBusErrorCapture(t *testing.T) {
+	bus := New()
+	store := &memStore{handled: make(map[string][]string)}
+	bus.SetStore(store)
+
+	var mu sync.Mutex
+	var reported []error
+	bus.SetErrorReporter(func(event Event, handler string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, err)
+	})
+
+	bus.Subscribe("boom", "panic", func(e Event) { panic("oops") })
+	bus.SubscribeWithError("boom", "error", func(e Event) error { return errors.New("failed") })
+	bus.Subscribe(AllEvents, "all", func(e Event) {})
+
+	bus.Publish(Event{Type: "boom"})
+	bus.Publish(Event{Type: "quiet"})
+	bus.Close(5 * time.Second)
+
+	if len(reported) != 2 {
+		t.Fatalf("expected 2 reported errors, got %d", len(reported))
+	}
+	if store.saved != 2 {
+		t.Fatalf("expected 2 saved events, got %d", store.saved)
+	}
+	if len(store.handled["boom"]) != 2 || len(store.handled["quiet"]) != 0 {
+		t.Fatalf("unexpected handled result: %v", store.handled)
+	}
+
+	// 关闭后发布的事件不再分发
+	bus.Publish(Event{Type: "quiet"})
+	if bus.Stats().Dropped != 1 {
+		t.Fatalf("expected 1 dropped event, got %d", bus.Stats().Dropped)
+	}
+}
+
+func TestEventBusReplayFailedHandlers(t *testing.T) {
+	bus := New()
+	store := &memStore{handled: make(map[string][]string)}
+	bus.SetStore(store)
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	bus.Subscribe("job", "notify", func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls["notify"]++
+	})
+	bus.SubscribeWithError("job", "flaky", func(e Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls["flaky"]++
+		if !e.Replay {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	bus.Publish(Event{Type: "job"})
+	time.Sleep(100 * time.Millisecond)
+
+	store.mu.Lock()
+	failed := store.handled["job"]
+	store.mu.Unlock()
+	if len(failed) != 1 || failed[0] != "flaky" {
+		t.Fatalf("expected flaky to fail, got %v", failed)
+	}
+
+	// 只重放出错的句柄，已成功的句柄不再执行
+	bus.Replay(Event{ID: "job", Type: "job", Handlers: failed})
+	bus.Close(5 * time.Second)
+
+	if calls["notify"] != 1 || calls["flaky"] != 2 {
+		t.Fatalf("unexpected calls: %v", calls)
+	}
+	if len(store.handled["job"]) != 0 {
+		t.Fatalf("replay should succeed, got %v", store.handled["job"])
+	}
+}
+
+func TestEventBusSubscriberNameRequired(t *testing.T) {
+	bus := New()
+	bus.Subscribe("job", "notify", func(e Event) {})
+
+	for _, name := range []string{"", "notify"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic for subscriber name %q", name)
+				}
+			}()
+			bus.Subscribe("job", name, func(e Event) {})
+		}()
+	}
+
+	// 不同事件类型可以复用同一名称
+	bus.Subscribe("other", "notify", func(e Event) {})
+}
+
+func TestEventBusPublishNeverBlocks(t *testing.T) {
+	bus := New()
+	bus.Start(Options{Workers: 1, QueueSize: 2, EnqueueTimeout: 50 * time.Millisecond})
+
+	release := make(chan struct{})
+	handled := make(chan struct{})
+	bus.Subscribe("slow", "slow", func(e Event) { <-release })
+	// 句柄内部发布事件不会等待自身所在工作协程的队列
+	bus.Subscribe("outer", "republish", func(e Event) {
+		bus.Publish(Event{Type: "inner"})
+	})
+	bus.Subscribe("inner", "count", func(e Event) { close(handled) })
+
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		bus.Publish(Event{Type: "slow"})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("publish blocked for %v", elapsed)
+	}
+	if bus.Stats().Dropped == 0 {
+		t.Fatal("expected events to be dropped while the handler is stuck")
+	}
+
+	close(release)
+	time.Sleep(200 * time.Millisecond)
+	bus.Publish(Event{Type: "outer"})
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event published from a handler was not delivered")
+	}
+	if !bus.Close(5 * time.Second) {
+		t.Fatal("close timed out")
+	}
+}
+
+func TestEventBusCloseWithoutPublish(t *testing.T) {
+	bus := New()
+	if !bus.Close(time.Second) {
+		t.Fatal("close timed out")
+	}
+	// 关闭后发布的事件直接丢弃
+	bus.Publish(Event{Type: "late"})
+	if bus.Stats().Dropped != 1 {
+		t.Fatalf("dropped = %d, want 1", bus.Stats().Dropped)
+	}
+}
@@ -22,6 +22,7 @@ type CronManager struct {
 	entryMap  map[string]cron.EntryID // task ID -> cron entry ID
 	mu        sync.RWMutex
 	logger    SchedulerLogger
+	skipCheck func(taskID string) string // 定时触发前的检查，返回非空原因时跳过本次执行
 }
 
 // NewCronManager 创建一个新的计划任务管理器
@@ -57,6 +58,24 @@ func (m *CronManager) SetScheduler(scheduler *Scheduler) {
 	m.scheduler = scheduler
 }
 
+// SetSkipCheck 设置定时触发前的检查，fn 返回非空原因时跳过本次执行并记录日志
+func (m *CronManager) SetSkipCheck(fn func(taskID string) string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.skipCheck = fn
+}
+
+// skipReason 返回跳过本次定时执行的原因，为空表示照常执行
+func (m *CronManager) skipReason(taskID string) string {
+	m.mu.RLock()
+	check := m.skipCheck
+	m.mu.RUnlock()
+	if check == nil {
+		return ""
+	}
+	return check(taskID)
+}
+
 // Start 启动调度器
 func (m *CronManager) Start() {
 	m.cron.Start()
@@ -102,8 +121,12 @@ func (m *CronManager) AddTask(task CronTask) error {
 			}
 		}()
 
-		// 构造执行请求的 Builder
+		// 构造执行请求的 Builder，返回 nil 表示跳过本次执行
 		reqBuilder := func() *ExecutionRequest {
+			if reason := m.skipReason(taskID); reason != "" {
+				m.logger.Warnf("[CronManager] 跳过计划任务 %s (#%s) 的本次执行: %s", name, taskID, reason)
+				return nil
+			}
 			return &ExecutionRequest{
 				TaskID:    taskID,
 				Name:      name,
@@ -135,7 +158,9 @@ func (m *CronManager) AddTask(task CronTask) error {
 		} else {
 			m.logger.Infof("[CronManager] 触发计划任务: %s (#%s)", name, taskID)
 			if m.scheduler != nil {
-				m.scheduler.EnqueueOrExecute(reqBuilder())
+				if req := reqBuilder(); req != nil {
+					m.scheduler.EnqueueOrExecute(req)
+				}
 			}
 		}
 
@@ -78,8 +78,9 @@ type ExecutionRequest struct {
 
 // ExecutionMetadata 执行额外元数据
 type ExecutionMetadata struct {
-	GoID       int64 // 关联的 goroutine ID
-	RetryIndex int   // 当前重试索引
+	GoID       int64  // 关联的 goroutine ID
+	RetryIndex int    // 当前重试索引
+	AgentID    string // 实际执行的 Agent ID，为空表示本地执行
 }
 
 // ExecutionResult 执行结果（标准接口）
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"strings"
+
 	"github.com/engigu/baihu-panel/internal/constant"
 )
 
@@ -11,6 +13,7 @@ type Agent struct {
 	Token       string         `json:"token" gorm:"size:64;index"`                    // 认证 Token（可重复使用）
 	MachineID   string         `json:"machine_id" gorm:"size:64;uniqueIndex"`         // 机器识别码（唯一）
 	Description string         `json:"description" gorm:"size:255"`                   // 描述
+	Labels      string         `json:"labels" gorm:"size:255;default:''"`             // 标签，逗号分隔，供任务按标签选择器调度
 	Status      string         `json:"status" gorm:"size:20;default:'pending';index"` // 状态: constant.AgentStatusOnline, constant.AgentStatusOffline
 	LastSeen    *LocalTime     `json:"last_seen"`                                     // 最后心跳时间
 	IP          string         `json:"ip" gorm:"size:45"`                             // Agent IP 地址
@@ -21,6 +24,7 @@ type Agent struct {
 	Arch        string         `json:"arch" gorm:"size:20"`                           // 架构
 	ForceUpdate bool           `json:"force_update" gorm:"default:false"`             // 强制更新标志
 	Enabled     *bool          `json:"enabled" gorm:"default:true"`                   // 是否启用
+	CertEnroll  *bool          `json:"cert_enroll" gorm:"default:true"`               // 是否允许凭 Token 申请 mTLS 客户端证书（签发后关闭，管理员吊销时可重新开启）
 	CreatedAt   LocalTime      `json:"created_at"`
 	UpdatedAt   LocalTime      `json:"updated_at"`
 }
@@ -29,6 +33,42 @@ func (Agent) TableName() string {
 	return constant.TablePrefix + "agents"
 }
 
+// MatchLabels 判断 Agent 是否拥有选择器中的全部标签，选择器为空时不匹配
+func (a *Agent) MatchLabels(selector string) bool {
+	required := SplitLabels(selector)
+	if len(required) == 0 {
+		return false
+	}
+	owned := make(map[string]struct{})
+	for _, l := range SplitLabels(a.Labels) {
+		owned[l] = struct{}{}
+	}
+	for _, l := range required {
+		if _, ok := owned[l]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SplitLabels 解析逗号分隔的标签，去除空白与重复项
+func SplitLabels(s string) []string {
+	var labels []string
+	seen := make(map[string]struct{})
+	for _, l := range strings.Split(s, ",") {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		if _, ok := seen[l]; ok {
+			continue
+		}
+		seen[l] = struct{}{}
+		labels = append(labels, l)
+	}
+	return labels
+}
+
 // AgentToken Agent 令牌
 type AgentToken struct {
 	ID        string         `json:"id" gorm:"primaryKey;size:20"`
@@ -59,6 +99,8 @@ type AgentTask struct {
 	RandomRange int                 `json:"random_range"`
 	Secrets     []string            `json:"secrets"`
 	Enabled     bool                `json:"enabled"`
+	// FailoverDelay 配置了故障转移时，Agent 失联超过该秒数后面板会接管调度，0 表示不会接管
+	FailoverDelay int `json:"failover_delay,omitempty"`
 }
 
 func (t AgentTask) GetID() string {
@@ -100,6 +142,22 @@ type AgentTaskResult struct {
 	EndTime   int64  `json:"end_time"`   // Unix 时间戳
 }
 
+// AgentMetrics Agent 随心跳上报的主机指标
+type AgentMetrics struct {
+	CPUPercent   float64 `json:"cpu_percent"`
+	MemPercent   float64 `json:"mem_percent"`
+	MemUsed      uint64  `json:"mem_used"`
+	MemTotal     uint64  `json:"mem_total"`
+	DiskPercent  float64 `json:"disk_percent"` // Agent 工作目录所在磁盘
+	DiskUsed     uint64  `json:"disk_used"`
+	DiskTotal    uint64  `json:"disk_total"`
+	Load1        float64 `json:"load1"` // Windows 下为 0
+	Load5        float64 `json:"load5"`
+	Load15       float64 `json:"load15"`
+	RunningTasks int     `json:"running_tasks"`
+	Time         int64   `json:"time"` // 面板收到心跳的时间（Unix 时间戳）
+}
+
 // AgentRegisterRequest Agent 注册请求
 type AgentRegisterRequest struct {
 	Name      string `json:"name"`
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// AgentCert 面板为 Agent 签发的 mTLS 客户端证书（只保存指纹，证书与私钥仅存于 Agent 本地）
+type AgentCert struct {
+	ID           string     `json:"id" gorm:"primaryKey;size:20"`
+	AgentID      string     `json:"agent_id" gorm:"size:20;index"`
+	Fingerprint  string     `json:"fingerprint" gorm:"size:64;uniqueIndex"` // 证书 DER 的 SHA-256（十六进制）
+	SerialNumber string     `json:"serial_number" gorm:"size:64"`
+	NotBefore    LocalTime  `json:"not_before"`
+	NotAfter     LocalTime  `json:"not_after"`
+	Status       string     `json:"status" gorm:"size:20;index"` // constant.AgentCertStatusActive/Revoked
+	RevokeReason string     `json:"revoke_reason" gorm:"size:255"`
+	RevokedAt    *LocalTime `json:"revoked_at"`
+	CreatedAt    LocalTime  `json:"created_at"`
+}
+
+func (AgentCert) TableName() string {
+	return constant.TablePrefix + "agent_certs"
+}
+
+// IsValid 证书未吊销且在有效期内
+func (c *AgentCert) IsValid() bool {
+	now := time.Now()
+	return c.Status == constant.AgentCertStatusActive && now.After(time.Time(c.NotBefore)) && now.Before(time.Time(c.NotAfter))
+}
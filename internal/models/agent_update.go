@@ -0,0 +1,103 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// RolloutWave 分批发布中的一批：Percent 为累计覆盖的 Agent 百分比，Labels 为标签选择器，二者取其一
+type RolloutWave struct {
+	Percent int    `json:"percent,omitempty"`
+	Labels  string `json:"labels,omitempty"`
+}
+
+// RolloutWaves 批次列表，以 JSON 存储
+type RolloutWaves []RolloutWave
+
+func (w RolloutWaves) Value() (driver.Value, error) {
+	if w == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(w)
+	return string(b), err
+}
+
+func (w *RolloutWaves) Scan(v interface{}) error {
+	if v == nil {
+		*w = nil
+		return nil
+	}
+	var data []byte
+	switch s := v.(type) {
+	case string:
+		data = []byte(s)
+	case []byte:
+		data = s
+	default:
+		return fmt.Errorf("invalid type for RolloutWaves: %T", v)
+	}
+	return json.Unmarshal(data, w)
+}
+
+// AgentRollout Agent 新版本的分批发布：前一批全部完成且失败数未超限后自动进入下一批
+type AgentRollout struct {
+	ID             string       `json:"id" gorm:"primaryKey;size:20"`
+	Version        string       `json:"version" gorm:"size:50"` // 目标版本
+	Waves          RolloutWaves `json:"waves" gorm:"type:text"`
+	CurrentWave    int          `json:"current_wave"`                // 当前批次（从 0 开始）
+	MaxFailures    int          `json:"max_failures"`                // 单批失败（含回滚）数超过该值时暂停发布
+	MinWaveMinutes int          `json:"min_wave_minutes"`            // 每批至少持续的分钟数，0 表示本批结束后立即进入下一批
+	Status         string       `json:"status" gorm:"size:20;index"` // constant.RolloutStatus*
+	Message        string       `json:"message" gorm:"size:255"`
+	WaveStartedAt  *LocalTime   `json:"wave_started_at"`
+	CreatedAt      LocalTime    `json:"created_at"`
+	UpdatedAt      LocalTime    `json:"updated_at"`
+}
+
+func (AgentRollout) TableName() string {
+	return constant.TablePrefix + "agent_rollouts"
+}
+
+// AgentUpdate 单个 Agent 的一次版本更新记录（分批发布、强制更新或 Agent 自动更新）
+type AgentUpdate struct {
+	ID          string     `json:"id" gorm:"primaryKey;size:20"`
+	AgentID     string     `json:"agent_id" gorm:"size:20;index"`
+	RolloutID   string     `json:"rollout_id" gorm:"size:20;index;default:''"` // 非分批发布时为空
+	Wave        int        `json:"wave"`
+	FromVersion string     `json:"from_version" gorm:"size:50"`
+	ToVersion   string     `json:"to_version" gorm:"size:50"`
+	Status      string     `json:"status" gorm:"size:20;index"` // constant.AgentUpdate*
+	Message     string     `json:"message" gorm:"size:255"`
+	StartedAt   *LocalTime `json:"started_at"`
+	FinishedAt  *LocalTime `json:"finished_at"`
+	CreatedAt   LocalTime  `json:"created_at"`
+	UpdatedAt   LocalTime  `json:"updated_at"`
+}
+
+func (AgentUpdate) TableName() string {
+	return constant.TablePrefix + "agent_updates"
+}
+
+// IsFinished 更新已结束（成功、回滚或失败）
+func (u *AgentUpdate) IsFinished() bool {
+	switch u.Status {
+	case constant.AgentUpdateSucceeded, constant.AgentUpdateRolledBack, constant.AgentUpdateFailed:
+		return true
+	}
+	return false
+}
+
+// AgentUpdateManifest 更新包清单：Signature 为发布时用离线 ed25519 私钥对更新包生成的分离签名（.sig），
+// 面板只负责转发，Agent 用本地固定的公钥校验签名并比对 SHA-256
+type AgentUpdateManifest struct {
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	SHA256    string `json:"sha256"`
+	Size      int64  `json:"size"`
+	Signature string `json:"signature"` // base64，对更新包全部内容的 ed25519 签名
+}
@@ -14,6 +14,7 @@ type AppLog struct {
 	Status      string     `json:"status" gorm:"size:20;index"`            // 状态：系统通知为 constant.LogStatusRead/constant.LogStatusUnread，推送为 constant.LogStatusSuccess/constant.LogStatusFailed
 	RefID       string     `json:"ref_id" gorm:"size:50;index"`            // 关联对象ID（选填，比如绑定的通知渠道ID、任务ID等）
 	ErrorMsg    BigText    `json:"error_msg"`                              // 执行错误信息详情
+	Suppressed  int        `json:"suppressed" gorm:"default:0"`            // 推送记录：此前被节流/去重而未发送的消息数量
 	CreatedAt   LocalTime  `json:"created_at" gorm:"index"`
 	ReadAt      *LocalTime `json:"read_at"`               // 已读时间（仅对通知生效）
 	ChannelName string     `json:"channel_name" gorm:"-"` // 推送记录的关联渠道名称（动态查询）
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// Check 外部心跳检测（由无法安装 Agent 的机器主动上报 ping）
+type Check struct {
+	ID           string     `json:"id" gorm:"primaryKey;size:20"`
+	Name         string     `json:"name" gorm:"size:255;not null"`
+	Remark       string     `json:"remark" gorm:"size:255;default:''"`
+	Tags         string     `json:"tags" gorm:"size:255;default:''"`           // 标签，逗号分隔
+	PingKey      string     `json:"ping_key" gorm:"size:64;uniqueIndex"`       // ping 地址中的唯一标识 (uuid)
+	Period       int        `json:"period" gorm:"default:86400"`               // 期望的上报周期（秒）
+	Grace        int        `json:"grace" gorm:"default:3600"`                 // 宽限时间（秒）
+	Status       string     `json:"status" gorm:"size:20;default:'new';index"` // 状态: constant.CheckStatusNew/Up/Down/Started/Paused
+	LastPing     *LocalTime `json:"last_ping"`                                 // 最后一次成功/失败 ping 时间
+	LastStart    *LocalTime `json:"last_start"`                                // 最后一次 start ping 时间
+	LastDuration int64      `json:"last_duration"`                             // start 到成功 ping 的耗时（毫秒）
+	WasDown      bool       `json:"-" gorm:"default:false"`                    // start 前处于异常状态，本轮结束时据此判断是否恢复
+	Enabled      *bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt    LocalTime  `json:"created_at"`
+	UpdatedAt    LocalTime  `json:"updated_at"`
+}
+
+func (Check) TableName() string {
+	return constant.TablePrefix + "checks"
+}
+
+// CheckPing 心跳检测的 ping 记录
+type CheckPing struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:20"`
+	CheckID   string    `json:"check_id" gorm:"size:20;index"`
+	Kind      string    `json:"kind" gorm:"size:20"` // constant.CheckPingSuccess/Start/Fail
+	IP        string    `json:"ip" gorm:"size:45"`
+	UserAgent string    `json:"user_agent" gorm:"size:255"`
+	Body      BigText   `json:"body"` // 请求体（截断保存，便于脚本上报输出）
+	CreatedAt LocalTime `json:"created_at" gorm:"index"`
+}
+
+func (CheckPing) TableName() string {
+	return constant.TablePrefix + "check_pings"
+}
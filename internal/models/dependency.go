@@ -11,8 +11,9 @@ type Dependency struct {
 	ID          string    `json:"id" gorm:"primaryKey;size:20"`
 	Name        string    `json:"name" gorm:"size:100;not null"`
 	Version     string    `json:"version" gorm:"size:50"`
-	Language    string    `json:"language" gorm:"size:100;index"`     // 关联语言 (node, python...)
-	LangVersion string    `json:"lang_version" gorm:"size:100;index"` // 关联语言版本
+	Language    string    `json:"language" gorm:"size:100;index"`           // 关联语言 (node, python...)
+	LangVersion string    `json:"lang_version" gorm:"size:100;index"`       // 关联语言版本
+	AgentID     string    `json:"agent_id" gorm:"size:20;index;default:''"` // 安装所在的 Agent，为空表示面板本机
 	Remark      string    `json:"remark" gorm:"size:255"`
 	Log         BigText   `json:"log"`
 	CreatedAt   time.Time `json:"created_at"`
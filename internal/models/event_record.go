@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// EventRecord 持久化的事件总线事件（开启持久化后记录，用于重放）
+type EventRecord struct {
+	ID             string     `json:"id" gorm:"primaryKey;size:20"`
+	Type           string     `json:"type" gorm:"size:50;index"`
+	Payload        BigText    `json:"payload"`                     // JSON
+	Status         string     `json:"status" gorm:"size:20;index"` // constant.EventRecordPending/Handled/Failed
+	Failed         int        `json:"failed" gorm:"default:0"`     // 最近一次分发中出错的句柄数量
+	FailedHandlers BigText    `json:"failed_handlers"`             // 最近一次分发中出错的句柄名称，换行分隔，重放时只分发给这些句柄
+	Replays        int        `json:"replays" gorm:"default:0"`    // 重放次数
+	HandledAt      *LocalTime `json:"handled_at"`
+	CreatedAt      LocalTime  `json:"created_at" gorm:"index"`
+}
+
+func (EventRecord) TableName() string {
+	return constant.TablePrefix + "event_records"
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// NotifyAck 紧急通知的确认记录，超时未确认时升级到备用渠道
+type NotifyAck struct {
+	ID            string     `json:"id" gorm:"primaryKey;size:20"`
+	Token         string     `json:"-" gorm:"size:64;uniqueIndex"` // 确认链接中的随机令牌
+	Event         string     `json:"event" gorm:"size:50"`
+	DataID        string     `json:"data_id" gorm:"size:20"`
+	Title         string     `json:"title" gorm:"size:255"`
+	Content       BigText    `json:"content"`
+	WayID         string     `json:"way_id" gorm:"size:20"`          // 首次发送的渠道
+	EscalateWayID string     `json:"escalate_way_id" gorm:"size:20"` // 升级渠道
+	EscalateAt    LocalTime  `json:"escalate_at" gorm:"index"`
+	Status        string     `json:"status" gorm:"size:20;index"` // constant.NotifyAckPending/Acked/Escalated
+	AckedAt       *LocalTime `json:"acked_at"`
+	CreatedAt     LocalTime  `json:"created_at"`
+}
+
+func (NotifyAck) TableName() string {
+	return constant.TablePrefix + "notify_acks"
+}
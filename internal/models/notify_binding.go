@@ -18,8 +18,37 @@ type NotifyBinding struct {
 
 // BindingExtra 存储在 Extra 字段中的 JSON 配置
 type BindingExtra struct {
-	EnableLog bool `json:"enable_log"`
-	LogLimit  int  `json:"log_limit"` // 日志字数限制，默认 1000
+	EnableLog       bool          `json:"enable_log"`
+	LogLimit        int           `json:"log_limit"`          // 日志字数限制，默认 1000
+	Throttle        *ThrottleRule `json:"throttle,omitempty"` // 节流规则，未设置时使用渠道的节流规则
+	MinFailures     int           `json:"min_failures"`       // 连续失败达到 N 次才通知（失败/超时事件），默认 1
+	StateChangeOnly bool          `json:"state_change_only"`  // 仅在状态变化时通知：首次进入失败状态时通知，成功事件仅在从失败中恢复时通知
+
+	// 富文本消息（均为可选），Markdown/URL/ImageURL 支持与通知模板相同的语法
+	Markdown  string   `json:"markdown,omitempty"`    // Markdown 正文模板，渠道支持时优先发送
+	URL       string   `json:"url,omitempty"`         // 跳转链接模板
+	ImageURL  string   `json:"image_url,omitempty"`   // 图片链接模板
+	AtMobiles []string `json:"at_mobiles,omitempty"`  // @ 的手机号
+	AtUserIds []string `json:"at_user_ids,omitempty"` // @ 的用户ID
+	AtAll     bool     `json:"at_all,omitempty"`      // @ 所有人
+
+	// 告警级别与升级
+	Severity      string `json:"severity,omitempty"`        // constant.NotifySeverityNormal / NotifySeverityCritical，紧急通知不受静默时段限制
+	EscalateWayID string `json:"escalate_way_id,omitempty"` // 紧急通知未确认时升级发送的渠道（如短信）
+	EscalateAfter int    `json:"escalate_after,omitempty"`  // 发送后多少分钟内未确认则升级，0 表示不升级
+}
+
+// ThrottleRule 通知节流规则（可配置在渠道或事件绑定上）
+type ThrottleRule struct {
+	DedupeWindow int  `json:"dedupe_window"` // 去重窗口（秒），窗口内相同事件只发送一次，0 表示不去重
+	RateLimit    int  `json:"rate_limit"`    // 限流窗口内最多发送条数，0 表示不限流
+	RateWindow   int  `json:"rate_window"`   // 限流窗口（秒），默认 60
+	Digest       bool `json:"digest"`        // 超出限流的消息在窗口结束时合并为一条摘要发送
+}
+
+// Enabled 规则是否生效
+func (r *ThrottleRule) Enabled() bool {
+	return r != nil && (r.DedupeWindow > 0 || r.RateLimit > 0)
 }
 
 func (NotifyBinding) TableName() string {
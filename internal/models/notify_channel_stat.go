@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// NotifyChannelStat 渠道发送统计，每个渠道一条记录
+type NotifyChannelStat struct {
+	WayID         string     `json:"way_id" gorm:"primaryKey;size:20"`
+	SuccessCount  int64      `json:"success_count" gorm:"default:0"`
+	FailedCount   int64      `json:"failed_count" gorm:"default:0"`  // 重试后仍失败的消息数
+	AttemptCount  int64      `json:"attempt_count" gorm:"default:0"` // 实际请求次数（含重试）
+	TotalLatency  int64      `json:"total_latency" gorm:"default:0"` // 全部请求耗时之和（毫秒）
+	LastError     BigText    `json:"last_error"`
+	LastErrorAt   *LocalTime `json:"last_error_at"`
+	LastSuccessAt *LocalTime `json:"last_success_at"`
+	UpdatedAt     LocalTime  `json:"updated_at"`
+}
+
+func (NotifyChannelStat) TableName() string {
+	return constant.TablePrefix + "notify_channel_stats"
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// NotifyQuietItem 渠道静默时段内暂存的通知，时段结束时合并发送后删除
+type NotifyQuietItem struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:20"`
+	WayID     string    `json:"way_id" gorm:"size:20;index"` // 暂存的渠道
+	Summary   BigText   `json:"summary"`                     // 通知摘要，汇总消息中的一行
+	FlushAt   LocalTime `json:"flush_at"`                    // 静默结束、应汇总发送的时间
+	CreatedAt LocalTime `json:"created_at"`
+}
+
+func (NotifyQuietItem) TableName() string {
+	return constant.TablePrefix + "notify_quiet_items"
+}
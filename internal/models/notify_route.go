@@ -0,0 +1,26 @@
+package models
+
+import (
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// NotifyRoute 通知路由规则：按标签、Agent、任务类型或名称匹配任务，命中后将事件推送到指定渠道
+// 与 NotifyBinding 一同生效，新建的任务无需逐个配置绑定即可继承告警
+type NotifyRoute struct {
+	ID          string    `json:"id" gorm:"primaryKey;size:20"`
+	Name        string    `json:"name" gorm:"size:100;not null"`
+	Events      string    `json:"events" gorm:"size:500;not null"`         // 匹配的事件类型，逗号分隔
+	WayID       string    `json:"way_id" gorm:"size:20;not null;index"`    // 通知渠道ID
+	Tags        string    `json:"tags" gorm:"size:255;default:''"`         // 任务包含任一标签即匹配，逗号分隔，为空不限
+	AgentID     string    `json:"agent_id" gorm:"size:20;default:''"`      // 执行位置，constant.NotifyRouteAgentLocal 表示本地，为空不限
+	TaskType    string    `json:"task_type" gorm:"size:20;default:''"`     // 任务类型: constant.TaskTypeNormal, constant.TaskTypeRepo，为空不限
+	NamePattern string    `json:"name_pattern" gorm:"size:255;default:''"` // 任务名称通配符（支持 * ?，不区分大小写），为空不限
+	Extra       BigText   `json:"extra"`                                   // 与 NotifyBinding.Extra 相同（BindingExtra 结构）
+	Enabled     *bool     `json:"enabled" gorm:"default:true"`
+	CreatedAt   LocalTime `json:"created_at"`
+	UpdatedAt   LocalTime `json:"updated_at"`
+}
+
+func (NotifyRoute) TableName() string {
+	return constant.TablePrefix + "notify_routes"
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/engigu/baihu-panel/internal/constant"
 )
 
@@ -11,6 +14,9 @@ type NotifyWay struct {
 	Type      string         `json:"type" gorm:"size:50;not null;index"`
 	Config    BigText        `json:"config"`
 	Enabled   *bool          `json:"enabled" gorm:"default:true;index"`
+	Throttle  BigText        `json:"throttle"`    // 节流规则 JSON（对应 ThrottleRule 结构）
+	QuietHours BigText       `json:"quiet_hours"` // 静默时段 JSON（对应 QuietHours 结构）
+	Retry     BigText        `json:"retry"`       // 失败重试策略 JSON（对应 RetryPolicy 结构）
 	CreatedAt LocalTime      `json:"created_at"`
 	UpdatedAt LocalTime      `json:"updated_at"`
 }
@@ -18,3 +24,120 @@ type NotifyWay struct {
 func (NotifyWay) TableName() string {
 	return constant.TablePrefix + "notify_ways"
 }
+
+// QuietHours 渠道静默时段，时段内的非紧急通知会暂存，结束时合并为一条汇总发送
+type QuietHours struct {
+	Start    string `json:"start"`    // 开始时间 HH:MM
+	End      string `json:"end"`      // 结束时间 HH:MM，早于开始时间表示跨天（如 23:00-07:00）
+	Timezone string `json:"timezone"` // IANA 时区，如 Asia/Shanghai，为空使用服务器时区
+}
+
+// Enabled 是否配置了有效的静默时段
+func (q *QuietHours) Enabled() bool {
+	return q != nil && q.Start != "" && q.End != "" && q.Start != q.End
+}
+
+// Validate 校验时间格式与时区
+func (q *QuietHours) Validate() error {
+	if !q.Enabled() {
+		return nil
+	}
+	if _, err := time.Parse("15:04", q.Start); err != nil {
+		return fmt.Errorf("静默开始时间格式错误: %s", q.Start)
+	}
+	if _, err := time.Parse("15:04", q.End); err != nil {
+		return fmt.Errorf("静默结束时间格式错误: %s", q.End)
+	}
+	if _, err := q.location(); err != nil {
+		return fmt.Errorf("无效的时区: %s", q.Timezone)
+	}
+	return nil
+}
+
+func (q *QuietHours) location() (*time.Location, error) {
+	if q.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(q.Timezone)
+}
+
+// Until 判断 now 是否处于静默时段内，是则返回本次静默的结束时间
+func (q *QuietHours) Until(now time.Time) (time.Time, bool) {
+	if !q.Enabled() {
+		return time.Time{}, false
+	}
+	loc, err := q.location()
+	if err != nil {
+		return time.Time{}, false
+	}
+	start, err1 := time.Parse("15:04", q.Start)
+	end, err2 := time.Parse("15:04", q.End)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, false
+	}
+
+	local := now.In(loc)
+	minutes := local.Hour()*60 + local.Minute()
+	startMin := start.Hour()*60 + start.Minute()
+	endMin := end.Hour()*60 + end.Minute()
+
+	var inside bool
+	if startMin < endMin {
+		inside = minutes >= startMin && minutes < endMin
+	} else {
+		// 跨天
+		inside = minutes >= startMin || minutes < endMin
+	}
+	if !inside {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
+
+const (
+	maxRetryTimes = 5                // 最多重试次数
+	maxRetryDelay = 60 * time.Second // 单次重试等待上限
+)
+
+// RetryPolicy 渠道发送失败后的重试策略，重试间隔按指数退避递增
+type RetryPolicy struct {
+	Times    int `json:"times"`    // 失败后最多重试次数，0 表示不重试
+	Interval int `json:"interval"` // 首次重试间隔（秒），之后每次翻倍，默认 2
+}
+
+// Enabled 是否需要重试
+func (r *RetryPolicy) Enabled() bool {
+	return r != nil && r.Times > 0
+}
+
+// Validate 校验重试次数与间隔
+func (r *RetryPolicy) Validate() error {
+	if !r.Enabled() {
+		return nil
+	}
+	if r.Times > maxRetryTimes {
+		return fmt.Errorf("重试次数不能超过 %d 次", maxRetryTimes)
+	}
+	if r.Interval < 0 || time.Duration(r.Interval)*time.Second > maxRetryDelay {
+		return fmt.Errorf("重试间隔需在 0-%d 秒之间", int(maxRetryDelay.Seconds()))
+	}
+	return nil
+}
+
+// Delay 第 n 次重试（从 1 开始）前的等待时间
+func (r *RetryPolicy) Delay(n int) time.Duration {
+	interval := time.Duration(r.Interval) * time.Second
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	delay := interval << (n - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		delay = maxRetryDelay
+	}
+	return delay
+}
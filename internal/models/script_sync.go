@@ -0,0 +1,59 @@
+package models
+
+import (
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// ScriptSync 脚本目录同步规则：将面板 scripts 下的目录推送到匹配的 Agent
+type ScriptSync struct {
+	ID            string    `json:"id" gorm:"primaryKey;size:20"`
+	Path          string    `json:"path" gorm:"size:255;not null"`             // 相对 scripts 目录的路径，Agent 上同步到 data/scripts 下的同名目录
+	AgentSelector string    `json:"agent_selector" gorm:"size:255;default:''"` // Agent 标签选择器，逗号分隔需同时满足
+	AgentIDs      string    `json:"agent_ids" gorm:"size:1000;default:''"`     // 显式指定的 Agent ID，逗号分隔；与选择器均为空时同步到全部 Agent
+	Remark        string    `json:"remark" gorm:"size:255"`
+	Enabled       *bool     `json:"enabled" gorm:"default:true"`
+	CreatedAt     LocalTime `json:"created_at"`
+	UpdatedAt     LocalTime `json:"updated_at"`
+}
+
+func (ScriptSync) TableName() string {
+	return constant.TablePrefix + "script_syncs"
+}
+
+// MatchAgent 判断规则是否作用于指定 Agent
+func (s *ScriptSync) MatchAgent(agent *Agent) bool {
+	ids := SplitLabels(s.AgentIDs)
+	if len(ids) == 0 && s.AgentSelector == "" {
+		return true
+	}
+	for _, id := range ids {
+		if id == agent.ID {
+			return true
+		}
+	}
+	return agent.MatchLabels(s.AgentSelector)
+}
+
+// ScriptManifestFile 同步清单中的单个文件
+type ScriptManifestFile struct {
+	Path string `json:"path"` // 相对同步目录，使用 / 分隔
+	Hash string `json:"hash"` // sha256
+	Size int64  `json:"size"`
+	Mode uint32 `json:"mode"`
+}
+
+// ScriptManifest 同步目录的内容清单，Hash 为全部文件路径与摘要的总摘要
+type ScriptManifest struct {
+	Hash  string               `json:"hash"`
+	Files []ScriptManifestFile `json:"files"`
+}
+
+// ScriptSyncResult Agent 回报的同步结果
+type ScriptSyncResult struct {
+	RequestID  string `json:"request_id"`
+	SyncID     string `json:"sync_id"`
+	Hash       string `json:"hash"`
+	Downloaded int    `json:"downloaded"`
+	Removed    int    `json:"removed"`
+	Error      string `json:"error"`
+}
@@ -66,6 +66,27 @@ type TaskConfig struct {
 	AllEnvs     bool `json:"$task_all_envs"`    // 开启则注入全部环境变量
 }
 
+// TaskPlacement 任务在 Agent 上的调度配置（AgentID 为空时生效）
+type TaskPlacement struct {
+	AgentSelector   string `json:"agent_selector" gorm:"size:255;default:''"`   // Agent 标签选择器，逗号分隔需同时满足
+	AgentMode       string `json:"agent_mode" gorm:"size:20;default:''"`        // 调度模式: constant.AgentModeSingle, constant.AgentModeBroadcast
+	AgentIDs        string `json:"agent_ids" gorm:"size:1000;default:''"`       // 广播模式下显式指定的 Agent ID，逗号分隔
+	BroadcastPolicy string `json:"broadcast_policy" gorm:"size:10;default:''"`  // 广播成功判定: constant.BroadcastPolicyAll, constant.BroadcastPolicyAny
+	FailoverMode    string `json:"failover_mode" gorm:"size:20;default:''"`     // Agent 不可用时的故障转移策略，为空表示直接失败
+	FailoverAgentID string `json:"failover_agent_id" gorm:"size:20;default:''"` // 备用 Agent ID（FailoverModeAgent）
+	FailoverWait    int    `json:"failover_wait" gorm:"default:0"`              // 等待 Agent 恢复的最长分钟数（FailoverModeWait）
+}
+
+// HasFailover 是否配置了故障转移策略
+func (p TaskPlacement) HasFailover() bool {
+	return p.FailoverMode != ""
+}
+
+// IsBroadcast 是否广播到多个 Agent 执行
+func (p TaskPlacement) IsBroadcast() bool {
+	return p.AgentMode == constant.AgentModeBroadcast
+}
+
 // Task 代表一个计划任务
 type Task struct {
 	ID            string              `json:"id" gorm:"primaryKey;size:20"`
@@ -84,6 +105,7 @@ type Task struct {
 	Envs          BigText             `json:"envs"`                      // 环境变量ID列表，逗号分隔
 	Languages     TaskLanguages       `json:"languages" gorm:"type:text"`                      // 针对本地任务的语言配置列表
 	AgentID       *string             `json:"agent_id" gorm:"size:20;index"`              // Agent ID，为空表示本地执行
+	TaskPlacement
 	RetryCount    int                 `json:"retry_count" gorm:"default:0"`               // 失败重试次数
 	RetryInterval int                 `json:"retry_interval" gorm:"default:0"`            // 失败重试间隔(秒)
 	RandomRange   int                 `json:"random_range" gorm:"default:0"`              // 随机延迟范围(秒)
@@ -147,7 +169,17 @@ func (t *Task) GetSecrets() []string {
 }
 
 func (t *Task) GetUseMise() bool {
-	return t.AgentID == nil || *t.AgentID == ""
+	return !t.IsRemote()
+}
+
+// IsRemote 是否在 Agent 上执行（指定 Agent、按标签选择或广播）
+func (t *Task) IsRemote() bool {
+	return t.HasFixedAgent() || t.AgentSelector != "" || t.IsBroadcast()
+}
+
+// HasFixedAgent 是否固定指定了 Agent（由 Agent 自行调度）
+func (t *Task) HasFixedAgent() bool {
+	return t.AgentID != nil && *t.AgentID != ""
 }
 
 func (t *Task) UseMise() bool {
@@ -168,6 +200,8 @@ type TaskLog struct {
 	ID        string     `json:"id" gorm:"primaryKey;size:20"`
 	TaskID    string     `json:"task_id" gorm:"size:20;index"`
 	AgentID   *string    `json:"agent_id" gorm:"size:20;index"` // Agent ID，为空表示本地执行
+	ParentID  string     `json:"parent_id" gorm:"size:20;index;default:''"` // 广播执行时指向父执行记录，为空表示顶层记录
+	RetryIndex int       `json:"retry_index" gorm:"default:0"`               // 失败重试的序号，0 表示首次执行
 	Command   BigText    `json:"command"`
 	Output    BigText    `json:"-"`          // gzip+base64 压缩后的日志
 	Error     BigText    `json:"error"`      // 额外的系统错误信息
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// TerminalSession Agent 远程终端会话（审计记录，输入输出录像保存在 constant.TerminalRecordDir）
+type TerminalSession struct {
+	ID          string     `json:"id" gorm:"primaryKey;size:20"`
+	AgentID     string     `json:"agent_id" gorm:"size:20;index"`
+	AgentName   string     `json:"agent_name" gorm:"size:100"`
+	UserID      string     `json:"user_id" gorm:"size:20"`
+	Username    string     `json:"username" gorm:"size:100"`
+	ClientIP    string     `json:"client_ip" gorm:"size:45"`
+	Status      string     `json:"status" gorm:"size:20;index"` // constant.TerminalStatusActive/Closed
+	CloseReason string     `json:"close_reason" gorm:"size:255"`
+	RecordFile  string     `json:"-" gorm:"size:255"`
+	RecordSize  int64      `json:"record_size" gorm:"default:0"`
+	StartedAt   LocalTime  `json:"started_at" gorm:"index"`
+	EndedAt     *LocalTime `json:"ended_at"`
+}
+
+func (TerminalSession) TableName() string {
+	return constant.TablePrefix + "terminal_sessions"
+}
@@ -7,21 +7,24 @@ import (
 
 // AgentVO 代理视图对象
 type AgentVO struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	Description string            `json:"description"`
-	Status      string            `json:"status"`
-	LastSeen    *models.LocalTime `json:"last_seen"`
-	IP          string            `json:"ip"`
-	Version     string            `json:"version"`
-	BuildTime   string            `json:"build_time"`
-	Hostname    string            `json:"hostname"`
-	OS          string            `json:"os"`
-	Arch        string            `json:"arch"`
-	ForceUpdate bool              `json:"force_update"`
-	Enabled     bool              `json:"enabled"`
-	CreatedAt   models.LocalTime  `json:"created_at"`
-	UpdatedAt   models.LocalTime  `json:"updated_at"`
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Labels      string               `json:"labels"`
+	Status      string               `json:"status"`
+	LastSeen    *models.LocalTime    `json:"last_seen"`
+	IP          string               `json:"ip"`
+	Version     string               `json:"version"`
+	BuildTime   string               `json:"build_time"`
+	Hostname    string               `json:"hostname"`
+	OS          string               `json:"os"`
+	Arch        string               `json:"arch"`
+	ForceUpdate bool                 `json:"force_update"`
+	Enabled     bool                 `json:"enabled"`
+	CertEnroll  bool                 `json:"cert_enroll"` // 是否允许凭 Token 申请 mTLS 客户端证书
+	CreatedAt   models.LocalTime     `json:"created_at"`
+	UpdatedAt   models.LocalTime     `json:"updated_at"`
+	Metrics     *models.AgentMetrics `json:"metrics"` // 最近一次心跳上报的主机指标
 	// 隐藏 Token 和 MachineID
 }
 
@@ -34,6 +37,7 @@ func ToAgentVO(agent *models.Agent) *AgentVO {
 		ID:          agent.ID,
 		Name:        agent.Name,
 		Description: agent.Description,
+		Labels:      agent.Labels,
 		Status:      agent.Status,
 		LastSeen:    agent.LastSeen,
 		IP:          agent.IP,
@@ -44,6 +48,7 @@ func ToAgentVO(agent *models.Agent) *AgentVO {
 		Arch:        agent.Arch,
 		ForceUpdate: agent.ForceUpdate,
 		Enabled:     utils.DerefBool(agent.Enabled, true),
+		CertEnroll:  utils.DerefBool(agent.CertEnroll, true),
 		CreatedAt:   agent.CreatedAt,
 		UpdatedAt:   agent.UpdatedAt,
 	}
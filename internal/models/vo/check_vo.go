@@ -0,0 +1,76 @@
+package vo
+
+import (
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// CheckVO 心跳检测视图对象
+type CheckVO struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Remark       string            `json:"remark"`
+	Tags         string            `json:"tags"`
+	PingKey      string            `json:"ping_key"`
+	PingPath     string            `json:"ping_path"` // 相对 ping 地址（不含域名，含 URL 前缀）
+	Period       int               `json:"period"`
+	Grace        int               `json:"grace"`
+	Status       string            `json:"status"`
+	LastPing     *models.LocalTime `json:"last_ping"`
+	LastStart    *models.LocalTime `json:"last_start"`
+	LastDuration int64             `json:"last_duration"`
+	NextDeadline *models.LocalTime `json:"next_deadline"` // 超过该时间未上报将被标记为异常
+	Enabled      bool              `json:"enabled"`
+	CreatedAt    models.LocalTime  `json:"created_at"`
+	UpdatedAt    models.LocalTime  `json:"updated_at"`
+}
+
+// ToCheckVO 将 Check 模型转换为 CheckVO
+func ToCheckVO(check *models.Check, urlPrefix string) *CheckVO {
+	if check == nil {
+		return nil
+	}
+	v := &CheckVO{
+		ID:           check.ID,
+		Name:         check.Name,
+		Remark:       check.Remark,
+		Tags:         check.Tags,
+		PingKey:      check.PingKey,
+		PingPath:     urlPrefix + "/ping/" + check.PingKey,
+		Period:       check.Period,
+		Grace:        check.Grace,
+		Status:       check.Status,
+		LastPing:     check.LastPing,
+		LastStart:    check.LastStart,
+		LastDuration: check.LastDuration,
+		Enabled:      utils.DerefBool(check.Enabled, true),
+		CreatedAt:    check.CreatedAt,
+		UpdatedAt:    check.UpdatedAt,
+	}
+
+	switch check.Status {
+	case constant.CheckStatusUp:
+		if check.LastPing != nil {
+			deadline := models.LocalTime(check.LastPing.Time().Add(time.Duration(check.Period+check.Grace) * time.Second))
+			v.NextDeadline = &deadline
+		}
+	case constant.CheckStatusStarted:
+		if check.LastStart != nil {
+			deadline := models.LocalTime(check.LastStart.Time().Add(time.Duration(check.Grace) * time.Second))
+			v.NextDeadline = &deadline
+		}
+	}
+	return v
+}
+
+// ToCheckVOListFromModels 将 Check 模型列表转换为 CheckVO 列表
+func ToCheckVOListFromModels(checks []models.Check, urlPrefix string) []*CheckVO {
+	vos := make([]*CheckVO, len(checks))
+	for i := range checks {
+		vos[i] = ToCheckVO(&checks[i], urlPrefix)
+	}
+	return vos
+}
@@ -13,6 +13,7 @@ type DependencyVO struct {
 	Version     string    `json:"version"`
 	Language    string    `json:"language"`
 	LangVersion string    `json:"lang_version"`
+	AgentID     string    `json:"agent_id"`
 	Remark      string    `json:"remark"`
 	Log         string    `json:"log,omitempty"` // 仅在需要时返回
 	CreatedAt   time.Time `json:"created_at"`
@@ -30,6 +31,7 @@ func ToDependencyVO(dep *models.Dependency) *DependencyVO {
 		Version:     dep.Version,
 		Language:    dep.Language,
 		LangVersion: dep.LangVersion,
+		AgentID:     dep.AgentID,
 		Remark:      dep.Remark,
 		Log:         string(dep.Log),
 		CreatedAt:   dep.CreatedAt,
@@ -22,6 +22,7 @@ type TaskVO struct {
 	Envs        string              `json:"envs"`
 	Languages   models.TaskLanguages `json:"languages"`
 	AgentID     *string             `json:"agent_id"`
+	models.TaskPlacement
 	RepoTaskID  string              `json:"repo_task_id"`
 	Enabled       bool                `json:"enabled"`
 	RetryCount    int                 `json:"retry_count"`
@@ -55,6 +56,7 @@ func ToTaskVO(task *models.Task) *TaskVO {
 		Envs:        string(task.Envs),
 		Languages:   task.Languages,
 		AgentID:       task.AgentID,
+		TaskPlacement: task.TaskPlacement,
 		RepoTaskID:    task.RepoTaskID,
 		Enabled:       utils.DerefBool(task.Enabled, true),
 		RetryCount:    task.RetryCount,
@@ -102,6 +104,7 @@ type TaskLogVO struct {
 	TaskName  string            `json:"task_name"`
 	TaskType  string            `json:"task_type"`
 	AgentID   *string           `json:"agent_id"`
+	ParentID  string            `json:"parent_id"`
 	Command   string            `json:"command"`
 	Error     string            `json:"error"`
 	Status    string            `json:"status"`
@@ -124,6 +127,7 @@ func ToTaskLogVO(log *models.TaskLog) *TaskLogVO {
 		ID:        log.ID,
 		TaskID:    log.TaskID,
 		AgentID:   log.AgentID,
+		ParentID:  log.ParentID,
 		Command:   string(log.Command),
 		Error:     string(log.Error),
 		Status:    log.Status,
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// Webhook 出站 Webhook 配置（订阅事件总线事件并推送 JSON 到外部系统）
+type Webhook struct {
+	ID         string    `json:"id" gorm:"primaryKey;size:20"`
+	Name       string    `json:"name" gorm:"size:100;not null"`
+	URL        string    `json:"url" gorm:"size:500;not null"`
+	Secret     string    `json:"secret" gorm:"size:128"`             // HMAC-SHA256 签名密钥
+	Events     string    `json:"events" gorm:"size:1000;default:''"` // 订阅的事件类型，逗号分隔，* 表示全部
+	MaxRetries int       `json:"max_retries" gorm:"default:3"`       // 失败后最大重试次数
+	Timeout    int       `json:"timeout" gorm:"default:10"`          // 单次请求超时（秒）
+	Enabled    *bool     `json:"enabled" gorm:"default:true;index"`
+	CreatedAt  LocalTime `json:"created_at"`
+	UpdatedAt  LocalTime `json:"updated_at"`
+}
+
+func (Webhook) TableName() string {
+	return constant.TablePrefix + "webhooks"
+}
+
+// WebhookDelivery Webhook 投递记录
+type WebhookDelivery struct {
+	ID             string    `json:"id" gorm:"primaryKey;size:20"`
+	WebhookID      string    `json:"webhook_id" gorm:"size:20;index"`
+	Event          string    `json:"event" gorm:"size:50;index"`
+	Status         string    `json:"status" gorm:"size:20;index"` // constant.WebhookStatusPending/Success/Failed
+	Attempts       int       `json:"attempts" gorm:"default:0"`   // 已尝试次数
+	RequestURL     string    `json:"request_url" gorm:"size:500"`
+	RequestHeaders BigText   `json:"request_headers"` // JSON
+	RequestBody    BigText   `json:"request_body"`
+	ResponseCode   int       `json:"response_code"`
+	ResponseBody   BigText   `json:"response_body"`
+	Latency        int64     `json:"latency"` // 最后一次请求耗时（毫秒）
+	Error          BigText   `json:"error"`
+	RedeliveryOf   string    `json:"redelivery_of" gorm:"size:20"` // 手动重新投递时指向原记录
+	CreatedAt      LocalTime `json:"created_at" gorm:"index"`
+	UpdatedAt      LocalTime `json:"updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return constant.TablePrefix + "webhook_deliveries"
+}
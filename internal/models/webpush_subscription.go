@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/engigu/baihu-panel/internal/constant"
+)
+
+// WebPushSubscription 浏览器 Web Push 订阅
+type WebPushSubscription struct {
+	ID         string     `json:"id" gorm:"primaryKey;size:20"`
+	UserID     string     `json:"user_id" gorm:"size:20;index"`
+	Endpoint   string     `json:"endpoint" gorm:"size:1000;uniqueIndex"`
+	P256dh     string     `json:"-" gorm:"size:255"`
+	Auth       string     `json:"-" gorm:"size:64"`
+	UserAgent  string     `json:"user_agent" gorm:"size:255"`
+	LastUsedAt *LocalTime `json:"last_used_at"`
+	CreatedAt  LocalTime  `json:"created_at"`
+}
+
+func (WebPushSubscription) TableName() string {
+	return constant.TablePrefix + "web_push_subscriptions"
+}
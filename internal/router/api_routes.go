@@ -23,6 +23,10 @@ func initPublicAPIRoutes(api *gin.RouterGroup, c *Controllers) {
 
 	// 公开的站点设置（无需认证）
 	api.GET("/settings/public", c.Settings.GetPublicSiteSettings)
+
+	// 紧急通知确认链接（凭令牌确认，无需认证）；GET 只展示确认页面，提交 POST 后才确认
+	api.GET("/notify/ack/:token", c.Notification.AckPage)
+	api.POST("/notify/ack/:token", c.Notification.AckByToken)
 }
 
 func initAuthorizedAPIRoutes(api *gin.RouterGroup, c *Controllers) {
@@ -32,6 +36,15 @@ func initAuthorizedAPIRoutes(api *gin.RouterGroup, c *Controllers) {
 		// 获取当前用户 (普通用户即可访问)
 		authorized.GET("/auth/me", c.Auth.GetCurrentUser)
 
+		// 浏览器推送订阅（登录用户即可订阅自己的浏览器）
+		webPush := authorized.Group("/notify/webpush")
+		{
+			webPush.GET("/key", c.WebPush.GetPublicKey)
+			webPush.POST("/subscribe", c.WebPush.Subscribe)
+			webPush.POST("/unsubscribe", c.WebPush.Unsubscribe)
+			webPush.POST("/test", c.WebPush.Test)
+		}
+
 		// 以下管理接口需要管理员权限
 		adminOnly := authorized.Group("")
 		adminOnly.Use(middleware.AdminRequired())
@@ -54,6 +67,9 @@ func initAuthorizedAPIRoutes(api *gin.RouterGroup, c *Controllers) {
 			registerMiseRoutes(adminOnly, c)
 			registerNotificationRoutes(adminOnly, c)
 			registerAppLogRoutes(adminOnly, c)
+			registerCheckRoutes(adminOnly, c)
+			registerWebhookRoutes(adminOnly, c)
+			registerEventRoutes(adminOnly, c)
 		}
 	}
 
@@ -192,10 +208,34 @@ func registerAgentRoutes(g *gin.RouterGroup, c *Controllers) {
 	{
 		agents.GET("", c.Agent.List)
 		agents.GET("/version", c.Agent.GetVersion)
+		agents.GET("/labels", c.Agent.ListLabels)
 		agents.PUT("/:id", c.Agent.Update)
 		agents.DELETE("/:id", c.Agent.Delete)
 		agents.POST("/:id/token", c.Agent.RegenerateToken)
 		agents.POST("/:id/update", c.Agent.ForceUpdate)
+		agents.GET("/:id/updates", c.Agent.ListUpdates)
+		agents.GET("/:id/metrics", c.Agent.Metrics)
+		agents.GET("/:id/certs", c.Agent.ListCerts)
+		agents.POST("/:id/certs/rotate", c.Agent.RotateCert)
+		agents.POST("/:id/certs/enroll", c.Agent.AllowCertEnroll)
+		agents.POST("/certs/:id/revoke", c.Agent.RevokeCert)
+		agents.GET("/pki/ca", c.Agent.DownloadCA)
+		// 分批发布
+		agents.GET("/rollouts", c.Agent.ListRollouts)
+		agents.POST("/rollouts", c.Agent.CreateRollout)
+		agents.GET("/rollouts/:id", c.Agent.GetRollout)
+		agents.POST("/rollouts/:id/pause", c.Agent.PauseRollout)
+		agents.POST("/rollouts/:id/resume", c.Agent.ResumeRollout)
+		agents.POST("/rollouts/:id/cancel", c.Agent.CancelRollout)
+		// 远程终端审计
+		agents.GET("/terminal/sessions", c.Agent.ListTerminalSessions)
+		agents.GET("/terminal/sessions/:id/record", c.Agent.DownloadTerminalRecord)
+		// 脚本同步
+		agents.GET("/script-syncs", c.Agent.ListScriptSyncs)
+		agents.POST("/script-syncs", c.Agent.SaveScriptSync)
+		agents.DELETE("/script-syncs/:id", c.Agent.DeleteScriptSync)
+		agents.GET("/script-syncs/status", c.Agent.ScriptSyncStatus)
+		agents.POST("/script-syncs/trigger", c.Agent.TriggerScriptSync)
 		// 令牌管理
 		agents.GET("/tokens", c.Agent.ListTokens)
 		agents.POST("/tokens", c.Agent.CreateToken)
@@ -232,11 +272,22 @@ func registerNotificationRoutes(g *gin.RouterGroup, c *Controllers) {
 		notify.GET("/channels", c.Notification.GetChannels)
 		notify.POST("/channels", c.Notification.SaveChannel)
 		notify.DELETE("/channels/:id", c.Notification.DeleteChannel)
+		notify.DELETE("/channels/:id/stats", c.Notification.ResetChannelStats)
 		notify.POST("/channels/test", c.Notification.TestChannel)
 		notify.GET("/bindings", c.Notification.GetBindings)
 		notify.POST("/bindings", c.Notification.SaveBinding)
 		notify.POST("/bindings/batch", c.Notification.BatchSaveBindings)
 		notify.DELETE("/bindings/:id", c.Notification.DeleteBinding)
+		notify.GET("/routes", c.Notification.GetRoutes)
+		notify.POST("/routes", c.Notification.SaveRoute)
+		notify.POST("/routes/preview", c.Notification.PreviewRoute)
+		notify.DELETE("/routes/:id", c.Notification.DeleteRoute)
+		notify.GET("/webpush/subscriptions", c.WebPush.GetSubscriptions)
+		notify.DELETE("/webpush/subscriptions/:id", c.WebPush.DeleteSubscription)
+		notify.GET("/acks", c.Notification.GetAcks)
+		notify.POST("/acks/:id/ack", c.Notification.AckAlert)
+		notify.GET("/templates", c.Notification.GetTemplateFuncs)
+		notify.POST("/templates/preview", c.Notification.PreviewTemplate)
 	}
 }
 
@@ -249,6 +300,44 @@ func registerAppLogRoutes(g *gin.RouterGroup, c *Controllers) {
 	}
 }
 
+func registerCheckRoutes(g *gin.RouterGroup, c *Controllers) {
+	checks := g.Group("/checks")
+	{
+		checks.GET("", c.Check.List)
+		checks.POST("", c.Check.Create)
+		checks.GET("/:id", c.Check.Get)
+		checks.PUT("/:id", c.Check.Update)
+		checks.DELETE("/:id", c.Check.Delete)
+		checks.POST("/:id/key", c.Check.RegenerateKey)
+		checks.GET("/:id/pings", c.Check.GetPings)
+	}
+}
+
+func registerWebhookRoutes(g *gin.RouterGroup, c *Controllers) {
+	webhooks := g.Group("/webhooks")
+	{
+		webhooks.GET("/events", c.Webhook.GetEvents)
+		webhooks.GET("", c.Webhook.List)
+		webhooks.POST("", c.Webhook.Save)
+		webhooks.DELETE("/:id", c.Webhook.Delete)
+		webhooks.POST("/:id/test", c.Webhook.Test)
+		webhooks.GET("/:id/deliveries", c.Webhook.GetDeliveries)
+		webhooks.GET("/deliveries/:id", c.Webhook.GetDelivery)
+		webhooks.POST("/deliveries/:id/redeliver", c.Webhook.Redeliver)
+	}
+}
+
+func registerEventRoutes(g *gin.RouterGroup, c *Controllers) {
+	events := g.Group("/events")
+	{
+		events.GET("/stream", c.Event.Stream)
+		events.GET("/stats", c.Event.GetStats)
+		events.GET("", c.Event.GetRecords)
+		events.POST("/replay", c.Event.ReplayByStatus)
+		events.POST("/:id/replay", c.Event.Replay)
+	}
+}
+
 func initAgentAPIRoutes(root *gin.RouterGroup, c *Controllers) {
 	// Agent API（供远程 Agent 调用，不使用 /v1 版本号）
 	agentAPI := root.Group("/api/agent")
@@ -256,7 +345,20 @@ func initAgentAPIRoutes(root *gin.RouterGroup, c *Controllers) {
 		agentAPI.POST("/heartbeat", c.Agent.Heartbeat)
 		agentAPI.GET("/tasks", c.Agent.GetTasks)
 		agentAPI.POST("/report", c.Agent.ReportResult)
-		agentAPI.GET("/download", c.Agent.Download) // 也在这里注册，兼容 Agent 调用
-		agentAPI.GET("/ws", c.Agent.WSConnect)      // WebSocket 连接
+		agentAPI.GET("/scripts/file", c.Agent.ScriptFile)        // 脚本同步下载
+		agentAPI.POST("/cert", c.Agent.IssueCert)                // 申请 / 轮换 mTLS 客户端证书
+		agentAPI.GET("/download", c.Agent.UpdateDownload)        // Agent 自动更新下载，仅提供已签名的更新包
+		agentAPI.GET("/update/manifest", c.Agent.UpdateManifest) // 更新包签名清单
+		agentAPI.GET("/ws", c.Agent.WSConnect)                   // WebSocket 连接
+	}
+}
+
+func initPingRoutes(root *gin.RouterGroup, c *Controllers) {
+	// 心跳检测上报（供无法安装 Agent 的外部机器通过 curl/wget 调用）
+	ping := root.Group("/ping/:key")
+	for _, method := range []string{"GET", "POST", "HEAD"} {
+		ping.Handle(method, "", c.Check.Ping)
+		ping.Handle(method, "/start", c.Check.PingStart)
+		ping.Handle(method, "/fail", c.Check.PingFail)
 	}
 }
@@ -2,9 +2,10 @@ package router
 
 import (
 	// "fmt"
+	"os"
 	"time"
 
-	// "github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/constant"
 	"github.com/engigu/baihu-panel/internal/eventbus"
 	// "github.com/engigu/baihu-panel/internal/logger"
 	// "github.com/engigu/baihu-panel/internal/models"
@@ -21,6 +22,20 @@ func setupEventHandlers(subscribers ...eventbus.Subscriber) {
 }
 
 
+// publishPanelStarted 发布面板启动事件，需在通知等订阅者初始化之后调用
+func publishPanelStarted() {
+	hostname, _ := os.Hostname()
+	eventbus.DefaultBus.Publish(eventbus.Event{
+		Type: constant.EventPanelStarted,
+		Payload: map[string]interface{}{
+			"version":  constant.Version,
+			"hostname": hostname,
+			"pid":      os.Getpid(),
+			"time":     time.Now().Format("2006-01-02 15:04:05"),
+		},
+	})
+}
+
 func startAppLogCleanup(appLogSvc *services.AppLogService) {
 	// 初始化时执行一次清理
 	appLogSvc.CleanUp()
@@ -31,3 +46,12 @@ func startAppLogCleanup(appLogSvc *services.AppLogService) {
 		appLogSvc.CleanUp()
 	}
 }
+
+func startEventRecordCleanup(eventStoreSvc *services.EventStoreService) {
+	eventStoreSvc.CleanUp()
+
+	ticker := time.NewTicker(time.Hour)
+	for range ticker.C {
+		eventStoreSvc.CleanUp()
+	}
+}
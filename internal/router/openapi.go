@@ -22,6 +22,8 @@ func initOpenAPIV1Routes(root *gin.RouterGroup, c *Controllers) {
 		registerOpenAPILogRoutes(open, c)
 		// 任务执行相关接口
 		registerOpenAPIExecutorRoutes(open, c)
+		// 事件流（SSE），只推送任务与 Agent 事件
+		open.GET("/events/stream", c.Event.OpenStream)
 	}
 }
 
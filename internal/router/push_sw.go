@@ -0,0 +1,35 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// pushServiceWorker Web Push 的 Service Worker 处理逻辑
+// 由前端 Service Worker 通过 importScripts('push-sw.js') 引入，负责展示通知与处理点击
+const pushServiceWorker = `self.addEventListener('push', function (event) {
+  var data = {};
+  try { data = event.data ? event.data.json() : {}; } catch (e) { data = { body: event.data && event.data.text() }; }
+  var options = { body: data.body || '', icon: 'pwa-icon-192.png', badge: 'pwa-icon-192.png', data: { url: data.url || '' } };
+  if (data.image) { options.image = data.image; }
+  event.waitUntil(self.registration.showNotification(data.title || '白虎面板', options));
+});
+
+self.addEventListener('notificationclick', function (event) {
+  event.notification.close();
+  var url = (event.notification.data && event.notification.data.url) || self.registration.scope;
+  event.waitUntil(self.clients.matchAll({ type: 'window', includeUncontrolled: true }).then(function (list) {
+    for (var i = 0; i < list.length; i++) {
+      if (list[i].url.indexOf(self.registration.scope) === 0 && 'focus' in list[i]) {
+        list[i].navigate(url).catch(function () {});
+        return list[i].focus();
+      }
+    }
+    return self.clients.openWindow(url);
+  }));
+});
+`
+
+func handlePushServiceWorker(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, no-cache")
+	ctx.Data(200, "application/javascript", []byte(pushServiceWorker))
+}
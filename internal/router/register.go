@@ -3,6 +3,7 @@ package router
 import (
 	"github.com/engigu/baihu-panel/internal/constant"
 	"github.com/engigu/baihu-panel/internal/controllers"
+	"github.com/engigu/baihu-panel/internal/eventbus"
 	"github.com/engigu/baihu-panel/internal/services"
 	"github.com/engigu/baihu-panel/internal/services/tasks"
 )
@@ -28,6 +29,13 @@ func RegisterControllers() *Controllers {
 	// 创建任务执行服务（需要依赖注入）
 	notifyService := services.NewNotificationService()
 	appLogService := services.NewAppLogService()
+	checkService := services.GetCheckService()
+	webhookService := services.NewWebhookService()
+	eventStreamService := services.GetEventStreamService()
+	eventStoreService := services.NewEventStoreService()
+
+	// 按设置启动事件总线（需在任何服务发布事件之前完成）
+	eventStoreService.Setup(eventbus.DefaultBus)
 
 	// 清理 task 运行状态的任务可以直接由 executorService 承担或在此处通过 Database 直接清理
 	// 简单期间，我们使用一个新方法 tasks.CleanupRunningTasks() 或者让 executorService 启动时清理
@@ -40,8 +48,22 @@ func RegisterControllers() *Controllers {
 	executorService.StartCron()
 
 	// 初始化所有关注系统总线的服务
-	setupEventHandlers(appLogService, notifyService, loginLogService)
+	setupEventHandlers(appLogService, notifyService, loginLogService, webhookService, eventStreamService)
 	go startAppLogCleanup(appLogService)
+	go startEventRecordCleanup(eventStoreService)
+	// 启动磁盘空间检查
+	go services.NewDiskMonitor().Start()
+	// 启动心跳检测超时巡检
+	go checkService.StartMonitor()
+	// 恢复重启前静默时段内暂存的通知
+	notifyService.RestoreQuietQueue()
+	// 启动紧急通知升级检查
+	go notifyService.StartEscalationLoop()
+	// 启动 Agent 更新超时与分批发布巡检
+	go services.NewAgentService().StartUpdateMonitor()
+	// 启动 Telegram Bot 命令接收（未配置时空闲等待）
+	go tasks.NewTelegramBot(executorService, settingsService).Start()
+	publishPanelStarted()
 
 	// 初始化并返回控制器
 	return &Controllers{
@@ -57,10 +79,14 @@ func RegisterControllers() *Controllers {
 		Terminal:     controllers.NewTerminalController(envService),
 		Settings:     controllers.NewSettingsController(userService, loginLogService, executorService),
 		Dependency:   controllers.NewDependencyController(),
-		Agent:        controllers.NewAgentController(settingsService),
+		Agent:        controllers.NewAgentController(settingsService, executorService),
 		Mise:         controllers.NewMiseController(services.NewMiseService()),
 		Notification: controllers.NewNotificationController(),
 		AppLog:       controllers.NewAppLogController(),
+		Check:        controllers.NewCheckController(checkService),
+		Webhook:      controllers.NewWebhookController(webhookService),
+		Event:        controllers.NewEventController(eventStreamService, eventStoreService),
+		WebPush:      controllers.NewWebPushController(services.NewWebPushService()),
 	}
 }
 
@@ -29,6 +29,10 @@ type Controllers struct {
 	Mise         *controllers.MiseController
 	Notification *controllers.NotificationController
 	AppLog       *controllers.AppLogController
+	Check        *controllers.CheckController
+	Webhook      *controllers.WebhookController
+	Event        *controllers.EventController
+	WebPush      *controllers.WebPushController
 }
 
 func Setup(c *Controllers) *gin.Engine {
@@ -72,6 +76,9 @@ func Setup(c *Controllers) *gin.Engine {
 	initAgentAPIRoutes(root, c)
 	initOpenAPIV1Routes(root, c)
 
+	// 5. [ location /ping ] 心跳检测上报路由 (无需认证，凭唯一地址识别)
+	initPingRoutes(root, c)
+
 	// =========================================================================
 	// [ location / ] 全局 404 兜底与 SPA 渲染
 	// 对应 Nginx: try_files $uri $uri/ /index.html;
@@ -125,6 +125,9 @@ func initPWARoutes(root *gin.RouterGroup) {
 	// 动态 manifest 处理 (支持由 Go 后端控制标题和图标)
 	root.GET("/manifest.webmanifest", handleManifest)
 
+	// Web Push 的 Service Worker 处理逻辑
+	root.GET("/push-sw.js", handlePushServiceWorker)
+
 	// 动态匹配 workbox-*.js (Vite PWA 生成的库文件)
 	root.GET("/workbox-:hash.js", func(ctx *gin.Context) {
 		file := "workbox-" + ctx.Param("hash") + ".js"
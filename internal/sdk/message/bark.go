@@ -134,6 +134,9 @@ func (b *Bark) Request(title, content string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkStatus("bark", resp.StatusCode, body); err != nil {
+		return body, err
+	}
 
 	var r barkResponse
 	err = json.Unmarshal(body, &r)
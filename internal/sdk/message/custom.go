@@ -42,5 +42,9 @@ func (cw *CustomWebhook) Request(url string, msg string, headers map[string]stri
 	if err != nil {
 		return nil, err
 	}
+	// 非 2xx 视为发送失败，以便按渠道策略重试
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return body, statusError(resp.StatusCode, "custom webhook response error (status %d): %s", resp.StatusCode, string(body))
+	}
 	return body, err
 }
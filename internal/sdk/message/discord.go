@@ -0,0 +1,66 @@
+package message
+
+// DiscordField Embed 中的字段
+type DiscordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// DiscordEmbed Discord 富文本卡片
+type DiscordEmbed struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Fields      []DiscordField `json:"fields,omitempty"`
+	Image       *discordImage  `json:"image,omitempty"`
+}
+
+type discordImage struct {
+	URL string `json:"url"`
+}
+
+type Discord struct {
+	WebhookURL string
+	Username   string // 可选，覆盖 Webhook 默认名称
+	AvatarURL  string // 可选，覆盖 Webhook 默认头像
+}
+
+// SendEmbed 发送带 Embed 的消息，content 为 Embed 之外的文本（用于 @ 提醒）
+func (d *Discord) SendEmbed(content string, embed DiscordEmbed, imageURL string, mentionUsers []string, mentionEveryone bool) ([]byte, error) {
+	embed.Title = truncateRunes(embed.Title, 256)
+	embed.Description = truncateRunes(embed.Description, 4096)
+	if imageURL != "" {
+		embed.Image = &discordImage{URL: imageURL}
+	}
+
+	// 仅允许解析明确指定的提醒，避免正文中的 @everyone 误触发
+	parse := []string{}
+	if mentionEveryone {
+		parse = append(parse, "everyone")
+	}
+	payload := map[string]interface{}{
+		"content": truncateRunes(content, 2000),
+		"embeds":  []DiscordEmbed{embed},
+		"allowed_mentions": map[string]interface{}{
+			"parse": parse,
+			"users": mentionUsers,
+		},
+	}
+	if d.Username != "" {
+		payload["username"] = d.Username
+	}
+	if d.AvatarURL != "" {
+		payload["avatar_url"] = d.AvatarURL
+	}
+
+	status, body, err := doJSON("POST", d.WebhookURL, nil, payload)
+	if err != nil {
+		return body, err
+	}
+	if status < 200 || status >= 300 {
+		return body, statusError(status, "discord webhook error: %d %s", status, string(body))
+	}
+	return body, nil
+}
@@ -42,6 +42,9 @@ func (t *Dtalk) Request(msg interface{}) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkStatus("dtalk", resp.StatusCode, body); err != nil {
+		return body, err
+	}
 	var r response
 	err = json.Unmarshal(body, &r)
 	if err != nil {
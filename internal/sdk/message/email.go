@@ -25,6 +25,14 @@ func (e *EmailMessage) Init(host string, port int, account string, passwd string
 }
 
 func (e *EmailMessage) SendTextMessage(toEmail string, title string, content string) string {
+	if err := e.Send(toEmail, title, content); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// Send 发送邮件，保留原始错误以便调用方区分连接失败等临时故障
+func (e *EmailMessage) Send(toEmail string, title string, content string) error {
 	m := gomail.NewMessage()
 	if e.FromName != "" {
 		m.SetAddressHeader("From", e.Account, e.FromName)
@@ -36,9 +44,9 @@ func (e *EmailMessage) SendTextMessage(toEmail string, title string, content str
 	m.SetBody("text/html", content)
 
 	if err := e.GM.DialAndSend(m); err != nil {
-		return fmt.Sprintf("邮件发送失败: %s", err)
+		return fmt.Errorf("邮件发送失败: %w", err)
 	}
-	return ""
+	return nil
 }
 
 func (e *EmailMessage) SendHtmlMessage(toEmail string, title string, content string) string {
@@ -111,13 +111,16 @@ func (f *Feishu) send(msg map[string]interface{}) ([]byte, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("发送请求失败: %v", err)
+		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if err := checkStatus("feishu", resp.StatusCode, body); err != nil {
+		return body, err
 	}
 
 	var result feishuResponse
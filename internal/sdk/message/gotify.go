@@ -52,6 +52,9 @@ func (g *Gotify) Request(title, content string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkStatus("gotify", resp.StatusCode, body); err != nil {
+		return body, err
+	}
 
 	var r gotifyResponse
 	err = json.Unmarshal(body, &r)
@@ -0,0 +1,96 @@
+package message
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+// httpClient 新增渠道共用的 HTTP 客户端，设置超时避免第三方服务无响应时阻塞发送
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// doJSON 以 JSON 形式发送请求，返回状态码与响应内容
+func doJSON(method, url string, headers map[string]string, payload interface{}) (int, []byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, body, err
+}
+
+// StatusError 第三方服务返回了非成功的 HTTP 状态码
+type StatusError struct {
+	StatusCode int
+	msg        string
+}
+
+func (e *StatusError) Error() string {
+	return e.msg
+}
+
+// statusError 构造 StatusError，错误文本沿用各渠道原有的写法
+func statusError(code int, format string, args ...any) error {
+	return &StatusError{StatusCode: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// checkStatus 仅根据响应内容判断成败的渠道，遇到 429 / 5xx 时先按状态码报错，避免被当作业务错误
+func checkStatus(service string, code int, body []byte) error {
+	if code == http.StatusTooManyRequests || code >= 500 {
+		return statusError(code, "%s response error (status %d): %s", service, code, string(body))
+	}
+	return nil
+}
+
+// IsRetryable 判断发送错误是否为临时故障：网络错误、超时、HTTP 429 与 5xx。
+// 配置错误、4xx、第三方返回的业务错误码等重试也不会成功，返回 false
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	// url.Error 自身实现了 net.Error，需要拆开看内部原因，避免把协议不支持之类的错误当成网络故障
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		if ue.Timeout() {
+			return true
+		}
+		err = ue.Err
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
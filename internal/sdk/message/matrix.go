@@ -0,0 +1,44 @@
+package message
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Matrix struct {
+	Homeserver  string // 如 https://matrix.org
+	AccessToken string
+	RoomID      string // 如 !abc:matrix.org
+	MsgType     string // m.text（默认）或 m.notice
+}
+
+// SendMessage 通过 Client-Server API 发送房间消息，html 不为空时同时发送富文本内容
+func (m *Matrix) SendMessage(body, html string) ([]byte, error) {
+	msgType := m.MsgType
+	if msgType == "" {
+		msgType = "m.text"
+	}
+	content := map[string]interface{}{
+		"msgtype": msgType,
+		"body":    body,
+	}
+	if html != "" {
+		content["format"] = "org.matrix.custom.html"
+		content["formatted_body"] = html
+	}
+
+	txnID := fmt.Sprintf("baihu%d", time.Now().UnixNano())
+	apiURL := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(m.Homeserver, "/"), url.PathEscape(m.RoomID), txnID)
+
+	status, resp, err := doJSON("PUT", apiURL, map[string]string{"Authorization": "Bearer " + m.AccessToken}, content)
+	if err != nil {
+		return resp, err
+	}
+	if status != 200 {
+		return resp, statusError(status, "matrix api error: %d %s", status, string(resp))
+	}
+	return resp, nil
+}
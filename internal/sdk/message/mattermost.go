@@ -0,0 +1,33 @@
+package message
+
+type Mattermost struct {
+	WebhookURL string
+	Channel    string // 可选，覆盖 Webhook 默认频道
+	Username   string // 可选
+	IconURL    string // 可选
+}
+
+// SendMessage 通过 Incoming Webhook 发送 Markdown 消息
+func (m *Mattermost) SendMessage(text string) ([]byte, error) {
+	payload := map[string]interface{}{
+		"text": text,
+	}
+	if m.Channel != "" {
+		payload["channel"] = m.Channel
+	}
+	if m.Username != "" {
+		payload["username"] = m.Username
+	}
+	if m.IconURL != "" {
+		payload["icon_url"] = m.IconURL
+	}
+
+	status, body, err := doJSON("POST", m.WebhookURL, nil, payload)
+	if err != nil {
+		return body, err
+	}
+	if status != 200 {
+		return body, statusError(status, "mattermost webhook error: %d %s", status, string(body))
+	}
+	return body, nil
+}
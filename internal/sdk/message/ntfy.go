@@ -67,6 +67,9 @@ func (n *Ntfy) Request(title, content string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkStatus("ntfy", resp.StatusCode, body); err != nil {
+		return body, err
+	}
 
 	if resp.StatusCode != http.StatusOK {
 		return body, fmt.Errorf("ntfy response error: %s", string(body))
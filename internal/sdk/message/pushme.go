@@ -42,6 +42,9 @@ func (p *PushMe) Request(title, content string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if err := checkStatus("pushme", resp.StatusCode, body); err != nil {
+		return string(body), err
+	}
 
 	if resp.StatusCode == 200 && string(body) == "success" {
 		return string(body), nil
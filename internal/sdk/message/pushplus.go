@@ -59,6 +59,9 @@ func (p *PushPlus) Request(title, content string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if err := checkStatus("pushplus", resp.StatusCode, respBody); err != nil {
+		return string(respBody), err
+	}
 
 	var res pushPlusResponse
 	if err := json.Unmarshal(respBody, &res); err != nil {
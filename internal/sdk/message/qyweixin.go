@@ -37,6 +37,9 @@ func (t *QyWeiXin) Request(msg interface{}) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkStatus("qyweixin", resp.StatusCode, body); err != nil {
+		return body, err
+	}
 	var r qywxResponse
 	err = json.Unmarshal(body, &r)
 	if err != nil {
@@ -0,0 +1,92 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+type slackResponse struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// Slack 支持 Incoming Webhook 与 Bot Token（chat.postMessage）两种方式，优先使用 Webhook
+type Slack struct {
+	WebhookURL string
+	BotToken   string
+	Channel    string // Bot Token 方式必填，Webhook 方式可选（覆盖默认频道，需旧版 Webhook 支持）
+	ApiURL     string // 可选，自定义 chat.postMessage 地址
+}
+
+// SendMessage 发送消息，mrkdwn 为 Slack 格式的正文，imageURL 不为空时附带图片块
+func (s *Slack) SendMessage(title, mrkdwn, fallback, imageURL string) ([]byte, error) {
+	blocks := make([]map[string]interface{}, 0, 3)
+	if title != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": truncateRunes(title, 150)},
+		})
+	}
+	if mrkdwn != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": truncateRunes(mrkdwn, 3000)},
+		})
+	}
+	if imageURL != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type":      "image",
+			"image_url": imageURL,
+			"alt_text":  "image",
+		})
+	}
+
+	payload := map[string]interface{}{
+		"text":   fallback,
+		"blocks": blocks,
+	}
+	if s.Channel != "" {
+		payload["channel"] = s.Channel
+	}
+
+	if s.WebhookURL != "" {
+		status, body, err := doJSON("POST", s.WebhookURL, nil, payload)
+		if err != nil {
+			return body, err
+		}
+		if status != 200 {
+			return body, statusError(status, "slack webhook error: %d %s", status, string(body))
+		}
+		return body, nil
+	}
+
+	apiURL := s.ApiURL
+	if apiURL == "" {
+		apiURL = slackPostMessageURL
+	}
+	status, body, err := doJSON("POST", apiURL, map[string]string{"Authorization": "Bearer " + s.BotToken}, payload)
+	if err != nil {
+		return body, err
+	}
+	if err := checkStatus("slack api", status, body); err != nil {
+		return body, err
+	}
+	var r slackResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return body, err
+	}
+	if !r.Ok {
+		return body, fmt.Errorf("slack api error: %s", r.Error)
+	}
+	return body, nil
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
@@ -28,7 +28,12 @@ type Telegram struct {
 }
 
 func (t *Telegram) Request(params map[string]interface{}) ([]byte, error) {
-	apiURL := t.getAPIURL()
+	return t.Call("sendMessage", params)
+}
+
+// Call 调用任意 Bot API 方法
+func (t *Telegram) Call(method string, params map[string]interface{}) ([]byte, error) {
+	apiURL := t.getMethodURL(method)
 
 	// 构建请求体
 	data := url.Values{}
@@ -55,6 +60,9 @@ func (t *Telegram) Request(params map[string]interface{}) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkStatus("telegram", resp.StatusCode, body); err != nil {
+		return body, err
+	}
 
 	var r telegramResponse
 	err = json.Unmarshal(body, &r)
@@ -104,12 +112,48 @@ func (t *Telegram) SendMessageHTML(text string) ([]byte, error) {
 	return t.Request(params)
 }
 
-func (t *Telegram) getAPIURL() string {
+// TelegramUpdate Bot 收到的更新（仅保留命令处理所需字段）
+type TelegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		MessageID int64 `json:"message_id"`
+		From      *struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+		} `json:"from"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// GetUpdates 长轮询获取更新，timeout 为服务端挂起等待的秒数
+func (t *Telegram) GetUpdates(offset int64, timeout int) ([]TelegramUpdate, error) {
+	body, err := t.Call("getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         timeout,
+		"allowed_updates": `["message"]`,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var r struct {
+		Result []TelegramUpdate `json:"result"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, err
+	}
+	return r.Result, nil
+}
+
+func (t *Telegram) getMethodURL(method string) string {
 	// 自定义 API 地址优先级最高
 	if t.ApiHost != "" {
-		return fmt.Sprintf("%s/bot%s/sendMessage", t.ApiHost, t.BotToken)
+		return fmt.Sprintf("%s/bot%s/%s", strings.TrimRight(t.ApiHost, "/"), t.BotToken, method)
 	}
-	return fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.BotToken)
+	return fmt.Sprintf("https://api.telegram.org/bot%s/%s", t.BotToken, method)
 }
 
 // getHTTPClient 获取配置了代理的 HTTP 客户端
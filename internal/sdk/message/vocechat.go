@@ -55,7 +55,7 @@ func (v *VoceChat) Request(title, content string) ([]byte, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return respBody, fmt.Errorf("vocechat response error (status %d): %s", resp.StatusCode, string(respBody))
+		return respBody, statusError(resp.StatusCode, "vocechat response error (status %d): %s", resp.StatusCode, string(respBody))
 	}
 
 	return respBody, nil
@@ -0,0 +1,224 @@
+package message
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// webPushRecordSize aes128gcm 记录大小，单条记录即可容纳推送服务允许的最大负载（4KB）
+const webPushRecordSize = 4096
+
+// webPushMaxPayload 推送服务普遍限制的明文负载上限
+const webPushMaxPayload = 3993
+
+// ErrWebPushPayloadTooLarge 负载超过推送服务限制
+var ErrWebPushPayloadTooLarge = errors.New("web push payload too large")
+
+var b64 = base64.RawURLEncoding
+
+// WebPushSubscription 浏览器 PushSubscription 的关键信息
+type WebPushSubscription struct {
+	Endpoint string
+	P256dh   string // 浏览器公钥（base64url）
+	Auth     string // 认证密钥（base64url）
+}
+
+// WebPush 通过 VAPID 认证向浏览器推送服务发送加密消息（RFC 8291 / RFC 8292）
+type WebPush struct {
+	PublicKey  string // VAPID 公钥（base64url，未压缩格式 65 字节）
+	PrivateKey string // VAPID 私钥（base64url，32 字节）
+	Subject    string // 联系方式，如 mailto:admin@example.com
+	TTL        int    // 推送服务保留消息的秒数
+	Urgency    string // very-low / low / normal / high
+}
+
+// GenerateVAPIDKeys 生成 VAPID 密钥对，返回 base64url 编码的公钥与私钥
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return b64.EncodeToString(key.PublicKey().Bytes()), b64.EncodeToString(key.Bytes()), nil
+}
+
+// Send 加密并发送一条推送，返回推送服务的状态码
+// 404/410 表示订阅已失效，调用方应删除该订阅
+func (w *WebPush) Send(sub WebPushSubscription, payload []byte) (int, []byte, error) {
+	if len(payload) > webPushMaxPayload {
+		return 0, nil, ErrWebPushPayloadTooLarge
+	}
+
+	body, err := encryptWebPush(sub, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	auth, err := w.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req, err := http.NewRequest("POST", sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	ttl := w.TTL
+	if ttl <= 0 {
+		ttl = 86400
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(ttl))
+	req.Header.Set("Authorization", auth)
+	if w.Urgency != "" {
+		req.Header.Set("Urgency", w.Urgency)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, respBody, statusError(resp.StatusCode, "web push error: %d %s", resp.StatusCode, string(respBody))
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// vapidAuthorization 生成 VAPID 认证头（RFC 8292）
+func (w *WebPush) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	rawKey, err := b64.DecodeString(w.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid vapid private key: %w", err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), rawKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid vapid private key: %w", err)
+	}
+
+	header := b64.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": w.Subject,
+	})
+	unsigned := header + "." + b64.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// ES256 签名为定长的 r || s
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	return fmt.Sprintf("vapid t=%s.%s, k=%s", unsigned, b64.EncodeToString(sig), w.PublicKey), nil
+}
+
+// encryptWebPush 按 RFC 8291 (aes128gcm) 加密负载
+func encryptWebPush(sub WebPushSubscription, payload []byte) ([]byte, error) {
+	uaPublicRaw, err := b64.DecodeString(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh: %w", err)
+	}
+	authSecret, err := b64.DecodeString(sub.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth: %w", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicRaw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh: %w", err)
+	}
+
+	// 每条消息使用新的临时密钥对与盐
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encryptWebPushWith(asPrivate, salt, uaPublic, authSecret, payload)
+}
+
+func encryptWebPushWith(asPrivate *ecdh.PrivateKey, salt []byte, uaPublic *ecdh.PublicKey, authSecret, payload []byte) ([]byte, error) {
+	uaPublicRaw := uaPublic.Bytes()
+	asPublicRaw := asPrivate.PublicKey().Bytes()
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	// PRK_key = HKDF-Extract(auth_secret, ecdh_secret)
+	// IKM = HKDF-Expand(PRK_key, "WebPush: info" || 0x00 || ua_public || as_public, 32)
+	prkKey, err := hkdf.Extract(sha256.New, ecdhSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPublicRaw) + string(asPublicRaw)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 单条记录，以 0x02 作为最后一条记录的分隔符
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+	// 头部: salt(16) || rs(4) || idlen(1) || keyid(as_public)
+	var buf bytes.Buffer
+	buf.Write(salt)
+	rs := make([]byte, 4)
+	binary.BigEndian.PutUint32(rs, webPushRecordSize)
+	buf.Write(rs)
+	buf.WriteByte(byte(len(asPublicRaw)))
+	buf.Write(asPublicRaw)
+	buf.Write(ciphertext)
+	return buf.Bytes(), nil
+}
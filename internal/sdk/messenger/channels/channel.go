@@ -1,6 +1,10 @@
 package channels
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/engigu/baihu-panel/internal/sdk/message"
+)
 
 // Channel 渠道接口 - SDK 版本，零业务依赖
 type Channel interface {
@@ -54,13 +58,13 @@ func SuccessResult(response string) *Result {
 	return &Result{Success: true, Response: response}
 }
 
-// ErrorResult 创建失败结果
+// ErrorResult 创建失败结果，并根据错误类型标记是否值得重试
 func ErrorResult(response string, err error) *Result {
 	errMsg := ""
 	if err != nil {
 		errMsg = err.Error()
 	}
-	return &Result{Success: false, Response: response, Error: errMsg}
+	return &Result{Success: false, Response: response, Error: errMsg, Retryable: message.IsRetryable(err)}
 }
 
 // ErrorResultStr 创建失败结果（字符串错误）
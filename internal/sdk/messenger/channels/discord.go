@@ -0,0 +1,104 @@
+package channels
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/engigu/baihu-panel/internal/sdk/message"
+)
+
+// discordDefaultColor Embed 默认颜色
+const discordDefaultColor = 0x5865F2
+
+type DiscordChannel struct{ *BaseChannel }
+
+func NewDiscordChannel() Channel {
+	return &DiscordChannel{NewBaseChannel(ChannelDiscord, []string{FormatTypeMarkdown, FormatTypeText})}
+}
+
+func (c *DiscordChannel) Send(config ChannelConfig, msg *Message) (*Result, error) {
+	webhookURL := config.GetString("webhook_url")
+	if webhookURL == "" {
+		return SendError("discord config missing: webhook_url is required"), nil
+	}
+
+	color := discordDefaultColor
+	if v := strings.TrimPrefix(config.GetString("color"), "#"); v != "" {
+		if n, err := strconv.ParseInt(v, 16, 32); err == nil {
+			color = int(n)
+		}
+	}
+
+	_, formattedContent := c.FormatContent(msg)
+	embed := message.DiscordEmbed{
+		Title:       msg.Title,
+		Description: formattedContent,
+		URL:         msg.URL,
+		Color:       color,
+		Fields:      discordFields(msg.Extra["fields"]),
+	}
+
+	var mentions []string
+	if msg.AtAll {
+		mentions = append(mentions, "@everyone")
+	}
+	for _, id := range msg.GetAtUserIds() {
+		mentions = append(mentions, "<@"+id+">")
+	}
+
+	cli := message.Discord{
+		WebhookURL: webhookURL,
+		Username:   config.GetString("username"),
+		AvatarURL:  config.GetString("avatar_url"),
+	}
+	res, err := cli.SendEmbed(strings.Join(mentions, " "), embed, msg.ImageURL, msg.GetAtUserIds(), msg.AtAll)
+	if err != nil {
+		return ErrorResult(string(res), err), nil
+	}
+	return SuccessResult(string(res)), nil
+}
+
+// discordFields 解析 Extra["fields"]，支持 [{name, value, inline}] 列表或 {name: value} 映射
+func discordFields(v any) []message.DiscordField {
+	var fields []message.DiscordField
+	switch items := v.(type) {
+	case []any:
+		for _, item := range items {
+			m, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			inline, _ := m["inline"].(bool)
+			fields = append(fields, message.DiscordField{
+				Name:   fmt.Sprint(m["name"]),
+				Value:  fmt.Sprint(m["value"]),
+				Inline: inline,
+			})
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(items))
+		for k := range items {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fields = append(fields, message.DiscordField{Name: k, Value: fmt.Sprint(items[k]), Inline: true})
+		}
+	case map[string]string:
+		keys := make([]string, 0, len(items))
+		for k := range items {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fields = append(fields, message.DiscordField{Name: k, Value: items[k], Inline: true})
+		}
+	}
+	// Discord 单个 Embed 最多 25 个字段
+	if len(fields) > 25 {
+		fields = fields[:25]
+	}
+	return fields
+}
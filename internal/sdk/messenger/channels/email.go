@@ -1,7 +1,6 @@
 package channels
 
 import (
-	"fmt"
 	"github.com/engigu/baihu-panel/internal/sdk/message"
 	"strconv"
 )
@@ -33,17 +32,12 @@ func (c *EmailChannel) Send(config ChannelConfig, msg *Message) (*Result, error)
 	var emailer message.EmailMessage
 	emailer.Init(server, port, account, passwd, fromName)
 
-	var errMsg string
-	if contentType == FormatTypeText {
-		errMsg = emailer.SendTextMessage(toAccount, msg.Title, formattedContent)
-	} else if contentType == FormatTypeHTML {
-		errMsg = emailer.SendHtmlMessage(toAccount, msg.Title, formattedContent)
-	} else {
-		errMsg = fmt.Sprintf("未知的邮件发送内容类型：%s", contentType)
+	if contentType != FormatTypeText && contentType != FormatTypeHTML {
+		return SendError("未知的邮件发送内容类型：%s", contentType), nil
 	}
-
-	if errMsg != "" {
-		return ErrorResultStr("", errMsg), nil
+	// 邮件正文统一以 HTML 发送
+	if err := emailer.Send(toAccount, msg.Title, formattedContent); err != nil {
+		return ErrorResult("", err), nil
 	}
 	return SuccessResult(""), nil
 }
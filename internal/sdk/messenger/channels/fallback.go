@@ -0,0 +1,135 @@
+package channels
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	mdImagePattern   = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)[^)]*\)`)
+	mdLinkPattern    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	mdHeadingPattern = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	mdQuotePattern   = regexp.MustCompile(`(?m)^>\s?`)
+	mdEmphPattern    = regexp.MustCompile("(\\*\\*|__|~~|`)")
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|h[1-6]|tr)>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]+>`)
+	blankLinePattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// urlNativeChannels 自身支持跳转链接的渠道，不需要将 URL 拼接到正文中
+var urlNativeChannels = map[string]bool{
+	ChannelWeChatOFAccount: true,
+	ChannelDiscord:         true,
+	ChannelWebPush:         true,
+}
+
+// imageNativeChannels 自身支持图片消息的渠道，不需要将图片链接拼接到正文中
+var imageNativeChannels = map[string]bool{
+	ChannelSlack:   true,
+	ChannelDiscord: true,
+	ChannelWebPush: true,
+}
+
+// mentionNativeChannels 自身支持 @ 提醒的渠道
+var mentionNativeChannels = map[string]bool{
+	ChannelDtalk:      true,
+	ChannelFeishu:     true,
+	ChannelQyWeiXin:   true,
+	ChannelSlack:      true,
+	ChannelDiscord:    true,
+	ChannelMattermost: true,
+}
+
+// ApplyFormatFallback 按渠道支持的格式（GetSupportedFormats）补全消息，返回新的消息副本
+//   - 未提供纯文本时，由 Markdown / HTML 转换得到，保证只支持文本的渠道也有内容
+//   - 链接与图片以渠道支持的格式追加到正文中
+//   - 渠道不支持 @ 提醒时，将提醒对象以文本形式附加在正文开头
+func ApplyFormatFallback(ch Channel, msg *Message) *Message {
+	out := *msg
+	formats := make(map[string]bool)
+	for _, f := range ch.GetSupportedFormats() {
+		formats[f] = true
+	}
+
+	if out.Text == "" {
+		switch {
+		case out.Markdown != "" && formats[FormatTypeMarkdown] && !formats[FormatTypeText]:
+			// 仅支持 Markdown 的渠道直接使用原文
+			out.Text = out.Markdown
+		case out.Markdown != "":
+			out.Text = MarkdownToText(out.Markdown)
+		case out.HTML != "":
+			out.Text = HTMLToText(out.HTML)
+		}
+	}
+
+	if !urlNativeChannels[ch.GetType()] {
+		if out.URL != "" {
+			out.Text = appendLine(out.Text, "链接: "+out.URL)
+			if out.Markdown != "" {
+				out.Markdown = appendLine(out.Markdown, "\n[查看详情]("+out.URL+")")
+			}
+			if out.HTML != "" {
+				out.HTML += `<p><a href="` + html.EscapeString(out.URL) + `">查看详情</a></p>`
+			}
+		}
+	}
+	if out.ImageURL != "" && !imageNativeChannels[ch.GetType()] {
+		out.Text = appendLine(out.Text, "图片: "+out.ImageURL)
+		if out.Markdown != "" {
+			out.Markdown = appendLine(out.Markdown, "\n![image]("+out.ImageURL+")")
+		}
+		if out.HTML != "" {
+			out.HTML += `<p><img src="` + html.EscapeString(out.ImageURL) + `"/></p>`
+		}
+	}
+
+	if !mentionNativeChannels[ch.GetType()] {
+		var mentions []string
+		if out.AtAll {
+			mentions = append(mentions, "@所有人")
+		}
+		for _, m := range append(out.GetAtMobiles(), out.GetAtUserIds()...) {
+			mentions = append(mentions, "@"+m)
+		}
+		if len(mentions) > 0 {
+			prefix := strings.Join(mentions, " ")
+			out.Text = prefix + "\n" + out.Text
+			if out.Markdown != "" {
+				out.Markdown = prefix + "\n\n" + out.Markdown
+			}
+		}
+	}
+	return &out
+}
+
+// MarkdownToText 将 Markdown 转换为纯文本（保留链接地址）
+func MarkdownToText(md string) string {
+	s := mdImagePattern.ReplaceAllString(md, "$2")
+	s = mdLinkPattern.ReplaceAllString(s, "$1 ($2)")
+	s = mdHeadingPattern.ReplaceAllString(s, "")
+	s = mdQuotePattern.ReplaceAllString(s, "")
+	s = mdEmphPattern.ReplaceAllString(s, "")
+	return strings.TrimSpace(s)
+}
+
+// HTMLToText 将 HTML 转换为纯文本
+func HTMLToText(h string) string {
+	s := htmlBreakPattern.ReplaceAllString(h, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = blankLinePattern.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+func escapeHTML(s string) string {
+	return html.EscapeString(s)
+}
+
+func appendLine(s, line string) string {
+	if s == "" {
+		return strings.TrimLeft(line, "\n")
+	}
+	return s + "\n" + line
+}
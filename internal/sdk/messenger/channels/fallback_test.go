@@ -0,0 +1,56 @@
+package channels
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyFormatFallback(t *testing.T) {
+	msg := &Message{
+		Title:    "备份完成",
+		Markdown: "### 结果\n- 共 **3** 个文件，[详情](https://example.com/a)",
+		URL:      "https://example.com",
+		AtAll:    true,
+	}
+
+	// 仅支持文本的渠道：Markdown 转为纯文本，链接和 @ 附加在正文中
+	out := ApplyFormatFallback(NewBarkChannel(), msg)
+	if strings.Contains(out.Text, "**") || strings.Contains(out.Text, "###") {
+		t.Fatalf("markdown not stripped: %q", out.Text)
+	}
+	if !strings.Contains(out.Text, "详情 (https://example.com/a)") || !strings.Contains(out.Text, "链接: https://example.com") {
+		t.Fatalf("links missing: %q", out.Text)
+	}
+	if !strings.HasPrefix(out.Text, "@所有人\n") {
+		t.Fatalf("mention missing: %q", out.Text)
+	}
+
+	// 支持 @ 的 Markdown 渠道：保留原始 Markdown，不附加文本形式的 @
+	out = ApplyFormatFallback(NewDtalkChannel(), msg)
+	if !strings.Contains(out.Markdown, "**3**") || !strings.Contains(out.Markdown, "[查看详情](https://example.com)") {
+		t.Fatalf("unexpected markdown: %q", out.Markdown)
+	}
+	if strings.Contains(out.Markdown, "@所有人") {
+		t.Fatalf("native mention duplicated: %q", out.Markdown)
+	}
+
+	// 原消息不应被修改
+	if msg.Text != "" {
+		t.Fatalf("original message modified: %q", msg.Text)
+	}
+
+	if got := HTMLToText("<p>a &amp; b</p><p>c<br/>d</p>"); got != "a & b\nc\nd" {
+		t.Fatalf("unexpected html text: %q", got)
+	}
+}
+
+func TestMarkdownConverters(t *testing.T) {
+	md := "## 标题\n- 共 **3** 个 [文件](https://e.com) <x>"
+
+	if got := MarkdownToSlack(md); got != "*标题*\n• 共 *3* 个 <https://e.com|文件> &lt;x&gt;" {
+		t.Fatalf("unexpected slack text: %q", got)
+	}
+	if got := MarkdownToHTML(md); got != `<h2>标题</h2><ul><li>共 <strong>3</strong> 个 <a href="https://e.com">文件</a> &lt;x&gt;</li></ul>` {
+		t.Fatalf("unexpected html: %q", got)
+	}
+}
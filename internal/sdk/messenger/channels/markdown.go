@@ -0,0 +1,104 @@
+package channels
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	mdBoldPattern       = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	mdItalicPattern     = regexp.MustCompile(`(^|[^*])\*([^*\n]+)\*`)
+	mdStrikePattern     = regexp.MustCompile(`~~(.+?)~~`)
+	mdCodePattern       = regexp.MustCompile("`([^`\n]+)`")
+	mdHeadingLinePat    = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	mdListLinePattern   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	slackEscapeReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+// MarkdownToSlack 将常用 Markdown 语法转换为 Slack mrkdwn
+func MarkdownToSlack(md string) string {
+	lines := strings.Split(slackEscapeReplacer.Replace(md), "\n")
+	for i, line := range lines {
+		if m := mdHeadingLinePat.FindStringSubmatch(line); m != nil {
+			lines[i] = "*" + m[2] + "*"
+			continue
+		}
+		if m := mdListLinePattern.FindStringSubmatch(line); m != nil {
+			line = "• " + m[1]
+		}
+		line = mdImagePattern.ReplaceAllString(line, "<$2|$1>")
+		line = mdLinkPattern.ReplaceAllString(line, "<$2|$1>")
+		line = mdItalicPattern.ReplaceAllString(line, "${1}_${2}_")
+		line = mdBoldPattern.ReplaceAllString(line, "*$1$2*")
+		line = mdStrikePattern.ReplaceAllString(line, "~$1~")
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MarkdownToHTML 将常用 Markdown 语法（标题、粗体、斜体、代码、链接、图片、列表、代码块）转换为 HTML
+func MarkdownToHTML(md string) string {
+	var sb strings.Builder
+	inCode, inList := false, false
+	closeList := func() {
+		if inList {
+			sb.WriteString("</ul>")
+			inList = false
+		}
+	}
+
+	for _, line := range strings.Split(md, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			closeList()
+			if inCode {
+				sb.WriteString("</code></pre>")
+			} else {
+				sb.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			sb.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		if m := mdHeadingLinePat.FindStringSubmatch(line); m != nil {
+			closeList()
+			level := string('0' + rune(len(m[1])))
+			sb.WriteString("<h" + level + ">" + markdownInlineToHTML(m[2]) + "</h" + level + ">")
+			continue
+		}
+		if m := mdListLinePattern.FindStringSubmatch(line); m != nil {
+			if !inList {
+				sb.WriteString("<ul>")
+				inList = true
+			}
+			sb.WriteString("<li>" + markdownInlineToHTML(m[1]) + "</li>")
+			continue
+		}
+		closeList()
+		if strings.TrimSpace(line) == "" {
+			sb.WriteString("<br/>")
+			continue
+		}
+		sb.WriteString(markdownInlineToHTML(line) + "<br/>")
+	}
+	closeList()
+	if inCode {
+		sb.WriteString("</code></pre>")
+	}
+	return strings.TrimSuffix(sb.String(), "<br/>")
+}
+
+func markdownInlineToHTML(s string) string {
+	s = html.EscapeString(s)
+	s = mdImagePattern.ReplaceAllString(s, `<img src="$2" alt="$1"/>`)
+	s = mdLinkPattern.ReplaceAllString(s, `<a href="$2">$1</a>`)
+	s = mdCodePattern.ReplaceAllString(s, "<code>$1</code>")
+	s = mdBoldPattern.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = mdItalicPattern.ReplaceAllString(s, "$1<em>$2</em>")
+	s = mdStrikePattern.ReplaceAllString(s, "<del>$1</del>")
+	return s
+}
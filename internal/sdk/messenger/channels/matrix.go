@@ -0,0 +1,48 @@
+package channels
+
+import "github.com/engigu/baihu-panel/internal/sdk/message"
+
+type MatrixChannel struct{ *BaseChannel }
+
+func NewMatrixChannel() Channel {
+	return &MatrixChannel{NewBaseChannel(ChannelMatrix, []string{FormatTypeHTML, FormatTypeMarkdown, FormatTypeText})}
+}
+
+func (c *MatrixChannel) Send(config ChannelConfig, msg *Message) (*Result, error) {
+	homeserver := config.GetString("homeserver")
+	accessToken := config.GetString("access_token")
+	roomID := config.GetString("room_id")
+
+	if homeserver == "" || accessToken == "" || roomID == "" {
+		return SendError("matrix config missing: homeserver, access_token, room_id are required"), nil
+	}
+
+	contentType, formattedContent := c.FormatContent(msg)
+	var htmlBody string
+	switch contentType {
+	case FormatTypeHTML:
+		htmlBody = formattedContent
+	case FormatTypeMarkdown:
+		htmlBody = MarkdownToHTML(formattedContent)
+	}
+
+	body := msg.Text
+	if msg.Title != "" {
+		body = msg.Title + "\n" + body
+		if htmlBody != "" {
+			htmlBody = "<strong>" + escapeHTML(msg.Title) + "</strong><br/>" + htmlBody
+		}
+	}
+
+	cli := message.Matrix{
+		Homeserver:  homeserver,
+		AccessToken: accessToken,
+		RoomID:      roomID,
+		MsgType:     config.GetString("msg_type"),
+	}
+	res, err := cli.SendMessage(body, htmlBody)
+	if err != nil {
+		return ErrorResult(string(res), err), nil
+	}
+	return SuccessResult(string(res)), nil
+}
@@ -0,0 +1,49 @@
+package channels
+
+import (
+	"strings"
+
+	"github.com/engigu/baihu-panel/internal/sdk/message"
+)
+
+type MattermostChannel struct{ *BaseChannel }
+
+func NewMattermostChannel() Channel {
+	return &MattermostChannel{NewBaseChannel(ChannelMattermost, []string{FormatTypeMarkdown, FormatTypeText})}
+}
+
+func (c *MattermostChannel) Send(config ChannelConfig, msg *Message) (*Result, error) {
+	webhookURL := config.GetString("webhook_url")
+	if webhookURL == "" {
+		return SendError("mattermost config missing: webhook_url is required"), nil
+	}
+
+	_, formattedContent := c.FormatContent(msg)
+	var parts []string
+	var mentions []string
+	if msg.AtAll {
+		mentions = append(mentions, "@all")
+	}
+	for _, user := range msg.GetAtUserIds() {
+		mentions = append(mentions, "@"+strings.TrimPrefix(user, "@"))
+	}
+	if len(mentions) > 0 {
+		parts = append(parts, strings.Join(mentions, " "))
+	}
+	if msg.Title != "" {
+		parts = append(parts, "#### "+msg.Title)
+	}
+	parts = append(parts, formattedContent)
+
+	cli := message.Mattermost{
+		WebhookURL: webhookURL,
+		Channel:    config.GetString("channel"),
+		Username:   config.GetString("username"),
+		IconURL:    config.GetString("icon_url"),
+	}
+	res, err := cli.SendMessage(strings.Join(parts, "\n\n"))
+	if err != nil {
+		return ErrorResult(string(res), err), nil
+	}
+	return SuccessResult(string(res)), nil
+}
@@ -0,0 +1,54 @@
+package channels
+
+import "github.com/engigu/baihu-panel/internal/sdk/message"
+
+type SlackChannel struct{ *BaseChannel }
+
+func NewSlackChannel() Channel {
+	return &SlackChannel{NewBaseChannel(ChannelSlack, []string{FormatTypeMarkdown, FormatTypeText})}
+}
+
+func (c *SlackChannel) Send(config ChannelConfig, msg *Message) (*Result, error) {
+	webhookURL := config.GetString("webhook_url")
+	botToken := config.GetString("bot_token")
+	channel := config.GetString("channel")
+
+	if webhookURL == "" && (botToken == "" || channel == "") {
+		return SendError("slack config missing: webhook_url or bot_token + channel is required"), nil
+	}
+
+	contentType, formattedContent := c.FormatContent(msg)
+	if contentType == FormatTypeMarkdown {
+		formattedContent = MarkdownToSlack(formattedContent)
+	} else {
+		formattedContent = slackEscapeReplacer.Replace(formattedContent)
+	}
+
+	mentions := ""
+	if msg.AtAll {
+		mentions += "<!channel> "
+	}
+	for _, id := range msg.GetAtUserIds() {
+		mentions += "<@" + id + "> "
+	}
+	if mentions != "" {
+		formattedContent = mentions + "\n" + formattedContent
+	}
+
+	cli := message.Slack{
+		WebhookURL: webhookURL,
+		BotToken:   botToken,
+		Channel:    channel,
+		ApiURL:     config.GetString("api_url"),
+	}
+	fallback := msg.Title
+	if msg.Text != "" {
+		fallback += "\n" + msg.Text
+	}
+
+	res, err := cli.SendMessage(msg.Title, formattedContent, fallback, msg.ImageURL)
+	if err != nil {
+		return ErrorResult(string(res), err), nil
+	}
+	return SuccessResult(string(res)), nil
+}
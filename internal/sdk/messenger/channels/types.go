@@ -49,6 +49,8 @@ type Result struct {
 	Success  bool   `json:"success"`
 	Response string `json:"response"` // 原始响应
 	Error    string `json:"error"`    // 错误信息
+	// Retryable 失败原因为网络错误、超时、HTTP 429 或 5xx 等临时故障，重试可能成功
+	Retryable bool `json:"retryable,omitempty"`
 }
 
 // 消息格式类型常量
@@ -74,4 +76,9 @@ const (
 	ChannelGotify          = "Gotify"
 	ChannelPushPlus        = "PushPlus"
 	ChannelVoceChat        = "VoceChat"
+	ChannelSlack           = "Slack"
+	ChannelDiscord         = "Discord"
+	ChannelMatrix          = "Matrix"
+	ChannelMattermost      = "Mattermost"
+	ChannelWebPush         = "WebPush"
 )
@@ -49,6 +49,11 @@ const (
 	ChannelGotify          = channels.ChannelGotify
 	ChannelPushPlus        = channels.ChannelPushPlus
 	ChannelVoceChat        = channels.ChannelVoceChat
+	ChannelSlack           = channels.ChannelSlack
+	ChannelDiscord         = channels.ChannelDiscord
+	ChannelMatrix          = channels.ChannelMatrix
+	ChannelMattermost      = channels.ChannelMattermost
+	ChannelWebPush         = channels.ChannelWebPush
 )
 
 // 重导出辅助函数
@@ -58,6 +63,8 @@ var (
 	ErrorResultStr = channels.ErrorResultStr
 	SendError      = channels.SendError
 	NewBaseChannel = channels.NewBaseChannel
+
+	ApplyFormatFallback = channels.ApplyFormatFallback
 )
 
 // channelFactory 渠道工厂注册表
@@ -82,6 +89,10 @@ func init() {
 	RegisterChannel(ChannelAliyunSMS, func() Channel { return channels.NewAliyunSMSChannel() })
 	RegisterChannel(ChannelPushPlus, func() Channel { return channels.NewPushPlusChannel() })
 	RegisterChannel(ChannelVoceChat, func() Channel { return channels.NewVoceChatChannel() })
+	RegisterChannel(ChannelSlack, func() Channel { return channels.NewSlackChannel() })
+	RegisterChannel(ChannelDiscord, func() Channel { return channels.NewDiscordChannel() })
+	RegisterChannel(ChannelMatrix, func() Channel { return channels.NewMatrixChannel() })
+	RegisterChannel(ChannelMattermost, func() Channel { return channels.NewMattermostChannel() })
 }
 
 // RegisterChannel 注册自定义渠道（可用于扩展）
@@ -133,7 +144,7 @@ func Send(channelType string, config ChannelConfig, msg *Message) (*Result, erro
 	if err != nil {
 		return nil, err
 	}
-	return ch.Send(config, msg)
+	return ch.Send(config, ApplyFormatFallback(ch, msg))
 }
 
 // Client 消息发送客户端（支持预设默认配置）
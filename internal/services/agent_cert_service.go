@@ -0,0 +1,129 @@
+package services
+
+import (
+	"crypto/x509"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// AgentMTLSMode 当前生效的 mTLS 模式，未开启 Agent TLS 端口时始终为 off
+func AgentMTLSMode() string {
+	if GetAgentPKI().TLSPort() == 0 {
+		return constant.AgentMTLSOff
+	}
+	switch mode := NewSettingsService().Get(constant.SectionAgent, constant.KeyAgentMTLSMode); mode {
+	case constant.AgentMTLSOptional, constant.AgentMTLSRequired:
+		return mode
+	default:
+		return constant.AgentMTLSOff
+	}
+}
+
+// IssueAgentCert 为 Agent 签发客户端证书，只保存指纹；该 Agent 此前的有效证书一并吊销
+func (s *AgentService) IssueAgentCert(agent *models.Agent, csrPEM string) (string, *models.AgentCert, error) {
+	cert, certPEM, err := GetAgentPKI().SignAgentCSR(agent.ID, csrPEM)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := models.LocalTime(time.Now())
+	database.DB.Model(&models.AgentCert{}).
+		Where("agent_id = ? AND status = ?", agent.ID, constant.AgentCertStatusActive).
+		Updates(map[string]interface{}{
+			"status":        constant.AgentCertStatusRevoked,
+			"revoke_reason": "已轮换",
+			"revoked_at":    now,
+		})
+
+	record := &models.AgentCert{
+		ID:           utils.GenerateID(),
+		AgentID:      agent.ID,
+		Fingerprint:  CertFingerprint(cert),
+		SerialNumber: cert.SerialNumber.Text(16),
+		NotBefore:    models.LocalTime(cert.NotBefore),
+		NotAfter:     models.LocalTime(cert.NotAfter),
+		Status:       constant.AgentCertStatusActive,
+	}
+	if err := database.DB.Create(record).Error; err != nil {
+		return "", nil, err
+	}
+	// 签发后不再允许仅凭 Token 申请，后续只能用当前证书轮换
+	database.DB.Model(&models.Agent{}).Where("id = ?", agent.ID).Update("cert_enroll", false)
+
+	logger.Infof("[AgentPKI] 已为 Agent #%s 签发客户端证书 %s...（有效期至 %s）", agent.ID, record.Fingerprint[:16], cert.NotAfter.Local().Format("2006-01-02"))
+	return certPEM, record, nil
+}
+
+// VerifyAgentCert 根据客户端证书指纹查找签发记录，未签发、已吊销或已过期时返回错误
+func (s *AgentService) VerifyAgentCert(cert *x509.Certificate) (*models.AgentCert, error) {
+	var record models.AgentCert
+	res := database.DB.Where("fingerprint = ?", CertFingerprint(cert)).Limit(1).Find(&record)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil, &ServiceError{Message: "未知的客户端证书", Code: constant.AgentCertErrUnknown}
+	}
+	if record.Status == constant.AgentCertStatusRevoked {
+		return nil, &ServiceError{Message: "客户端证书已吊销", Code: constant.AgentCertErrRevoked}
+	}
+	if !record.IsValid() {
+		return nil, &ServiceError{Message: "客户端证书已过期", Code: constant.AgentCertErrExpired}
+	}
+	return &record, nil
+}
+
+// ListAgentCerts 获取 Agent 的证书签发记录（新的在前）
+func (s *AgentService) ListAgentCerts(agentID string) []models.AgentCert {
+	var certs []models.AgentCert
+	database.DB.Where("agent_id = ?", agentID).Order("created_at DESC").Find(&certs)
+	return certs
+}
+
+// RevokeAgentCert 吊销证书并断开使用该证书的连接，allowReenroll 时允许 Agent 再次凭 Token 申请证书
+func (s *AgentService) RevokeAgentCert(id, reason string, allowReenroll bool) (*models.AgentCert, error) {
+	var record models.AgentCert
+	res := database.DB.Where("id = ?", id).Limit(1).Find(&record)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil, &ServiceError{Message: "证书不存在"}
+	}
+	if record.Status == constant.AgentCertStatusRevoked {
+		return nil, &ServiceError{Message: "证书已吊销"}
+	}
+	if reason == "" {
+		reason = "管理员吊销"
+	}
+
+	now := models.LocalTime(time.Now())
+	if err := database.DB.Model(&record).Updates(map[string]interface{}{
+		"status":        constant.AgentCertStatusRevoked,
+		"revoke_reason": reason,
+		"revoked_at":    now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	if allowReenroll {
+		database.DB.Model(&models.Agent{}).Where("id = ?", record.AgentID).Update("cert_enroll", true)
+	}
+	GetAgentWSManager().DisconnectCert(record.AgentID, record.Fingerprint)
+
+	record.Status = constant.AgentCertStatusRevoked
+	record.RevokeReason = reason
+	record.RevokedAt = &now
+	logger.Infof("[AgentPKI] 已吊销 Agent #%s 的客户端证书 %s...: %s", record.AgentID, record.Fingerprint[:16], reason)
+	return &record, nil
+}
+
+// AllowCertEnroll 允许 Agent 再次凭 Token 申请证书（证书丢失且已吊销时使用）
+func (s *AgentService) AllowCertEnroll(agentID string) error {
+	res := database.DB.Model(&models.Agent{}).Where("id = ?", agentID).Update("cert_enroll", true)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return &ServiceError{Message: "Agent 不存在"}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/services/deps"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+const (
+	// agentQueryTimeout 查询类命令（mise ls、pip list 等）的超时时间
+	agentQueryTimeout = 2 * time.Minute
+	// agentInstallTimeout 安装类命令（mise use、依赖安装）的超时时间
+	agentInstallTimeout = 30 * time.Minute
+	// pendingCommandTTL 预备命令的有效期，过期未执行则丢弃
+	pendingCommandTTL = 10 * time.Minute
+)
+
+// AgentCommand 下发给 Agent 执行的运行环境管理命令，Args 直接执行，Command 经 Shell 执行
+type AgentCommand struct {
+	Args    []string `json:"args,omitempty"`
+	Command string   `json:"command,omitempty"`
+	Env     []string `json:"env,omitempty"`
+	Timeout int      `json:"timeout"` // 秒
+}
+
+// AgentCommandResult Agent 命令执行结果
+type AgentCommandResult struct {
+	Stdout   []byte // 仅标准输出
+	Output   []byte // 标准输出与错误输出，按到达顺序
+	ExitCode int
+}
+
+// agentCommandRun 一次等待中的命令执行
+type agentCommandRun struct {
+	agentID  string
+	onOutput func([]byte)
+	result   AgentCommandResult
+	err      string
+	done     chan struct{}
+	doneOnce sync.Once
+	mu       sync.Mutex
+}
+
+func (r *agentCommandRun) finish(exitCode int, errMsg string) {
+	r.doneOnce.Do(func() {
+		r.mu.Lock()
+		r.result.ExitCode = exitCode
+		r.err = errMsg
+		r.mu.Unlock()
+		close(r.done)
+	})
+}
+
+// PendingAgentCommand 已生成但尚未执行的命令，浏览器凭 ID 经终端 WebSocket 执行并查看实时输出
+type PendingAgentCommand struct {
+	AgentID  string
+	Command  AgentCommand
+	expireAt time.Time
+}
+
+// agentCommandStore 运行环境管理命令的等待者与预备命令（独立的锁，可在持有 AgentWSManager.mu 时调用）
+type agentCommandStore struct {
+	mu      sync.Mutex
+	runs    map[string]*agentCommandRun
+	pending map[string]*PendingAgentCommand
+}
+
+func (s *agentCommandStore) add(id string, r *agentCommandRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.runs == nil {
+		s.runs = make(map[string]*agentCommandRun)
+	}
+	s.runs[id] = r
+}
+
+func (s *agentCommandStore) get(id string) *agentCommandRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs[id]
+}
+
+func (s *agentCommandStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.runs, id)
+}
+
+// failAgent Agent 断开时结束其全部等待中的命令
+func (s *agentCommandStore) failAgent(agentID, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.runs {
+		if r.agentID == agentID {
+			r.finish(-1, reason)
+		}
+	}
+}
+
+// RunAgentCommand 在 Agent 上执行命令并等待结束，onOutput 非空时实时接收输出
+// 命令退出码非 0 时同时返回结果与错误
+func (m *AgentWSManager) RunAgentCommand(agentID string, cmd AgentCommand, onOutput func([]byte)) (*AgentCommandResult, error) {
+	if !m.IsAgentOnline(agentID) {
+		return nil, fmt.Errorf("Agent #%s 不在线", agentID)
+	}
+	if cmd.Timeout <= 0 {
+		cmd.Timeout = int(agentQueryTimeout.Seconds())
+	}
+
+	id := utils.GenerateID()
+	run := &agentCommandRun{agentID: agentID, onOutput: onOutput, done: make(chan struct{})}
+	m.commands.add(id, run)
+	defer m.commands.remove(id)
+
+	m.SendToAgent(agentID, WSTypeCommand, map[string]interface{}{
+		"request_id": id,
+		"args":       cmd.Args,
+		"command":    cmd.Command,
+		"env":        cmd.Env,
+		"timeout":    cmd.Timeout,
+	})
+
+	// Agent 自身会在超时后终止命令，这里多等一会儿以便收到结果
+	timer := time.NewTimer(time.Duration(cmd.Timeout)*time.Second + 30*time.Second)
+	defer timer.Stop()
+	select {
+	case <-run.done:
+	case <-timer.C:
+		m.SendToAgent(agentID, WSTypeCommandCancel, map[string]interface{}{"request_id": id})
+		run.finish(-1, "等待 Agent 执行结果超时")
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	result := run.result
+	if run.err != "" {
+		return &result, fmt.Errorf("%s", run.err)
+	}
+	if result.ExitCode != 0 {
+		return &result, fmt.Errorf("exit status %d", result.ExitCode)
+	}
+	return &result, nil
+}
+
+// HandleCommandMessage 处理 Agent 回传的命令输出与结果
+func (m *AgentWSManager) HandleCommandMessage(agentID, msgType string, data json.RawMessage) {
+	var msg struct {
+		RequestID string `json:"request_id"`
+		Stream    string `json:"stream"`
+		Data      []byte `json:"data"`
+		ExitCode  int    `json:"exit_code"`
+		Error     string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+	run := m.commands.get(msg.RequestID)
+	if run == nil || run.agentID != agentID {
+		return
+	}
+
+	if msgType == WSTypeCommandResult {
+		run.finish(msg.ExitCode, msg.Error)
+		return
+	}
+	run.mu.Lock()
+	if msg.Stream == "stdout" {
+		run.result.Stdout = append(run.result.Stdout, msg.Data...)
+	}
+	run.result.Output = append(run.result.Output, msg.Data...)
+	onOutput := run.onOutput
+	run.mu.Unlock()
+	if onOutput != nil {
+		onOutput(msg.Data)
+	}
+}
+
+// PrepareAgentCommand 登记一条待执行的命令并返回其 ID，仅可执行一次
+func (m *AgentWSManager) PrepareAgentCommand(agentID string, cmd AgentCommand) string {
+	s := &m.commands
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		s.pending = make(map[string]*PendingAgentCommand)
+	}
+	// 预备命令多为安装命令，未指定时按安装命令的超时时间执行
+	if cmd.Timeout <= 0 {
+		cmd.Timeout = int(agentInstallTimeout.Seconds())
+	}
+	now := time.Now()
+	for id, p := range s.pending {
+		if now.After(p.expireAt) {
+			delete(s.pending, id)
+		}
+	}
+	id := utils.GenerateID()
+	s.pending[id] = &PendingAgentCommand{AgentID: agentID, Command: cmd, expireAt: now.Add(pendingCommandTTL)}
+	return id
+}
+
+// TakeAgentCommand 取出预备命令（取出后即失效）
+func (m *AgentWSManager) TakeAgentCommand(id, agentID string) *PendingAgentCommand {
+	s := &m.commands
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.pending[id]
+	if p == nil || p.AgentID != agentID || time.Now().After(p.expireAt) {
+		return nil
+	}
+	delete(s.pending, id)
+	return p
+}
+
+// AgentRunner 返回在 Agent 上执行依赖管理命令的执行器
+func (m *AgentWSManager) AgentRunner(agentID string, timeout time.Duration) deps.CommandRunner {
+	return func(args []string) ([]byte, error) {
+		logger.Infof("[AgentCommand] 在 Agent #%s 上执行: %v", agentID, args)
+		result, err := m.RunAgentCommand(agentID, AgentCommand{Args: args, Timeout: int(timeout.Seconds())}, nil)
+		if result == nil {
+			return nil, err
+		}
+		return result.Output, err
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/services/tasks"
+)
+
+// agentMetricsHistory 每个 Agent 在内存中保留的指标采样数（按 30 秒心跳约 1 小时）
+const agentMetricsHistory = 120
+
+// agentMetricsStore 按 Agent 保存最近的指标采样及是否处于超载状态
+type agentMetricsStore struct {
+	mu         sync.RWMutex
+	samples    map[string][]models.AgentMetrics
+	overloaded map[string]bool
+}
+
+// RecordMetrics 记录一次心跳上报的指标
+func (m *AgentWSManager) RecordMetrics(agentID string, metrics models.AgentMetrics) {
+	s := &m.metrics
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.samples == nil {
+		s.samples = make(map[string][]models.AgentMetrics)
+	}
+	samples := append(s.samples[agentID], metrics)
+	if len(samples) > agentMetricsHistory {
+		samples = samples[len(samples)-agentMetricsHistory:]
+	}
+	s.samples[agentID] = samples
+}
+
+// GetMetrics 获取最近一次上报的指标，没有数据时返回 nil
+func (m *AgentWSManager) GetMetrics(agentID string) *models.AgentMetrics {
+	s := &m.metrics
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	samples := s.samples[agentID]
+	if len(samples) == 0 {
+		return nil
+	}
+	latest := samples[len(samples)-1]
+	return &latest
+}
+
+// GetMetricsHistory 获取最近的指标采样（按时间升序）
+func (m *AgentWSManager) GetMetricsHistory(agentID string) []models.AgentMetrics {
+	s := &m.metrics
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]models.AgentMetrics{}, s.samples[agentID]...)
+}
+
+// RemoveMetrics 删除 Agent 时清理其指标
+func (m *AgentWSManager) RemoveMetrics(agentID string) {
+	s := &m.metrics
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.samples, agentID)
+	delete(s.overloaded, agentID)
+}
+
+// setOverloaded 更新超载状态，返回是否由正常变为超载
+func (m *AgentWSManager) setOverloaded(agentID string, overloaded bool) bool {
+	s := &m.metrics
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.overloaded == nil {
+		s.overloaded = make(map[string]bool)
+	}
+	was := s.overloaded[agentID]
+	s.overloaded[agentID] = overloaded
+	return overloaded && !was
+}
+
+// RecordMetrics 记录 Agent 指标，超过负载上限时发布 agent_overloaded 事件（恢复后再次超限才会重新告警）
+func (s *AgentService) RecordMetrics(agent *models.Agent, ip string, metrics models.AgentMetrics) {
+	manager := GetAgentWSManager()
+	metrics.Time = time.Now().Unix()
+	manager.RecordMetrics(agent.ID, metrics)
+
+	reason := tasks.LoadAgentLoadLimits(NewSettingsService()).Exceeded(&metrics)
+	if !manager.setOverloaded(agent.ID, reason != "") {
+		return
+	}
+
+	logger.Warnf("[Agent] Agent %s (#%s) 负载过高: %s", agent.Name, agent.ID, reason)
+	eventbus.DefaultBus.Publish(eventbus.Event{
+		Type: constant.EventAgentOverloaded,
+		Payload: map[string]interface{}{
+			"agent_id":      agent.ID,
+			"agent_name":    agent.Name,
+			"ip":            ip,
+			"reason":        reason,
+			"cpu_percent":   int(metrics.CPUPercent),
+			"mem_percent":   int(metrics.MemPercent),
+			"disk_percent":  int(metrics.DiskPercent),
+			"load1":         metrics.Load1,
+			"running_tasks": metrics.RunningTasks,
+		},
+	})
+}
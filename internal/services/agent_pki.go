@@ -0,0 +1,262 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/logger"
+)
+
+const (
+	// agentCACertValidity 面板 CA 有效期
+	agentCACertValidity = 10 * 365 * 24 * time.Hour
+	// agentServerCertValidity Agent TLS 端口服务器证书有效期（每次启动重新签发）
+	agentServerCertValidity = 365 * 24 * time.Hour
+	// AgentClientCertValidity Agent 客户端证书有效期，剩余不足三分之一时 Agent 自动轮换
+	AgentClientCertValidity = 90 * 24 * time.Hour
+)
+
+// AgentPKI 面板内置的小型 CA：签发 Agent 客户端证书与 Agent TLS 端口的服务器证书
+type AgentPKI struct {
+	mu      sync.Mutex
+	caCert  *x509.Certificate
+	caKey   *ecdsa.PrivateKey
+	caPEM   []byte
+	tlsPort int // Agent TLS 端口，0 表示未开启
+}
+
+var agentPKI = &AgentPKI{}
+
+// GetAgentPKI 获取面板 CA 单例
+func GetAgentPKI() *AgentPKI {
+	return agentPKI
+}
+
+// loadCA 加载 CA，不存在时生成（调用方需持有 p.mu）
+func (p *AgentPKI) loadCA() error {
+	if p.caCert != nil {
+		return nil
+	}
+	certPath := filepath.Join(constant.AgentPKIDir, "ca.crt")
+	keyPath := filepath.Join(constant.AgentPKIDir, "ca.key")
+
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	if certErr == nil && keyErr == nil {
+		cert, key, err := parseCA(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("加载面板 CA 失败: %w", err)
+		}
+		p.caCert, p.caKey, p.caPEM = cert, key, certPEM
+		return nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Baihu Panel Agent CA", Organization: []string{"Baihu Panel"}},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(agentCACertValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	if err := os.MkdirAll(constant.AgentPKIDir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return err
+	}
+	cert, _ := x509.ParseCertificate(der)
+	p.caCert, p.caKey, p.caPEM = cert, key, certPEM
+	logger.Infof("[AgentPKI] 已生成面板 CA: %s", certPath)
+	return nil
+}
+
+func parseCA(certPEM, keyPEM []byte) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, fmt.Errorf("PEM 格式错误")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// CACertPEM 返回 CA 证书（PEM），Agent 用其校验 TLS 端口的服务器证书
+func (p *AgentPKI) CACertPEM() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.loadCA(); err != nil {
+		return nil, err
+	}
+	return p.caPEM, nil
+}
+
+// ServerTLSConfig 生成 Agent TLS 端口的配置：服务器证书由面板 CA 签发，客户端证书按需校验
+// hosts 为逗号分隔的域名或 IP，为空时使用本机主机名与回环地址
+func (p *AgentPKI) ServerTLSConfig(port int, hosts string) (*tls.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.loadCA(); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, h := range strings.Split(hosts, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			names = append(names, h)
+		}
+	}
+	if len(names) == 0 {
+		if hostname, err := os.Hostname(); err == nil {
+			names = append(names, hostname)
+		}
+		names = append(names, "localhost", "127.0.0.1", "::1")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: names[0], Organization: []string{"Baihu Panel"}},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(agentServerCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, name)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.caCert, &key.PublicKey, p.caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(p.caCert)
+	p.tlsPort = port
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der, p.caCert.Raw}, PrivateKey: key}},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// TLSPort 返回 Agent TLS 端口，未开启时为 0
+func (p *AgentPKI) TLSPort() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tlsPort
+}
+
+// SignAgentCSR 校验 CSR 并签发客户端证书，CN 固定为 Agent ID（忽略 CSR 中的主题）
+func (p *AgentPKI) SignAgentCSR(agentID string, csrPEM string) (*x509.Certificate, string, error) {
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, "", &ServiceError{Message: "CSR 格式错误"}
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, "", &ServiceError{Message: "解析 CSR 失败: " + err.Error()}
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, "", &ServiceError{Message: "CSR 签名无效"}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.loadCA(); err != nil {
+		return nil, "", err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: agentID, Organization: []string{"Baihu Panel Agent"}},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(AgentClientCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.caCert, csr.PublicKey, p.caKey)
+	if err != nil {
+		return nil, "", err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, "", err
+	}
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), nil
+}
+
+// CertFingerprint 证书指纹：DER 的 SHA-256（十六进制）
+func CertFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
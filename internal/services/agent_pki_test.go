@@ -0,0 +1,86 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+)
+
+func newTestCSR(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "spoofed"}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+func TestAgentPKISignAndVerify(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	p := &AgentPKI{}
+	cfg, err := p.ServerTLSConfig(8053, "panel.example.com, 10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.TLSPort() != 8053 {
+		t.Fatalf("TLSPort() = %d", p.TLSPort())
+	}
+
+	// 服务器证书覆盖配置的域名与 IP，并由面板 CA 签发
+	serverCert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"panel.example.com", "10.0.0.1"} {
+		if err := serverCert.VerifyHostname(host); err != nil {
+			t.Fatalf("服务器证书不包含 %s: %v", host, err)
+		}
+	}
+	if _, err := serverCert.Verify(x509.VerifyOptions{Roots: cfg.ClientCAs}); err != nil {
+		t.Fatalf("服务器证书校验失败: %v", err)
+	}
+
+	// 客户端证书 CN 固定为 Agent ID，只能用于客户端认证
+	cert, certPEM, err := p.SignAgentCSR("agent-1", newTestCSR(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Subject.CommonName != "agent-1" {
+		t.Fatalf("CN = %q, want agent-1", cert.Subject.CommonName)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: cfg.ClientCAs, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Fatalf("客户端证书校验失败: %v", err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: cfg.ClientCAs, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}); err == nil {
+		t.Fatal("客户端证书不应可用于服务器认证")
+	}
+	if block, _ := pem.Decode([]byte(certPEM)); block == nil || !bytes.Equal(block.Bytes, cert.Raw) {
+		t.Fatal("返回的 PEM 与证书不一致")
+	}
+	if fp := CertFingerprint(cert); len(fp) != 64 {
+		t.Fatalf("指纹长度 = %d, want 64", len(fp))
+	}
+
+	if _, _, err := p.SignAgentCSR("agent-1", "not a csr"); err == nil {
+		t.Fatal("无效 CSR 应返回错误")
+	}
+
+	// 重启后从磁盘加载同一个 CA
+	caPEM, _ := p.CACertPEM()
+	reloaded, err := (&AgentPKI{}).CACertPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(caPEM, reloaded) {
+		t.Fatal("重新加载的 CA 与生成的不一致")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -203,14 +204,24 @@ func (s *AgentService) Register(req *models.AgentRegisterRequest, ip string) (*m
 }
 
 // Update 更新 Agent
-func (s *AgentService) Update(id string, name, description string, enabled bool) error {
+func (s *AgentService) Update(id string, name, description, labels string, enabled bool) error {
 	return database.DB.Model(&models.Agent{}).Where("id = ?", id).Updates(map[string]interface{}{
 		"name":        name,
 		"description": description,
+		"labels":      strings.Join(models.SplitLabels(labels), ","),
 		"enabled":     &enabled,
 	}).Error
 }
 
+// ListLabels 获取全部 Agent 标签（去重并排序）
+func (s *AgentService) ListLabels() []string {
+	var rows []string
+	database.DB.Model(&models.Agent{}).Where("labels <> ''").Pluck("labels", &rows)
+	labels := models.SplitLabels(strings.Join(rows, ","))
+	sort.Strings(labels)
+	return labels
+}
+
 // Delete 删除 Agent（物理删除）
 func (s *AgentService) Delete(id string) error {
 	// 检查是否有关联任务
@@ -220,7 +231,15 @@ func (s *AgentService) Delete(id string) error {
 		return &ServiceError{Message: "该 Agent 下还有关联任务，无法删除"}
 	}
 
-	return database.DB.Where("id = ?", id).Delete(&models.Agent{}).Error
+	if err := database.DB.Where("id = ?", id).Delete(&models.Agent{}).Error; err != nil {
+		return err
+	}
+	GetAgentWSManager().RemoveMetrics(id)
+	GetAgentWSManager().scriptSyncs.resetAgent(id)
+	database.DB.Where("agent_id = ?", id).Delete(&models.Dependency{})
+	database.DB.Where("agent_id = ?", id).Delete(&models.AgentCert{})
+	database.DB.Where("agent_id = ?", id).Delete(&models.AgentUpdate{})
+	return nil
 }
 
 // GetByID 根据 ID 获取 Agent
@@ -271,7 +290,11 @@ func (s *AgentService) Heartbeat(token, ip, version, buildTime, hostname, osType
 	if agent == nil {
 		return nil, &ServiceError{Message: "无效的 Token"}
 	}
+	return s.HeartbeatAgent(agent, ip, version, buildTime, hostname, osType, arch)
+}
 
+// HeartbeatAgent 更新已认证 Agent 的在线状态与版本信息（mTLS 证书认证时使用）
+func (s *AgentService) HeartbeatAgent(agent *models.Agent, ip, version, buildTime, hostname, osType, arch string) (*models.Agent, error) {
 	if !utils.DerefBool(agent.Enabled, true) {
 		return nil, &ServiceError{Message: "Agent 已禁用"}
 	}
@@ -314,6 +337,7 @@ func (s *AgentService) Heartbeat(token, ip, version, buildTime, hostname, osType
 
 // GetTasks 获取 Agent 的任务列表
 func (s *AgentService) GetTasks(agentID string) []models.AgentTask {
+	takeoverDelay := int(tasks.FailoverTakeoverDelay(NewSettingsService()).Seconds())
 	var tasks []models.Task
 	database.DB.Where("agent_id = ? AND enabled = ?", agentID, true).Find(&tasks)
 
@@ -357,38 +381,15 @@ func (s *AgentService) GetTasks(agentID string) []models.AgentTask {
 			Secrets:     secrets,
 			Enabled:     utils.DerefBool(task.Enabled, true),
 		}
+		if task.HasFailover() {
+			result[i].FailoverDelay = takeoverDelay
+		}
 	}
 
 	return result
 }
 
 
-// ReportResult Agent 上报执行结果
-func (s *AgentService) ReportResult(result *models.AgentTaskResult) error {
-	// 获取依赖的服务
-	agentWSManager := GetAgentWSManager()
-
-	// 先尝试通知正在等待的 goroutine
-	if agentWSManager.NotifyRemoteResult(result) {
-		logger.Infof("[Agent] 已通知正在等待任务 #%s 结果的 goroutine", result.TaskID)
-		return nil
-	}
-
-	// 如果没有人在等待（例如服务重启后），则由本协程负责处理结果入库
-	// 如果没有人在等待（例如服务重启后），则由本协程负责处理结果入库（记录日志并清理）
-	logger.Infof("[Agent] 没有找到等待任务 #%s 结果的 goroutine，直接处理结果", result.TaskID)
-	sendStatsService := NewSendStatsService()
-	taskLogService := tasks.NewTaskLogService(sendStatsService)
-
-	// 创建日志对象
-	taskLog, err := taskLogService.CreateTaskLogFromAgentResult(result)
-	if err != nil {
-		return err
-	}
-	// 处理完成逻辑（保存日志、更新统计、清理旧日志等）
-	return taskLogService.ProcessTaskCompletion(taskLog)
-}
-
 // UpdateTaskDuration 更新任务耗时（心跳）
 func (s *AgentService) UpdateTaskDuration(logID string, duration int64) error {
 	taskLogService := tasks.NewTaskLogService(nil)
@@ -497,23 +498,24 @@ func (s *AgentService) GetAvailablePlatforms() []map[string]string {
 
 // GetAgentBinary 获取 Agent 压缩包
 func (s *AgentService) GetAgentBinary(osType, arch string) ([]byte, string, error) {
-	filename := fmt.Sprintf("baihu-agent-%s-%s.tar.gz", osType, arch)
-
-	// 优先从 /opt/agent 读取（容器内）
-	filePath := filepath.Join("/opt/agent", filename)
+	filePath, filename := agentBinaryPath(osType, arch)
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		// 回退到 data/agent（本地开发）
-		filePath = filepath.Join("data/agent", filename)
-		data, err = os.ReadFile(filePath)
-		if err != nil {
-			return nil, "", &ServiceError{Message: "未找到对应平台的 Agent 程序"}
-		}
+		return nil, "", &ServiceError{Message: "未找到对应平台的 Agent 程序"}
 	}
-
 	return data, filename, nil
 }
 
+// agentBinaryPath 返回 Agent 压缩包路径：优先 /opt/agent（容器内），不存在时回退到 data/agent（本地开发）
+func agentBinaryPath(osType, arch string) (string, string) {
+	filename := fmt.Sprintf("baihu-agent-%s-%s.tar.gz", osType, arch)
+	filePath := filepath.Join("/opt/agent", filename)
+	if _, err := os.Stat(filePath); err != nil {
+		filePath = filepath.Join("data/agent", filename)
+	}
+	return filePath, filename
+}
+
 // SetForceUpdate 设置强制更新标志
 func (s *AgentService) SetForceUpdate(id string) error {
 	return database.DB.Model(&models.Agent{}).Where("id = ?", id).Update("force_update", true).Error
@@ -527,6 +529,7 @@ func (s *AgentService) ClearForceUpdate(id string) error {
 // ServiceError 服务错误
 type ServiceError struct {
 	Message string
+	Code    string // 可选的机器可读错误码
 }
 
 func (e *ServiceError) Error() string {
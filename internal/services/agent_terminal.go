@@ -0,0 +1,305 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// TerminalEvent Agent 发来的终端消息（opened/output）
+type TerminalEvent struct {
+	Type string // constant.WSTypeTerminalOpened / constant.WSTypeTerminalOutput
+	Mode string // opened 时为 pty 或 pipe
+	Text string // output 时为转换为 UTF-8 的终端输出，已写入录像
+}
+
+// terminalOutputTimeout 会话输出队列已满时最长等待浏览器接收的时间，超时后结束会话
+var terminalOutputTimeout = 5 * time.Second
+
+// AgentTerminal 经由 Agent WebSocket 转发的一路远程终端会话，输入输出以 asciicast v2 格式录像
+type AgentTerminal struct {
+	ID      string
+	AgentID string
+	Events  chan TerminalEvent
+
+	manager   *AgentWSManager
+	file      *os.File
+	start     time.Time
+	mu        sync.Mutex
+	done      chan struct{}
+	doneOnce  sync.Once
+	reason    string
+	closeOnce sync.Once
+}
+
+// agentTerminalStore 会话 ID -> 远程终端（独立的锁，可在持有 AgentWSManager.mu 时调用）
+type agentTerminalStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*AgentTerminal
+}
+
+func (s *agentTerminalStore) add(t *AgentTerminal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]*AgentTerminal)
+	}
+	s.sessions[t.ID] = t
+}
+
+func (s *agentTerminalStore) get(id string) *AgentTerminal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sessions[id]
+}
+
+func (s *agentTerminalStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// terminateAgent 结束指定 Agent 的全部终端会话
+func (s *agentTerminalStore) terminateAgent(agentID, reason string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.sessions {
+		if t.AgentID == agentID {
+			t.terminate(reason)
+		}
+	}
+}
+
+// OpenTerminal 创建远程终端会话（写入审计记录并开始录像），并通知 Agent 启动 Shell
+func (m *AgentWSManager) OpenTerminal(agent *models.Agent, userID, username, clientIP string, rows, cols int) (*AgentTerminal, error) {
+	if !m.IsAgentOnline(agent.ID) {
+		return nil, fmt.Errorf("Agent %s 不在线", agent.Name)
+	}
+
+	id := utils.GenerateID()
+	if err := os.MkdirAll(constant.TerminalRecordDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建录像目录失败: %v", err)
+	}
+	recordFile := filepath.Join(constant.TerminalRecordDir, id+".cast")
+	file, err := os.OpenFile(recordFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("创建录像文件失败: %v", err)
+	}
+
+	start := time.Now()
+	header, _ := json.Marshal(map[string]interface{}{
+		"version":   2,
+		"width":     cols,
+		"height":    rows,
+		"timestamp": start.Unix(),
+		"title":     fmt.Sprintf("%s@%s", username, agent.Name),
+		"env":       map[string]string{"TERM": "xterm-256color"},
+	})
+	file.Write(append(header, '\n'))
+
+	record := &models.TerminalSession{
+		ID:         id,
+		AgentID:    agent.ID,
+		AgentName:  agent.Name,
+		UserID:     userID,
+		Username:   username,
+		ClientIP:   clientIP,
+		Status:     constant.TerminalStatusActive,
+		RecordFile: recordFile,
+		StartedAt:  models.LocalTime(start),
+	}
+	if err := database.DB.Create(record).Error; err != nil {
+		file.Close()
+		os.Remove(recordFile)
+		return nil, fmt.Errorf("保存会话记录失败: %v", err)
+	}
+
+	t := &AgentTerminal{
+		ID:      id,
+		AgentID: agent.ID,
+		Events:  make(chan TerminalEvent, 256),
+		manager: m,
+		file:    file,
+		start:   start,
+		done:    make(chan struct{}),
+	}
+	m.terminals.add(t)
+	logger.Infof("[AgentTerminal] 用户 %s (%s) 打开 Agent %s (#%s) 的远程终端 #%s", username, clientIP, agent.Name, agent.ID, id)
+
+	m.SendToAgent(agent.ID, WSTypeTerminalOpen, map[string]interface{}{
+		"session_id": id,
+		"rows":       rows,
+		"cols":       cols,
+	})
+	return t, nil
+}
+
+// HandleTerminalMessage 将 Agent 发来的终端消息转交给对应会话
+func (m *AgentWSManager) HandleTerminalMessage(agentID, msgType string, data json.RawMessage) {
+	var msg struct {
+		SessionID string `json:"session_id"`
+		Mode      string `json:"mode"`
+		Data      []byte `json:"data"`
+		Reason    string `json:"reason"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+	t := m.terminals.get(msg.SessionID)
+	if t == nil || t.AgentID != agentID {
+		return
+	}
+
+	if msgType == WSTypeTerminalClosed {
+		t.terminate(msg.Reason)
+		return
+	}
+
+	ev := TerminalEvent{Type: msgType, Mode: msg.Mode}
+	if msgType == WSTypeTerminalOutput {
+		// 先录像再转发，保证录像完整，不受浏览器接收速度影响
+		ev.Text = utils.ToUTF8(msg.Data)
+		t.recordEvent("o", ev.Text)
+	}
+
+	select {
+	case t.Events <- ev:
+		return
+	case <-t.done:
+		return
+	default:
+	}
+
+	// 队列已满时阻塞该 Agent 连接的读取，由 TCP 反压让 Agent 放慢输出；
+	// 超时仍无法转发说明浏览器已跟不上，结束会话而不是丢弃输出
+	timer := time.NewTimer(terminalOutputTimeout)
+	defer timer.Stop()
+	select {
+	case t.Events <- ev:
+	case <-t.done:
+	case <-timer.C:
+		logger.Warnf("[AgentTerminal] 会话 #%s 输出积压超过 %v，结束会话", t.ID, terminalOutputTimeout)
+		t.terminate("终端输出积压，浏览器未及时接收")
+	}
+}
+
+// Done 会话被 Agent 或面板结束时关闭
+func (t *AgentTerminal) Done() <-chan struct{} {
+	return t.done
+}
+
+// Reason 会话结束原因
+func (t *AgentTerminal) Reason() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.reason
+}
+
+func (t *AgentTerminal) terminate(reason string) {
+	t.doneOnce.Do(func() {
+		t.mu.Lock()
+		t.reason = reason
+		t.mu.Unlock()
+		close(t.done)
+	})
+}
+
+// Input 转发用户输入
+func (t *AgentTerminal) Input(data []byte) {
+	t.recordEvent("i", string(data))
+	t.manager.SendToAgent(t.AgentID, WSTypeTerminalInput, map[string]interface{}{
+		"session_id": t.ID,
+		"data":       data,
+	})
+}
+
+// Resize 调整终端窗口大小
+func (t *AgentTerminal) Resize(rows, cols int) {
+	t.recordEvent("r", fmt.Sprintf("%dx%d", cols, rows))
+	t.manager.SendToAgent(t.AgentID, WSTypeTerminalResize, map[string]interface{}{
+		"session_id": t.ID,
+		"rows":       rows,
+		"cols":       cols,
+	})
+}
+
+func (t *AgentTerminal) recordEvent(code, data string) {
+	line, _ := json.Marshal([]interface{}{
+		float64(time.Since(t.start).Milliseconds()) / 1000, code, data,
+	})
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file != nil {
+		t.file.Write(append(line, '\n'))
+	}
+}
+
+// Close 结束会话：通知 Agent 关闭 Shell，结束录像并更新审计记录
+func (t *AgentTerminal) Close(reason string) {
+	t.closeOnce.Do(func() {
+		t.terminate(reason)
+		t.manager.terminals.remove(t.ID)
+		t.manager.SendToAgent(t.AgentID, WSTypeTerminalClose, map[string]interface{}{
+			"session_id": t.ID,
+		})
+
+		t.mu.Lock()
+		var size int64
+		if t.file != nil {
+			if info, err := t.file.Stat(); err == nil {
+				size = info.Size()
+			}
+			t.file.Close()
+			t.file = nil
+		}
+		reason := t.reason
+		t.mu.Unlock()
+
+		endedAt := models.Now()
+		database.DB.Model(&models.TerminalSession{}).Where("id = ?", t.ID).Updates(map[string]interface{}{
+			"status":       constant.TerminalStatusClosed,
+			"close_reason": reason,
+			"record_size":  size,
+			"ended_at":     &endedAt,
+		})
+		logger.Infof("[AgentTerminal] 远程终端 #%s 已结束: %s", t.ID, reason)
+	})
+}
+
+// CloseStaleTerminalSessions 将面板重启前遗留的活动会话标记为已结束
+func CloseStaleTerminalSessions() {
+	database.DB.Model(&models.TerminalSession{}).
+		Where("status = ?", constant.TerminalStatusActive).
+		Updates(map[string]interface{}{"status": constant.TerminalStatusClosed, "close_reason": "面板重启"})
+}
+
+// ListTerminalSessions 分页查询远程终端会话记录（agentID 为空时查询全部）
+func (s *AgentService) ListTerminalSessions(agentID string, page, pageSize int) ([]models.TerminalSession, int64) {
+	var sessions []models.TerminalSession
+	var total int64
+	query := database.DB.Model(&models.TerminalSession{})
+	if agentID != "" {
+		query = query.Where("agent_id = ?", agentID)
+	}
+	query.Count(&total)
+	query.Order("started_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&sessions)
+	return sessions, total
+}
+
+// GetTerminalSession 获取远程终端会话记录
+func (s *AgentService) GetTerminalSession(id string) *models.TerminalSession {
+	var session models.TerminalSession
+	if err := database.DB.Where("id = ?", id).First(&session).Error; err != nil {
+		return nil
+	}
+	return &session
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTerminalOutputIsRecordedAndNeverDropped(t *testing.T) {
+	prev := terminalOutputTimeout
+	terminalOutputTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { terminalOutputTimeout = prev })
+
+	recordFile := filepath.Join(t.TempDir(), "s1.cast")
+	file, err := os.Create(recordFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	m := &AgentWSManager{}
+	term := &AgentTerminal{
+		ID:      "s1",
+		AgentID: "a1",
+		Events:  make(chan TerminalEvent, 1),
+		manager: m,
+		file:    file,
+		start:   time.Now(),
+		done:    make(chan struct{}),
+	}
+	m.terminals.add(term)
+
+	output := func(text string) {
+		data, _ := json.Marshal(map[string]interface{}{"session_id": "s1", "data": []byte(text)})
+		m.HandleTerminalMessage("a1", WSTypeTerminalOutput, data)
+	}
+
+	// 浏览器未读取：第一条进入队列，第二条等待超时后结束会话
+	output("line-1")
+	output("line-2")
+	select {
+	case <-term.Done():
+	default:
+		t.Fatal("expected session to be terminated when output backs up")
+	}
+	if ev := <-term.Events; ev.Text != "line-1" {
+		t.Fatalf("queued output = %q", ev.Text)
+	}
+
+	// 录像包含全部输出，包括未能转发给浏览器的部分
+	content, err := os.ReadFile(recordFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"o","line-1"`, `"o","line-2"`} {
+		if !strings.Contains(string(content), want) {
+			t.Fatalf("recording missing %s:\n%s", want, content)
+		}
+	}
+}
@@ -0,0 +1,508 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// agentUpdateTimeout 下发更新后等待 Agent 以新版本上线的最长时间，超时记为失败
+const agentUpdateTimeout = 10 * time.Minute
+
+// GetUpdateManifest 生成指定平台更新包的清单，签名取自发布时生成的 .sig 文件
+func (s *AgentService) GetUpdateManifest(osType, arch string) (*models.AgentUpdateManifest, error) {
+	data, sig, err := s.GetSignedAgentBinary(osType, arch)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return &models.AgentUpdateManifest{
+		OS:        osType,
+		Arch:      arch,
+		Version:   s.GetLatestVersion(),
+		BuildTime: s.GetLatestBuildTime(),
+		SHA256:    hex.EncodeToString(sum[:]),
+		Size:      int64(len(data)),
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	}, nil
+}
+
+// GetSignedAgentBinary 获取 Agent 更新包及其分离签名（同目录下的 <文件名>.sig）。
+// 签名由发布流程用离线私钥生成，面板不持有签名私钥，只转发签名；没有签名的更新包不会提供给 Agent
+func (s *AgentService) GetSignedAgentBinary(osType, arch string) ([]byte, []byte, error) {
+	filePath, filename := agentBinaryPath(osType, arch)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, &ServiceError{Message: "未找到对应平台的 Agent 程序"}
+	}
+	raw, err := os.ReadFile(filePath + ".sig")
+	if err != nil {
+		return nil, nil, &ServiceError{Message: fmt.Sprintf("更新包 %s 缺少签名文件 %s.sig，拒绝下发", filename, filename)}
+	}
+	sig, err := parseUpdateSignature(raw)
+	if err != nil {
+		return nil, nil, &ServiceError{Message: fmt.Sprintf("更新包签名文件 %s.sig 无效: %v", filename, err)}
+	}
+	return data, sig, nil
+}
+
+// parseUpdateSignature 解析 .sig 文件，支持 openssl pkeyutl 输出的 64 字节原始签名或其 base64 文本
+func parseUpdateSignature(raw []byte) ([]byte, error) {
+	if len(raw) == ed25519.SignatureSize {
+		return raw, nil
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("不是 ed25519 签名")
+	}
+	return sig, nil
+}
+
+// latestAgentUpdate 获取 Agent 升级到指定版本的最近一条记录
+func latestAgentUpdate(agentID, version string) *models.AgentUpdate {
+	var u models.AgentUpdate
+	res := database.DB.Where("agent_id = ? AND to_version = ?", agentID, version).Order("created_at DESC").Limit(1).Find(&u)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil
+	}
+	return &u
+}
+
+// activeRollout 获取进行中或已暂停的分批发布，同一时间只允许一个
+func activeRollout() *models.AgentRollout {
+	var r models.AgentRollout
+	res := database.DB.Where("status IN ?", []string{constant.RolloutStatusRunning, constant.RolloutStatusPaused}).
+		Order("created_at DESC").Limit(1).Find(&r)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil
+	}
+	return &r
+}
+
+// startAgentUpdate 标记更新已下发，Agent 需在超时时间内以新版本上线
+func startAgentUpdate(u *models.AgentUpdate, fromVersion string) {
+	now := models.LocalTime(time.Now())
+	u.Status = constant.AgentUpdateUpdating
+	u.StartedAt = &now
+	if fromVersion != "" {
+		u.FromVersion = fromVersion
+	}
+	database.DB.Model(u).Updates(map[string]interface{}{
+		"status":       u.Status,
+		"started_at":   now,
+		"from_version": u.FromVersion,
+	})
+}
+
+// finishAgentUpdate 结束一条更新记录
+func finishAgentUpdate(u *models.AgentUpdate, status, message string) {
+	now := models.LocalTime(time.Now())
+	u.Status = status
+	u.Message = message
+	u.FinishedAt = &now
+	database.DB.Model(u).Updates(map[string]interface{}{
+		"status":      status,
+		"message":     message,
+		"finished_at": now,
+	})
+	switch status {
+	case constant.AgentUpdateRolledBack, constant.AgentUpdateFailed:
+		logger.Warnf("[AgentUpdate] Agent #%s 更新到 %s 未成功（%s）: %s", u.AgentID, u.ToVersion, status, message)
+	case constant.AgentUpdateSucceeded:
+		logger.Infof("[AgentUpdate] Agent #%s 已从 %s 更新到 %s", u.AgentID, u.FromVersion, u.ToVersion)
+	}
+}
+
+// UpdateDecision 根据心跳上报的版本决定是否让 Agent 更新，返回 needUpdate 与 forceUpdate。
+// 分批发布进行中时只有当前批次内的 Agent 会收到更新；更新失败或已回滚的版本不再自动重试，需管理员强制更新
+func (s *AgentService) UpdateDecision(agentID, version, buildTime string) (bool, bool) {
+	agent := s.GetByID(agentID)
+	if agent == nil {
+		return false, false
+	}
+	latest := s.GetLatestVersion()
+	rec := latestAgentUpdate(agentID, latest)
+
+	if !s.CheckNeedUpdate(version, buildTime) {
+		if rec != nil && !rec.IsFinished() && version == latest {
+			finishAgentUpdate(rec, constant.AgentUpdateSucceeded, "")
+		}
+		return false, false
+	}
+
+	if agent.ForceUpdate {
+		s.ClearForceUpdate(agentID)
+		if rec == nil || rec.IsFinished() {
+			rec = &models.AgentUpdate{ID: utils.GenerateID(), AgentID: agentID, ToVersion: latest, Status: constant.AgentUpdatePending}
+			database.DB.Create(rec)
+		}
+		startAgentUpdate(rec, version)
+		return true, true
+	}
+
+	if rec != nil {
+		if rec.Status != constant.AgentUpdatePending {
+			// 更新中等待结果；失败或已回滚时不再自动重试
+			return false, false
+		}
+		if r := activeRollout(); r != nil && r.ID == rec.RolloutID && r.Status != constant.RolloutStatusRunning {
+			return false, false
+		}
+		startAgentUpdate(rec, version)
+		return true, true
+	}
+
+	// 分批发布进行中，批次外的 Agent 即使开启了自动更新也需等待
+	if activeRollout() != nil {
+		return false, false
+	}
+	return true, false
+}
+
+// HandleUpdateStatus 处理 Agent 上报的自更新进度：updating、succeeded、rolled_back、failed
+func (s *AgentService) HandleUpdateStatus(agent *models.Agent, data json.RawMessage) {
+	var req struct {
+		Status      string `json:"status"`
+		FromVersion string `json:"from_version"`
+		ToVersion   string `json:"to_version"`
+		Message     string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return
+	}
+	if req.ToVersion == "" {
+		// 获取清单前失败时 Agent 不知道目标版本
+		req.ToVersion = s.GetLatestVersion()
+	}
+	switch req.Status {
+	case constant.AgentUpdateUpdating, constant.AgentUpdateSucceeded, constant.AgentUpdateRolledBack, constant.AgentUpdateFailed:
+	default:
+		return
+	}
+
+	rec := latestAgentUpdate(agent.ID, req.ToVersion)
+	if rec == nil || (rec.IsFinished() && req.Status == constant.AgentUpdateUpdating) {
+		// Agent 自动更新或旧记录已结束后的重试
+		rec = &models.AgentUpdate{ID: utils.GenerateID(), AgentID: agent.ID, ToVersion: req.ToVersion, Status: constant.AgentUpdatePending}
+		if err := database.DB.Create(rec).Error; err != nil {
+			logger.Errorf("[AgentUpdate] 保存更新记录失败: %v", err)
+			return
+		}
+	}
+	if rec.IsFinished() {
+		return
+	}
+
+	if req.Status == constant.AgentUpdateUpdating {
+		if rec.Status != constant.AgentUpdateUpdating {
+			startAgentUpdate(rec, req.FromVersion)
+		}
+		return
+	}
+	if rec.FromVersion == "" && req.FromVersion != "" {
+		rec.FromVersion = req.FromVersion
+		database.DB.Model(rec).Update("from_version", rec.FromVersion)
+	}
+	finishAgentUpdate(rec, req.Status, req.Message)
+}
+
+// ListAgentUpdates 获取 Agent 的更新记录（新的在前）
+func (s *AgentService) ListAgentUpdates(agentID string) []models.AgentUpdate {
+	var updates []models.AgentUpdate
+	database.DB.Where("agent_id = ?", agentID).Order("created_at DESC").Limit(50).Find(&updates)
+	return updates
+}
+
+// CreateRollout 创建分批发布并立即开始第一批。
+// 百分比批次按累计比例覆盖，例如 10、50、100 依次覆盖 10%、50%、全部 Agent；标签批次覆盖拥有全部标签的 Agent。
+// minWaveMinutes 大于 0 时每批至少观察该时长才进入下一批
+func (s *AgentService) CreateRollout(waves models.RolloutWaves, maxFailures, minWaveMinutes int) (*models.AgentRollout, error) {
+	if len(waves) == 0 {
+		return nil, &ServiceError{Message: "至少需要一个批次"}
+	}
+	for i, w := range waves {
+		if w.Labels != "" && w.Percent != 0 {
+			return nil, &ServiceError{Message: fmt.Sprintf("第 %d 批只能指定百分比或标签之一", i+1)}
+		}
+		if w.Labels == "" && (w.Percent < 1 || w.Percent > 100) {
+			return nil, &ServiceError{Message: fmt.Sprintf("第 %d 批百分比需在 1-100 之间", i+1)}
+		}
+	}
+	if maxFailures < 0 {
+		maxFailures = 0
+	}
+	if minWaveMinutes < 0 {
+		minWaveMinutes = 0
+	}
+	latest := s.GetLatestVersion()
+	if latest == "" {
+		return nil, &ServiceError{Message: "未找到可发布的 Agent 版本"}
+	}
+	if activeRollout() != nil {
+		return nil, &ServiceError{Message: "已有进行中的分批发布，请先完成或取消"}
+	}
+
+	r := &models.AgentRollout{
+		ID:             utils.GenerateID(),
+		Version:        latest,
+		Waves:          waves,
+		MaxFailures:    maxFailures,
+		MinWaveMinutes: minWaveMinutes,
+		Status:         constant.RolloutStatusRunning,
+	}
+	if err := database.DB.Create(r).Error; err != nil {
+		return nil, err
+	}
+	logger.Infof("[AgentUpdate] 开始分批发布 Agent %s，共 %d 批", latest, len(waves))
+	s.startWave(r, 0)
+	return r, nil
+}
+
+// rolloutOrder Agent 在分批发布中的固定顺序，同一发布内稳定，不同发布间打散
+func rolloutOrder(rolloutID, agentID string) string {
+	sum := sha256.Sum256([]byte(rolloutID + agentID))
+	return hex.EncodeToString(sum[:])
+}
+
+// waveAgents 计算批次覆盖的 Agent（只含已启用且有对应平台更新包的）
+func (s *AgentService) waveAgents(r *models.AgentRollout, idx int) []models.Agent {
+	platforms := make(map[string]bool)
+	for _, p := range s.GetAvailablePlatforms() {
+		platforms[p["os"]+"/"+p["arch"]] = true
+	}
+	var candidates []models.Agent
+	for _, a := range s.List() {
+		if utils.DerefBool(a.Enabled, true) && platforms[a.OS+"/"+a.Arch] {
+			candidates = append(candidates, a)
+		}
+	}
+
+	wave := r.Waves[idx]
+	if wave.Labels != "" {
+		var matched []models.Agent
+		for i := range candidates {
+			if candidates[i].MatchLabels(wave.Labels) {
+				matched = append(matched, candidates[i])
+			}
+		}
+		return matched
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return rolloutOrder(r.ID, candidates[i].ID) < rolloutOrder(r.ID, candidates[j].ID)
+	})
+	n := (len(candidates)*wave.Percent + 99) / 100
+	return candidates[:n]
+}
+
+// startWave 开始指定批次：为批次内需要更新的 Agent 创建待更新记录并通知在线 Agent
+func (s *AgentService) startWave(r *models.AgentRollout, idx int) {
+	now := models.LocalTime(time.Now())
+	r.CurrentWave = idx
+	r.WaveStartedAt = &now
+	database.DB.Model(r).Updates(map[string]interface{}{"current_wave": idx, "wave_started_at": now})
+
+	var existing []string
+	database.DB.Model(&models.AgentUpdate{}).Where("rollout_id = ?", r.ID).Pluck("agent_id", &existing)
+	done := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		done[id] = true
+	}
+
+	count := 0
+	for _, a := range s.waveAgents(r, idx) {
+		if done[a.ID] || !s.CheckNeedUpdate(a.Version, a.BuildTime) {
+			continue
+		}
+		rec := &models.AgentUpdate{
+			ID:          utils.GenerateID(),
+			AgentID:     a.ID,
+			RolloutID:   r.ID,
+			Wave:        idx,
+			FromVersion: a.Version,
+			ToVersion:   r.Version,
+			Status:      constant.AgentUpdatePending,
+		}
+		if err := database.DB.Create(rec).Error; err != nil {
+			logger.Errorf("[AgentUpdate] 创建更新记录失败: %v", err)
+			continue
+		}
+		count++
+		GetAgentWSManager().SendToAgent(a.ID, WSTypeUpdate, nil)
+	}
+	logger.Infof("[AgentUpdate] 分批发布 %s 第 %d/%d 批已下发 %d 个 Agent", r.Version, idx+1, len(r.Waves), count)
+}
+
+// ListRollouts 获取分批发布列表（新的在前）
+func (s *AgentService) ListRollouts() []models.AgentRollout {
+	var rollouts []models.AgentRollout
+	database.DB.Order("created_at DESC").Limit(50).Find(&rollouts)
+	return rollouts
+}
+
+// GetRollout 获取分批发布及其更新记录
+func (s *AgentService) GetRollout(id string) (*models.AgentRollout, []models.AgentUpdate) {
+	var r models.AgentRollout
+	res := database.DB.Where("id = ?", id).Limit(1).Find(&r)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil, nil
+	}
+	var updates []models.AgentUpdate
+	database.DB.Where("rollout_id = ?", id).Order("wave ASC, created_at ASC").Find(&updates)
+	return &r, updates
+}
+
+func (s *AgentService) getRolloutForChange(id string) (*models.AgentRollout, error) {
+	r, _ := s.GetRollout(id)
+	if r == nil {
+		return nil, &ServiceError{Message: "分批发布不存在"}
+	}
+	if r.Status == constant.RolloutStatusCompleted || r.Status == constant.RolloutStatusCancelled {
+		return nil, &ServiceError{Message: "分批发布已结束"}
+	}
+	return r, nil
+}
+
+// PauseRollout 暂停分批发布，已在更新中的 Agent 不受影响
+func (s *AgentService) PauseRollout(id string) error {
+	r, err := s.getRolloutForChange(id)
+	if err != nil {
+		return err
+	}
+	return database.DB.Model(r).Updates(map[string]interface{}{
+		"status":  constant.RolloutStatusPaused,
+		"message": "管理员暂停",
+	}).Error
+}
+
+// ResumeRollout 继续分批发布；因失败数超限暂停时视为已确认本批结果，直接进入下一批
+func (s *AgentService) ResumeRollout(id string) error {
+	r, err := s.getRolloutForChange(id)
+	if err != nil {
+		return err
+	}
+	if r.Status != constant.RolloutStatusPaused {
+		return &ServiceError{Message: "分批发布未暂停"}
+	}
+	r.Status = constant.RolloutStatusRunning
+	if err := database.DB.Model(r).Updates(map[string]interface{}{"status": r.Status, "message": ""}).Error; err != nil {
+		return err
+	}
+	if waveFailures(r) > r.MaxFailures {
+		s.advanceRollout(r)
+	}
+	return nil
+}
+
+// CancelRollout 取消分批发布，尚未下发的 Agent 不再更新
+func (s *AgentService) CancelRollout(id string) error {
+	r, err := s.getRolloutForChange(id)
+	if err != nil {
+		return err
+	}
+	return s.endRollout(r, constant.RolloutStatusCancelled, "管理员取消")
+}
+
+// endRollout 结束分批发布；取消时删除尚未下发的记录，完成时保留离线 Agent 的记录待其上线后更新
+func (s *AgentService) endRollout(r *models.AgentRollout, status, message string) error {
+	if status == constant.RolloutStatusCancelled {
+		database.DB.Where("rollout_id = ? AND status = ?", r.ID, constant.AgentUpdatePending).Delete(&models.AgentUpdate{})
+	}
+	logger.Infof("[AgentUpdate] 分批发布 %s 已结束（%s）%s", r.Version, status, message)
+	return database.DB.Model(r).Updates(map[string]interface{}{"status": status, "message": message}).Error
+}
+
+// advanceRollout 进入下一批，已是最后一批时完成发布
+func (s *AgentService) advanceRollout(r *models.AgentRollout) {
+	if r.CurrentWave+1 >= len(r.Waves) {
+		s.endRollout(r, constant.RolloutStatusCompleted, "")
+		return
+	}
+	s.startWave(r, r.CurrentWave+1)
+}
+
+// waveFailures 当前批次失败（含回滚）的 Agent 数
+func waveFailures(r *models.AgentRollout) int {
+	var count int64
+	database.DB.Model(&models.AgentUpdate{}).
+		Where("rollout_id = ? AND wave = ? AND status IN ?", r.ID, r.CurrentWave,
+			[]string{constant.AgentUpdateFailed, constant.AgentUpdateRolledBack}).
+		Count(&count)
+	return int(count)
+}
+
+// waveMinDurationPassed 本批是否已达到最短持续时间
+func waveMinDurationPassed(r *models.AgentRollout, now time.Time) bool {
+	if r.MinWaveMinutes <= 0 || r.WaveStartedAt == nil {
+		return true
+	}
+	return !now.Before(time.Time(*r.WaveStartedAt).Add(time.Duration(r.MinWaveMinutes) * time.Minute))
+}
+
+// ProcessRollouts 处理更新超时并推进分批发布：本批在线 Agent 全部结束且达到最短持续时间后进入下一批，失败数超限时暂停。
+// 离线 Agent 的待更新记录保留，上线后按心跳继续更新，不阻塞后续批次
+func (s *AgentService) ProcessRollouts() {
+	var stale []models.AgentUpdate
+	database.DB.Where("status = ? AND started_at < ?", constant.AgentUpdateUpdating, time.Now().Add(-agentUpdateTimeout)).Find(&stale)
+	for i := range stale {
+		finishAgentUpdate(&stale[i], constant.AgentUpdateFailed, "更新超时：Agent 未在规定时间内以新版本上线")
+	}
+
+	r := activeRollout()
+	if r == nil || r.Status != constant.RolloutStatusRunning {
+		return
+	}
+	if latest := s.GetLatestVersion(); latest != r.Version {
+		s.endRollout(r, constant.RolloutStatusCancelled, fmt.Sprintf("最新版本已变更为 %s", latest))
+		return
+	}
+
+	if failures := waveFailures(r); failures > r.MaxFailures {
+		msg := fmt.Sprintf("第 %d 批失败 %d 个 Agent，超过上限 %d，已暂停", r.CurrentWave+1, failures, r.MaxFailures)
+		database.DB.Model(r).Updates(map[string]interface{}{"status": constant.RolloutStatusPaused, "message": msg})
+		logger.Warnf("[AgentUpdate] 分批发布 %s %s", r.Version, msg)
+		return
+	}
+	// 已更新的 Agent 可能在运行一段时间后才暴露问题，未到最短持续时间时继续观察
+	if !waveMinDurationPassed(r, time.Now()) {
+		return
+	}
+
+	var inflight []models.AgentUpdate
+	database.DB.Where("rollout_id = ? AND wave = ? AND status IN ?", r.ID, r.CurrentWave,
+		[]string{constant.AgentUpdatePending, constant.AgentUpdateUpdating}).Find(&inflight)
+	for _, u := range inflight {
+		if u.Status == constant.AgentUpdateUpdating || GetAgentWSManager().IsAgentOnline(u.AgentID) {
+			return
+		}
+	}
+	s.advanceRollout(r)
+}
+
+// StartUpdateMonitor 启动 Agent 更新巡检
+func (s *AgentService) StartUpdateMonitor() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Errorf("[AgentUpdate] 更新巡检异常: %v", r)
+				}
+			}()
+			s.ProcessRollouts()
+		}()
+	}
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// setupRolloutTest 在临时目录中准备 SQLite 数据库和 linux/amd64 的 v2 更新包
+func setupRolloutTest(t *testing.T) *AgentService {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	agentDir := filepath.Join("data", "agent")
+	if err := os.MkdirAll(agentDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(agentDir, "version.txt"), []byte("v2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(agentDir, "baihu-agent-linux-amd64.tar.gz"), []byte("pkg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Init(&database.Config{Type: "sqlite", Path: filepath.Join(dir, "test.db")}); err != nil {
+		t.Fatal(err)
+	}
+	// 测试会启动 AgentWSManager 单例，其后台协程可能在测试结束后访问 database.DB，
+	// 因此这里只关闭连接而不把 DB 还原为 nil，避免协程解引用空指针
+	db := database.DB
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.AutoMigrate(&models.Agent{}, &models.AgentRollout{}, &models.AgentUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewAgentService()
+}
+
+func createTestAgent(t *testing.T, id, osType, labels string, enabled bool) {
+	t.Helper()
+	agent := &models.Agent{
+		ID:        id,
+		Name:      id,
+		MachineID: "machine-" + id,
+		Labels:    labels,
+		Version:   "v1",
+		OS:        osType,
+		Arch:      "amd64",
+		Enabled:   utils.BoolPtr(enabled),
+	}
+	if err := database.DB.Create(agent).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func rolloutAgentIDs(agents []models.Agent) map[string]bool {
+	ids := make(map[string]bool, len(agents))
+	for _, a := range agents {
+		ids[a.ID] = true
+	}
+	return ids
+}
+
+func TestRolloutWaveAgents(t *testing.T) {
+	s := setupRolloutTest(t)
+	for i := 0; i < 10; i++ {
+		labels := ""
+		if i < 2 {
+			labels = "canary,cn"
+		}
+		createTestAgent(t, fmt.Sprintf("a%d", i), "linux", labels, true)
+	}
+	createTestAgent(t, "disabled", "linux", "canary", false)
+	createTestAgent(t, "windows", "windows", "canary", true)
+
+	r := &models.AgentRollout{ID: "r1", Waves: models.RolloutWaves{
+		{Labels: "canary"}, {Labels: "canary,us"}, {Percent: 25}, {Percent: 100},
+	}}
+
+	canary := rolloutAgentIDs(s.waveAgents(r, 0))
+	if len(canary) != 2 || !canary["a0"] || !canary["a1"] {
+		t.Fatalf("label wave should only cover enabled agents with a package, got %v", canary)
+	}
+	if got := s.waveAgents(r, 1); len(got) != 0 {
+		t.Fatalf("label wave requires all labels, got %d agents", len(got))
+	}
+
+	// 百分比向上取整，且后续批次包含之前批次的 Agent
+	first := s.waveAgents(r, 2)
+	if len(first) != 3 {
+		t.Fatalf("25%% of 10 agents should be 3, got %d", len(first))
+	}
+	all := rolloutAgentIDs(s.waveAgents(r, 3))
+	if len(all) != 10 || all["disabled"] || all["windows"] {
+		t.Fatalf("100%% wave should cover the 10 eligible agents, got %v", all)
+	}
+	for _, a := range first {
+		if !all[a.ID] {
+			t.Fatalf("agent %s of an earlier wave missing from a later wave", a.ID)
+		}
+	}
+	again := s.waveAgents(r, 2)
+	for i := range first {
+		if first[i].ID != again[i].ID {
+			t.Fatal("wave order should be stable within a rollout")
+		}
+	}
+}
+
+func rolloutStatus(t *testing.T, id string) *models.AgentRollout {
+	t.Helper()
+	var r models.AgentRollout
+	if err := database.DB.Where("id = ?", id).First(&r).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &r
+}
+
+func TestRolloutPausesOnFailuresAndResumeAdvances(t *testing.T) {
+	s := setupRolloutTest(t)
+	createTestAgent(t, "a1", "linux", "canary", true)
+	createTestAgent(t, "a2", "linux", "", true)
+
+	r, err := s.CreateRollout(models.RolloutWaves{{Labels: "canary"}, {Percent: 100}}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var updates []models.AgentUpdate
+	database.DB.Where("rollout_id = ?", r.ID).Find(&updates)
+	if len(updates) != 1 || updates[0].AgentID != "a1" || updates[0].Wave != 0 {
+		t.Fatalf("first wave should only schedule a1, got %+v", updates)
+	}
+
+	finishAgentUpdate(&updates[0], constant.AgentUpdateRolledBack, "boom")
+	s.ProcessRollouts()
+	got := rolloutStatus(t, r.ID)
+	if got.Status != constant.RolloutStatusPaused || got.CurrentWave != 0 {
+		t.Fatalf("rollout should pause on wave 0, got %s wave %d", got.Status, got.CurrentWave)
+	}
+
+	// 暂停期间不推进
+	s.ProcessRollouts()
+	if got := rolloutStatus(t, r.ID); got.CurrentWave != 0 {
+		t.Fatalf("paused rollout advanced to wave %d", got.CurrentWave)
+	}
+
+	// 继续视为已确认失败，直接进入下一批
+	if err := s.ResumeRollout(r.ID); err != nil {
+		t.Fatal(err)
+	}
+	got = rolloutStatus(t, r.ID)
+	if got.Status != constant.RolloutStatusRunning || got.CurrentWave != 1 {
+		t.Fatalf("resume should advance to wave 1, got %s wave %d", got.Status, got.CurrentWave)
+	}
+	var next models.AgentUpdate
+	if err := database.DB.Where("rollout_id = ? AND wave = 1", r.ID).First(&next).Error; err != nil || next.AgentID != "a2" {
+		t.Fatalf("second wave should schedule a2 only, got %+v, %v", next, err)
+	}
+
+	// 离线 Agent 不阻塞，最后一批结束后发布完成
+	s.ProcessRollouts()
+	if got := rolloutStatus(t, r.ID); got.Status != constant.RolloutStatusCompleted {
+		t.Fatalf("rollout should complete, got %s", got.Status)
+	}
+}
+
+func TestRolloutMinWaveDuration(t *testing.T) {
+	s := setupRolloutTest(t)
+	createTestAgent(t, "a1", "linux", "canary", true)
+	createTestAgent(t, "a2", "linux", "", true)
+
+	r, err := s.CreateRollout(models.RolloutWaves{{Labels: "canary"}, {Percent: 100}}, 0, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var u models.AgentUpdate
+	database.DB.Where("rollout_id = ? AND agent_id = ?", r.ID, "a1").First(&u)
+	finishAgentUpdate(&u, constant.AgentUpdateSucceeded, "")
+
+	s.ProcessRollouts()
+	if got := rolloutStatus(t, r.ID); got.CurrentWave != 0 {
+		t.Fatalf("wave should not advance before the minimum duration, got wave %d", got.CurrentWave)
+	}
+
+	started := models.LocalTime(time.Now().Add(-31 * time.Minute))
+	database.DB.Model(&models.AgentRollout{}).Where("id = ?", r.ID).Update("wave_started_at", started)
+	s.ProcessRollouts()
+	if got := rolloutStatus(t, r.ID); got.CurrentWave != 1 {
+		t.Fatalf("wave should advance after the minimum duration, got wave %d", got.CurrentWave)
+	}
+}
+
+func TestUpdateManifestRequiresReleaseSignature(t *testing.T) {
+	s := setupRolloutTest(t)
+	bundle := filepath.Join("data", "agent", "baihu-agent-linux-amd64.tar.gz")
+
+	// 没有发布签名的更新包不下发
+	if _, err := s.GetUpdateManifest("linux", "amd64"); err == nil {
+		t.Fatal("manifest served for unsigned bundle")
+	}
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := ed25519.Sign(key, []byte("pkg"))
+	for name, content := range map[string][]byte{
+		"raw":    sig,
+		"base64": []byte(base64.StdEncoding.EncodeToString(sig) + "\n"),
+	} {
+		if err := os.WriteFile(bundle+".sig", content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		m, err := s.GetUpdateManifest("linux", "amd64")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, _ := base64.StdEncoding.DecodeString(m.Signature)
+		if m.Version != "v2" || m.Size != 3 || !ed25519.Verify(pub, []byte("pkg"), got) {
+			t.Fatalf("%s: unexpected manifest %+v", name, m)
+		}
+	}
+
+	if err := os.WriteFile(bundle+".sig", []byte("not a signature"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.GetSignedAgentBinary("linux", "amd64"); err == nil {
+		t.Fatal("invalid signature file accepted")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/engigu/baihu-panel/internal/constant"
 	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/eventbus"
 	"github.com/engigu/baihu-panel/internal/logger"
 	"github.com/engigu/baihu-panel/internal/models"
 
@@ -20,6 +21,10 @@ type AgentWSManager struct {
 	ipLastAttempt map[string]time.Time                  // IP -> 最后连接尝试时间
 	ipFailCount   map[string]int                        // IP -> 连续失败次数
 	remoteWaiters map[string]chan *models.AgentTaskResult // 日志 ID -> 结果通道
+	metrics       agentMetricsStore                       // Agent ID -> 最近的主机指标
+	terminals     agentTerminalStore                      // 会话 ID -> 远程终端
+	scriptSyncs   scriptSyncStore                         // Agent 脚本目录同步状态
+	commands      agentCommandStore                       // 请求 ID -> 运行环境管理命令
 	mu            sync.RWMutex
 }
 
@@ -35,6 +40,7 @@ const (
 type AgentConnection struct {
 	AgentID  string
 	IP       string
+	CertFP   string // mTLS 客户端证书指纹，仅凭 Token 连接时为空
 	Conn     *websocket.Conn
 	Send     chan []byte
 	LastPing time.Time
@@ -63,6 +69,25 @@ const (
 	WSTypeTaskLog       = constant.WSTypeTaskLog
 	WSTypeExecute       = constant.WSTypeExecute
 	WSTypeTaskHeartbeat = constant.WSTypeTaskHeartbeat
+
+	WSTypeTerminalOpen   = constant.WSTypeTerminalOpen
+	WSTypeTerminalInput  = constant.WSTypeTerminalInput
+	WSTypeTerminalResize = constant.WSTypeTerminalResize
+	WSTypeTerminalClose  = constant.WSTypeTerminalClose
+	WSTypeTerminalOpened = constant.WSTypeTerminalOpened
+	WSTypeTerminalOutput = constant.WSTypeTerminalOutput
+	WSTypeTerminalClosed = constant.WSTypeTerminalClosed
+
+	WSTypeScriptSync       = constant.WSTypeScriptSync
+	WSTypeScriptSyncResult = constant.WSTypeScriptSyncResult
+
+	WSTypeCommand       = constant.WSTypeCommand
+	WSTypeCommandCancel = constant.WSTypeCommandCancel
+	WSTypeCommandOutput = constant.WSTypeCommandOutput
+	WSTypeCommandResult = constant.WSTypeCommandResult
+
+	WSTypeCertRotate   = constant.WSTypeCertRotate
+	WSTypeUpdateStatus = constant.WSTypeUpdateStatus
 )
 
 var agentWSManager *AgentWSManager
@@ -137,7 +162,7 @@ func (m *AgentWSManager) RecordConnectSuccess(ip string) {
 }
 
 // Register 注册连接
-func (m *AgentWSManager) Register(agentID string, conn *websocket.Conn, ip string) *AgentConnection {
+func (m *AgentWSManager) Register(agentID string, conn *websocket.Conn, ip, certFP string) *AgentConnection {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -155,6 +180,7 @@ func (m *AgentWSManager) Register(agentID string, conn *websocket.Conn, ip strin
 	ac := &AgentConnection{
 		AgentID:  agentID,
 		IP:       ip,
+		CertFP:   certFP,
 		Conn:     conn,
 		Send:     make(chan []byte, 256),
 		LastPing: time.Now(),
@@ -165,6 +191,7 @@ func (m *AgentWSManager) Register(agentID string, conn *websocket.Conn, ip strin
 	m.ipConnections[ip]++
 
 	logger.Infof("[AgentWS] Agent #%s 已连接 (%s)", agentID, ip)
+	publishAgentStatusEvent(constant.EventAgentOnline, agentID, ip, "")
 	return ac
 }
 
@@ -184,6 +211,17 @@ func (m *AgentWSManager) Unregister(agentID string, ac *AgentConnection) {
 		conn.Close()
 		delete(m.connections, agentID)
 		logger.Infof("[AgentWS] Agent #%s 已断开", agentID)
+		publishAgentStatusEvent(constant.EventAgentOffline, agentID, conn.IP, "连接断开")
+		m.terminals.terminateAgent(agentID, "Agent 连接断开")
+		m.commands.failAgent(agentID, "Agent 连接断开")
+	}
+}
+
+// DisconnectCert 断开使用指定客户端证书的连接（证书吊销时调用）
+func (m *AgentWSManager) DisconnectCert(agentID, fingerprint string) {
+	if conn := m.GetConnection(agentID); conn != nil && conn.CertFP == fingerprint {
+		logger.Infof("[AgentWS] Agent #%s 的客户端证书已吊销，断开连接", agentID)
+		m.Unregister(agentID, conn)
 	}
 }
 
@@ -277,6 +315,8 @@ func (m *AgentWSManager) cleanupLoop() {
 	// 因为 WebSocket 连接在应用启动时是空的，所有 Agent 客观上都是离线状态
 	// 等它们重新连接上来后，会变为 "online"
 	NewAgentService().ResetAllAgentsToOffline()
+	// 同理，面板重启前未正常结束的远程终端会话也已失效
+	CloseStaleTerminalSessions()
 
 	for range ticker.C {
 		func() {
@@ -302,6 +342,9 @@ func (m *AgentWSManager) cleanupLoop() {
 					// 更新数据库状态
 					database.DB.Model(&models.Agent{}).Where("id = ?", agentID).Update("status", constant.AgentStatusOffline)
 					logger.Infof("[AgentWS] Agent #%s 心跳超时，已断开", agentID)
+					publishAgentStatusEvent(constant.EventAgentOffline, agentID, conn.IP, "心跳超时")
+					m.terminals.terminateAgent(agentID, "Agent 心跳超时")
+					m.commands.failAgent(agentID, "Agent 心跳超时")
 				}
 			}
 
@@ -404,3 +447,22 @@ func (c *AgentConnection) WritePing() error {
 func (c *AgentConnection) UpdatePing() {
 	c.LastPing = time.Now()
 }
+
+// publishAgentStatusEvent 发布 Agent 上下线事件
+func publishAgentStatusEvent(eventType, agentID, ip, reason string) {
+	agentName := agentID
+	var agent models.Agent
+	if res := database.DB.Select("name").Where("id = ?", agentID).Limit(1).Find(&agent); res.RowsAffected > 0 && agent.Name != "" {
+		agentName = agent.Name
+	}
+
+	eventbus.DefaultBus.Publish(eventbus.Event{
+		Type: eventType,
+		Payload: map[string]interface{}{
+			"agent_id":   agentID,
+			"agent_name": agentName,
+			"ip":         ip,
+			"reason":     reason,
+		},
+	})
+}
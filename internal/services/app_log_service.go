@@ -136,7 +136,7 @@ func (s *AppLogService) GetRetentionConfigs() map[string]LogRetentionConfig {
 
 func (s *AppLogService) CleanUp() {
 	configs := s.GetRetentionConfigs()
-	categories := []string{constant.LogCategorySystemNotice, constant.LogCategoryPushLog, constant.LogCategoryLoginLog}
+	categories := []string{constant.LogCategorySystemNotice, constant.LogCategoryPushLog, constant.LogCategoryLoginLog, constant.LogCategoryBotLog, constant.LogCategoryDeadLetter}
 
 	for _, cat := range categories {
 		cfg, ok := configs[cat]
@@ -166,11 +166,14 @@ func (s *AppLogService) CleanUp() {
 }
 
 func (s *AppLogService) SubscribeEvents(bus *eventbus.EventBus) {
+	// 0. 事件处理句柄出错（返回错误或 panic）时写入系统通知，不再经过总线避免循环
+	bus.SetErrorReporter(s.recordHandlerError)
+
 	// 1. [订阅] 系统通知 -> 存储到数据库表现为红点消息
-	bus.Subscribe(constant.EventSystemNotice, func(e eventbus.Event) {
+	bus.SubscribeWithError(constant.EventSystemNotice, "app_log", func(e eventbus.Event) error {
 		payload, ok := e.Payload.(map[string]interface{})
 		if !ok {
-			return
+			return nil
 		}
 
 		title, _ := payload["title"].(string)
@@ -180,7 +183,7 @@ func (s *AppLogService) SubscribeEvents(bus *eventbus.EventBus) {
 			level = constant.LogLevelInfo
 		}
 
-		s.Add(&models.AppLog{
+		return s.Add(&models.AppLog{
 			Category: constant.LogCategorySystemNotice,
 			Title:    title,
 			Content:  models.BigText(content),
@@ -190,10 +193,10 @@ func (s *AppLogService) SubscribeEvents(bus *eventbus.EventBus) {
 	})
 
 	// 2. [订阅] 推送结果 -> 存储到数据库供推送日志查看
-	bus.Subscribe(constant.EventNotifySent, func(e eventbus.Event) {
+	bus.SubscribeWithError(constant.EventNotifySent, "app_log", func(e eventbus.Event) error {
 		payload, ok := e.Payload.(map[string]interface{})
 		if !ok {
-			return
+			return nil
 		}
 
 		title, _ := payload["title"].(string)
@@ -201,6 +204,7 @@ func (s *AppLogService) SubscribeEvents(bus *eventbus.EventBus) {
 		success, _ := payload["success"].(bool)
 		errorMsg, _ := payload["error_msg"].(string)
 		channelID, _ := payload["channel_id"].(string)
+		suppressed := utils.ToIntValue(payload["suppressed"])
 
 		status := constant.LogStatusSuccess
 		level := constant.LogLevelInfo
@@ -209,20 +213,37 @@ func (s *AppLogService) SubscribeEvents(bus *eventbus.EventBus) {
 			level = constant.LogLevelError
 		}
 
-		s.Add(&models.AppLog{
-			Category: constant.LogCategoryPushLog,
-			Title:    title,
-			Content:  models.BigText(content),
-			Level:    level,
-			Status:   status,
-			RefID:    channelID,
-			ErrorMsg: models.BigText(errorMsg),
-		})
+		if err := s.Add(&models.AppLog{
+			Category:   constant.LogCategoryPushLog,
+			Title:      title,
+			Content:    models.BigText(content),
+			Level:      level,
+			Status:     status,
+			RefID:      channelID,
+			ErrorMsg:   models.BigText(errorMsg),
+			Suppressed: suppressed,
+		}); err != nil {
+			return err
+		}
+
+		// 重试后仍失败的通知额外记录为死信，便于排查与人工补发
+		if deadLetter, _ := payload["dead_letter"].(bool); deadLetter {
+			return s.Add(&models.AppLog{
+				Category: constant.LogCategoryDeadLetter,
+				Title:    title,
+				Content:  models.BigText(content),
+				Level:    constant.LogLevelError,
+				Status:   constant.LogStatusUnread,
+				RefID:    channelID,
+				ErrorMsg: models.BigText(fmt.Sprintf("共尝试 %d 次，最终错误: %s", utils.ToIntValue(payload["attempts"]), errorMsg)),
+			})
+		}
+		return nil
 	})
 
 	// 3. 将某些业务事件转化为系统内部通知 (自动出现在小铃铛)
 	/*
-		bus.Subscribe(constant.EventTaskFailed, func(e eventbus.Event) {
+		bus.Subscribe(constant.EventTaskFailed, "app_log", func(e eventbus.Event) {
 			payload, ok := e.Payload.(map[string]interface{})
 			if !ok {
 				return
@@ -240,7 +261,7 @@ func (s *AppLogService) SubscribeEvents(bus *eventbus.EventBus) {
 			})
 		})
 
-		bus.Subscribe(constant.EventTaskTimeout, func(e eventbus.Event) {
+		bus.Subscribe(constant.EventTaskTimeout, "app_log", func(e eventbus.Event) {
 			payload, ok := e.Payload.(map[string]interface{})
 			if !ok {
 				return
@@ -258,7 +279,7 @@ func (s *AppLogService) SubscribeEvents(bus *eventbus.EventBus) {
 		})
 	*/
 
-	bus.Subscribe(constant.EventPasswordChanged, func(e eventbus.Event) {
+	bus.Subscribe(constant.EventPasswordChanged, "app_log", func(e eventbus.Event) {
 		payload, ok := e.Payload.(map[string]interface{})
 		if !ok {
 			return
@@ -275,3 +296,22 @@ func (s *AppLogService) SubscribeEvents(bus *eventbus.EventBus) {
 		})
 	})
 }
+
+// recordHandlerError 记录事件总线句柄的执行错误
+func (s *AppLogService) recordHandlerError(event eventbus.Event, handler string, err error) {
+	logger.Errorf("[EventBus] 事件 %s 处理失败 (%s): %v", event.Type, handler, err)
+
+	content := fmt.Sprintf("事件类型: %s\n处理句柄: %s\n", event.Type, handler)
+	if event.ID != "" {
+		content += fmt.Sprintf("事件ID: %s\n", event.ID)
+	}
+	s.Add(&models.AppLog{
+		Category: constant.LogCategorySystemNotice,
+		Title:    fmt.Sprintf("事件 [%s] 处理异常", event.Type),
+		Content:  models.BigText(content),
+		Level:    constant.LogLevelError,
+		Status:   constant.LogStatusUnread,
+		RefID:    event.ID,
+		ErrorMsg: models.BigText(err.Error()),
+	})
+}
@@ -13,8 +13,11 @@ import (
 	"github.com/engigu/baihu-panel/internal/cache"
 	"github.com/engigu/baihu-panel/internal/constant"
 	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/logger"
 	"github.com/engigu/baihu-panel/internal/models"
 	"github.com/engigu/baihu-panel/internal/systime"
+	"github.com/engigu/baihu-panel/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -128,8 +131,31 @@ func (s *BackupService) restoreSettings(data []byte) error {
 	return json.Unmarshal(data, &settings)
 }
 
-// CreateBackup 创建备份
+// CreateBackup 创建备份，完成或失败时发布对应事件
 func (s *BackupService) CreateBackup() (string, error) {
+	start := time.Now()
+	zipPath, err := s.createBackup()
+
+	payload := map[string]interface{}{
+		"file":     zipPath,
+		"size":     "",
+		"duration": time.Since(start).Milliseconds(),
+		"error":    "",
+	}
+	eventType := constant.EventBackupSuccess
+	if err != nil {
+		eventType = constant.EventBackupFailed
+		payload["error"] = err.Error()
+		logger.Errorf("[Backup] 创建备份失败: %v", err)
+	} else if info, statErr := os.Stat(zipPath); statErr == nil {
+		payload["size"] = utils.FormatBytes(uint64(info.Size()))
+	}
+	eventbus.DefaultBus.Publish(eventbus.Event{Type: eventType, Payload: payload})
+
+	return zipPath, err
+}
+
+func (s *BackupService) createBackup() (string, error) {
 	if err := os.MkdirAll(BackupDir, 0755); err != nil {
 		return "", err
 	}
@@ -254,6 +280,7 @@ func (s *BackupService) Restore(zipPath string) error {
 		// 备份恢复成功后，需要同时刷新内存中的配置缓存以免数据不一致导致异常
 		constant.Secret = s.settingsService.Get(constant.SectionSecurity, constant.KeySecret)
 		cache.LoadSiteCache()
+		eventPersistEnabled.Store(s.settingsService.Get(constant.SectionEventBus, constant.KeyEventPersist) == "true")
 	}
 
 	return err
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+const (
+	// checkPingKeepCount 每个心跳检测保留的 ping 记录条数
+	checkPingKeepCount = 100
+	// checkPingBodyLimit ping 请求体最大保存长度
+	checkPingBodyLimit = 10 * 1024
+	// checkMonitorInterval 超时巡检周期
+	checkMonitorInterval = 30 * time.Second
+)
+
+// CheckService 外部心跳检测服务
+type CheckService struct {
+	sendStatsService *SendStatsService
+	mu               sync.Mutex
+}
+
+var checkService *CheckService
+var checkServiceOnce sync.Once
+
+// GetCheckService 获取单例（ping 上报与超时巡检共用同一把锁）
+func GetCheckService() *CheckService {
+	checkServiceOnce.Do(func() {
+		checkService = &CheckService{
+			sendStatsService: NewSendStatsService(),
+		}
+	})
+	return checkService
+}
+
+// List 分页获取心跳检测列表
+func (s *CheckService) List(name, status string, page, pageSize int) ([]models.Check, int64) {
+	var checks []models.Check
+	var total int64
+
+	query := database.DB.Model(&models.Check{})
+	if name != "" {
+		query = query.Where("name LIKE ?", "%"+name+"%")
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&checks)
+	return checks, total
+}
+
+// GetByID 根据 ID 获取心跳检测
+func (s *CheckService) GetByID(id string) *models.Check {
+	var check models.Check
+	res := database.DB.Where("id = ?", id).Limit(1).Find(&check)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil
+	}
+	return &check
+}
+
+// GetByPingKey 根据 ping 标识获取心跳检测
+func (s *CheckService) GetByPingKey(key string) *models.Check {
+	var check models.Check
+	res := database.DB.Where("ping_key = ?", key).Limit(1).Find(&check)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil
+	}
+	return &check
+}
+
+// Create 创建心跳检测
+func (s *CheckService) Create(check *models.Check) error {
+	if err := validateCheckTiming(check); err != nil {
+		return err
+	}
+	check.ID = utils.GenerateID()
+	check.PingKey = utils.GenerateUUID()
+	check.Status = constant.CheckStatusNew
+	if check.Enabled == nil {
+		check.Enabled = utils.BoolPtr(true)
+	}
+	if !*check.Enabled {
+		check.Status = constant.CheckStatusPaused
+	}
+	return database.DB.Create(check).Error
+}
+
+// Update 更新心跳检测配置
+func (s *CheckService) Update(id string, name, remark, tags string, period, grace int, enabled *bool) (*models.Check, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	check := s.GetByID(id)
+	if check == nil {
+		return nil, fmt.Errorf("心跳检测不存在")
+	}
+	check.Name = name
+	check.Remark = remark
+	check.Tags = tags
+	check.Period = period
+	check.Grace = grace
+	if err := validateCheckTiming(check); err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"name":   check.Name,
+		"remark": check.Remark,
+		"tags":   check.Tags,
+		"period": check.Period,
+		"grace":  check.Grace,
+	}
+	if enabled != nil {
+		check.Enabled = enabled
+		updates["enabled"] = enabled
+		if !*enabled {
+			check.Status = constant.CheckStatusPaused
+		} else if check.Status == constant.CheckStatusPaused {
+			// 恢复后重新等待第一次上报
+			check.Status = constant.CheckStatusNew
+		}
+		updates["status"] = check.Status
+	}
+
+	if err := database.DB.Model(&models.Check{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return check, nil
+}
+
+// RegeneratePingKey 重新生成 ping 地址，旧地址立即失效
+func (s *CheckService) RegeneratePingKey(id string) (*models.Check, error) {
+	check := s.GetByID(id)
+	if check == nil {
+		return nil, fmt.Errorf("心跳检测不存在")
+	}
+	check.PingKey = utils.GenerateUUID()
+	if err := database.DB.Model(&models.Check{}).Where("id = ?", id).Update("ping_key", check.PingKey).Error; err != nil {
+		return nil, err
+	}
+	return check, nil
+}
+
+// Delete 删除心跳检测及其 ping 记录、事件绑定
+func (s *CheckService) Delete(id string) error {
+	if err := database.DB.Where("id = ?", id).Delete(&models.Check{}).Error; err != nil {
+		return err
+	}
+	database.DB.Where("check_id = ?", id).Delete(&models.CheckPing{})
+	database.DB.Where("type = ? AND data_id = ?", constant.BindingTypeCheck, id).Delete(&models.NotifyBinding{})
+	return nil
+}
+
+// GetPings 获取心跳检测最近的 ping 记录
+func (s *CheckService) GetPings(checkID string, page, pageSize int) ([]models.CheckPing, int64) {
+	var pings []models.CheckPing
+	var total int64
+	query := database.DB.Model(&models.CheckPing{}).Where("check_id = ?", checkID)
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&pings)
+	return pings, total
+}
+
+// Ping 处理外部上报的 ping 请求
+func (s *CheckService) Ping(key, kind, ip, userAgent, body string) error {
+	if kind != constant.CheckPingSuccess && kind != constant.CheckPingStart && kind != constant.CheckPingFail {
+		return fmt.Errorf("不支持的 ping 类型: %s", kind)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	check := s.GetByPingKey(key)
+	if check == nil {
+		return fmt.Errorf("心跳检测不存在")
+	}
+
+	if len(body) > checkPingBodyLimit {
+		body = body[:checkPingBodyLimit]
+	}
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	now := models.Now()
+	database.DB.Create(&models.CheckPing{
+		ID:        utils.GenerateID(),
+		CheckID:   check.ID,
+		Kind:      kind,
+		IP:        ip,
+		UserAgent: userAgent,
+		Body:      models.BigText(body),
+		CreatedAt: now,
+	})
+	s.trimPings(check.ID)
+
+	// 暂停中的检测仅记录 ping，不参与状态流转
+	if !utils.DerefBool(check.Enabled, true) {
+		return nil
+	}
+
+	prevStatus := check.Status
+	// start 会把异常状态改为执行中，判断恢复需看 start 之前的状态
+	wasDown := prevStatus == constant.CheckStatusDown || (prevStatus == constant.CheckStatusStarted && check.WasDown)
+	updates := map[string]interface{}{}
+
+	switch kind {
+	case constant.CheckPingStart:
+		check.LastStart = &now
+		check.Status = constant.CheckStatusStarted
+		check.WasDown = wasDown
+		updates["last_start"] = &now
+		updates["was_down"] = wasDown
+	case constant.CheckPingSuccess:
+		if check.LastStart != nil && prevStatus == constant.CheckStatusStarted {
+			check.LastDuration = now.Time().Sub(check.LastStart.Time()).Milliseconds()
+			updates["last_duration"] = check.LastDuration
+		}
+		check.LastPing = &now
+		check.Status = constant.CheckStatusUp
+		updates["last_ping"] = &now
+		s.incrementStats(check.ID, constant.TaskStatusSuccess)
+	case constant.CheckPingFail:
+		check.LastPing = &now
+		check.Status = constant.CheckStatusDown
+		updates["last_ping"] = &now
+		s.incrementStats(check.ID, constant.TaskStatusFailed)
+	}
+	updates["status"] = check.Status
+	if kind != constant.CheckPingStart {
+		updates["was_down"] = false
+	}
+
+	if err := database.DB.Model(&models.Check{}).Where("id = ?", check.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	// 状态流转事件
+	if check.Status == constant.CheckStatusDown && !wasDown {
+		s.publishStatusEvent(constant.EventCheckDown, check, "脚本上报执行失败", body)
+	} else if check.Status == constant.CheckStatusUp && wasDown {
+		s.publishStatusEvent(constant.EventCheckUp, check, "", body)
+	}
+	return nil
+}
+
+// StartMonitor 启动超时巡检，将超过 period + grace 未上报的检测标记为异常
+func (s *CheckService) StartMonitor() {
+	ticker := time.NewTicker(checkMonitorInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Errorf("[Check] 超时巡检 panic: %v", r)
+				}
+			}()
+			s.checkOverdue(time.Now())
+		}()
+	}
+}
+
+// checkOverdue 检查所有处于 up/started 状态的检测是否超时
+func (s *CheckService) checkOverdue(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var checks []models.Check
+	database.DB.Where("status IN ? AND enabled = ?", []string{constant.CheckStatusUp, constant.CheckStatusStarted}, true).Find(&checks)
+
+	for i := range checks {
+		check := &checks[i]
+		var reason string
+
+		switch check.Status {
+		case constant.CheckStatusStarted:
+			// 已发送 start 但在宽限时间内没有收到结束 ping
+			if check.LastStart != nil && now.After(check.LastStart.Time().Add(time.Duration(check.Grace)*time.Second)) {
+				reason = fmt.Sprintf("已开始执行，但超过宽限时间 %s 仍未收到完成上报", formatCheckSeconds(check.Grace))
+			}
+		case constant.CheckStatusUp:
+			if check.LastPing != nil && now.After(check.LastPing.Time().Add(time.Duration(check.Period+check.Grace)*time.Second)) {
+				reason = fmt.Sprintf("超过 %s (周期 + 宽限) 未收到上报", formatCheckSeconds(check.Period+check.Grace))
+			}
+		}
+
+		if reason == "" {
+			continue
+		}
+
+		res := database.DB.Model(&models.Check{}).Where("id = ? AND status = ?", check.ID, check.Status).
+			Updates(map[string]interface{}{"status": constant.CheckStatusDown, "was_down": false})
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		check.Status = constant.CheckStatusDown
+		s.incrementStats(check.ID, constant.TaskStatusTimeout)
+		logger.Warnf("[Check] 心跳检测 #%s %s 已标记为异常: %s", check.ID, check.Name, reason)
+		// start 前已异常的不重复通知
+		if !check.WasDown {
+			s.publishStatusEvent(constant.EventCheckDown, check, reason, "")
+		}
+	}
+}
+
+// CountByStatus 按状态统计心跳检测数量
+func (s *CheckService) CountByStatus() map[string]int64 {
+	var rows []struct {
+		Status string
+		Total  int64
+	}
+	database.DB.Model(&models.Check{}).Select("status, COUNT(*) as total").Group("status").Scan(&rows)
+
+	result := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		result[r.Status] = r.Total
+	}
+	return result
+}
+
+func (s *CheckService) publishStatusEvent(eventType string, check *models.Check, reason, body string) {
+	lastPing := ""
+	if check.LastPing != nil {
+		lastPing = check.LastPing.Time().Format(models.TimeFormat)
+	}
+	eventbus.DefaultBus.Publish(eventbus.Event{
+		Type: eventType,
+		Payload: map[string]interface{}{
+			"check_id":   check.ID,
+			"check_name": check.Name,
+			"status":     check.Status,
+			"reason":     reason,
+			"last_ping":  lastPing,
+			"output":     body,
+		},
+	})
+}
+
+func (s *CheckService) incrementStats(checkID, status string) {
+	if err := s.sendStatsService.IncrementStats(checkID, status); err != nil {
+		logger.Errorf("[Check] 更新心跳检测统计失败: %v", err)
+	}
+}
+
+// trimPings 仅保留最近 checkPingKeepCount 条 ping 记录
+func (s *CheckService) trimPings(checkID string) {
+	var ids []string
+	database.DB.Model(&models.CheckPing{}).Where("check_id = ?", checkID).
+		Order("id DESC").Offset(checkPingKeepCount).Limit(1000).Pluck("id", &ids)
+	if len(ids) > 0 {
+		database.DB.Where("id IN ?", ids).Delete(&models.CheckPing{})
+	}
+}
+
+func validateCheckTiming(check *models.Check) error {
+	if check.Period < 60 {
+		return fmt.Errorf("上报周期不能小于 60 秒")
+	}
+	if check.Grace < 60 {
+		return fmt.Errorf("宽限时间不能小于 60 秒")
+	}
+	return nil
+}
+
+func formatCheckSeconds(seconds int) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+// checkStep 一次 ping 上报或超时巡检；ping 为空时执行巡检，
+// 巡检时间为最近一次上报（up 取 last_ping，started 取 last_start）加 after
+type checkStep struct {
+	ping   string
+	after  time.Duration
+	status string
+	event  string
+}
+
+func TestCheckStatusTransitions(t *testing.T) {
+	// period 60s、grace 60s：up 超过 120s 才判定超时，started 超过 60s 判定超时
+	cases := []struct {
+		name  string
+		steps []checkStep
+	}{
+		{
+			name: "new 状态不参与巡检",
+			steps: []checkStep{
+				{after: time.Hour, status: constant.CheckStatusNew},
+				{ping: constant.CheckPingSuccess, status: constant.CheckStatusUp},
+			},
+		},
+		{
+			name: "new→up→late→down→up",
+			steps: []checkStep{
+				{ping: constant.CheckPingSuccess, status: constant.CheckStatusUp},
+				{after: 90 * time.Second, status: constant.CheckStatusUp},
+				{after: 121 * time.Second, status: constant.CheckStatusDown, event: constant.EventCheckDown},
+				{after: time.Hour, status: constant.CheckStatusDown},
+				{ping: constant.CheckPingSuccess, status: constant.CheckStatusUp, event: constant.EventCheckUp},
+			},
+		},
+		{
+			name: "宽限时间边界",
+			steps: []checkStep{
+				{ping: constant.CheckPingSuccess, status: constant.CheckStatusUp},
+				{after: 120 * time.Second, status: constant.CheckStatusUp},
+				{after: 120*time.Second + time.Millisecond, status: constant.CheckStatusDown, event: constant.EventCheckDown},
+			},
+		},
+		{
+			name: "start 超过宽限时间",
+			steps: []checkStep{
+				{ping: constant.CheckPingSuccess, status: constant.CheckStatusUp},
+				{ping: constant.CheckPingStart, status: constant.CheckStatusStarted},
+				{after: 60 * time.Second, status: constant.CheckStatusStarted},
+				{after: 61 * time.Second, status: constant.CheckStatusDown, event: constant.EventCheckDown},
+			},
+		},
+		{
+			name: "down 后 start 再 success 恢复",
+			steps: []checkStep{
+				{ping: constant.CheckPingSuccess, status: constant.CheckStatusUp},
+				{ping: constant.CheckPingFail, status: constant.CheckStatusDown, event: constant.EventCheckDown},
+				{ping: constant.CheckPingFail, status: constant.CheckStatusDown},
+				{ping: constant.CheckPingStart, status: constant.CheckStatusStarted},
+				{ping: constant.CheckPingSuccess, status: constant.CheckStatusUp, event: constant.EventCheckUp},
+			},
+		},
+		{
+			name: "down 后 start 超时不重复通知",
+			steps: []checkStep{
+				{ping: constant.CheckPingFail, status: constant.CheckStatusDown, event: constant.EventCheckDown},
+				{ping: constant.CheckPingStart, status: constant.CheckStatusStarted},
+				{after: 61 * time.Second, status: constant.CheckStatusDown},
+				{ping: constant.CheckPingStart, status: constant.CheckStatusStarted},
+				{ping: constant.CheckPingFail, status: constant.CheckStatusDown},
+				{ping: constant.CheckPingSuccess, status: constant.CheckStatusUp, event: constant.EventCheckUp},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupNotifyTestDB(t, &models.Check{}, &models.CheckPing{}, &models.SendStats{})
+
+			events := make(chan string, 16)
+			bus := eventbus.New()
+			for _, typ := range []string{constant.EventCheckDown, constant.EventCheckUp} {
+				bus.Subscribe(typ, "test", func(e eventbus.Event) { events <- e.Type })
+			}
+			saved := eventbus.DefaultBus
+			eventbus.DefaultBus = bus
+			t.Cleanup(func() { eventbus.DefaultBus = saved })
+
+			s := &CheckService{sendStatsService: NewSendStatsService()}
+			check := &models.Check{Name: "backup", Period: 60, Grace: 60}
+			if err := s.Create(check); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, step := range tc.steps {
+				if step.ping != "" {
+					if err := s.Ping(check.PingKey, step.ping, "127.0.0.1", "test", ""); err != nil {
+						t.Fatalf("step %d: ping %s: %v", i, step.ping, err)
+					}
+				} else {
+					cur := s.GetByID(check.ID)
+					ref := time.Now()
+					if cur.Status == constant.CheckStatusUp && cur.LastPing != nil {
+						ref = cur.LastPing.Time()
+					} else if cur.Status == constant.CheckStatusStarted && cur.LastStart != nil {
+						ref = cur.LastStart.Time()
+					}
+					s.checkOverdue(ref.Add(step.after))
+				}
+
+				if got := s.GetByID(check.ID).Status; got != step.status {
+					t.Fatalf("step %d: status = %s, want %s", i, got, step.status)
+				}
+				if step.event == "" {
+					continue
+				}
+				select {
+				case got := <-events:
+					if got != step.event {
+						t.Fatalf("step %d: event = %s, want %s", i, got, step.event)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("step %d: event %s not published", i, step.event)
+				}
+			}
+
+			if !bus.Close(2 * time.Second) {
+				t.Fatal("event bus close timeout")
+			}
+			if len(events) > 0 {
+				t.Fatalf("unexpected event %s", <-events)
+			}
+		})
+	}
+}
@@ -16,6 +16,10 @@ type ServerConfig struct {
 	Host         string `ini:"host"`
 	URLPrefix    string `ini:"url_prefix"`
 	PprofEnabled bool   `ini:"pprof_enabled"`
+	// AgentTLSPort Agent 专用 HTTPS 端口（校验 mTLS 客户端证书），0 表示不开启
+	AgentTLSPort int `ini:"agent_tls_port"`
+	// AgentTLSHosts 写入该端口服务器证书的域名或 IP，逗号分隔，需包含 Agent 访问面板使用的地址
+	AgentTLSHosts string `ini:"agent_tls_hosts"`
 }
 
 type DatabaseConfig struct {
@@ -151,6 +155,9 @@ func LoadConfig(path string) (*AppConfig, error) {
 	if Config.Server.URLPrefix != "" {
 		logger.Infof("[Config] URL前缀: %s", Config.Server.URLPrefix)
 	}
+	if Config.Server.AgentTLSPort > 0 {
+		logger.Infof("[Config] Agent TLS 端口: %d", Config.Server.AgentTLSPort)
+	}
 
 	maskedHost := utils.MaskString(Config.Database.Host)
 	maskedDBName := utils.MaskString(Config.Database.DBName)
@@ -169,6 +176,8 @@ func applyEnvOverrides() {
 	getEnvStr("BH_SERVER_HOST", &Config.Server.Host)
 	getEnvStr("BH_SERVER_URL_PREFIX", &Config.Server.URLPrefix)
 	getEnvBool("BH_SERVER_PPROF", &Config.Server.PprofEnabled)
+	getEnvInt("BH_SERVER_AGENT_TLS_PORT", &Config.Server.AgentTLSPort)
+	getEnvStr("BH_SERVER_AGENT_TLS_HOSTS", &Config.Server.AgentTLSHosts)
 
 	// Database
 	getEnvStr("BH_DB_TYPE", &Config.Database.Type)
@@ -2,8 +2,11 @@ package services
 
 import (
 	"errors"
+	"time"
 
+	"github.com/engigu/baihu-panel/internal/constant"
 	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/eventbus"
 	"github.com/engigu/baihu-panel/internal/models"
 	"github.com/engigu/baihu-panel/internal/services/deps"
 	"github.com/engigu/baihu-panel/internal/utils"
@@ -15,10 +18,18 @@ func NewDependencyService() *DependencyService {
 	return &DependencyService{}
 }
 
-// List 获取依赖列表
-func (s *DependencyService) List(language, langVersion string) ([]models.Dependency, error) {
+// getManager 获取依赖管理器，agentID 非空时命令交由对应 Agent 执行
+func (s *DependencyService) getManager(language, agentID string, timeout time.Duration) deps.Manager {
+	if agentID == "" {
+		return deps.GetManager(language)
+	}
+	return deps.GetManagerWithRunner(language, GetAgentWSManager().AgentRunner(agentID, timeout))
+}
+
+// List 获取依赖列表（agentID 为空时为面板本机的依赖）
+func (s *DependencyService) List(language, langVersion, agentID string) ([]models.Dependency, error) {
 	var results []models.Dependency
-	query := database.DB
+	query := database.DB.Where("agent_id = ?", agentID)
 	if language != "" {
 		query = query.Where("language = ?", language)
 	}
@@ -29,11 +40,21 @@ func (s *DependencyService) List(language, langVersion string) ([]models.Depende
 	return results, err
 }
 
+// GetByID 根据 ID 获取依赖记录
+func (s *DependencyService) GetByID(id string) *models.Dependency {
+	var dep models.Dependency
+	res := database.DB.Where("id = ?", id).Limit(1).Find(&dep)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil
+	}
+	return &dep
+}
+
 // Create 创建依赖记录
 func (s *DependencyService) Create(dep *models.Dependency) error {
-	// 检查是否已存在（名称、版本、语言及版本必须完全匹配）
+	// 检查是否已存在（名称、版本、语言及版本、所在 Agent 必须完全匹配）
 	var existing models.Dependency
-	res := database.DB.Where("name = ? AND version = ? AND language = ? AND lang_version = ?", dep.Name, dep.Version, dep.Language, dep.LangVersion).Limit(1).Find(&existing)
+	res := database.DB.Where("name = ? AND version = ? AND language = ? AND lang_version = ? AND agent_id = ?", dep.Name, dep.Version, dep.Language, dep.LangVersion, dep.AgentID).Limit(1).Find(&existing)
 	if res.Error == nil && res.RowsAffected > 0 {
 		// 如果已存在，更新 ID 并执行更新
 		dep.ID = existing.ID
@@ -52,18 +73,38 @@ func (s *DependencyService) Delete(id string) error {
 	return database.DB.Where("id = ?", id).Delete(&models.Dependency{}).Error
 }
 
-// Install 安装依赖
+// Install 安装依赖，失败时发布 dependency_failed 事件
 func (s *DependencyService) Install(dep *models.Dependency) error {
-	m := deps.GetManager(dep.Language)
+	m := s.getManager(dep.Language, dep.AgentID, agentInstallTimeout)
 	if m == nil {
 		return errors.New("不支持的依赖类型: " + dep.Language)
 	}
-	return m.Install(dep)
+	err := m.Install(dep)
+	if err != nil {
+		publishDependencyFailed(dep, err)
+	}
+	return err
+}
+
+// publishDependencyFailed 发布 dependency_failed 事件，依赖包与 mise 运行时安装失败共用
+func publishDependencyFailed(dep *models.Dependency, err error) {
+	eventbus.DefaultBus.Publish(eventbus.Event{
+		Type: constant.EventDependencyFailed,
+		Payload: map[string]interface{}{
+			"dependency_id": dep.ID,
+			"name":          dep.Name,
+			"version":       dep.Version,
+			"language":      dep.Language,
+			"lang_version":  dep.LangVersion,
+			"agent_id":      dep.AgentID,
+			"error":         err.Error(),
+		},
+	})
 }
 
 // Uninstall 卸载依赖
 func (s *DependencyService) Uninstall(dep *models.Dependency) error {
-	m := deps.GetManager(dep.Language)
+	m := s.getManager(dep.Language, dep.AgentID, agentInstallTimeout)
 	if m == nil {
 		return errors.New("不支持的依赖类型: " + dep.Language)
 	}
@@ -71,8 +112,8 @@ func (s *DependencyService) Uninstall(dep *models.Dependency) error {
 }
 
 // GetInstalledPackages 获取已安装的包列表
-func (s *DependencyService) GetInstalledPackages(language, langVersion string) ([]models.Dependency, error) {
-	m := deps.GetManager(language)
+func (s *DependencyService) GetInstalledPackages(language, langVersion, agentID string) ([]models.Dependency, error) {
+	m := s.getManager(language, agentID, agentQueryTimeout)
 	if m == nil {
 		return nil, errors.New("不支持的依赖类型: " + language)
 	}
@@ -89,13 +130,13 @@ func (s *DependencyService) GetInstallCommand(dep *models.Dependency) (string, e
 }
 
 // GetReinstallAllCommand 获取全部重装命令
-func (s *DependencyService) GetReinstallAllCommand(language, langVersion string) (string, error) {
+func (s *DependencyService) GetReinstallAllCommand(language, langVersion, agentID string) (string, error) {
 	m := deps.GetManager(language)
 	if m == nil {
 		return "", errors.New("不支持的依赖类型: " + language)
 	}
 
-	deps_list, err := s.List(language, langVersion)
+	deps_list, err := s.List(language, langVersion, agentID)
 	if err != nil {
 		return "", err
 	}
@@ -20,6 +20,9 @@ type Manager interface {
 	GetVerifyCommand(langVersion string) (string, error)
 }
 
+// CommandRunner 命令执行器，返回合并后的输出（为空时在本机执行）
+type CommandRunner func(args []string) ([]byte, error)
+
 // BaseManager 基础管理器，提供通用方法
 type BaseManager struct {
 	Language     string
@@ -28,10 +31,19 @@ type BaseManager struct {
 	ListCmd      []string
 	VerifyCmd    []string
 	Separator    string
+	Runner       CommandRunner
+}
+
+// SetRunner 指定命令执行器，如交由 Agent 执行
+func (m *BaseManager) SetRunner(runner CommandRunner) {
+	m.Runner = runner
 }
 
 func (m *BaseManager) runMiseCommand(langVersion string, cmdArgs []string) ([]byte, error) {
 	args := utils.BuildMiseCommandArgsSimple(cmdArgs, m.Language, langVersion)
+	if m.Runner != nil {
+		return m.Runner(args)
+	}
 	cmd := exec.Command(args[0], args[1:]...)
 	return cmd.CombinedOutput()
 }
@@ -122,6 +134,18 @@ func (m *BaseManager) Uninstall(dep *models.Dependency) error {
 	return nil
 }
 
+// GetManagerWithRunner 获取管理器并指定命令执行器
+func GetManagerWithRunner(language string, runner CommandRunner) Manager {
+	m := GetManager(language)
+	if m == nil {
+		return nil
+	}
+	if r, ok := m.(interface{ SetRunner(CommandRunner) }); ok {
+		r.SetRunner(runner)
+	}
+	return m
+}
+
 // GetManager 根据语言获取对应的管理器
 func GetManager(language string) Manager {
 	lang := strings.ToLower(language)
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/utils"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// diskCheckInterval 磁盘空间检查间隔
+const diskCheckInterval = 10 * time.Minute
+
+// defaultDiskLowThreshold 默认剩余空间告警阈值（百分比）
+const defaultDiskLowThreshold = 10
+
+// DiskMonitor 定期检查数据目录所在磁盘的剩余空间
+type DiskMonitor struct {
+	settingsService *SettingsService
+	low             bool // 是否已处于空间不足状态，恢复到阈值以上后才会再次告警
+}
+
+func NewDiskMonitor() *DiskMonitor {
+	return &DiskMonitor{settingsService: NewSettingsService()}
+}
+
+// Start 启动定期检查
+func (m *DiskMonitor) Start() {
+	m.Check()
+
+	ticker := time.NewTicker(diskCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.Check()
+	}
+}
+
+// Check 检查一次剩余空间，低于阈值时发布 disk_low 事件
+func (m *DiskMonitor) Check() {
+	threshold := utils.ToInt(m.settingsService.Get(constant.SectionSystem, constant.KeyDiskLowThreshold), defaultDiskLowThreshold)
+	if threshold <= 0 {
+		m.low = false
+		return
+	}
+
+	usage, err := disk.Usage(constant.DataDir)
+	if err != nil || usage.Total == 0 {
+		logger.Warnf("[DiskMonitor] 获取磁盘使用情况失败: %v", err)
+		return
+	}
+
+	freePercent := int(usage.Free * 100 / usage.Total)
+	if freePercent >= threshold {
+		m.low = false
+		return
+	}
+	if m.low {
+		return
+	}
+	m.low = true
+
+	logger.Warnf("[DiskMonitor] 数据目录剩余空间不足: %s (%d%%)", utils.FormatBytes(usage.Free), freePercent)
+	eventbus.DefaultBus.Publish(eventbus.Event{
+		Type: constant.EventDiskLow,
+		Payload: map[string]interface{}{
+			"path":         constant.DataDir,
+			"free":         utils.FormatBytes(usage.Free),
+			"total":        utils.FormatBytes(usage.Total),
+			"free_percent": freePercent,
+			"threshold":    threshold,
+		},
+	})
+}
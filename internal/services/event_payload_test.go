@@ -0,0 +1,83 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// 通知、Webhook 与 SSE 在不同工作协程中处理同一个事件数据，通知补充字段不能写回共享的 map
+// （使用 go test -race 运行可检测并发读写）
+func TestEventPayloadSharedBySubscribers(t *testing.T) {
+	setupNotifyTestDB(t, &models.Setting{}, &models.NotifyWay{}, &models.NotifyBinding{}, &models.NotifyRoute{},
+		&models.Webhook{}, &models.WebhookDelivery{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	hook := &models.Webhook{ID: "h1", Name: "hook", URL: srv.URL, Events: constant.EventTaskFailed, MaxRetries: 0, Timeout: 5, Enabled: utils.BoolPtr(true)}
+	// max_retries 的零值会被 gorm 默认值覆盖，需显式创建
+	if err := database.DB.Select("*").Create(hook).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	bus := eventbus.New()
+	bus.Start(eventbus.Options{Workers: 4, QueueSize: 100})
+	NewNotificationService().SubscribeEvents(bus)
+	NewWebhookService().SubscribeEvents(bus)
+	stream := &EventStreamService{clients: make(map[*eventStreamClient]struct{})}
+	stream.SubscribeEvents(bus)
+	events, cancel := stream.Subscribe(EventStreamFilter{})
+	defer cancel()
+
+	output := strings.Repeat("x", 5000)
+	const n = 20
+	for i := 0; i < n; i++ {
+		bus.Publish(eventbus.Event{Type: constant.EventTaskFailed, Payload: map[string]interface{}{
+			"task_id":   "t1",
+			"task_name": "backup",
+			"output":    output,
+		}})
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case ev := <-events:
+			if _, err := json.Marshal(ev); err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream event not received")
+		}
+	}
+	if !bus.Close(5 * time.Second) {
+		t.Fatal("close timed out")
+	}
+
+	// 投递在后台协程中执行，需等待全部完成，避免测试结束后仍访问数据库
+	var deliveries []models.WebhookDelivery
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		database.DB.Where("status = ?", constant.WebhookStatusSuccess).Find(&deliveries)
+		if len(deliveries) == n || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(deliveries) != n {
+		t.Fatalf("expected %d webhook deliveries, got %d", n, len(deliveries))
+	}
+	for _, d := range deliveries {
+		if strings.Contains(string(d.RequestBody), "截断") || !strings.Contains(string(d.RequestBody), output) {
+			t.Fatal("webhook payload was modified by the notification handler")
+		}
+	}
+}
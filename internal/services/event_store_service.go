@@ -0,0 +1,205 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// eventReplayLimit 批量重放单次最多处理的事件数量
+const eventReplayLimit = 500
+
+// eventPersistEnabled 事件持久化开关的缓存，避免每次发布事件都查询设置表；保存 eventbus 设置时刷新
+var eventPersistEnabled atomic.Bool
+
+// EventStoreService 事件持久化与重放（实现 eventbus.Store）
+type EventStoreService struct {
+	settingsService *SettingsService
+}
+
+func NewEventStoreService() *EventStoreService {
+	return &EventStoreService{
+		settingsService: NewSettingsService(),
+	}
+}
+
+// Setup 按设置启动事件总线工作协程，并挂载持久化存储
+func (s *EventStoreService) Setup(bus *eventbus.EventBus) {
+	opts := eventbus.Options{
+		Workers:   utils.ToInt(s.settingsService.Get(constant.SectionEventBus, constant.KeyWorkerCount), eventbus.DefaultOptions.Workers),
+		QueueSize: utils.ToInt(s.settingsService.Get(constant.SectionEventBus, constant.KeyQueueSize), eventbus.DefaultOptions.QueueSize),
+	}
+	eventPersistEnabled.Store(s.settingsService.Get(constant.SectionEventBus, constant.KeyEventPersist) == "true")
+	bus.Start(opts)
+	bus.SetStore(s)
+	logger.Infof("[EventBus] 事件总线已启动: workers=%d, queue=%d, persist=%v", opts.Workers, opts.QueueSize, s.enabled())
+}
+
+func (s *EventStoreService) enabled() bool {
+	return eventPersistEnabled.Load()
+}
+
+// Save 持久化事件（未开启持久化时直接跳过）
+func (s *EventStoreService) Save(event *eventbus.Event) error {
+	if !s.enabled() {
+		return nil
+	}
+
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+
+	record := &models.EventRecord{
+		ID:        utils.GenerateID(),
+		Type:      event.Type,
+		Payload:   models.BigText(payload),
+		Status:    constant.EventRecordPending,
+		CreatedAt: models.LocalTime(event.Time),
+	}
+	if err := database.DB.Create(record).Error; err != nil {
+		return err
+	}
+	event.ID = record.ID
+	return nil
+}
+
+// MarkHandled 记录事件处理结果及出错的句柄
+func (s *EventStoreService) MarkHandled(event eventbus.Event, failed []string) {
+	status := constant.EventRecordHandled
+	if len(failed) > 0 {
+		status = constant.EventRecordFailed
+	}
+	now := models.Now()
+	database.DB.Model(&models.EventRecord{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+		"status":          status,
+		"failed":          len(failed),
+		"failed_handlers": models.BigText(strings.Join(failed, "\n")),
+		"handled_at":      &now,
+	})
+}
+
+// List 分页查询持久化的事件
+func (s *EventStoreService) List(eventType, status string, page, pageSize int) ([]models.EventRecord, int64) {
+	var records []models.EventRecord
+	var total int64
+
+	query := database.DB.Model(&models.EventRecord{})
+	if eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query.Count(&total)
+	query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records)
+	return records, total
+}
+
+// Replay 重放单个事件
+func (s *EventStoreService) Replay(id string) error {
+	var record models.EventRecord
+	if res := database.DB.Where("id = ?", id).Limit(1).Find(&record); res.RowsAffected == 0 {
+		return errors.New("事件不存在")
+	}
+	return s.replay(&record)
+}
+
+// ReplayByStatus 批量重放指定状态的事件（按发生时间顺序），返回重放数量
+func (s *EventStoreService) ReplayByStatus(status string) (int, error) {
+	if status != constant.EventRecordPending && status != constant.EventRecordFailed {
+		return 0, errors.New("仅支持重放 pending 或 failed 状态的事件")
+	}
+
+	var records []models.EventRecord
+	database.DB.Where("status = ?", status).Order("created_at ASC").Limit(eventReplayLimit).Find(&records)
+	for i := range records {
+		if err := s.replay(&records[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+func (s *EventStoreService) replay(record *models.EventRecord) error {
+	payload, err := decodeEventPayload(string(record.Payload))
+	if err != nil {
+		return err
+	}
+
+	// 失败的事件只重放给出错的句柄，已成功的通知、Webhook 等不再重复执行；
+	// pending 事件（如面板中途退出）无法确定哪些句柄已执行，重放给全部句柄
+	var handlers []string
+	if record.Status == constant.EventRecordFailed && record.FailedHandlers != "" {
+		handlers = strings.Split(string(record.FailedHandlers), "\n")
+	}
+
+	database.DB.Model(&models.EventRecord{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+		"status":  constant.EventRecordPending,
+		"replays": record.Replays + 1,
+	})
+
+	eventbus.DefaultBus.Replay(eventbus.Event{
+		ID:       record.ID,
+		Type:     record.Type,
+		Payload:  payload,
+		Time:     record.CreatedAt.Time(),
+		Handlers: handlers,
+	})
+	return nil
+}
+
+// decodeEventPayload 还原持久化的事件数据：JSON 中的整数还原为 int（与发布时一致），其余数字为 float64
+func decodeEventPayload(data string) (map[string]interface{}, error) {
+	if data == "" {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	var payload map[string]interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+	return normalizeJSONNumbers(payload).(map[string]interface{}), nil
+}
+
+func normalizeJSONNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return int(i)
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeJSONNumbers(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeJSONNumbers(item)
+		}
+		return val
+	}
+	return v
+}
+
+// CleanUp 清理过期的事件记录
+func (s *EventStoreService) CleanUp() {
+	days := utils.ToInt(s.settingsService.Get(constant.SectionEventBus, constant.KeyEventPersistDays), 7)
+	if days <= 0 {
+		return
+	}
+	deadline := time.Now().AddDate(0, 0, -days)
+	database.DB.Where("created_at < ?", deadline).Delete(&models.EventRecord{})
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+)
+
+// eventStreamBuffer 每个订阅者的缓冲区大小，消费过慢时丢弃新事件，避免阻塞总线
+const eventStreamBuffer = 64
+
+// StreamEvent 推送给 SSE 客户端的事件
+type StreamEvent struct {
+	ID        uint64      `json:"id"`
+	Event     string      `json:"event"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// OpenAPIStreamEvents 开放接口（令牌访问）可订阅的事件类型；
+// 登录、密码修改、通知发送等包含用户名、IP 与消息内容的事件只在管理员登录后可订阅
+var OpenAPIStreamEvents = map[string]bool{
+	constant.EventTaskScheduled:   true,
+	constant.EventTaskStarted:     true,
+	constant.EventTaskSuccess:     true,
+	constant.EventTaskFailed:      true,
+	constant.EventTaskTimeout:     true,
+	constant.EventTaskRecovered:   true,
+	constant.EventAgentOnline:     true,
+	constant.EventAgentOffline:    true,
+	constant.EventAgentOverloaded: true,
+}
+
+// EventStreamFilter 订阅过滤条件，为空表示不过滤
+type EventStreamFilter struct {
+	Types   map[string]bool
+	TaskIDs map[string]bool
+	Allowed map[string]bool // 允许推送的事件类型，为空表示不限制
+}
+
+// NewEventStreamFilter 根据逗号分隔的事件类型和任务ID构造过滤条件
+func NewEventStreamFilter(types, taskIDs string) EventStreamFilter {
+	return EventStreamFilter{
+		Types:   splitToSet(types),
+		TaskIDs: splitToSet(taskIDs),
+	}
+}
+
+// Match 判断事件是否满足过滤条件
+func (f EventStreamFilter) Match(event eventbus.Event) bool {
+	if len(f.Allowed) > 0 && !f.Allowed[event.Type] {
+		return false
+	}
+	if len(f.Types) > 0 && !f.Types[event.Type] {
+		return false
+	}
+	if len(f.TaskIDs) > 0 {
+		payload, ok := event.Payload.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		taskID, _ := payload["task_id"].(string)
+		if !f.TaskIDs[taskID] {
+			return false
+		}
+	}
+	return true
+}
+
+type eventStreamClient struct {
+	filter EventStreamFilter
+	ch     chan StreamEvent
+}
+
+// EventStreamService 将事件总线上的事件转发给 SSE 订阅者
+type EventStreamService struct {
+	clients map[*eventStreamClient]struct{}
+	seq     uint64
+	closed  bool
+	mu      sync.RWMutex
+}
+
+var (
+	eventStreamService     *EventStreamService
+	eventStreamServiceOnce sync.Once
+)
+
+// GetEventStreamService 获取事件流服务单例
+func GetEventStreamService() *EventStreamService {
+	eventStreamServiceOnce.Do(func() {
+		eventStreamService = &EventStreamService{
+			clients: make(map[*eventStreamClient]struct{}),
+		}
+	})
+	return eventStreamService
+}
+
+// SubscribeEvents 实现 eventbus.Subscriber，订阅全部事件
+func (s *EventStreamService) SubscribeEvents(bus *eventbus.EventBus) {
+	bus.Subscribe(eventbus.AllEvents, "event_stream", s.broadcast)
+}
+
+// Subscribe 注册一个订阅者，返回事件通道和取消函数
+func (s *EventStreamService) Subscribe(filter EventStreamFilter) (<-chan StreamEvent, func()) {
+	client := &eventStreamClient{
+		filter: filter,
+		ch:     make(chan StreamEvent, eventStreamBuffer),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		close(client.ch)
+		return client.ch, func() {}
+	}
+	s.clients[client] = struct{}{}
+	s.mu.Unlock()
+
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.clients[client]; ok {
+			delete(s.clients, client)
+			close(client.ch)
+		}
+	}
+	return client.ch, cancel
+}
+
+// Close 关闭全部订阅者（服务停止时调用，使 SSE 连接尽快结束）
+func (s *EventStreamService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for client := range s.clients {
+		delete(s.clients, client)
+		close(client.ch)
+	}
+}
+
+// ClientCount 当前订阅者数量
+func (s *EventStreamService) ClientCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.clients)
+}
+
+func (s *EventStreamService) broadcast(event eventbus.Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.clients) == 0 {
+		return
+	}
+
+	msg := StreamEvent{
+		ID:        atomic.AddUint64(&s.seq, 1),
+		Event:     event.Type,
+		Timestamp: time.Now().Unix(),
+		Data:      event.Payload,
+	}
+	for client := range s.clients {
+		if !client.filter.Match(event) {
+			continue
+		}
+		select {
+		case client.ch <- msg:
+		default:
+			// 客户端消费过慢，丢弃
+		}
+	}
+}
+
+func splitToSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			set[item] = true
+		}
+	}
+	return set
+}
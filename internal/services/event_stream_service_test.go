@@ -0,0 +1,34 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+)
+
+func TestEventStreamFilterAllowed(t *testing.T) {
+	filter := NewEventStreamFilter("", "")
+	filter.Allowed = OpenAPIStreamEvents
+
+	cases := map[string]bool{
+		constant.EventTaskFailed:      true,
+		constant.EventAgentOffline:    true,
+		constant.EventUserLogin:       false,
+		constant.EventBruteForceLogin: false,
+		constant.EventPasswordChanged: false,
+		constant.EventNotifySent:      false,
+	}
+	for eventType, want := range cases {
+		if got := filter.Match(eventbus.Event{Type: eventType, Payload: map[string]interface{}{}}); got != want {
+			t.Errorf("Match(%s) = %v, want %v", eventType, got, want)
+		}
+	}
+
+	// 显式指定类型也不能越过允许范围
+	filter = NewEventStreamFilter(constant.EventUserLogin, "")
+	filter.Allowed = OpenAPIStreamEvents
+	if filter.Match(eventbus.Event{Type: constant.EventUserLogin}) {
+		t.Fatal("open API stream must not deliver user_login")
+	}
+}
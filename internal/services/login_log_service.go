@@ -39,7 +39,7 @@ func (s *LoginLogService) Create(username, ip, userAgent, status, message string
 // SubscribeEvents 注册订阅事件
 func (s *LoginLogService) SubscribeEvents(bus *eventbus.EventBus) {
 	// 用户登录事件
-	bus.Subscribe(constant.EventUserLogin, func(e eventbus.Event) {
+	bus.Subscribe(constant.EventUserLogin, "login_log", func(e eventbus.Event) {
 		payload, ok := e.Payload.(map[string]interface{})
 		if !ok {
 			return
@@ -67,7 +67,7 @@ func (s *LoginLogService) SubscribeEvents(bus *eventbus.EventBus) {
 	})
 
 	// 暴力破解防御触发事件
-	bus.Subscribe(constant.EventBruteForceLogin, func(e eventbus.Event) {
+	bus.Subscribe(constant.EventBruteForceLogin, "login_log", func(e eventbus.Event) {
 		payload, ok := e.Payload.(map[string]interface{})
 		if !ok {
 			return
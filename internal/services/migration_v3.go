@@ -112,7 +112,8 @@ func RunMigrationV3() error {
 	if len(backups) == 0 {
 		logger.Infof("[MigrationV3] 执行关键备份...")
 		backupService := NewBackupService()
-		zipPath, err := backupService.CreateBackup()
+		// 迁移发生在事件总线初始化之前，直接备份而不发布事件
+		zipPath, err := backupService.createBackup()
 		if err != nil {
 			return fmt.Errorf("自动备份失败，流程终止: %v", err)
 		}
@@ -18,12 +18,46 @@ import (
 	"gorm.io/gorm"
 )
 
-type MiseService struct{}
+type MiseService struct {
+	agentID string // 非空时 mise 命令交由该 Agent 执行
+}
 
 func NewMiseService() *MiseService {
 	return &MiseService{}
 }
 
+// ForAgent 返回管理指定 Agent 上 mise 环境的服务（agentID 为空时即面板本机）
+func (s *MiseService) ForAgent(agentID string) *MiseService {
+	return &MiseService{agentID: agentID}
+}
+
+// runMise 执行 mise 命令并返回合并输出，stdoutOnly 时仅返回标准输出
+func (s *MiseService) runMise(stdoutOnly bool, timeout time.Duration, args ...string) ([]byte, error) {
+	env := []string{"MISE_NO_COLOR=1", "TERM=dumb"}
+	if s.agentID != "" {
+		result, err := GetAgentWSManager().RunAgentCommand(s.agentID, AgentCommand{
+			Args:    append([]string{"mise"}, args...),
+			Env:     env,
+			Timeout: int(timeout.Seconds()),
+		}, nil)
+		if result == nil {
+			return nil, err
+		}
+		if stdoutOnly {
+			return result.Stdout, err
+		}
+		return result.Output, err
+	}
+
+	cmd := exec.Command("mise", args...)
+	// 继承父进程环境变量
+	cmd.Env = append(os.Environ(), env...)
+	if stdoutOnly {
+		return cmd.Output()
+	}
+	return cmd.CombinedOutput()
+}
+
 type MiseLanguage struct {
 	Plugin      string     `json:"plugin"`
 	Version     string     `json:"version"`
@@ -45,8 +79,10 @@ func (s *MiseService) List() ([]MiseLanguage, error) {
 		return nil, err
 	}
 
-	// 异步同步到数据库，确保列表响应速度
-	go s.syncToDB(langs)
+	// 异步同步到数据库，确保列表响应速度（语言表仅记录面板本机的环境）
+	if s.agentID == "" {
+		go s.syncToDB(langs)
+	}
 
 	return langs, nil
 }
@@ -60,19 +96,16 @@ func (s *MiseService) Sync() error {
 	}
 
 	// 同步到数据库
-	s.syncToDB(langs)
+	if s.agentID == "" {
+		s.syncToDB(langs)
+	}
 	return nil
 }
 
 // fetchLiveLanguages 实时从系统检测 mise 语言列表
 func (s *MiseService) fetchLiveLanguages() ([]MiseLanguage, error) {
 	// 使用 --json 获取格式化数据
-	cmd := exec.Command("mise", "ls", "--json")
-	// 继承父进程环境变量
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, "MISE_NO_COLOR=1", "TERM=dumb")
-
-	output, err := cmd.Output()
+	output, err := s.runMise(true, agentQueryTimeout, "ls", "--json")
 	if err != nil {
 		logger.Warnf("[Mise] mise ls --json failed: %v", err)
 		return s.listFallback()
@@ -111,10 +144,7 @@ func (s *MiseService) fetchLiveLanguages() ([]MiseLanguage, error) {
 
 
 func (s *MiseService) listFallback() ([]MiseLanguage, error) {
-	cmd := exec.Command("mise", "ls")
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, "MISE_NO_COLOR=1", "TERM=dumb")
-	output, err := cmd.CombinedOutput()
+	output, err := s.runMise(false, agentQueryTimeout, "ls")
 	if err != nil {
 		return nil, fmt.Errorf("mise ls failed: %v, output: %s", err, string(output))
 	}
@@ -159,9 +189,7 @@ func (s *MiseService) Versions(plugin string) ([]string, error) {
 	}
 
 	// 只获取最新版本列表
-	cmd := exec.Command("mise", "ls-remote", plugin)
-	cmd.Env = append(cmd.Env, "MISE_NO_COLOR=1", "TERM=dumb")
-	output, err := cmd.CombinedOutput()
+	output, err := s.runMise(false, agentQueryTimeout, "ls-remote", plugin)
 
 	if err != nil && len(output) == 0 {
 		logger.Errorf("[Mise] Fetch versions for %s failed: %v", plugin, err)
@@ -198,7 +226,8 @@ func (s *MiseService) enrichInstallDates(languages []MiseLanguage) {
 			languages[i].IsGlobal = true
 		}
 
-		if languages[i].InstallPath != "" {
+		// 安装目录在 Agent 主机上时无法获取安装时间
+		if languages[i].InstallPath != "" && s.agentID == "" {
 			if installDate := s.getInstallDate(languages[i].InstallPath); installDate != "" {
 				languages[i].InstalledAt = installDate
 			}
@@ -329,13 +358,19 @@ func (s *MiseService) GetVerifyCommand(plugin, version string) (string, error) {
 	}
 	return m.GetVerifyCommand(version)
 }
-// UseGlobal 设置全局默认版本
+// UseGlobal 设置全局默认版本（未安装时由 mise 安装），失败时发布 dependency_failed 事件
 func (s *MiseService) UseGlobal(plugin, version string) error {
-	cmd := exec.Command("mise", "use", "-g", fmt.Sprintf("%s@%s", plugin, version))
-	cmd.Env = os.Environ()
-	output, err := cmd.CombinedOutput()
+	output, err := s.runMise(false, agentInstallTimeout, "use", "-g", fmt.Sprintf("%s@%s", plugin, version))
 	if err != nil {
-		return fmt.Errorf("mise use -g failed: %v, output: %s", err, string(output))
+		err = fmt.Errorf("mise use -g failed: %v, output: %s", err, string(output))
+		publishDependencyFailed(&models.Dependency{
+			Name:        plugin,
+			Version:     version,
+			Language:    plugin,
+			LangVersion: version,
+			AgentID:     s.agentID,
+		}, err)
+		return err
 	}
 	return nil
 }
@@ -347,9 +382,7 @@ func (s *MiseService) UnsetGlobal(plugin, version string) error {
 	if version != "" {
 		target = fmt.Sprintf("%s@%s", plugin, version)
 	}
-	cmd := exec.Command("mise", "unuse", "-g", target)
-	cmd.Env = os.Environ()
-	output, err := cmd.CombinedOutput()
+	output, err := s.runMise(false, agentQueryTimeout, "unuse", "-g", target)
 	if err != nil {
 		return fmt.Errorf("mise unuse global failed: %v, output: %s", err, string(output))
 	}
@@ -357,9 +390,7 @@ func (s *MiseService) UnsetGlobal(plugin, version string) error {
 }
 // Envs 获取全局环境变量
 func (s *MiseService) Envs() (map[string]string, error) {
-	cmd := exec.Command("mise", "set")
-	cmd.Env = os.Environ()
-	output, err := cmd.CombinedOutput()
+	output, err := s.runMise(false, agentQueryTimeout, "set")
 	if err != nil {
 		return nil, fmt.Errorf("mise set failed: %v, output: %s", err, string(output))
 	}
@@ -383,9 +414,7 @@ func (s *MiseService) Envs() (map[string]string, error) {
 
 // SetEnv 设置全局环境变量
 func (s *MiseService) SetEnv(key, value string) error {
-	cmd := exec.Command("mise", "set", "-g", fmt.Sprintf("%s=%s", key, value))
-	cmd.Env = os.Environ()
-	output, err := cmd.CombinedOutput()
+	output, err := s.runMise(false, agentQueryTimeout, "set", "-g", fmt.Sprintf("%s=%s", key, value))
 	if err != nil {
 		return fmt.Errorf("mise set -g failed: %v, output: %s", err, string(output))
 	}
@@ -394,9 +423,7 @@ func (s *MiseService) SetEnv(key, value string) error {
 
 // UnsetEnv 取消全局环境变量
 func (s *MiseService) UnsetEnv(key string) error {
-	cmd := exec.Command("mise", "unset", "-g", key)
-	cmd.Env = os.Environ()
-	output, err := cmd.CombinedOutput()
+	output, err := s.runMise(false, agentQueryTimeout, "unset", "-g", key)
 	if err != nil {
 		return fmt.Errorf("mise unset -g failed: %v, output: %s", err, string(output))
 	}
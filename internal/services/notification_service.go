@@ -2,8 +2,11 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"sync"
+	"time"
 
 	"github.com/engigu/baihu-panel/internal/constant"
 	"github.com/engigu/baihu-panel/internal/database"
@@ -20,24 +23,58 @@ import (
 
 // NotifyChannel 通知渠道配置
 type NotifyChannel struct {
-	ID        string            `json:"id"`
-	Name      string            `json:"name"`
-	Type      string            `json:"type"`
-	Enabled   bool              `json:"enabled"`
-	CreatedAt models.LocalTime  `json:"created_at"`
-	Config    map[string]string `json:"config"`
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	Type      string               `json:"type"`
+	Enabled   bool                 `json:"enabled"`
+	CreatedAt models.LocalTime     `json:"created_at"`
+	Config    map[string]string    `json:"config"`
+	Throttle  *models.ThrottleRule `json:"throttle,omitempty"` // 渠道级节流规则
+	// 静默时段，仅作用于事件通知
+	QuietHours *models.QuietHours `json:"quiet_hours,omitempty"`
+	// 失败重试策略
+	Retry *models.RetryPolicy `json:"retry,omitempty"`
+	// 发送统计，仅在渠道列表中返回
+	Stats *ChannelStats `json:"stats,omitempty"`
 }
 
 // NotifyMessage 通知消息
 type NotifyMessage struct {
-	Title string `json:"title"`
-	Text  string `json:"text"`
+	Title     string   `json:"title"`
+	Text      string   `json:"text"`
+	Markdown  string   `json:"markdown,omitempty"`
+	HTML      string   `json:"html,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	ImageURL  string   `json:"image_url,omitempty"`
+	AtMobiles []string `json:"at_mobiles,omitempty"`
+	AtUserIds []string `json:"at_user_ids,omitempty"`
+	AtAll     bool     `json:"at_all,omitempty"`
+	// 结构化字段（任务、状态、耗时等），支持的渠道（如 Discord）以字段形式展示
+	Fields []NotifyField `json:"fields,omitempty"`
+}
+
+// NotifyField 消息中的一个结构化字段
+type NotifyField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// content 推送记录中保存的正文：优先纯文本，其次 Markdown / HTML
+func (m *NotifyMessage) content() string {
+	switch {
+	case m.Text != "":
+		return m.Text
+	case m.Markdown != "":
+		return m.Markdown
+	}
+	return m.HTML
 }
 
 // NotifyResult 发送结果
 type NotifyResult struct {
 	Success bool   `json:"success"`
 	Error   string `json:"error,omitempty"`
+	Pending bool   `json:"pending,omitempty"` // 等待超时，发送仍在后台重试
 }
 
 // SupportedChannelTypes 支持的渠道类型
@@ -56,6 +93,11 @@ var SupportedChannelTypes = []map[string]string{
 	{"type": messenger.ChannelAliyunSMS, "label": "阿里云短信"},
 	{"type": messenger.ChannelPushPlus, "label": "PushPlus"},
 	{"type": messenger.ChannelVoceChat, "label": "VoceChat"},
+	{"type": messenger.ChannelSlack, "label": "Slack"},
+	{"type": messenger.ChannelDiscord, "label": "Discord"},
+	{"type": messenger.ChannelMatrix, "label": "Matrix"},
+	{"type": messenger.ChannelMattermost, "label": "Mattermost"},
+	{"type": messenger.ChannelWebPush, "label": "浏览器推送"},
 }
 
 // SupportedEvents 支持的事件类型
@@ -66,24 +108,45 @@ var SupportedEvents = []map[string]string{
 	{"type": constant.EventTaskSuccess, "label": "任务成功", "binding_type": constant.BindingTypeTask},
 	{"type": constant.EventTaskFailed, "label": "任务失败", "binding_type": constant.BindingTypeTask},
 	{"type": constant.EventTaskTimeout, "label": "任务超时", "binding_type": constant.BindingTypeTask},
+	{"type": constant.EventTaskRecovered, "label": "任务恢复", "binding_type": constant.BindingTypeTask},
+	{"type": constant.EventCheckDown, "label": "心跳异常", "binding_type": constant.BindingTypeCheck},
+	{"type": constant.EventCheckUp, "label": "心跳恢复", "binding_type": constant.BindingTypeCheck},
+	{"type": constant.EventAgentOffline, "label": "Agent 离线", "binding_type": constant.BindingTypeSystem},
+	{"type": constant.EventAgentOnline, "label": "Agent 上线", "binding_type": constant.BindingTypeSystem},
+	{"type": constant.EventAgentOverloaded, "label": "Agent 负载过高", "binding_type": constant.BindingTypeSystem},
+	{"type": constant.EventDiskLow, "label": "磁盘空间不足", "binding_type": constant.BindingTypeSystem},
+	{"type": constant.EventBackupSuccess, "label": "备份完成", "binding_type": constant.BindingTypeSystem},
+	{"type": constant.EventBackupFailed, "label": "备份失败", "binding_type": constant.BindingTypeSystem},
+	{"type": constant.EventDependencyFailed, "label": "依赖安装失败", "binding_type": constant.BindingTypeSystem},
+	{"type": constant.EventPanelStarted, "label": "面板启动", "binding_type": constant.BindingTypeSystem},
 }
 
 type NotificationService struct {
 	settingsService *SettingsService
+	throttler       *NotifyThrottler
+	quietQueue      *NotifyQuietQueue
 	mu              sync.RWMutex
 }
 
 func NewNotificationService() *NotificationService {
 	return &NotificationService{
 		settingsService: NewSettingsService(),
+		throttler:       defaultNotifyThrottler,
+		quietQueue:      defaultNotifyQuietQueue,
 	}
 }
 
-// GetChannels 获取所有渠道
+// GetChannels 获取所有渠道（附带发送统计）
 func (s *NotificationService) GetChannels() []NotifyChannel {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.getChannelsInternal()
+
+	channels := s.getChannelsInternal()
+	stats := loadChannelStats()
+	for i := range channels {
+		channels[i].Stats = stats[channels[i].ID]
+	}
+	return channels
 }
 
 // SaveChannel 保存/更新渠道
@@ -96,25 +159,61 @@ func (s *NotificationService) SaveChannel(channel NotifyChannel) error {
 		return err
 	}
 
+	var throttleJSON []byte
+	if channel.Throttle.Enabled() {
+		if throttleJSON, err = json.Marshal(channel.Throttle); err != nil {
+			return err
+		}
+	}
+
+	var quietJSON []byte
+	if channel.QuietHours.Enabled() {
+		if err := channel.QuietHours.Validate(); err != nil {
+			return err
+		}
+		if quietJSON, err = json.Marshal(channel.QuietHours); err != nil {
+			return err
+		}
+	}
+
+	var retryJSON []byte
+	if channel.Retry.Enabled() {
+		if err := channel.Retry.Validate(); err != nil {
+			return err
+		}
+		if retryJSON, err = json.Marshal(channel.Retry); err != nil {
+			return err
+		}
+	}
+
 	if channel.ID == "" {
 		// 新建
 		channel.ID = utils.GenerateID()
 		notifyWay := &models.NotifyWay{
-			ID:      channel.ID,
-			Name:    channel.Name,
-			Type:    channel.Type,
-			Config:  models.BigText(configJSON),
-			Enabled: utils.BoolPtr(channel.Enabled),
+			ID:         channel.ID,
+			Name:       channel.Name,
+			Type:       channel.Type,
+			Config:     models.BigText(configJSON),
+			Throttle:   models.BigText(throttleJSON),
+			QuietHours: models.BigText(quietJSON),
+			Retry:      models.BigText(retryJSON),
+			Enabled:    utils.BoolPtr(channel.Enabled),
 		}
 		return database.DB.Create(notifyWay).Error
 	}
 
+	// 节流规则可能变化，重置运行状态
+	s.throttler.Reset(channelThrottleScope(channel.ID))
+
 	// 更新
 	updates := map[string]interface{}{
-		"name":    channel.Name,
-		"type":    channel.Type,
-		"config":  models.BigText(configJSON),
-		"enabled": &channel.Enabled,
+		"name":        channel.Name,
+		"type":        channel.Type,
+		"config":      models.BigText(configJSON),
+		"throttle":    models.BigText(throttleJSON),
+		"quiet_hours": models.BigText(quietJSON),
+		"retry":       models.BigText(retryJSON),
+		"enabled":     &channel.Enabled,
 	}
 	return database.DB.Model(&models.NotifyWay{}).Where("id = ?", channel.ID).Updates(updates).Error
 }
@@ -136,10 +235,17 @@ func (s *NotificationService) DeleteChannel(id string) error {
 		return err
 	}
 
+	s.throttler.Reset(channelThrottleScope(id))
+
 	// 同时清理事件绑定中引用此渠道的配置
 	if err := database.DB.Where("way_id = ?", id).Delete(&models.NotifyBinding{}).Error; err != nil {
 		logger.Errorf("[Notify] 清理事件绑定失败: %v", err)
 	}
+	if err := database.DB.Where("way_id = ?", id).Delete(&models.NotifyRoute{}).Error; err != nil {
+		logger.Errorf("[Notify] 清理路由规则失败: %v", err)
+	}
+	database.DB.Where("way_id = ?", id).Delete(&models.NotifyChannelStat{})
+	database.DB.Where("way_id = ?", id).Delete(&models.NotifyQuietItem{})
 
 	return nil
 }
@@ -199,6 +305,7 @@ func (s *NotificationService) BatchSaveBindings(bindingType, dataID string, bind
 
 // DeleteBinding 删除事件绑定
 func (s *NotificationService) DeleteBinding(id string) error {
+	s.throttler.Reset(bindingThrottleScope(id))
 	return database.DB.Where("id = ?", id).Delete(&models.NotifyBinding{}).Error
 }
 
@@ -206,9 +313,9 @@ func (s *NotificationService) DeleteBinding(id string) error {
 func (s *NotificationService) GetBindingsByEvent(bindingType, event, dataID string) []models.NotifyBinding {
 	var bindings []models.NotifyBinding
 
-	// 如果是任务事件且带有 dataID，只获取特定任务的绑定（禁用全局任务配置）
-	if bindingType == constant.BindingTypeTask && dataID != "" {
-		database.DB.Where("type = ? AND event = ? AND data_id = ?", constant.BindingTypeTask, event, dataID).Find(&bindings)
+	// 如果是任务/心跳检测事件且带有 dataID，只获取特定对象的绑定（禁用全局配置）
+	if (bindingType == constant.BindingTypeTask || bindingType == constant.BindingTypeCheck) && dataID != "" {
+		database.DB.Where("type = ? AND event = ? AND data_id = ?", bindingType, event, dataID).Find(&bindings)
 		return bindings
 	}
 
@@ -230,93 +337,132 @@ func (s *NotificationService) GetBindingsByEvent(bindingType, event, dataID stri
 
 // SendToChannel 使用 messenger SDK 发送通知到指定渠道
 func (s *NotificationService) SendToChannel(channel NotifyChannel, msg *NotifyMessage) *NotifyResult {
-	result, err := messenger.Send(channel.Type, messenger.ChannelConfig(channel.Config), &messenger.Message{
-		Title: msg.Title,
-		Text:  msg.Text,
-	})
+	return s.sendToChannel(channel, msg, 0)
+}
+
+// TestChannel 发送测试消息，不重试，也不计入渠道统计与死信记录
+func (s *NotificationService) TestChannel(channel NotifyChannel, msg *NotifyMessage) *NotifyResult {
+	return s.send(channel, msg, 0, false)
+}
+
+// sendToChannel 发送通知，suppressed 为此前被节流抑制的消息数量，会记录到推送日志中
+func (s *NotificationService) sendToChannel(channel NotifyChannel, msg *NotifyMessage, suppressed int) *NotifyResult {
+	return s.send(channel, msg, suppressed, true)
+}
+
+// send 发送通知并发布推送记录事件，track 为 true 时按渠道策略重试，并记录统计与死信
+func (s *NotificationService) send(channel NotifyChannel, msg *NotifyMessage, suppressed int, track bool) *NotifyResult {
+	errMsg, attempts := s.deliver(channel, msg, track)
 
 	payload := map[string]interface{}{
 		"title":        msg.Title,
-		"content":      msg.Text,
+		"content":      msg.content(),
 		"channel_id":   channel.ID,
 		"channel_name": channel.Name,
-		"success":      false,
-		"error_msg":    "",
-	}
-
-	if err != nil {
-		payload["error_msg"] = err.Error()
-		eventbus.DefaultBus.Publish(eventbus.Event{
-			Type:    constant.EventNotifySent,
-			Payload: payload,
-		})
-		return &NotifyResult{Success: false, Error: err.Error()}
+		"success":      errMsg == "",
+		"error_msg":    errMsg,
+		"suppressed":   suppressed,
+		"attempts":     attempts,
+		// 最终仍失败的消息写入死信记录
+		"dead_letter": track && errMsg != "" && channel.ID != "",
 	}
-
-	if !result.Success {
-		payload["error_msg"] = result.Error
-		eventbus.DefaultBus.Publish(eventbus.Event{
-			Type:    constant.EventNotifySent,
-			Payload: payload,
-		})
-		return &NotifyResult{Success: false, Error: result.Error}
-	}
-
-	payload["success"] = true
 	eventbus.DefaultBus.Publish(eventbus.Event{
 		Type:    constant.EventNotifySent,
 		Payload: payload,
 	})
 
+	if errMsg != "" {
+		return &NotifyResult{Success: false, Error: errMsg}
+	}
 	return &NotifyResult{Success: true}
 }
 
-// SendByChannelID 根据渠道ID发送通知
+// SendByChannelID 根据渠道ID发送通知，按渠道策略重试，失败重试期间会一直阻塞
 func (s *NotificationService) SendByChannelID(channelID string, msg *NotifyMessage) *NotifyResult {
+	ch, result := s.loadChannel(channelID)
+	if result != nil {
+		return result
+	}
+	return s.SendToChannel(ch, msg)
+}
+
+// SendByChannelIDWithin 根据渠道ID发送通知，最多等待 wait；
+// 超时仍未完成（通常是在等待重试）时返回 Pending 结果，发送在后台继续，最终结果见推送记录
+func (s *NotificationService) SendByChannelIDWithin(channelID string, msg *NotifyMessage, wait time.Duration) *NotifyResult {
+	ch, result := s.loadChannel(channelID)
+	if result != nil {
+		return result
+	}
+
+	done := make(chan *NotifyResult, 1)
+	go func() { done <- s.SendToChannel(ch, msg) }()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result
+	case <-timer.C:
+		return &NotifyResult{Success: false, Pending: true, Error: "发送尚未完成，正在后台按渠道策略重试"}
+	}
+}
+
+// loadChannel 读取发送所需的渠道配置；只在读取期间持有锁，避免发送重试时阻塞渠道的增删改
+func (s *NotificationService) loadChannel(channelID string) (NotifyChannel, *NotifyResult) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	var notifyWay models.NotifyWay
 	res := database.DB.Where("id = ?", channelID).Limit(1).Find(&notifyWay)
 	if res.Error != nil || res.RowsAffected == 0 {
-		return &NotifyResult{Success: false, Error: "渠道不存在"}
+		return NotifyChannel{}, &NotifyResult{Success: false, Error: "渠道不存在"}
 	}
 
 	if !utils.DerefBool(notifyWay.Enabled, true) {
-		return &NotifyResult{Success: false, Error: "渠道已禁用"}
+		return NotifyChannel{}, &NotifyResult{Success: false, Error: "渠道已禁用"}
 	}
 
 	var config map[string]string
 	if err := json.Unmarshal([]byte(notifyWay.Config), &config); err != nil {
-		return &NotifyResult{Success: false, Error: "渠道配置解析失败"}
+		return NotifyChannel{}, &NotifyResult{Success: false, Error: "渠道配置解析失败"}
 	}
 
-	ch := NotifyChannel{
+	return NotifyChannel{
 		ID:      notifyWay.ID,
 		Name:    notifyWay.Name,
 		Type:    notifyWay.Type,
 		Enabled: utils.DerefBool(notifyWay.Enabled, true),
 		Config:  config,
-	}
-	return s.SendToChannel(ch, msg)
+		Retry:   parseRetryPolicy(notifyWay),
+	}, nil
 }
 
 // SubscribeEvents 注册通知服务自身为事件流的订阅者
 func (s *NotificationService) SubscribeEvents(bus *eventbus.EventBus) {
 	// 系统事件
-	systemEvents := []string{constant.EventUserLogin, constant.EventBruteForceLogin, constant.EventPasswordChanged}
+	systemEvents := []string{
+		constant.EventUserLogin, constant.EventBruteForceLogin, constant.EventPasswordChanged,
+		constant.EventAgentOnline, constant.EventAgentOffline, constant.EventAgentOverloaded, constant.EventDiskLow,
+		constant.EventBackupSuccess, constant.EventBackupFailed, constant.EventDependencyFailed, constant.EventPanelStarted,
+	}
 	for _, evt := range systemEvents {
-		bus.Subscribe(evt, s.handleEvent(constant.BindingTypeSystem))
+		bus.SubscribeWithError(evt, "notify", s.handleEvent(constant.BindingTypeSystem))
 	}
 
 	// 任务事件
-	taskEvents := []string{constant.EventTaskSuccess, constant.EventTaskFailed, constant.EventTaskTimeout}
+	taskEvents := []string{constant.EventTaskSuccess, constant.EventTaskFailed, constant.EventTaskTimeout, constant.EventTaskRecovered}
 	for _, evt := range taskEvents {
-		bus.Subscribe(evt, s.handleEvent(constant.BindingTypeTask))
+		bus.SubscribeWithError(evt, "notify", s.handleEvent(constant.BindingTypeTask))
+	}
+
+	// 心跳检测事件
+	checkEvents := []string{constant.EventCheckDown, constant.EventCheckUp}
+	for _, evt := range checkEvents {
+		bus.SubscribeWithError(evt, "notify", s.handleEvent(constant.BindingTypeCheck))
 	}
 
 	// 通用系统通知
-	bus.Subscribe(constant.EventSystemNotice, s.handleEvent(constant.BindingTypeSystem))
+	bus.SubscribeWithError(constant.EventSystemNotice, "notify", s.handleEvent(constant.BindingTypeSystem))
 }
 
 var ansiRegexp = regexp.MustCompile(`[\x1b\x9b][\[()#;?]*([0-9]{1,4}(;[0-9]{0,4})*)?[0-9A-ORZcf-nqry=><]`)
@@ -326,13 +472,13 @@ func stripAnsi(str string) string {
 	return ansiRegexp.ReplaceAllString(str, "")
 }
 
-// parseTemplate 简单的 {{key}} 模板替换
+// parseTemplate 渲染通知模板（text/template，兼容旧版 {{key}} 写法）
+// 渲染失败时返回空字符串，由调用方回退到默认消息
 func (s *NotificationService) parseTemplate(tmpl string, payload map[string]interface{}) string {
-	result := tmpl
-	for k, v := range payload {
-		placeholder := fmt.Sprintf("{{%s}}", k)
-		valStr := fmt.Sprintf("%v", v)
-		result = strings.ReplaceAll(result, placeholder, valStr)
+	result, err := RenderNotifyTemplate(tmpl, payload)
+	if err != nil {
+		logger.Warnf("[Notify] 渲染通知模板失败: %v", err)
+		return ""
 	}
 	return result
 }
@@ -370,21 +516,59 @@ func (s *NotificationService) getDefaultMessage(eventType string, payload map[st
 	case constant.EventTaskTimeout:
 		title = fmt.Sprintf("任务[%v] 超时", payload["task_name"])
 		text = fmt.Sprintf("任务 #%v %v\n执行超时\n执行时间: %v\n耗时: %vms", payload["task_id"], payload["task_name"], payload["start_time"], payload["duration"])
+	case constant.EventTaskRecovered:
+		title = fmt.Sprintf("任务[%v] 已恢复", payload["task_name"])
+		text = fmt.Sprintf("任务 #%v %v\n状态: 已恢复（此前连续失败 %v 次）\n执行时间: %v\n耗时: %vms", payload["task_id"], payload["task_name"], payload["previous_failures"], payload["start_time"], payload["duration"])
+	case constant.EventCheckDown:
+		title = fmt.Sprintf("心跳[%v] 异常", payload["check_name"])
+		text = fmt.Sprintf("心跳检测 %v\n状态: 异常\n原因: %v\n最后上报: %v", payload["check_name"], payload["reason"], payload["last_ping"])
+	case constant.EventCheckUp:
+		title = fmt.Sprintf("心跳[%v] 恢复", payload["check_name"])
+		text = fmt.Sprintf("心跳检测 %v\n状态: 已恢复\n最后上报: %v", payload["check_name"], payload["last_ping"])
+	case constant.EventAgentOnline:
+		title = fmt.Sprintf("Agent[%v] 上线", payload["agent_name"])
+		text = fmt.Sprintf("Agent %v (#%v) 已上线\nIP: %v", payload["agent_name"], payload["agent_id"], payload["ip"])
+	case constant.EventAgentOffline:
+		title = fmt.Sprintf("Agent[%v] 离线", payload["agent_name"])
+		text = fmt.Sprintf("Agent %v (#%v) 已离线\nIP: %v\n原因: %v", payload["agent_name"], payload["agent_id"], payload["ip"], payload["reason"])
+	case constant.EventAgentOverloaded:
+		title = fmt.Sprintf("Agent[%v] 负载过高", payload["agent_name"])
+		text = fmt.Sprintf("Agent %v (#%v) %v，暂停派发任务", payload["agent_name"], payload["agent_id"], payload["reason"])
+	case constant.EventDiskLow:
+		title = "磁盘空间不足"
+		text = fmt.Sprintf("数据目录 %v 剩余空间 %v（%v%%）", payload["path"], payload["free"], payload["free_percent"])
+	case constant.EventBackupSuccess:
+		title = "备份完成"
+		text = fmt.Sprintf("备份文件: %v\n大小: %v", payload["file"], payload["size"])
+	case constant.EventBackupFailed:
+		title = "备份失败"
+		text = fmt.Sprintf("面板数据备份失败\n错误: %v", payload["error"])
+	case constant.EventDependencyFailed:
+		title = fmt.Sprintf("依赖[%v] 安装失败", payload["name"])
+		text = fmt.Sprintf("语言: %v\n依赖: %v\n错误: %v", payload["language"], payload["name"], payload["error"])
+	case constant.EventPanelStarted:
+		title = "面板已启动"
+		text = fmt.Sprintf("白虎面板 %v 已启动\n启动时间: %v", payload["version"], payload["time"])
 	}
 	return title, text
 }
 
 // handleEvent 处理事件订阅并发送通知
-func (s *NotificationService) handleEvent(bindingType string) eventbus.Handler {
-	return func(e eventbus.Event) {
-		payload, ok := e.Payload.(map[string]interface{})
+// 各渠道并行发送，句柄等待本次发送（含重试）全部结束，发送失败的渠道作为错误返回给总线记录，持久化的事件可重放
+func (s *NotificationService) handleEvent(bindingType string) eventbus.ErrorHandler {
+	return func(e eventbus.Event) error {
+		shared, ok := e.Payload.(map[string]interface{})
 		if !ok {
-			return
+			return nil
 		}
+		// 事件数据由 Webhook、SSE 等订阅者在其他协程并发读取，补充的字段只写入本地副本
+		payload := maps.Clone(shared)
 
 		var dataID string
 		if id, ok := payload["task_id"].(string); ok {
 			dataID = id
+		} else if id, ok := payload["check_id"].(string); ok {
+			dataID = id
 		}
 
 		var title, text string
@@ -413,7 +597,7 @@ func (s *NotificationService) handleEvent(bindingType string) eventbus.Handler {
 			tmplTitleKey = constant.KeyNotifyTemplatePasswordChangedTitle
 			tmplTextKey = constant.KeyNotifyTemplatePasswordChangedText
 
-		case constant.EventTaskSuccess, constant.EventTaskFailed, constant.EventTaskTimeout:
+		case constant.EventTaskSuccess, constant.EventTaskFailed, constant.EventTaskTimeout, constant.EventTaskRecovered:
 			switch e.Type {
 			case constant.EventTaskSuccess:
 				tmplTitleKey = constant.KeyNotifyTemplateTaskSuccessTitle
@@ -424,6 +608,9 @@ func (s *NotificationService) handleEvent(bindingType string) eventbus.Handler {
 			case constant.EventTaskTimeout:
 				tmplTitleKey = constant.KeyNotifyTemplateTaskTimeoutTitle
 				tmplTextKey = constant.KeyNotifyTemplateTaskTimeoutText
+			case constant.EventTaskRecovered:
+				tmplTitleKey = constant.KeyNotifyTemplateTaskRecoveredTitle
+				tmplTextKey = constant.KeyNotifyTemplateTaskRecoveredText
 			}
 
 			// 处理输出内容，避免过长
@@ -435,11 +622,51 @@ func (s *NotificationService) handleEvent(bindingType string) eventbus.Handler {
 				}
 			}
 
+		case constant.EventCheckDown:
+			tmplTitleKey = constant.KeyNotifyTemplateCheckDownTitle
+			tmplTextKey = constant.KeyNotifyTemplateCheckDownText
+
+		case constant.EventCheckUp:
+			tmplTitleKey = constant.KeyNotifyTemplateCheckUpTitle
+			tmplTextKey = constant.KeyNotifyTemplateCheckUpText
+
+		case constant.EventAgentOnline:
+			tmplTitleKey = constant.KeyNotifyTemplateAgentOnlineTitle
+			tmplTextKey = constant.KeyNotifyTemplateAgentOnlineText
+
+		case constant.EventAgentOffline:
+			tmplTitleKey = constant.KeyNotifyTemplateAgentOfflineTitle
+			tmplTextKey = constant.KeyNotifyTemplateAgentOfflineText
+
+		case constant.EventAgentOverloaded:
+			tmplTitleKey = constant.KeyNotifyTemplateAgentOverloadedTitle
+			tmplTextKey = constant.KeyNotifyTemplateAgentOverloadedText
+
+		case constant.EventDiskLow:
+			tmplTitleKey = constant.KeyNotifyTemplateDiskLowTitle
+			tmplTextKey = constant.KeyNotifyTemplateDiskLowText
+
+		case constant.EventBackupSuccess:
+			tmplTitleKey = constant.KeyNotifyTemplateBackupSuccessTitle
+			tmplTextKey = constant.KeyNotifyTemplateBackupSuccessText
+
+		case constant.EventBackupFailed:
+			tmplTitleKey = constant.KeyNotifyTemplateBackupFailedTitle
+			tmplTextKey = constant.KeyNotifyTemplateBackupFailedText
+
+		case constant.EventDependencyFailed:
+			tmplTitleKey = constant.KeyNotifyTemplateDependencyFailedTitle
+			tmplTextKey = constant.KeyNotifyTemplateDependencyFailedText
+
+		case constant.EventPanelStarted:
+			tmplTitleKey = constant.KeyNotifyTemplatePanelStartedTitle
+			tmplTextKey = constant.KeyNotifyTemplatePanelStartedText
+
 		case constant.EventSystemNotice:
 			title, _ = payload["title"].(string)
 			text, _ = payload["content"].(string)
 		default:
-			return
+			return nil
 		}
 
 		if tmplTitleKey != "" {
@@ -459,14 +686,24 @@ func (s *NotificationService) handleEvent(bindingType string) eventbus.Handler {
 			}
 		}
 
+		// 摘要中使用不带前缀的标题；系统事件标题相同，附带正文首行便于区分
+		summary := title
+		if dataID == "" && text != "" {
+			summary += ": " + strings.SplitN(text, "\n", 2)[0]
+		}
+
 		// 添加全局前缀
 		if prefix != "" {
 			title = fmt.Sprintf("%s %s", prefix, title)
 		}
 
 		bindings := s.GetBindingsByEvent(bindingType, e.Type, dataID)
+		// 任务事件额外匹配路由规则，新建的任务无需单独绑定即可继承告警
+		if bindingType == constant.BindingTypeTask {
+			bindings = append(bindings, s.GetRouteBindings(e.Type, dataID, bindings)...)
+		}
 		if len(bindings) == 0 {
-			return
+			return nil
 		}
 
 		channels := s.GetChannels()
@@ -475,6 +712,9 @@ func (s *NotificationService) handleEvent(bindingType string) eventbus.Handler {
 			channelMap[ch.ID] = ch
 		}
 
+		var wg sync.WaitGroup
+		var errMu sync.Mutex
+		var errs []error
 		for _, binding := range bindings {
 			ch, ok := channelMap[binding.WayID]
 			if !ok || !ch.Enabled {
@@ -494,11 +734,17 @@ func (s *NotificationService) handleEvent(bindingType string) eventbus.Handler {
 				extra.LogLimit = 1000
 			}
 
+			// 绑定条件（连续失败次数、仅状态变化时通知）
+			if !matchBindingCondition(e.Type, &extra, payload) {
+				continue
+			}
+
 			// 如果开启了日志推送
+			var logSnippet string
 			if extra.EnableLog {
 				if output, ok := payload["output"].(string); ok && output != "" {
 					// 仅保留指定字数的日志内容并移除 ANSI 颜色代码
-					logSnippet := stripAnsi(output)
+					logSnippet = stripAnsi(output)
 					if len(logSnippet) > extra.LogLimit {
 						logSnippet = "...\n" + logSnippet[len(logSnippet)-extra.LogLimit:]
 					}
@@ -506,14 +752,207 @@ func (s *NotificationService) handleEvent(bindingType string) eventbus.Handler {
 				}
 			}
 
-			go func(channel NotifyChannel, msgTitle, msgText string) {
-				result := s.SendToChannel(channel, &NotifyMessage{Title: msgTitle, Text: msgText})
+			// 节流：绑定上配置了规则则按绑定节流，否则按渠道节流
+			rule, scopeKey := extra.Throttle, bindingThrottleScope(binding.ID)
+			if !rule.Enabled() {
+				rule, scopeKey = ch.Throttle, channelThrottleScope(ch.ID)
+			}
+			// 去重键：任务/心跳事件按对象与标题判断，系统事件没有关联对象，需连同正文一起判断
+			dedupeKey := e.Type + "|" + dataID + "|" + summary
+			if dataID == "" {
+				dedupeKey = e.Type + "|" + summary + "|" + text
+			}
+			decision, suppressed := s.throttler.Check(scopeKey, rule, dedupeKey, summary, s.digestSender(ch, prefix))
+			if decision != throttleSend {
+				logger.Debugf("[Notify] 事件 %s 到渠道 %s 的通知被节流: %s", e.Type, ch.Name, decision)
+				continue
+			}
+
+			msg := s.buildBindingMessage(&extra, payload, title, currentText, logSnippet)
+
+			// 非紧急通知在渠道静默时段内暂存，时段结束后汇总发送；紧急通知可在未确认时升级
+			if extra.Severity != constant.NotifySeverityCritical {
+				if until, quiet := ch.QuietHours.Until(time.Now()); quiet {
+					s.quietQueue.Hold(ch.ID, until, summary, s.quietSender(ch, prefix))
+					logger.Debugf("[Notify] 渠道 %s 处于静默时段，事件 %s 的通知将在 %s 汇总发送", ch.Name, e.Type, until.Format("15:04"))
+					continue
+				}
+			} else if extra.EscalateWayID != "" && extra.EscalateAfter > 0 {
+				s.attachAck(msg, e.Type, dataID, ch.ID, &extra)
+			}
+
+			wg.Add(1)
+			go func(channel NotifyChannel, msg *NotifyMessage, suppressed int) {
+				defer wg.Done()
+				result := s.sendToChannel(channel, msg, suppressed)
 				if !result.Success {
 					logger.Warnf("[Notify] 发送事件 %s 到渠道 %s(%s) 失败: %s", e.Type, channel.Name, channel.Type, result.Error)
+					errMu.Lock()
+					errs = append(errs, fmt.Errorf("渠道 %s: %s", channel.Name, result.Error))
+					errMu.Unlock()
 				}
-			}(ch, title, currentText)
+			}(ch, msg, suppressed)
+		}
+		wg.Wait()
+		return errors.Join(errs...)
+	}
+}
+
+// buildBindingMessage 根据绑定上的富文本配置构造消息，模板渲染失败的字段会被忽略
+func (s *NotificationService) buildBindingMessage(extra *models.BindingExtra, payload map[string]interface{}, title, text, logSnippet string) *NotifyMessage {
+	msg := &NotifyMessage{
+		Title:     title,
+		Text:      text,
+		AtMobiles: extra.AtMobiles,
+		AtUserIds: extra.AtUserIds,
+		AtAll:     extra.AtAll,
+		Fields:    buildNotifyFields(payload),
+	}
+
+	render := func(field, tmpl string) string {
+		if tmpl == "" {
+			return ""
+		}
+		out, err := RenderNotifyTemplate(tmpl, payload)
+		if err != nil {
+			logger.Warnf("[Notify] 渲染绑定的 %s 模板失败: %v", field, err)
+			return ""
 		}
+		return strings.TrimSpace(out)
 	}
+
+	msg.Markdown = render("markdown", extra.Markdown)
+	msg.URL = render("url", extra.URL)
+	msg.ImageURL = render("image_url", extra.ImageURL)
+	if msg.Markdown != "" && logSnippet != "" {
+		msg.Markdown += "\n\n**执行日志**\n```\n" + logSnippet + "\n```"
+	}
+	return msg
+}
+
+// buildNotifyFields 从事件数据中提取对象、状态、耗时等结构化字段
+func buildNotifyFields(payload map[string]interface{}) []NotifyField {
+	var fields []NotifyField
+	add := func(name string, v interface{}) {
+		if value := toString(v); value != "" {
+			fields = append(fields, NotifyField{Name: name, Value: value})
+		}
+	}
+	add("任务", payload["task_name"])
+	add("检测", payload["check_name"])
+	add("Agent", payload["agent_name"])
+	add("状态", payload["status"])
+	if _, ok := payload["duration"]; ok {
+		add("耗时", formatNotifyDuration(payload["duration"]))
+	}
+	if n := utils.ToIntValue(payload["consecutive_failures"]); n > 0 {
+		add("连续失败", n)
+	}
+	return fields
+}
+
+// matchBindingCondition 判断事件是否满足绑定上配置的触发条件
+func matchBindingCondition(eventType string, extra *models.BindingExtra, payload map[string]interface{}) bool {
+	// 达到多少次连续失败才算进入失败状态
+	threshold := extra.MinFailures
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	switch eventType {
+	case constant.EventTaskFailed, constant.EventTaskTimeout:
+		failures, ok := payload["consecutive_failures"]
+		if !ok {
+			return true
+		}
+		n := utils.ToIntValue(failures)
+		if extra.StateChangeOnly {
+			// 只在刚进入失败状态时通知一次
+			return n == threshold
+		}
+		return n >= threshold
+
+	case constant.EventTaskSuccess:
+		if !extra.StateChangeOnly {
+			return true
+		}
+		// 仅当此前的失败已达到通知条件时，才发送成功（恢复）通知
+		return utils.ToIntValue(payload["previous_failures"]) >= threshold
+
+	case constant.EventTaskRecovered:
+		return utils.ToIntValue(payload["previous_failures"]) >= threshold
+	}
+	return true
+}
+
+// digestSender 返回摘要到期时的发送函数
+func (s *NotificationService) digestSender(channel NotifyChannel, prefix string) digestFlushFunc {
+	return func(items []string, suppressed int) {
+		// 摘要到期时渠道已进入静默时段，则并入静默汇总
+		if until, quiet := channel.QuietHours.Until(time.Now()); quiet {
+			for _, item := range items {
+				s.quietQueue.Hold(channel.ID, until, item, s.quietSender(channel, prefix))
+			}
+			return
+		}
+		title := fmt.Sprintf("通知摘要 (%d 条)", len(items))
+		if len(items) == 0 {
+			title = fmt.Sprintf("通知摘要 (已忽略 %d 条)", suppressed)
+		}
+		if prefix != "" {
+			title = fmt.Sprintf("%s %s", prefix, title)
+		}
+		// 合并在摘要中的消息已送达，推送记录只计入被忽略的数量
+		result := s.sendToChannel(channel, &NotifyMessage{Title: title, Text: buildDigestText("限流期间", items, suppressed)}, suppressed)
+		if !result.Success {
+			logger.Warnf("[Notify] 发送摘要到渠道 %s(%s) 失败: %s", channel.Name, channel.Type, result.Error)
+		}
+	}
+}
+
+// quietSender 返回静默时段结束时的汇总发送函数
+func (s *NotificationService) quietSender(channel NotifyChannel, prefix string) quietFlushFunc {
+	return func(items []string) {
+		title := fmt.Sprintf("静默时段通知汇总 (%d 条)", len(items))
+		if prefix != "" {
+			title = fmt.Sprintf("%s %s", prefix, title)
+		}
+		result := s.sendToChannel(channel, &NotifyMessage{Title: title, Text: buildDigestText("静默时段内", items, 0)}, len(items))
+		if !result.Success {
+			logger.Warnf("[Notify] 发送静默汇总到渠道 %s(%s) 失败: %s", channel.Name, channel.Type, result.Error)
+		}
+	}
+}
+
+// RestoreQuietQueue 面板启动时为静默时段内暂存的通知重新安排汇总发送
+func (s *NotificationService) RestoreQuietQueue() {
+	s.mu.RLock()
+	channels := s.getChannelsInternal()
+	s.mu.RUnlock()
+
+	byID := make(map[string]NotifyChannel, len(channels))
+	for _, ch := range channels {
+		byID[ch.ID] = ch
+	}
+	prefix := s.settingsService.Get(constant.SectionNotify, constant.KeyNotifyPrefix)
+	restored := s.quietQueue.Restore(func(channelID string) quietFlushFunc {
+		ch, ok := byID[channelID]
+		if !ok {
+			return nil
+		}
+		return s.quietSender(ch, prefix)
+	})
+	if restored > 0 {
+		logger.Infof("[Notify] 已恢复 %d 个渠道的静默时段暂存通知", restored)
+	}
+}
+
+func channelThrottleScope(channelID string) string {
+	return "channel:" + channelID
+}
+
+func bindingThrottleScope(bindingID string) string {
+	return "binding:" + bindingID
 }
 
 // --- 内部方法 ---
@@ -530,13 +969,32 @@ func (s *NotificationService) getChannelsInternal() []NotifyChannel {
 			logger.Warnf("[Notify] 解析渠道 %s 配置失败: %v", nw.ID, err)
 			continue
 		}
+		var throttle *models.ThrottleRule
+		if nw.Throttle != "" {
+			throttle = &models.ThrottleRule{}
+			if err := json.Unmarshal([]byte(nw.Throttle), throttle); err != nil {
+				logger.Warnf("[Notify] 解析渠道 %s 节流规则失败: %v", nw.ID, err)
+				throttle = nil
+			}
+		}
+		var quiet *models.QuietHours
+		if nw.QuietHours != "" {
+			quiet = &models.QuietHours{}
+			if err := json.Unmarshal([]byte(nw.QuietHours), quiet); err != nil {
+				logger.Warnf("[Notify] 解析渠道 %s 静默时段失败: %v", nw.ID, err)
+				quiet = nil
+			}
+		}
 		channels = append(channels, NotifyChannel{
-			ID:        nw.ID,
-			Name:      nw.Name,
-			Type:      nw.Type,
-			Enabled:   utils.DerefBool(nw.Enabled, true),
-			CreatedAt: nw.CreatedAt,
-			Config:    config,
+			ID:         nw.ID,
+			Name:       nw.Name,
+			Type:       nw.Type,
+			Enabled:    utils.DerefBool(nw.Enabled, true),
+			CreatedAt:  nw.CreatedAt,
+			Config:     config,
+			Throttle:   throttle,
+			QuietHours: quiet,
+			Retry:      parseRetryPolicy(nw),
 		})
 	}
 	return channels
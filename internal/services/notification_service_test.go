@@ -0,0 +1,170 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/sdk/messenger"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// setupNotifyTestDB 在临时目录中准备 SQLite 数据库并迁移指定的表
+func setupNotifyTestDB(t *testing.T, tables ...interface{}) {
+	t.Helper()
+	if err := database.Init(&database.Config{Type: "sqlite", Path: filepath.Join(t.TempDir(), "test.db")}); err != nil {
+		t.Fatal(err)
+	}
+	// 后台发送的协程可能在测试结束后访问 database.DB，只关闭连接不还原为 nil
+	db := database.DB
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendByChannelIDWithinDoesNotBlockChannelChanges(t *testing.T) {
+	setupNotifyTestDB(t, &models.NotifyWay{}, &models.NotifyBinding{}, &models.NotifyRoute{},
+		&models.NotifyChannelStat{}, &models.NotifyQuietItem{})
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	way := &models.NotifyWay{
+		ID:      "w1",
+		Name:    "webhook",
+		Type:    messenger.ChannelCustom,
+		Enabled: utils.BoolPtr(true),
+		Config:  models.BigText(`{"webhook":"` + srv.URL + `"}`),
+		Retry:   `{"times":1,"interval":1}`,
+	}
+	if err := database.DB.Create(way).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewNotificationService()
+	result := s.SendByChannelIDWithin("w1", &NotifyMessage{Title: "t", Text: "x"}, 200*time.Millisecond)
+	if result.Success || !result.Pending {
+		t.Fatalf("expected pending result while retrying, got %+v", result)
+	}
+
+	// 后台仍在等待重试，渠道的删除不应被阻塞
+	done := make(chan error, 1)
+	go func() { done <- s.DeleteChannel("w1") }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("DeleteChannel blocked by an in-flight send")
+	}
+
+	// 等待后台重试结束，避免协程在测试结束后继续运行
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&hits) < 2 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Fatalf("expected 2 attempts, got %d", n)
+	}
+}
+
+// 事件通知发送失败时，错误应交给总线的 ErrorReporter 记录（以便持久化的事件可重放）
+func TestNotifyEventSendErrorReachesReporter(t *testing.T) {
+	setupNotifyTestDB(t, &models.Setting{}, &models.NotifyWay{}, &models.NotifyBinding{}, &models.NotifyRoute{},
+		&models.NotifyChannelStat{}, &models.NotifyQuietItem{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	way := &models.NotifyWay{
+		ID:      "w1",
+		Name:    "webhook",
+		Type:    messenger.ChannelCustom,
+		Enabled: utils.BoolPtr(true),
+		Config:  models.BigText(`{"webhook":"` + srv.URL + `"}`),
+	}
+	binding := &models.NotifyBinding{ID: "b1", Type: constant.BindingTypeSystem, Event: constant.EventPanelStarted, WayID: "w1"}
+	if err := database.DB.Create(way).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Create(binding).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	bus := eventbus.New()
+	var mu sync.Mutex
+	var reported []string
+	bus.SetErrorReporter(func(event eventbus.Event, handler string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, handler)
+	})
+	NewNotificationService().SubscribeEvents(bus)
+
+	bus.Publish(eventbus.Event{Type: constant.EventPanelStarted, Payload: map[string]interface{}{"version": "v1"}})
+	if !bus.Close(5 * time.Second) {
+		t.Fatal("close timed out")
+	}
+	if len(reported) != 1 || reported[0] != "notify" {
+		t.Fatalf("expected the notify handler error to be reported, got %v", reported)
+	}
+}
+
+// 只有网络错误、超时、429 与 5xx 才按渠道策略重试，4xx 等重试也不会成功的错误只发送一次
+func TestDeliverRetriesOnlyTransientErrors(t *testing.T) {
+	cases := []struct {
+		status int
+		want   int32
+	}{
+		{http.StatusBadRequest, 1},
+		{http.StatusTooManyRequests, 2},
+		{http.StatusBadGateway, 2},
+	}
+	for _, c := range cases {
+		var hits int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits, 1)
+			w.WriteHeader(c.status)
+		}))
+		ch := NotifyChannel{
+			Name:   "webhook",
+			Type:   messenger.ChannelCustom,
+			Config: map[string]string{"webhook": srv.URL},
+			Retry:  &models.RetryPolicy{Times: 1, Interval: 1},
+		}
+		errMsg, attempts := NewNotificationService().deliver(ch, &NotifyMessage{Title: "t", Text: "x"}, true)
+		srv.Close()
+		if errMsg == "" || int32(attempts) != c.want || atomic.LoadInt32(&hits) != c.want {
+			t.Fatalf("status %d: attempts=%d hits=%d err=%q, want %d attempts", c.status, attempts, hits, errMsg, c.want)
+		}
+	}
+
+	// 连接被拒绝属于网络错误，同样会重试
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+	ch := NotifyChannel{Name: "webhook", Type: messenger.ChannelCustom, Config: map[string]string{"webhook": url}, Retry: &models.RetryPolicy{Times: 1, Interval: 1}}
+	if _, attempts := NewNotificationService().deliver(ch, &NotifyMessage{Title: "t", Text: "x"}, true); attempts != 2 {
+		t.Fatalf("connection refused: attempts=%d, want 2", attempts)
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/sdk/messenger"
+	"gorm.io/gorm"
+)
+
+// ChannelStats 渠道发送统计
+type ChannelStats struct {
+	Total         int64             `json:"total"`
+	Success       int64             `json:"success"`
+	Failed        int64             `json:"failed"`
+	Retries       int64             `json:"retries"`      // 重试请求次数
+	SuccessRate   float64           `json:"success_rate"` // 成功率（百分比）
+	AvgLatency    int64             `json:"avg_latency"`  // 单次请求平均耗时（毫秒）
+	LastError     string            `json:"last_error,omitempty"`
+	LastErrorAt   *models.LocalTime `json:"last_error_at,omitempty"`
+	LastSuccessAt *models.LocalTime `json:"last_success_at,omitempty"`
+}
+
+// notifyStatMu 保证统计记录的创建与累加不会并发冲突
+var notifyStatMu sync.Mutex
+
+// deliver 发送消息，retry 为 true 时遇到临时故障按渠道的重试策略退避重试，返回最终错误与请求次数
+func (s *NotificationService) deliver(channel NotifyChannel, msg *NotifyMessage, retry bool) (string, int) {
+	// messenger.Send 会按渠道支持的格式处理 Markdown / HTML / 链接 / @ 提醒的降级
+	m := &messenger.Message{
+		Title:     msg.Title,
+		Text:      msg.Text,
+		Markdown:  msg.Markdown,
+		HTML:      msg.HTML,
+		URL:       msg.URL,
+		ImageURL:  msg.ImageURL,
+		AtMobiles: msg.AtMobiles,
+		AtUserIds: msg.AtUserIds,
+		AtAll:     msg.AtAll,
+	}
+	// 结构化字段目前只有 Discord 以 Embed 字段展示；其他渠道的 Extra 另有用途（如短信模板参数），不附带
+	if len(msg.Fields) > 0 && channel.Type == messenger.ChannelDiscord {
+		fields := make([]any, 0, len(msg.Fields))
+		for _, f := range msg.Fields {
+			fields = append(fields, map[string]any{"name": f.Name, "value": f.Value, "inline": true})
+		}
+		m.Extra = map[string]any{"fields": fields}
+	}
+
+	maxAttempts := 1
+	if retry && channel.Retry.Enabled() {
+		maxAttempts += channel.Retry.Times
+	}
+
+	var errMsg string
+	var latency time.Duration
+	attempts := 0
+	for attempts < maxAttempts {
+		if attempts > 0 {
+			delay := channel.Retry.Delay(attempts)
+			logger.Warnf("[Notify] 渠道 %s 发送失败，%v 后进行第 %d 次重试: %s", channel.Name, delay, attempts, errMsg)
+			time.Sleep(delay)
+		}
+		attempts++
+
+		start := time.Now()
+		result, err := messenger.Send(channel.Type, messenger.ChannelConfig(channel.Config), m)
+		latency += time.Since(start)
+
+		retryable := false
+		switch {
+		case err != nil:
+			errMsg = err.Error()
+		case !result.Success:
+			errMsg = result.Error
+			retryable = result.Retryable
+		default:
+			errMsg = ""
+		}
+		// 只有网络错误、超时、HTTP 429 与 5xx 值得重试；配置错误、4xx 等重试也只会得到同样的结果
+		if errMsg == "" || !retryable {
+			break
+		}
+	}
+
+	if retry {
+		recordChannelStat(channel.ID, errMsg, attempts, latency)
+	}
+	return errMsg, attempts
+}
+
+// recordChannelStat 累加渠道发送统计
+func recordChannelStat(wayID, errMsg string, attempts int, latency time.Duration) {
+	if wayID == "" {
+		return
+	}
+	notifyStatMu.Lock()
+	defer notifyStatMu.Unlock()
+
+	now := models.Now()
+	updates := map[string]interface{}{
+		"attempt_count": gorm.Expr("attempt_count + ?", attempts),
+		"total_latency": gorm.Expr("total_latency + ?", latency.Milliseconds()),
+	}
+	if errMsg == "" {
+		updates["success_count"] = gorm.Expr("success_count + 1")
+		updates["last_success_at"] = &now
+	} else {
+		updates["failed_count"] = gorm.Expr("failed_count + 1")
+		updates["last_error"] = models.BigText(errMsg)
+		updates["last_error_at"] = &now
+	}
+
+	res := database.DB.Model(&models.NotifyChannelStat{}).Where("way_id = ?", wayID).Updates(updates)
+	if res.Error != nil {
+		logger.Errorf("[Notify] 更新渠道统计失败: %v", res.Error)
+		return
+	}
+	if res.RowsAffected > 0 {
+		return
+	}
+
+	stat := &models.NotifyChannelStat{
+		WayID:        wayID,
+		AttemptCount: int64(attempts),
+		TotalLatency: latency.Milliseconds(),
+	}
+	if errMsg == "" {
+		stat.SuccessCount = 1
+		stat.LastSuccessAt = &now
+	} else {
+		stat.FailedCount = 1
+		stat.LastError = models.BigText(errMsg)
+		stat.LastErrorAt = &now
+	}
+	if err := database.DB.Create(stat).Error; err != nil {
+		logger.Errorf("[Notify] 创建渠道统计失败: %v", err)
+	}
+}
+
+// loadChannelStats 读取全部渠道的发送统计
+func loadChannelStats() map[string]*ChannelStats {
+	var rows []models.NotifyChannelStat
+	database.DB.Find(&rows)
+
+	stats := make(map[string]*ChannelStats, len(rows))
+	for _, r := range rows {
+		st := &ChannelStats{
+			Total:         r.SuccessCount + r.FailedCount,
+			Success:       r.SuccessCount,
+			Failed:        r.FailedCount,
+			LastError:     string(r.LastError),
+			LastErrorAt:   r.LastErrorAt,
+			LastSuccessAt: r.LastSuccessAt,
+		}
+		if st.Total > 0 {
+			st.SuccessRate = float64(r.SuccessCount*10000/st.Total) / 100
+			st.Retries = r.AttemptCount - st.Total
+		}
+		if r.AttemptCount > 0 {
+			st.AvgLatency = r.TotalLatency / r.AttemptCount
+		}
+		stats[r.WayID] = st
+	}
+	return stats
+}
+
+// ResetChannelStats 清空渠道发送统计
+func (s *NotificationService) ResetChannelStats(id string) error {
+	return database.DB.Where("way_id = ?", id).Delete(&models.NotifyChannelStat{}).Error
+}
+
+// parseRetryPolicy 解析渠道的重试策略
+func parseRetryPolicy(nw models.NotifyWay) *models.RetryPolicy {
+	if nw.Retry == "" {
+		return nil
+	}
+	var retry models.RetryPolicy
+	if err := json.Unmarshal([]byte(nw.Retry), &retry); err != nil {
+		logger.Warnf("[Notify] 解析渠道 %s 重试策略失败: %v", nw.ID, err)
+		return nil
+	}
+	return &retry
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// notifyAckPath 告警确认页面路径（无需登录，打开后需在页面上提交才会确认）
+const notifyAckPath = "/api/v1/notify/ack/"
+
+// attachAck 为需要升级的紧急通知创建确认记录，并在消息中附加确认链接
+func (s *NotificationService) attachAck(msg *NotifyMessage, event, dataID, wayID string, extra *models.BindingExtra) {
+	ack := &models.NotifyAck{
+		ID:            utils.GenerateID(),
+		Token:         utils.RandomString(32),
+		Event:         event,
+		DataID:        dataID,
+		Title:         msg.Title,
+		Content:       models.BigText(msg.content()),
+		WayID:         wayID,
+		EscalateWayID: extra.EscalateWayID,
+		EscalateAt:    models.LocalTime(time.Now().Add(time.Duration(extra.EscalateAfter) * time.Minute)),
+		Status:        constant.NotifyAckPending,
+	}
+	if err := database.DB.Create(ack).Error; err != nil {
+		logger.Errorf("[Notify] 创建告警确认记录失败: %v", err)
+		return
+	}
+
+	line := fmt.Sprintf("%d 分钟内未确认将升级通知", extra.EscalateAfter)
+	if link := s.ackURL(ack.Token); link != "" {
+		line = fmt.Sprintf("确认告警: %s（%s）", link, line)
+	}
+	msg.Text += "\n\n" + line
+	if msg.Markdown != "" {
+		msg.Markdown += "\n\n" + line
+	}
+}
+
+// ackURL 生成确认链接，未配置面板对外地址时返回空
+func (s *NotificationService) ackURL(token string) string {
+	base := strings.TrimRight(s.settingsService.Get(constant.SectionNotify, constant.KeyNotifyPublicURL), "/")
+	if base == "" {
+		return ""
+	}
+	return base + notifyAckPath + token
+}
+
+// GetAckByToken 通过确认链接中的令牌查询告警，不改变确认状态
+func (s *NotificationService) GetAckByToken(token string) (*models.NotifyAck, error) {
+	var ack models.NotifyAck
+	if token == "" {
+		return nil, fmt.Errorf("确认链接无效")
+	}
+	if res := database.DB.Where("token = ?", token).Limit(1).Find(&ack); res.RowsAffected == 0 {
+		return nil, fmt.Errorf("确认链接无效")
+	}
+	return &ack, nil
+}
+
+// AckByToken 通过确认链接中的令牌确认告警
+func (s *NotificationService) AckByToken(token string) (*models.NotifyAck, error) {
+	ack, err := s.GetAckByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.ack(ack)
+}
+
+// AckByID 在面板中确认告警
+func (s *NotificationService) AckByID(id string) (*models.NotifyAck, error) {
+	var ack models.NotifyAck
+	if res := database.DB.Where("id = ?", id).Limit(1).Find(&ack); res.RowsAffected == 0 {
+		return nil, fmt.Errorf("告警记录不存在")
+	}
+	return s.ack(&ack)
+}
+
+func (s *NotificationService) ack(ack *models.NotifyAck) (*models.NotifyAck, error) {
+	if ack.Status == constant.NotifyAckAcked {
+		return ack, nil
+	}
+	now := models.Now()
+	// 已升级的告警仍允许确认，用于记录处理情况
+	if err := database.DB.Model(ack).Updates(map[string]interface{}{
+		"status":   constant.NotifyAckAcked,
+		"acked_at": &now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	ack.Status = constant.NotifyAckAcked
+	ack.AckedAt = &now
+	return ack, nil
+}
+
+// GetAcks 分页获取告警确认记录
+func (s *NotificationService) GetAcks(status string, page, size int) ([]models.NotifyAck, int64) {
+	var acks []models.NotifyAck
+	var total int64
+
+	query := database.DB.Model(&models.NotifyAck{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&acks)
+	return acks, total
+}
+
+// ProcessEscalations 将超时未确认的紧急通知升级发送到备用渠道
+func (s *NotificationService) ProcessEscalations() {
+	var acks []models.NotifyAck
+	database.DB.Where("status = ? AND escalate_at <= ?", constant.NotifyAckPending, models.Now()).
+		Order("id ASC").Limit(100).Find(&acks)
+
+	for _, ack := range acks {
+		// 条件更新，避免与确认操作并发时重复升级
+		res := database.DB.Model(&models.NotifyAck{}).
+			Where("id = ? AND status = ?", ack.ID, constant.NotifyAckPending).
+			Update("status", constant.NotifyAckEscalated)
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+
+		text := string(ack.Content) + "\n\n该告警发送后未在规定时间内确认，已升级通知"
+		if link := s.ackURL(ack.Token); link != "" {
+			text += "\n确认告警: " + link
+		}
+		// 升级渠道失败重试期间不阻塞其他告警的升级
+		go func(ack models.NotifyAck, text string) {
+			result := s.SendByChannelID(ack.EscalateWayID, &NotifyMessage{Title: "[升级] " + ack.Title, Text: text})
+			if !result.Success {
+				logger.Warnf("[Notify] 告警 %s 升级发送失败: %s", ack.ID, result.Error)
+			}
+		}(ack, text)
+	}
+}
+
+// StartEscalationLoop 定期检查需要升级的告警
+func (s *NotificationService) StartEscalationLoop() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Errorf("[Notify] 告警升级检查异常: %v", r)
+				}
+			}()
+			s.ProcessEscalations()
+		}()
+	}
+}
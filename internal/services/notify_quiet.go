@@ -0,0 +1,110 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// quietFlushFunc 静默时段结束时的发送回调
+type quietFlushFunc func(items []string)
+
+// NotifyQuietQueue 静默时段内暂存的通知，时段结束时合并发送
+// 暂存的通知保存在 notify_quiet_items 表中，内存中只有各渠道的汇总定时器，
+// 面板重启后通过 Restore 重新安排汇总发送
+type NotifyQuietQueue struct {
+	timers map[string]*time.Timer
+	mu     sync.Mutex
+}
+
+func NewNotifyQuietQueue() *NotifyQuietQueue {
+	return &NotifyQuietQueue{timers: make(map[string]*time.Timer)}
+}
+
+// defaultNotifyQuietQueue 各 NotificationService 实例共享的静默队列
+var defaultNotifyQuietQueue = NewNotifyQuietQueue()
+
+// Hold 暂存一条通知摘要，until 为静默结束时间
+// 同一渠道在本次静默内的通知会在第一次暂存时确定的结束时间统一发送
+func (q *NotifyQuietQueue) Hold(channelID string, until time.Time, summary string, flush quietFlushFunc) {
+	item := &models.NotifyQuietItem{
+		ID:      utils.GenerateID(),
+		WayID:   channelID,
+		Summary: models.BigText(summary),
+		FlushAt: models.LocalTime(until),
+	}
+	if err := database.DB.Create(item).Error; err != nil {
+		// 无法暂存时直接发送，宁可打扰也不丢失通知
+		logger.Errorf("[Notify] 保存静默时段通知失败，直接发送: %v", err)
+		flush([]string{summary})
+		return
+	}
+	q.schedule(channelID, until, flush)
+}
+
+// Pending 渠道当前暂存的通知数量
+func (q *NotifyQuietQueue) Pending(channelID string) int {
+	var count int64
+	database.DB.Model(&models.NotifyQuietItem{}).Where("way_id = ?", channelID).Count(&count)
+	return int(count)
+}
+
+// Restore 为数据库中暂存的通知重新安排汇总发送，返回涉及的渠道数
+// flushFor 返回渠道的发送回调，渠道已不存在时返回 nil，其暂存的通知会被清理
+func (q *NotifyQuietQueue) Restore(flushFor func(channelID string) quietFlushFunc) int {
+	var items []models.NotifyQuietItem
+	database.DB.Select("id", "way_id", "flush_at").Order("flush_at ASC").Find(&items)
+
+	restored := 0
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if seen[item.WayID] {
+			continue
+		}
+		seen[item.WayID] = true
+
+		flush := flushFor(item.WayID)
+		if flush == nil {
+			database.DB.Where("way_id = ?", item.WayID).Delete(&models.NotifyQuietItem{})
+			continue
+		}
+		// 已过静默结束时间的立即发送
+		q.schedule(item.WayID, item.FlushAt.Time(), flush)
+		restored++
+	}
+	return restored
+}
+
+func (q *NotifyQuietQueue) schedule(channelID string, until time.Time, flush quietFlushFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.timers[channelID] == nil {
+		q.timers[channelID] = time.AfterFunc(time.Until(until), func() { q.flush(channelID, flush) })
+	}
+}
+
+func (q *NotifyQuietQueue) flush(channelID string, flush quietFlushFunc) {
+	q.mu.Lock()
+	delete(q.timers, channelID)
+	q.mu.Unlock()
+
+	var items []models.NotifyQuietItem
+	database.DB.Where("way_id = ?", channelID).Order("created_at ASC, id ASC").Find(&items)
+	if len(items) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(items))
+	summaries := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+		summaries = append(summaries, string(item.Summary))
+	}
+	flush(summaries)
+	// 发送后再删除：发送途中面板退出时，重启后会重新汇总而不是丢失
+	database.DB.Where("id IN ?", ids).Delete(&models.NotifyQuietItem{})
+}
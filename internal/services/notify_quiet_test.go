@@ -0,0 +1,88 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+func TestQuietHoursUntil(t *testing.T) {
+	q := &models.QuietHours{Start: "23:00", End: "07:00", Timezone: "Asia/Shanghai"}
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+
+	cases := []struct {
+		now   time.Time
+		quiet bool
+		until time.Time
+	}{
+		{time.Date(2026, 1, 1, 23, 30, 0, 0, loc), true, time.Date(2026, 1, 2, 7, 0, 0, 0, loc)},
+		{time.Date(2026, 1, 2, 3, 0, 0, 0, loc), true, time.Date(2026, 1, 2, 7, 0, 0, 0, loc)},
+		{time.Date(2026, 1, 2, 7, 0, 0, 0, loc), false, time.Time{}},
+		{time.Date(2026, 1, 2, 12, 0, 0, 0, loc), false, time.Time{}},
+		// 其他时区的时间按渠道时区判断：UTC 16:00 即北京时间 00:00
+		{time.Date(2026, 1, 1, 16, 0, 0, 0, time.UTC), true, time.Date(2026, 1, 2, 7, 0, 0, 0, loc)},
+	}
+	for _, tc := range cases {
+		until, quiet := q.Until(tc.now)
+		if quiet != tc.quiet || (quiet && !until.Equal(tc.until)) {
+			t.Fatalf("Until(%v) = %v, %v; want %v, %v", tc.now, until, quiet, tc.until, tc.quiet)
+		}
+	}
+
+	day := &models.QuietHours{Start: "12:00", End: "14:00"}
+	if _, quiet := day.Until(time.Date(2026, 1, 1, 13, 0, 0, 0, time.Local)); !quiet {
+		t.Fatal("expected quiet inside same-day window")
+	}
+	if err := (&models.QuietHours{Start: "25:00", End: "07:00"}).Validate(); err == nil {
+		t.Fatal("expected invalid start time")
+	}
+}
+
+func TestNotifyQuietQueueSurvivesRestart(t *testing.T) {
+	setupNotifyTestDB(t, &models.NotifyQuietItem{})
+
+	until := time.Now().Add(time.Hour)
+	q := NewNotifyQuietQueue()
+	q.Hold("w1", until, "任务 a 执行失败", func(items []string) {})
+	q.Hold("w1", until, "任务 b 执行失败", func(items []string) {})
+	q.Hold("gone", until, "任务 c 执行失败", func(items []string) {})
+	if n := q.Pending("w1"); n != 2 {
+		t.Fatalf("Pending = %d, want 2", n)
+	}
+
+	// 模拟面板在静默期间重启，并在静默结束后才启动
+	database.DB.Model(&models.NotifyQuietItem{}).Where("1 = 1").Update("flush_at", models.LocalTime(time.Now().Add(-time.Minute)))
+
+	flushed := make(chan []string, 1)
+	restarted := NewNotifyQuietQueue()
+	n := restarted.Restore(func(channelID string) quietFlushFunc {
+		if channelID != "w1" {
+			return nil
+		}
+		return func(items []string) { flushed <- items }
+	})
+	if n != 1 {
+		t.Fatalf("Restore = %d, want 1", n)
+	}
+
+	select {
+	case items := <-flushed:
+		if want := []string{"任务 a 执行失败", "任务 b 执行失败"}; !reflect.DeepEqual(items, want) {
+			t.Fatalf("flushed %v, want %v", items, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("restored queue was not flushed")
+	}
+
+	// 发送完成后删除；已不存在的渠道在恢复时清理
+	deadline := time.Now().Add(5 * time.Second)
+	for restarted.Pending("w1") != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if restarted.Pending("w1") != 0 || restarted.Pending("gone") != 0 {
+		t.Fatalf("expected held items to be removed, got w1=%d gone=%d", restarted.Pending("w1"), restarted.Pending("gone"))
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// routeBindingPrefix 由路由规则生成的虚拟绑定ID前缀（用于节流作用域区分）
+const routeBindingPrefix = "route:"
+
+// GetRoutes 获取通知路由规则列表
+func (s *NotificationService) GetRoutes() []models.NotifyRoute {
+	var routes []models.NotifyRoute
+	database.DB.Order("id ASC").Find(&routes)
+	return routes
+}
+
+// SaveRoute 保存通知路由规则
+func (s *NotificationService) SaveRoute(route *models.NotifyRoute) error {
+	route.Events = normalizeCSV(route.Events)
+	route.Tags = normalizeCSV(route.Tags)
+	route.NamePattern = strings.TrimSpace(route.NamePattern)
+	if route.Name == "" || route.Events == "" || route.WayID == "" {
+		return fmt.Errorf("名称、事件和渠道不能为空")
+	}
+	for _, event := range strings.Split(route.Events, ",") {
+		if !isTaskEvent(event) {
+			return fmt.Errorf("路由规则仅支持任务事件: %s", event)
+		}
+	}
+	if route.NamePattern != "" {
+		if _, err := path.Match(route.NamePattern, ""); err != nil {
+			return fmt.Errorf("名称匹配规则无效: %v", err)
+		}
+	}
+
+	var count int64
+	database.DB.Model(&models.NotifyWay{}).Where("id = ?", route.WayID).Count(&count)
+	if count == 0 {
+		return fmt.Errorf("渠道 %s 不存在", route.WayID)
+	}
+
+	if route.Enabled == nil {
+		route.Enabled = utils.BoolPtr(true)
+	}
+	if route.ID == "" {
+		route.ID = utils.GenerateID()
+		return database.DB.Create(route).Error
+	}
+
+	var existing models.NotifyRoute
+	if err := database.DB.Where("id = ?", route.ID).First(&existing).Error; err != nil {
+		return fmt.Errorf("路由规则不存在")
+	}
+	s.throttler.Reset(bindingThrottleScope(routeBindingPrefix + route.ID))
+	updates := map[string]interface{}{
+		"name":         route.Name,
+		"events":       route.Events,
+		"way_id":       route.WayID,
+		"tags":         route.Tags,
+		"agent_id":     route.AgentID,
+		"task_type":    route.TaskType,
+		"name_pattern": route.NamePattern,
+		"extra":        route.Extra,
+		"enabled":      route.Enabled,
+	}
+	if err := database.DB.Model(&existing).Updates(updates).Error; err != nil {
+		return err
+	}
+	// 返回库中的完整记录（包含创建时间）
+	return database.DB.Where("id = ?", route.ID).First(route).Error
+}
+
+// DeleteRoute 删除通知路由规则
+func (s *NotificationService) DeleteRoute(id string) error {
+	s.throttler.Reset(bindingThrottleScope(routeBindingPrefix + id))
+	return database.DB.Where("id = ?", id).Delete(&models.NotifyRoute{}).Error
+}
+
+// GetRouteBindings 根据路由规则为任务事件生成绑定
+// 已被显式绑定覆盖的渠道不会重复生成，避免同一事件推送两次
+func (s *NotificationService) GetRouteBindings(event, taskID string, explicit []models.NotifyBinding) []models.NotifyBinding {
+	if taskID == "" {
+		return nil
+	}
+
+	var routes []models.NotifyRoute
+	database.DB.Where("enabled = ?", true).Order("id ASC").Find(&routes)
+	if len(routes) == 0 {
+		return nil
+	}
+
+	var task models.Task
+	if res := database.DB.Where("id = ?", taskID).Limit(1).Find(&task); res.RowsAffected == 0 {
+		return nil
+	}
+
+	covered := make(map[string]bool, len(explicit))
+	for _, b := range explicit {
+		covered[b.WayID] = true
+	}
+
+	var bindings []models.NotifyBinding
+	for _, route := range routes {
+		if covered[route.WayID] || !splitToSet(route.Events)[event] || !MatchNotifyRoute(&route, &task) {
+			continue
+		}
+		covered[route.WayID] = true
+		bindings = append(bindings, models.NotifyBinding{
+			ID:     routeBindingPrefix + route.ID,
+			Type:   constant.BindingTypeTask,
+			Event:  event,
+			WayID:  route.WayID,
+			DataID: taskID,
+			Extra:  route.Extra,
+		})
+	}
+	return bindings
+}
+
+// MatchNotifyRoute 判断任务是否满足路由规则的全部匹配条件（空条件视为不限）
+func MatchNotifyRoute(route *models.NotifyRoute, task *models.Task) bool {
+	if route.TaskType != "" {
+		taskType := task.Type
+		if taskType == "" {
+			taskType = constant.TaskTypeNormal
+		}
+		if taskType != route.TaskType {
+			return false
+		}
+	}
+
+	if route.AgentID != "" {
+		agentID := ""
+		if task.AgentID != nil {
+			agentID = *task.AgentID
+		}
+		if route.AgentID == constant.NotifyRouteAgentLocal {
+			if task.IsRemote() {
+				return false
+			}
+		} else if agentID != route.AgentID {
+			return false
+		}
+	}
+
+	if route.Tags != "" {
+		taskTags := splitToSet(task.Tags)
+		matched := false
+		for tag := range splitToSet(route.Tags) {
+			if taskTags[tag] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if route.NamePattern != "" {
+		ok, _ := path.Match(strings.ToLower(route.NamePattern), strings.ToLower(task.Name))
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// RouteMatchedTask 路由规则预览中的任务摘要
+type RouteMatchedTask struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Tags string `json:"tags"`
+}
+
+// GetRouteMatchedTasks 预览路由规则当前匹配的任务
+func (s *NotificationService) GetRouteMatchedTasks(route *models.NotifyRoute) []RouteMatchedTask {
+	var tasks []models.Task
+	database.DB.Select("id", "name", "tags", "type", "agent_id").Find(&tasks)
+
+	matched := make([]RouteMatchedTask, 0)
+	for i := range tasks {
+		if MatchNotifyRoute(route, &tasks[i]) {
+			matched = append(matched, RouteMatchedTask{ID: tasks[i].ID, Name: tasks[i].Name, Tags: tasks[i].Tags})
+		}
+	}
+	return matched
+}
+
+func isTaskEvent(event string) bool {
+	for _, e := range SupportedEvents {
+		if e["type"] == event {
+			return e["binding_type"] == constant.BindingTypeTask
+		}
+	}
+	return false
+}
+
+// normalizeCSV 去除逗号分隔列表中的空白与空项
+func normalizeCSV(s string) string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// notifyTemplateMaxOutput 模板渲染结果的最大长度，防止循环等写法产生过大的消息
+const notifyTemplateMaxOutput = 64 * 1024
+
+// legacyPlaceholder 旧版 {{key}} 占位符
+var legacyPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// templateKeywords text/template 保留字，不能当作旧版占位符转换
+var templateKeywords = map[string]bool{
+	"if": true, "else": true, "end": true, "range": true, "with": true, "define": true,
+	"template": true, "block": true, "break": true, "continue": true, "nil": true, "true": true, "false": true,
+}
+
+// NotifyTemplateFunc 模板函数说明（供前端展示）
+type NotifyTemplateFunc struct {
+	Name    string `json:"name"`
+	Usage   string `json:"usage"`
+	Summary string `json:"summary"`
+}
+
+// NotifyTemplateFuncs 模板中可用的函数
+var NotifyTemplateFuncs = []NotifyTemplateFunc{
+	{"default", `{{default "无" .error}}`, "值为空时使用默认值"},
+	{"truncate", `{{truncate 100 .output}}`, "保留前 N 个字符"},
+	{"tail", `{{tail 500 .output}}`, "保留最后 N 个字符（适合日志）"},
+	{"duration", `{{duration .duration}}`, "毫秒格式化为 1m23s"},
+	{"upper", `{{upper .status}}`, "转为大写"},
+	{"lower", `{{lower .status}}`, "转为小写"},
+	{"trim", `{{trim .output}}`, "去除首尾空白"},
+	{"replace", `{{replace "old" "new" .text}}`, "替换全部匹配的子串"},
+	{"contains", `{{if contains "timeout" .error}}...{{end}}`, "是否包含子串"},
+	{"hasPrefix", `{{if hasPrefix "task" .event}}...{{end}}`, "是否以指定前缀开头"},
+	{"split", `{{range split "," .tags}}{{.}}{{end}}`, "按分隔符拆分"},
+	{"join", `{{join ", " .items}}`, "用分隔符连接列表"},
+	{"lines", `{{range lines .output}}{{.}}{{end}}`, "按行拆分"},
+	{"stripAnsi", `{{stripAnsi .output}}`, "移除终端颜色代码"},
+	{"now", `{{now "2006-01-02 15:04:05"}}`, "当前时间"},
+	{"json", `{{json .}}`, "序列化为 JSON"},
+	{"add", `{{add .retry_index 1}}`, "整数相加"},
+}
+
+func notifyTemplateFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"default": func(def interface{}, v interface{}) interface{} {
+			if v == nil || fmt.Sprint(v) == "" {
+				return def
+			}
+			return v
+		},
+		"truncate": func(n int, v interface{}) string {
+			r := []rune(toString(v))
+			if n < 0 || len(r) <= n {
+				return string(r)
+			}
+			return string(r[:n]) + "..."
+		},
+		"tail": func(n int, v interface{}) string {
+			r := []rune(toString(v))
+			if n < 0 || len(r) <= n {
+				return string(r)
+			}
+			return "..." + string(r[len(r)-n:])
+		},
+		"duration":  formatNotifyDuration,
+		"upper":     func(v interface{}) string { return strings.ToUpper(toString(v)) },
+		"lower":     func(v interface{}) string { return strings.ToLower(toString(v)) },
+		"trim":      func(v interface{}) string { return strings.TrimSpace(toString(v)) },
+		"replace":   func(old, new string, v interface{}) string { return strings.ReplaceAll(toString(v), old, new) },
+		"contains":  func(sub string, v interface{}) bool { return strings.Contains(toString(v), sub) },
+		"hasPrefix": func(prefix string, v interface{}) bool { return strings.HasPrefix(toString(v), prefix) },
+		"split":     func(sep string, v interface{}) []string { return strings.Split(toString(v), sep) },
+		"join": func(sep string, v interface{}) string {
+			switch items := v.(type) {
+			case []string:
+				return strings.Join(items, sep)
+			case []interface{}:
+				parts := make([]string, 0, len(items))
+				for _, item := range items {
+					parts = append(parts, toString(item))
+				}
+				return strings.Join(parts, sep)
+			}
+			return toString(v)
+		},
+		"lines":     func(v interface{}) []string { return strings.Split(toString(v), "\n") },
+		"stripAnsi": func(v interface{}) string { return stripAnsi(toString(v)) },
+		"now":       func(layout string) string { return time.Now().Format(layout) },
+		"json": func(v interface{}) string {
+			data, _ := json.Marshal(v)
+			return string(data)
+		},
+		"add": func(a, b interface{}) int { return utils.ToIntValue(a) + utils.ToIntValue(b) },
+	}
+}
+
+// formatNotifyDuration 将毫秒格式化为 1m23s
+func formatNotifyDuration(v interface{}) string {
+	d := time.Duration(utils.ToIntValue(v)) * time.Millisecond
+	if d < time.Second {
+		return d.String()
+	}
+	return d.Round(time.Second).String()
+}
+
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// convertLegacyTemplate 将旧版 {{key}} 占位符转换为 {{.key}}
+// 模板函数都至少需要一个参数，因此不带参数的 {{duration}} 等写法一律视为字段引用
+func convertLegacyTemplate(tmpl string) string {
+	return legacyPlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		name := legacyPlaceholder.FindStringSubmatch(m)[1]
+		if templateKeywords[name] {
+			return m
+		}
+		return "{{." + name + "}}"
+	})
+}
+
+func parseNotifyTemplate(tmpl string) (*template.Template, error) {
+	return template.New("notify").
+		Funcs(notifyTemplateFuncMap()).
+		Option("missingkey=zero").
+		Parse(convertLegacyTemplate(tmpl))
+}
+
+// ValidateNotifyTemplate 校验模板语法
+func ValidateNotifyTemplate(tmpl string) error {
+	_, err := parseNotifyTemplate(tmpl)
+	return err
+}
+
+// limitedBuffer 超出长度后停止写入并返回错误，中止模板执行
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+var errTemplateOutputTooLarge = errors.New("模板渲染结果过大")
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > notifyTemplateMaxOutput {
+		return 0, errTemplateOutputTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// RenderNotifyTemplate 使用事件数据渲染通知模板
+// 兼容旧版 {{key}} 写法，缺失的字段渲染为空字符串
+func RenderNotifyTemplate(tmpl string, payload map[string]interface{}) (string, error) {
+	t, err := parseNotifyTemplate(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var buf limitedBuffer
+	if err := t.Execute(&buf, withMissingFields(t, payload)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// withMissingFields 为模板引用但事件数据中不存在的字段补上空字符串
+// map[string]interface{} 的缺失键即使设置 missingkey=zero 也会渲染为 "<no value>"，因此在执行前补齐
+func withMissingFields(t *template.Template, payload map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(payload))
+	for k, v := range payload {
+		data[k] = v
+	}
+	if t.Tree != nil {
+		collectTemplateFields(t.Tree.Root, func(name string) {
+			if _, ok := data[name]; !ok {
+				data[name] = ""
+			}
+		})
+	}
+	return data
+}
+
+// collectTemplateFields 遍历模板语法树，收集 .key 与 $.key 形式引用的顶层字段
+func collectTemplateFields(node parse.Node, fn func(name string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectTemplateFields(child, fn)
+		}
+	case *parse.ActionNode:
+		collectTemplateFields(n.Pipe, fn)
+	case *parse.IfNode:
+		collectTemplateBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		collectTemplateBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		collectTemplateBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		collectTemplateFields(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectTemplateFields(cmd, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectTemplateFields(arg, fn)
+		}
+	case *parse.FieldNode:
+		// 多级字段（如 .a.b）的上级为空字符串时会执行出错，只补齐单级字段
+		if len(n.Ident) == 1 {
+			fn(n.Ident[0])
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) == 2 && n.Ident[0] == "$" {
+			fn(n.Ident[1])
+		}
+	}
+}
+
+func collectTemplateBranch(n *parse.BranchNode, fn func(name string)) {
+	collectTemplateFields(n.Pipe, fn)
+	collectTemplateFields(n.List, fn)
+	collectTemplateFields(n.ElseList, fn)
+}
+
+// NotifyTemplateSamples 各事件的示例数据，用于模板预览
+var NotifyTemplateSamples = map[string]map[string]interface{}{
+	constant.EventUserLogin: {
+		"username": "admin", "ip": "192.168.1.10", "userAgent": "Mozilla/5.0",
+		"status": "failed", "status_label": "失败", "message": "用户名或密码错误",
+	},
+	constant.EventBruteForceLogin: {
+		"username": "admin", "ip": "192.168.1.10", "userAgent": "Mozilla/5.0",
+	},
+	constant.EventPasswordChanged: {
+		"username": "admin",
+	},
+	constant.EventTaskSuccess: {
+		"task_id": "d0example0task00000", "task_name": "每日签到", "status": "success",
+		"start_time": "2026-01-01 08:00:00", "duration": 83000, "output": "签到成功\n获得积分 10", "error": "",
+	},
+	constant.EventTaskFailed: {
+		"task_id": "d0example0task00000", "task_name": "每日签到", "status": "failed",
+		"start_time": "2026-01-01 08:00:00", "duration": 1200, "output": "请求失败: 401", "error": "exit status 1",
+		"consecutive_failures": 2,
+	},
+	constant.EventTaskTimeout: {
+		"task_id": "d0example0task00000", "task_name": "每日签到", "status": "timeout",
+		"start_time": "2026-01-01 08:00:00", "duration": 600000, "output": "等待响应中...", "error": "",
+	},
+	constant.EventTaskRecovered: {
+		"task_id": "d0example0task00000", "task_name": "每日签到", "status": "success",
+		"start_time": "2026-01-01 09:00:00", "duration": 2300, "output": "签到成功", "error": "", "previous_failures": 3,
+	},
+	constant.EventCheckDown: {
+		"check_id": "d0example0check0000", "check_name": "NAS 备份", "status": "down",
+		"reason": "超过预期时间未上报", "last_ping": "2026-01-01 03:00:00", "output": "",
+	},
+	constant.EventCheckUp: {
+		"check_id": "d0example0check0000", "check_name": "NAS 备份", "status": "up",
+		"reason": "", "last_ping": "2026-01-02 03:00:00", "output": "",
+	},
+	constant.EventAgentOnline: {
+		"agent_id": "d0example0agent0000", "agent_name": "家里的树莓派", "ip": "192.168.1.20", "reason": "",
+	},
+	constant.EventAgentOffline: {
+		"agent_id": "d0example0agent0000", "agent_name": "家里的树莓派", "ip": "192.168.1.20", "reason": "心跳超时",
+	},
+	constant.EventAgentOverloaded: {
+		"agent_id": "d0example0agent0000", "agent_name": "家里的树莓派", "ip": "192.168.1.20",
+		"reason": "内存使用率 93% 超过上限 90%", "cpu_percent": 35, "mem_percent": 93, "disk_percent": 61,
+		"load1": 1.52, "running_tasks": 2,
+	},
+	constant.EventDiskLow: {
+		"path": "./data", "free": "1.2 GB", "total": "20.0 GB", "free_percent": 6, "threshold": 10,
+	},
+	constant.EventBackupSuccess: {
+		"file": "data/backups/backup_20260101_030000.zip", "size": "12.5 MB", "duration": 1830, "error": "",
+	},
+	constant.EventBackupFailed: {
+		"file": "", "size": "", "duration": 120, "error": "no space left on device",
+	},
+	constant.EventDependencyFailed: {
+		"dependency_id": "d0example0dep000000", "name": "requests", "version": "2.32.0",
+		"language": "python", "lang_version": "3.12", "error": "安装失败: Could not find a version that satisfies the requirement",
+	},
+	constant.EventPanelStarted: {
+		"version": "v1.0.0", "hostname": "baihu", "pid": 1, "time": "2026-01-01 08:00:00",
+	},
+}
@@ -0,0 +1,46 @@
+package services
+
+import "testing"
+
+func TestRenderNotifyTemplate(t *testing.T) {
+	payload := map[string]interface{}{
+		"task_name": "签到",
+		"duration":  83000,
+		"error":     "",
+		"output":    "line1\nline2",
+		"message":   "got <no value>",
+	}
+
+	cases := []struct {
+		tmpl string
+		want string
+	}{
+		// 旧版占位符
+		{"任务[{{task_name}}] 耗时 {{duration}}ms", "任务[签到] 耗时 83000ms"},
+		{"{{ task_name }}-{{missing}}", "签到-"},
+		// 新语法
+		{"{{.task_name}} {{duration .duration}}", "签到 1m23s"},
+		{"{{if .error}}错误: {{.error}}{{else}}无错误{{end}}", "无错误"},
+		{`{{default "无" .error}}`, "无"},
+		{"{{truncate 2 .output}}", "li..."},
+		{"{{tail 5 .output}}", "...line2"},
+		{"{{range lines .output}}[{{.}}]{{end}}", "[line1][line2]"},
+		// 缺失字段渲染为空，但字段内容本身不会被改写
+		{"{{if .missing}}x{{end}}{{.message}}", "got <no value>"},
+		{`{{default "无" .missing}}`, "无"},
+		{"{{range lines .output}}{{$.missing}}{{end}}", ""},
+	}
+	for _, c := range cases {
+		got, err := RenderNotifyTemplate(c.tmpl, payload)
+		if err != nil {
+			t.Fatalf("render %q: %v", c.tmpl, err)
+		}
+		if got != c.want {
+			t.Errorf("render %q = %q, want %q", c.tmpl, got, c.want)
+		}
+	}
+
+	if err := ValidateNotifyTemplate("{{if .error}}"); err == nil {
+		t.Error("expected syntax error for unclosed if")
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+// digestMaxItems 摘要消息中最多列出的条目数
+const digestMaxItems = 20
+
+// 节流判定结果
+const (
+	throttleSend     = "send"
+	throttleDedupe   = "dedupe"
+	throttleLimited  = "rate_limited"
+	throttleDigested = "digested"
+)
+
+// digestFlushFunc 摘要到期时的发送回调，suppressed 为摘要之外被丢弃的消息数量
+type digestFlushFunc func(items []string, suppressed int)
+
+// throttleScope 一个节流作用域（某个渠道或某条事件绑定）的运行状态
+type throttleScope struct {
+	sent       []time.Time          // 限流窗口内已发送的时间点
+	seen       map[string]time.Time // 去重键 -> 过期时间
+	suppressed int                  // 尚未在推送记录中体现的被抑制数量
+	digest     []string             // 等待合并发送的消息摘要
+	timer      *time.Timer
+}
+
+// NotifyThrottler 通知节流器（去重、限流与摘要合并），状态仅保存在内存中
+type NotifyThrottler struct {
+	scopes map[string]*throttleScope
+	mu     sync.Mutex
+}
+
+func NewNotifyThrottler() *NotifyThrottler {
+	return &NotifyThrottler{scopes: make(map[string]*throttleScope)}
+}
+
+// defaultNotifyThrottler 各 NotificationService 实例共享的节流器
+var defaultNotifyThrottler = NewNotifyThrottler()
+
+// Check 判断消息是否可以立即发送
+// 返回判定结果以及（可发送时）此前被抑制、需在本条推送记录中体现的数量
+func (t *NotifyThrottler) Check(scopeKey string, rule *models.ThrottleRule, dedupeKey, summary string, flush digestFlushFunc) (string, int) {
+	if !rule.Enabled() {
+		return throttleSend, 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	scope := t.scopes[scopeKey]
+	if scope == nil {
+		scope = &throttleScope{seen: make(map[string]time.Time)}
+		t.scopes[scopeKey] = scope
+	}
+
+	// 1. 去重
+	if rule.DedupeWindow > 0 && dedupeKey != "" {
+		for key, expire := range scope.seen {
+			if now.After(expire) {
+				delete(scope.seen, key)
+			}
+		}
+		if _, ok := scope.seen[dedupeKey]; ok {
+			scope.suppressed++
+			// 之后没有新消息发出时，到期后单独报告被忽略的数量
+			t.scheduleFlush(scope, scopeKey, time.Duration(rule.DedupeWindow)*time.Second, flush)
+			return throttleDedupe, 0
+		}
+		scope.seen[dedupeKey] = now.Add(time.Duration(rule.DedupeWindow) * time.Second)
+	}
+
+	// 2. 限流
+	if rule.RateLimit > 0 {
+		window := time.Duration(rule.RateWindow) * time.Second
+		if window <= 0 {
+			window = time.Minute
+		}
+		cutoff := now.Add(-window)
+		kept := scope.sent[:0]
+		for _, ts := range scope.sent {
+			if ts.After(cutoff) {
+				kept = append(kept, ts)
+			}
+		}
+		scope.sent = kept
+
+		if len(scope.sent) >= rule.RateLimit {
+			// 窗口内最早一条发送记录过期时，限流额度恢复，此时发送摘要或被忽略数量
+			delay := scope.sent[0].Add(window).Sub(now)
+			t.scheduleFlush(scope, scopeKey, delay, flush)
+			if !rule.Digest {
+				scope.suppressed++
+				return throttleLimited, 0
+			}
+			scope.digest = append(scope.digest, summary)
+			return throttleDigested, 0
+		}
+		scope.sent = append(scope.sent, now)
+	}
+
+	suppressed := scope.suppressed
+	scope.suppressed = 0
+	return throttleSend, suppressed
+}
+
+// scheduleFlush 在 delay 后发送累计的摘要，已有定时器时不重复创建（调用方需持有锁）
+func (t *NotifyThrottler) scheduleFlush(scope *throttleScope, scopeKey string, delay time.Duration, flush digestFlushFunc) {
+	if scope.timer != nil {
+		return
+	}
+	scope.timer = time.AfterFunc(delay, func() { t.flush(scopeKey, flush) })
+}
+
+// flush 发送累计的摘要；期间已有消息发出（被抑制数量已随其上报）且没有摘要时不发送
+func (t *NotifyThrottler) flush(scopeKey string, flush digestFlushFunc) {
+	t.mu.Lock()
+	scope := t.scopes[scopeKey]
+	if scope == nil {
+		t.mu.Unlock()
+		return
+	}
+	items := scope.digest
+	suppressed := scope.suppressed
+	scope.timer = nil
+	if len(items) == 0 && suppressed == 0 {
+		t.mu.Unlock()
+		return
+	}
+	scope.digest = nil
+	scope.suppressed = 0
+	// 摘要本身占用一次发送额度
+	scope.sent = append(scope.sent, time.Now())
+	t.mu.Unlock()
+
+	flush(items, suppressed)
+}
+
+// Reset 清理指定作用域的状态（渠道或绑定被修改/删除时调用）
+func (t *NotifyThrottler) Reset(scopeKey string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if scope, ok := t.scopes[scopeKey]; ok && scope.timer == nil {
+		delete(t.scopes, scopeKey)
+	}
+}
+
+// buildDigestText 构造摘要消息正文，header 为首行说明
+func buildDigestText(header string, items []string, suppressed int) string {
+	var sb strings.Builder
+	if len(items) > 0 {
+		sb.WriteString(fmt.Sprintf("%s共合并 %d 条通知：\n", header, len(items)))
+		for i, item := range items {
+			if i >= digestMaxItems {
+				sb.WriteString(fmt.Sprintf("...等其余 %d 条\n", len(items)-digestMaxItems))
+				break
+			}
+			sb.WriteString("- " + item + "\n")
+		}
+		if suppressed > 0 {
+			sb.WriteString(fmt.Sprintf("另有 %d 条重复或超限通知已被忽略", suppressed))
+		}
+	} else if suppressed > 0 {
+		sb.WriteString(fmt.Sprintf("%s有 %d 条重复或超限通知已被忽略", header, suppressed))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
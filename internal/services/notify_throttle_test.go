@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+func TestThrottlerReportsSuppressedWithoutFollowUp(t *testing.T) {
+	throttler := NewNotifyThrottler()
+	rule := &models.ThrottleRule{RateLimit: 1, RateWindow: 1}
+
+	flushed := make(chan int, 1)
+	flush := func(items []string, suppressed int) {
+		if len(items) != 0 {
+			t.Errorf("unexpected digest items: %v", items)
+		}
+		flushed <- suppressed
+	}
+
+	if decision, _ := throttler.Check("s", rule, "", "a", flush); decision != throttleSend {
+		t.Fatalf("first message should be sent, got %s", decision)
+	}
+	for i := 0; i < 2; i++ {
+		if decision, _ := throttler.Check("s", rule, "", "b", flush); decision != throttleLimited {
+			t.Fatalf("message should be rate limited, got %s", decision)
+		}
+	}
+
+	// 限流窗口结束后没有新消息，被忽略的数量也要单独报告
+	select {
+	case n := <-flushed:
+		if n != 2 {
+			t.Fatalf("expected 2 suppressed, got %d", n)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("suppressed count was never flushed")
+	}
+}
+
+func TestBuildDigestText(t *testing.T) {
+	items := make([]string, digestMaxItems+5)
+	for i := range items {
+		items[i] = "item"
+	}
+	text := buildDigestText("限流期间", items, 3)
+	if !strings.Contains(text, "...等其余 5 条") || !strings.Contains(text, "另有 3 条") {
+		t.Fatalf("unexpected digest text: %s", text)
+	}
+
+	text = buildDigestText("限流期间", nil, 4)
+	if text != "限流期间有 4 条重复或超限通知已被忽略" {
+		t.Fatalf("unexpected suppressed-only text: %s", text)
+	}
+}
@@ -0,0 +1,490 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+const (
+	// scriptSyncTimeout 等待 Agent 完成一次目录同步的最长时间
+	scriptSyncTimeout = 2 * time.Minute
+	// scriptSyncDebounce 文件保存后延迟触发同步，合并连续的编辑
+	scriptSyncDebounce = 2 * time.Second
+)
+
+// ScriptSyncService 脚本同步规则管理
+type ScriptSyncService struct{}
+
+func NewScriptSyncService() *ScriptSyncService {
+	return &ScriptSyncService{}
+}
+
+// List 获取同步规则列表
+func (s *ScriptSyncService) List() []models.ScriptSync {
+	var rules []models.ScriptSync
+	database.DB.Order("id ASC").Find(&rules)
+	return rules
+}
+
+// GetByID 获取同步规则
+func (s *ScriptSyncService) GetByID(id string) *models.ScriptSync {
+	var rule models.ScriptSync
+	res := database.DB.Where("id = ?", id).Limit(1).Find(&rule)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil
+	}
+	return &rule
+}
+
+// Save 保存同步规则，同步目录不能与其他规则重叠
+func (s *ScriptSyncService) Save(rule *models.ScriptSync) error {
+	p, err := NormalizeScriptSyncPath(rule.Path)
+	if err != nil {
+		return err
+	}
+	rule.Path = p
+	rule.AgentIDs = strings.Join(models.SplitLabels(rule.AgentIDs), ",")
+	rule.AgentSelector = strings.Join(models.SplitLabels(rule.AgentSelector), ",")
+
+	if info, err := os.Stat(filepath.Join(constant.ScriptsWorkDir, filepath.FromSlash(p))); err != nil || !info.IsDir() {
+		return fmt.Errorf("目录 %s 不存在", p)
+	}
+	for _, other := range s.List() {
+		if other.ID != rule.ID && scriptSyncPathsOverlap(other.Path, p) {
+			return fmt.Errorf("与同步规则 %s 的目录重叠", other.Path)
+		}
+	}
+
+	if rule.Enabled == nil {
+		rule.Enabled = utils.BoolPtr(true)
+	}
+	if rule.ID == "" {
+		rule.ID = utils.GenerateID()
+		err = database.DB.Create(rule).Error
+	} else {
+		GetAgentWSManager().scriptSyncs.forget(rule.ID)
+		err = database.DB.Model(&models.ScriptSync{}).Where("id = ?", rule.ID).Updates(map[string]interface{}{
+			"path":           rule.Path,
+			"agent_selector": rule.AgentSelector,
+			"agent_ids":      rule.AgentIDs,
+			"remark":         rule.Remark,
+			"enabled":        rule.Enabled,
+		}).Error
+	}
+	if err == nil {
+		GetAgentWSManager().TriggerScriptSync()
+	}
+	return err
+}
+
+// Delete 删除同步规则（Agent 上已同步的文件保留）
+func (s *ScriptSyncService) Delete(id string) error {
+	GetAgentWSManager().scriptSyncs.forget(id)
+	return database.DB.Where("id = ?", id).Delete(&models.ScriptSync{}).Error
+}
+
+// NormalizeScriptSyncPath 校验并规范化相对 scripts 目录的同步路径（. 表示整个 scripts 目录）
+func NormalizeScriptSyncPath(p string) (string, error) {
+	p = strings.TrimSpace(strings.ReplaceAll(p, "\\", "/"))
+	if p == "" {
+		return "", fmt.Errorf("同步目录不能为空")
+	}
+	if strings.HasPrefix(p, "/") || filepath.IsAbs(p) {
+		return "", fmt.Errorf("同步目录必须是 scripts 下的相对路径")
+	}
+	p = path.Clean(p)
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("同步目录不能超出 scripts 目录")
+	}
+	return p, nil
+}
+
+func scriptSyncPathsOverlap(a, b string) bool {
+	return a == b || a == "." || b == "." || strings.HasPrefix(b, a+"/") || strings.HasPrefix(a, b+"/")
+}
+
+// ResolveScriptSyncFile 将同步清单中的文件路径解析为面板上的实际路径
+// 解析符号链接后仍须位于同步目录内，防止通过目录中的链接读取其他文件
+func ResolveScriptSyncFile(rule *models.ScriptSync, file string) (string, error) {
+	file = path.Clean(strings.ReplaceAll(file, "\\", "/"))
+	if file == "." || file == ".." || strings.HasPrefix(file, "../") || strings.HasPrefix(file, "/") || isScriptSyncIgnored(file) {
+		return "", fmt.Errorf("无效的文件路径")
+	}
+	root, err := filepath.EvalSymlinks(filepath.Join(constant.ScriptsWorkDir, filepath.FromSlash(rule.Path)))
+	if err != nil {
+		return "", fmt.Errorf("同步目录不存在")
+	}
+	real, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(file)))
+	if err != nil {
+		return "", fmt.Errorf("文件不存在")
+	}
+	if !strings.HasPrefix(real, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("无效的文件路径")
+	}
+	return real, nil
+}
+
+// isScriptSyncIgnored 不参与同步的路径（版本库元数据）
+func isScriptSyncIgnored(rel string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		if part == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptHashEntry 文件摘要缓存，大小与修改时间不变时复用
+type scriptHashEntry struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+var scriptHashCache sync.Map // 绝对路径 -> scriptHashEntry
+
+func hashScriptFile(file string, info fs.FileInfo) (string, error) {
+	if v, ok := scriptHashCache.Load(file); ok {
+		entry := v.(scriptHashEntry)
+		if entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+			return entry.hash, nil
+		}
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	scriptHashCache.Store(file, scriptHashEntry{size: info.Size(), modTime: info.ModTime(), hash: sum})
+	return sum, nil
+}
+
+// BuildScriptManifest 计算同步目录的内容清单（仅包含普通文件，忽略 .git）
+func BuildScriptManifest(rulePath string) (*models.ScriptManifest, error) {
+	root := filepath.Join(constant.ScriptsWorkDir, filepath.FromSlash(rulePath))
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("目录 %s 不存在", rulePath)
+	}
+
+	manifest := &models.ScriptManifest{Files: []models.ScriptManifestFile{}}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		sum, err := hashScriptFile(p, info)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, models.ScriptManifestFile{
+			Path: filepath.ToSlash(rel),
+			Hash: sum,
+			Size: info.Size(),
+			Mode: uint32(info.Mode().Perm()),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取目录 %s 失败: %v", rulePath, err)
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	h := sha256.New()
+	for _, f := range manifest.Files {
+		fmt.Fprintf(h, "%s\x00%s\x00%o\n", f.Path, f.Hash, f.Mode)
+	}
+	manifest.Hash = hex.EncodeToString(h.Sum(nil))
+	return manifest, nil
+}
+
+// ScriptSyncState Agent 上某条同步规则的最近同步状态
+type ScriptSyncState struct {
+	AgentID string `json:"agent_id"`
+	SyncID  string `json:"sync_id"`
+	Hash    string `json:"hash"`
+	Error   string `json:"error"`
+	Time    int64  `json:"time"`
+}
+
+// scriptSyncStore 按 Agent 记录已同步的清单摘要、等待中的同步请求及防抖定时器
+type scriptSyncStore struct {
+	mu      sync.Mutex
+	states  map[string]*ScriptSyncState // Agent ID/规则 ID -> 状态
+	waiters map[string]chan *models.ScriptSyncResult
+	locks   map[string]*sync.Mutex // 同一 Agent 的同步串行执行
+	timer   *time.Timer
+}
+
+func (s *scriptSyncStore) agentLock(agentID string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locks == nil {
+		s.locks = make(map[string]*sync.Mutex)
+	}
+	if s.locks[agentID] == nil {
+		s.locks[agentID] = &sync.Mutex{}
+	}
+	return s.locks[agentID]
+}
+
+func (s *scriptSyncStore) syncedHash(agentID, syncID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st := s.states[agentID+"/"+syncID]; st != nil && st.Error == "" {
+		return st.Hash
+	}
+	return ""
+}
+
+func (s *scriptSyncStore) setState(st *ScriptSyncState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states == nil {
+		s.states = make(map[string]*ScriptSyncState)
+	}
+	st.Time = time.Now().Unix()
+	s.states[st.AgentID+"/"+st.SyncID] = st
+}
+
+// forget 清除规则的同步状态，下次触发时重新推送
+func (s *scriptSyncStore) forget(syncID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, st := range s.states {
+		if st.SyncID == syncID {
+			delete(s.states, key)
+		}
+	}
+}
+
+// resetAgent 清除 Agent 的同步状态
+func (s *scriptSyncStore) resetAgent(agentID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, st := range s.states {
+		if st.AgentID == agentID {
+			delete(s.states, key)
+		}
+	}
+}
+
+// GetScriptSyncStates 获取全部 Agent 的同步状态
+func (m *AgentWSManager) GetScriptSyncStates() []ScriptSyncState {
+	s := &m.scriptSyncs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states := make([]ScriptSyncState, 0, len(s.states))
+	for _, st := range s.states {
+		states = append(states, *st)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].AgentID != states[j].AgentID {
+			return states[i].AgentID < states[j].AgentID
+		}
+		return states[i].SyncID < states[j].SyncID
+	})
+	return states
+}
+
+// SyncScripts 将匹配该 Agent 的同步目录推送到 Agent，已是最新的目录直接跳过
+func (m *AgentWSManager) SyncScripts(agentID string) error {
+	return m.syncScripts(agentID, nil)
+}
+
+// SyncTaskScripts 远程执行前调用，只同步任务工作目录或命令中引用的同步目录
+func (m *AgentWSManager) SyncTaskScripts(agentID, workDir, command string) error {
+	return m.syncScripts(agentID, func(rule *models.ScriptSync) bool {
+		return scriptSyncUsedBy(rule.Path, workDir, command)
+	})
+}
+
+// scriptSyncUsedBy 判断任务是否引用了同步目录：工作目录或命令中的某个路径位于该目录下
+func scriptSyncUsedBy(rulePath, workDir, command string) bool {
+	if rulePath == "." {
+		return true
+	}
+	tokens := strings.FieldsFunc(workDir+" "+command, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '"' || r == '\'' || r == ';' || r == '&' || r == '|' || r == '='
+	})
+	for _, token := range tokens {
+		token = path.Clean(strings.ReplaceAll(token, "\\", "/"))
+		// 兼容绝对路径（.../scripts/<目录>/...）与相对 scripts 的路径
+		if token == rulePath || strings.HasPrefix(token, rulePath+"/") ||
+			strings.HasSuffix(token, "/"+rulePath) || strings.Contains(token, "/"+rulePath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *AgentWSManager) syncScripts(agentID string, match func(rule *models.ScriptSync) bool) error {
+	var rules []models.ScriptSync
+	database.DB.Where("enabled = ?", true).Order("id ASC").Find(&rules)
+	if len(rules) == 0 {
+		return nil
+	}
+	var agent models.Agent
+	if res := database.DB.Where("id = ?", agentID).Limit(1).Find(&agent); res.Error != nil || res.RowsAffected == 0 {
+		return nil
+	}
+
+	lock := m.scriptSyncs.agentLock(agentID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	var errs []string
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.MatchAgent(&agent) || (match != nil && !match(rule)) {
+			continue
+		}
+		manifest, err := BuildScriptManifest(rule.Path)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if m.scriptSyncs.syncedHash(agentID, rule.ID) == manifest.Hash {
+			continue
+		}
+		if err := m.pushScriptSync(&agent, rule, manifest); err != nil {
+			m.scriptSyncs.setState(&ScriptSyncState{AgentID: agentID, SyncID: rule.ID, Hash: manifest.Hash, Error: err.Error()})
+			errs = append(errs, fmt.Sprintf("%s: %v", rule.Path, err))
+			continue
+		}
+		m.scriptSyncs.setState(&ScriptSyncState{AgentID: agentID, SyncID: rule.ID, Hash: manifest.Hash})
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("脚本同步失败: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// pushScriptSync 下发同步清单并等待 Agent 按差异下载、替换完成
+func (m *AgentWSManager) pushScriptSync(agent *models.Agent, rule *models.ScriptSync, manifest *models.ScriptManifest) error {
+	if !m.IsAgentOnline(agent.ID) {
+		return fmt.Errorf("Agent %s 不在线", agent.Name)
+	}
+
+	requestID := utils.GenerateID()
+	ch := make(chan *models.ScriptSyncResult, 1)
+	s := &m.scriptSyncs
+	s.mu.Lock()
+	if s.waiters == nil {
+		s.waiters = make(map[string]chan *models.ScriptSyncResult)
+	}
+	s.waiters[requestID] = ch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.waiters, requestID)
+		s.mu.Unlock()
+	}()
+
+	if err := m.SendToAgent(agent.ID, WSTypeScriptSync, map[string]interface{}{
+		"request_id": requestID,
+		"sync_id":    rule.ID,
+		"path":       rule.Path,
+		"manifest":   manifest,
+	}); err != nil {
+		return err
+	}
+
+	select {
+	case result := <-ch:
+		if result.Error != "" {
+			return fmt.Errorf("%s", result.Error)
+		}
+		if result.Downloaded > 0 || result.Removed > 0 {
+			logger.Infof("[ScriptSync] Agent %s (#%s) 已同步 %s: 更新 %d 个文件，删除 %d 个文件",
+				agent.Name, agent.ID, rule.Path, result.Downloaded, result.Removed)
+		}
+		return nil
+	case <-time.After(scriptSyncTimeout):
+		return fmt.Errorf("等待 Agent 同步超时")
+	}
+}
+
+// HandleScriptSyncResult 处理 Agent 回报的同步结果
+func (m *AgentWSManager) HandleScriptSyncResult(agentID string, data json.RawMessage) {
+	var result models.ScriptSyncResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return
+	}
+	s := &m.scriptSyncs
+	s.mu.Lock()
+	ch := s.waiters[result.RequestID]
+	s.mu.Unlock()
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- &result:
+	default:
+	}
+}
+
+// TriggerScriptSync 脚本文件变更后（防抖）向全部在线 Agent 推送同步
+func (m *AgentWSManager) TriggerScriptSync() {
+	s := &m.scriptSyncs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.timer = time.AfterFunc(scriptSyncDebounce, func() {
+		m.mu.RLock()
+		agentIDs := make([]string, 0, len(m.connections))
+		for id := range m.connections {
+			agentIDs = append(agentIDs, id)
+		}
+		m.mu.RUnlock()
+
+		for _, id := range agentIDs {
+			go func(agentID string) {
+				if err := m.SyncScripts(agentID); err != nil {
+					logger.Warnf("[ScriptSync] Agent #%s %v", agentID, err)
+				}
+			}(id)
+		}
+	})
+}
+
+// ResyncScripts Agent 重新连接后清除已同步状态并重新校验全部同步目录
+func (m *AgentWSManager) ResyncScripts(agentID string) {
+	m.scriptSyncs.resetAgent(agentID)
+	if err := m.SyncScripts(agentID); err != nil {
+		logger.Warnf("[ScriptSync] Agent #%s %v", agentID, err)
+	}
+}
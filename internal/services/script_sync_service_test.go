@@ -0,0 +1,61 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+func TestScriptSyncUsedBy(t *testing.T) {
+	cases := []struct {
+		rule, workDir, command string
+		want                   bool
+	}{
+		{".", "", "echo hi", true},
+		{"jd", "", "python jd/sign.py", true},
+		{"jd", "jd", "python sign.py", true},
+		{"jd", "", "cd ./jd && node a.js", true},
+		{"jd", "/app/data/scripts/jd", "node a.js", true},
+		{"jd", "", "python jdx/sign.py", false},
+		{"jd", "", "echo jd", true},
+		{"tools/jd", "", "python tools/jd/a.py", true},
+		{"jd", "other", "python sign.py", false},
+	}
+	for _, c := range cases {
+		if got := scriptSyncUsedBy(c.rule, c.workDir, c.command); got != c.want {
+			t.Errorf("scriptSyncUsedBy(%q, %q, %q) = %v, want %v", c.rule, c.workDir, c.command, got, c.want)
+		}
+	}
+}
+
+func TestResolveScriptSyncFileRejectsSymlinkEscape(t *testing.T) {
+	t.Chdir(t.TempDir())
+	syncDir := filepath.Join(constant.ScriptsWorkDir, "jd")
+	if err := os.MkdirAll(syncDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(syncDir, "a.py"), []byte("print(1)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(syncDir, "link")); err != nil {
+		t.Skipf("symlink not supported: %v", err)
+	}
+
+	rule := &models.ScriptSync{Path: "jd"}
+	if _, err := ResolveScriptSyncFile(rule, "a.py"); err != nil {
+		t.Fatalf("regular file should resolve: %v", err)
+	}
+	if _, err := ResolveScriptSyncFile(rule, "link/secret"); err == nil {
+		t.Fatal("file behind a symlink outside the sync directory should be rejected")
+	}
+	if _, err := ResolveScriptSyncFile(rule, "../a.py"); err == nil {
+		t.Fatal("parent path should be rejected")
+	}
+}
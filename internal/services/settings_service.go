@@ -145,16 +145,23 @@ func (s *SettingsService) Get(section, key string) string {
 // Set 设置单个值
 func (s *SettingsService) Set(section, key, value string) error {
 	var setting models.Setting
+	var err error
 	res := database.DB.Where(&models.Setting{Section: section, Key: key}).Limit(1).Find(&setting)
 	if res.Error != nil || res.RowsAffected == 0 {
-		return database.DB.Create(&models.Setting{
+		err = database.DB.Create(&models.Setting{
 			ID:      utils.GenerateID(),
 			Section: section,
 			Key:     key,
 			Value:   models.BigText(value),
 		}).Error
+	} else {
+		err = database.DB.Model(&setting).Update("value", models.BigText(value)).Error
+	}
+	// 事件持久化开关在发布事件时读取，使用缓存值
+	if err == nil && section == constant.SectionEventBus && key == constant.KeyEventPersist {
+		eventPersistEnabled.Store(value == "true")
 	}
-	return database.DB.Model(&setting).Update("value", models.BigText(value)).Error
+	return err
 }
 
 // Delete 删除单个设置
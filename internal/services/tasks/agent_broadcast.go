@@ -0,0 +1,209 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/executor"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// broadcastOutcome 单个 Agent 的广播执行结果
+type broadcastOutcome struct {
+	agent    models.Agent
+	logID    string
+	status   string
+	duration int64
+	err      string
+}
+
+// ResolveBroadcastAgents 获取广播任务的目标 Agent（显式列表与标签匹配的并集，仅包含已启用的 Agent）
+func (es *ExecutorService) ResolveBroadcastAgents(task *models.Task) []models.Agent {
+	var agents []models.Agent
+	database.DB.Where("enabled = ?", true).Order("id ASC").Find(&agents)
+
+	byID := make(map[string]models.Agent, len(agents))
+	for _, agent := range agents {
+		byID[agent.ID] = agent
+	}
+
+	targets := make([]models.Agent, 0)
+	seen := make(map[string]struct{})
+	for _, id := range models.SplitLabels(task.AgentIDs) {
+		if agent, ok := byID[id]; ok {
+			seen[id] = struct{}{}
+			targets = append(targets, agent)
+		}
+	}
+	if task.AgentSelector != "" {
+		for _, agent := range agents {
+			if _, ok := seen[agent.ID]; ok || !agent.MatchLabels(task.AgentSelector) {
+				continue
+			}
+			targets = append(targets, agent)
+		}
+	}
+	return targets
+}
+
+// executeBroadcast 将任务并发下发到全部目标 Agent，每个 Agent 生成一条子日志，按成功判定汇总父执行的状态
+func (es *ExecutorService) executeBroadcast(ctx context.Context, task *models.Task, req *executor.ExecutionRequest, stdout io.Writer) (*executor.Result, error) {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+
+	targets := es.ResolveBroadcastAgents(task)
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("广播任务没有可用的目标 Agent")
+	}
+
+	policy := task.BroadcastPolicy
+	if policy == "" {
+		policy = constant.BroadcastPolicyAll
+	}
+	fmt.Fprintf(stdout, "[System] 广播执行到 %d 个 Agent（成功判定: %s）\n", len(targets), policy)
+
+	start := time.Now()
+	envs := executor.FormatEnvVars(req.Envs)
+	outcomes := make([]broadcastOutcome, len(targets))
+
+	var running sync.Map // 子日志 ID -> Agent ID，供取消时下发停止指令
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			running.Range(func(logID, agentID any) bool {
+				es.agentWSManager.SendToAgent(agentID.(string), constant.WSTypeStop, map[string]interface{}{
+					"log_id": logID,
+				})
+				return true
+			})
+		case <-done:
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i, agent := range targets {
+		wg.Add(1)
+		go func(i int, agent models.Agent) {
+			defer wg.Done()
+			outcomes[i] = es.runBroadcastChild(task, req, agent, envs, &running)
+		}(i, agent)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, o := range outcomes {
+		if o.status == constant.TaskStatusSuccess {
+			succeeded++
+		}
+		line := fmt.Sprintf("[%s] %s (#%s) 耗时 %dms", o.status, o.agent.Name, o.agent.ID, o.duration)
+		if o.err != "" {
+			line += ": " + o.err
+		}
+		fmt.Fprintln(stdout, line)
+	}
+	fmt.Fprintf(stdout, "[System] 广播执行完成: 成功 %d/%d\n", succeeded, len(targets))
+
+	status := constant.TaskStatusFailed
+	if (policy == constant.BroadcastPolicyAny && succeeded > 0) || succeeded == len(targets) {
+		status = constant.TaskStatusSuccess
+	}
+	exitCode := 0
+	if status != constant.TaskStatusSuccess {
+		exitCode = 1
+	}
+
+	end := time.Now()
+	result := &executor.Result{
+		Status:    status,
+		Duration:  end.Sub(start).Milliseconds(),
+		ExitCode:  exitCode,
+		StartTime: start,
+		EndTime:   end,
+	}
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	return result, nil
+}
+
+// runBroadcastChild 在单个 Agent 上执行并落库子日志
+func (es *ExecutorService) runBroadcastChild(task *models.Task, req *executor.ExecutionRequest, agent models.Agent, envs string, running *sync.Map) broadcastOutcome {
+	outcome := broadcastOutcome{agent: agent, status: constant.TaskStatusFailed}
+
+	child, err := es.taskLogService.CreateChildLog(task.ID, req.LogID, agent.ID, req.Command)
+	if err != nil {
+		outcome.err = fmt.Sprintf("创建子日志失败: %v", err)
+		return outcome
+	}
+	outcome.logID = child.ID
+
+	tl, err := NewTinyLog(child.ID, req.Secrets)
+	if err != nil {
+		logger.Warnf("[Executor] 创建广播子日志收集器失败: %v", err)
+	}
+
+	start := time.Now()
+	var res *executor.Result
+	if es.agentWSManager == nil || !es.agentWSManager.IsAgentOnline(agent.ID) {
+		err = fmt.Errorf("Agent 不在线")
+	} else if loadErr := es.checkAgentLoad(&agent); loadErr != nil {
+		err = loadErr
+	} else {
+		running.Store(child.ID, agent.ID)
+		res, err = es.ExecuteRemoteForScheduler(task, agent.ID, child.ID, envs, req.Secrets)
+		running.Delete(child.ID)
+	}
+	end := time.Now()
+
+	if res == nil {
+		res = &executor.Result{
+			Status:    constant.TaskStatusFailed,
+			Duration:  end.Sub(start).Milliseconds(),
+			ExitCode:  1,
+			StartTime: start,
+			EndTime:   end,
+		}
+	}
+	if err != nil && res.Error == "" {
+		res.Error = err.Error()
+	}
+
+	var output string
+	if tl != nil {
+		if err != nil {
+			tl.Write([]byte(fmt.Sprintf("\n[System Error] %v\n", err)))
+		}
+		output, _ = tl.CompressAndCleanup()
+	} else {
+		output, _ = utils.CompressToBase64(res.Output)
+	}
+
+	startTime := models.LocalTime(res.StartTime)
+	endTime := models.LocalTime(res.EndTime)
+	child.Output = models.BigText(output)
+	child.Error = models.BigText(res.Error)
+	child.Status = res.Status
+	child.Duration = res.Duration
+	child.ExitCode = res.ExitCode
+	child.StartTime = &startTime
+	child.EndTime = &endTime
+	if err := es.taskLogService.SaveTaskLog(child); err != nil {
+		logger.Errorf("[Executor] 保存广播子日志 #%s 失败: %v", child.ID, err)
+	}
+
+	outcome.status = res.Status
+	outcome.duration = res.Duration
+	outcome.err = strings.TrimSpace(res.Error)
+	return outcome
+}
@@ -0,0 +1,211 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/executor"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+const (
+	// failoverPollInterval 等待 Agent 恢复时的检查间隔
+	failoverPollInterval = 5 * time.Second
+	// failoverStartupGrace 面板启动后等待 Agent 重连的时间，之后才接管离线 Agent 的定时任务
+	failoverStartupGrace = 2 * time.Minute
+	// failoverMinTakeoverDelay 接管时长下限，需明显大于 Agent 的心跳间隔（默认 30 秒）
+	failoverMinTakeoverDelay = 2 * time.Minute
+)
+
+// FailoverTakeoverDelay Agent 最后一次心跳超过该时长后，面板才接管其配置了故障转移的定时任务。
+// Agent 与面板失联达到该时长的一半后会跳过这些任务的本地定时执行，见 SyncAgentTaskCron
+func FailoverTakeoverDelay(settings SettingsService) time.Duration {
+	delay := 5 * time.Minute
+	if settings != nil {
+		delay = time.Duration(utils.ToInt(settings.Get(constant.SectionAgent, constant.KeyAgentFailoverDelay), 300)) * time.Second
+	}
+	return max(delay, failoverMinTakeoverDelay)
+}
+
+// availableAgent 检查 Agent 是否存在、已启用、在线且未超过负载上限
+func (es *ExecutorService) availableAgent(agentID string) (*models.Agent, error) {
+	var agent models.Agent
+	res := database.DB.Where("id = ?", agentID).Limit(1).Find(&agent)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil, fmt.Errorf("Agent #%s 不存在", agentID)
+	}
+	if !utils.DerefBool(agent.Enabled, true) {
+		return nil, fmt.Errorf("Agent %s (#%s) 已禁用", agent.Name, agent.ID)
+	}
+	if es.agentWSManager == nil || !es.agentWSManager.IsAgentOnline(agent.ID) {
+		return nil, fmt.Errorf("Agent %s (#%s) 当前不在线", agent.Name, agent.ID)
+	}
+	if err := es.checkAgentLoad(&agent); err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// primaryAgent 获取任务本身指定（或按标签选出）的执行 Agent
+func (es *ExecutorService) primaryAgent(task *models.Task) (*models.Agent, error) {
+	if task.HasFixedAgent() {
+		return es.availableAgent(*task.AgentID)
+	}
+	return es.PickAgent(task.AgentSelector)
+}
+
+// acquireAgent 确定远程任务的执行 Agent，不可用时按故障转移策略处理，决策写入任务日志。
+// 返回空字符串表示改为本机执行
+func (es *ExecutorService) acquireAgent(ctx context.Context, task *models.Task, stdout io.Writer) (string, error) {
+	note := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		logger.Infof("[Executor] 任务 #%s %s", task.ID, msg)
+		fmt.Fprintf(stdout, "[System] %s\n", msg)
+	}
+	picked := func(agent *models.Agent) string {
+		if !task.HasFixedAgent() {
+			note("按标签 [%s] 调度至 Agent %s (#%s)", task.AgentSelector, agent.Name, agent.ID)
+		}
+		return agent.ID
+	}
+
+	agent, err := es.primaryAgent(task)
+	if err == nil {
+		return picked(agent), nil
+	}
+
+	switch task.FailoverMode {
+	case constant.FailoverModeLocal:
+		note("%v，按故障转移策略改为本机执行", err)
+		return "", nil
+
+	case constant.FailoverModeAgent:
+		backup, backupErr := es.availableAgent(task.FailoverAgentID)
+		if backupErr != nil {
+			return "", fmt.Errorf("%v，备用 Agent 同样不可用: %v", err, backupErr)
+		}
+		note("%v，按故障转移策略改派至备用 Agent %s (#%s)", err, backup.Name, backup.ID)
+		return backup.ID, nil
+
+	case constant.FailoverModeWait:
+		note("%v，等待 Agent 恢复（最多 %d 分钟）", err, task.FailoverWait)
+		deadline := time.NewTimer(time.Duration(task.FailoverWait) * time.Minute)
+		defer deadline.Stop()
+		ticker := time.NewTicker(failoverPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-deadline.C:
+				return "", fmt.Errorf("等待 %d 分钟后 Agent 仍不可用: %v", task.FailoverWait, err)
+			case <-ticker.C:
+				if agent, err = es.primaryAgent(task); err == nil {
+					note("Agent 已恢复，继续执行")
+					return picked(agent), nil
+				}
+			}
+		}
+	}
+	return "", err
+}
+
+// executeLocalFallback 故障转移为本机执行
+func (es *ExecutorService) executeLocalFallback(ctx context.Context, task *models.Task, req *executor.ExecutionRequest, stdout, stderr io.Writer) (*executor.Result, error) {
+	workDir := req.WorkDir
+	if workDir == "" {
+		workDir = resolveAbsScriptsDir()
+	} else if !filepath.IsAbs(workDir) {
+		workDir = filepath.Join(resolveAbsScriptsDir(), workDir)
+	}
+
+	hooks := &LocalTaskHooks{es: es, logID: req.LogID}
+	return executor.ExecuteWithHooks(ctx, executor.Request{
+		Command:   req.Command,
+		WorkDir:   workDir,
+		Envs:      req.Envs,
+		Timeout:   req.Timeout,
+		Languages: []map[string]string(task.Languages),
+		UseMise:   true,
+	}, stdout, stderr, hooks)
+}
+
+// SyncAgentTaskCron 固定 Agent 且配置了故障转移的定时任务，在 Agent 失联超过接管时长后由面板接管调度，Agent 在线时交还。
+//
+// 网络分区时 Agent 仍在按自己的定时计划运行，面板只是收不到它的心跳。若面板一发现离线就接管，
+// 同一次调度会在两边各执行一次，Agent 重连后还会从 outbox 补传这次重复执行的结果。因此：
+//   - 面板以 Agent 最后一次心跳为准，失联超过 FailoverTakeoverDelay 才接管；
+//   - Agent 与面板失联达到接管时长的一半后，跳过这些任务的本地定时执行并在日志中记录。
+//
+// 两边都只比较各自本地经过的时长，不依赖时钟同步；只要心跳间隔远小于接管时长，Agent 总会先停下来，面板再接手
+func (es *ExecutorService) SyncAgentTaskCron(task *models.Task) {
+	if !task.HasFixedAgent() {
+		return
+	}
+	takeover := task.HasFailover() &&
+		utils.DerefBool(task.Enabled, true) &&
+		task.TriggerType == constant.TriggerTypeCron && task.Schedule != "" &&
+		(es.agentWSManager == nil || !es.agentWSManager.IsAgentOnline(*task.AgentID))
+	if takeover {
+		if wait := es.failoverTakeoverWait(*task.AgentID); wait > 0 {
+			// 失联时间尚短，到期后重新检查（期间 Agent 重连则不会接管）
+			taskID := task.ID
+			time.AfterFunc(wait, func() {
+				if latest := es.taskService.GetTaskByID(taskID); latest != nil {
+					es.SyncAgentTaskCron(latest)
+				}
+			})
+			takeover = false
+		}
+	}
+	if !takeover {
+		es.RemoveCronTask(task.ID)
+		return
+	}
+	logger.Infof("[Executor] Agent #%s 失联已超过接管时长，面板接管定时任务 #%s", *task.AgentID, task.ID)
+	if err := es.AddCronTask(task); err != nil {
+		logger.Warnf("[Executor] 接管离线 Agent 的定时任务 #%s 失败: %v", task.ID, err)
+	}
+}
+
+// failoverTakeoverWait 距离可以接管 Agent 的定时任务还需等待的时间，以 Agent 最后一次心跳为准
+func (es *ExecutorService) failoverTakeoverWait(agentID string) time.Duration {
+	var agent models.Agent
+	res := database.DB.Select("last_seen").Where("id = ?", agentID).Limit(1).Find(&agent)
+	if res.Error != nil || res.RowsAffected == 0 || agent.LastSeen == nil {
+		return 0
+	}
+	return time.Until(agent.LastSeen.Time().Add(FailoverTakeoverDelay(es.settingsService)))
+}
+
+// syncFailoverCrons 同步指定 Agent（为空表示全部）下配置了故障转移的定时任务
+func (es *ExecutorService) syncFailoverCrons(agentID string) {
+	query := database.DB.Where("agent_id IS NOT NULL AND agent_id <> '' AND failover_mode <> ''")
+	if agentID != "" {
+		query = query.Where("agent_id = ?", agentID)
+	}
+	var tasks []models.Task
+	query.Find(&tasks)
+	for i := range tasks {
+		es.SyncAgentTaskCron(&tasks[i])
+	}
+}
+
+// handleAgentStatus Agent 上下线时接管或交还其定时任务
+func (es *ExecutorService) handleAgentStatus(event eventbus.Event) {
+	payload, ok := event.Payload.(map[string]interface{})
+	if !ok || event.Replay {
+		return
+	}
+	if agentID, _ := payload["agent_id"].(string); agentID != "" {
+		es.syncFailoverCrons(agentID)
+	}
+}
@@ -0,0 +1,45 @@
+package tasks
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+// 面板以 Agent 最后一次心跳为准，失联超过接管时长才接管
+func TestFailoverTakeoverWait(t *testing.T) {
+	if err := database.Init(&database.Config{Type: "sqlite", Path: filepath.Join(t.TempDir(), "test.db")}); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.AutoMigrate(&models.Agent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	recent := models.LocalTime(time.Now().Add(-time.Minute))
+	stale := models.LocalTime(time.Now().Add(-10 * time.Minute))
+	for _, a := range []models.Agent{{ID: "recent", MachineID: "m1", LastSeen: &recent}, {ID: "stale", MachineID: "m2", LastSeen: &stale}} {
+		if err := database.DB.Create(&a).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	es := &ExecutorService{}
+	if wait := es.failoverTakeoverWait("recent"); wait < 3*time.Minute || wait > 4*time.Minute {
+		t.Fatalf("recent heartbeat: wait = %v, want about 4m", wait)
+	}
+	if wait := es.failoverTakeoverWait("stale"); wait > 0 {
+		t.Fatalf("stale heartbeat: wait = %v, want takeover now", wait)
+	}
+	if wait := es.failoverTakeoverWait("missing"); wait > 0 {
+		t.Fatalf("unknown agent: wait = %v", wait)
+	}
+}
@@ -0,0 +1,55 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// AgentLoadLimits Agent 负载上限（百分比，0 表示不限制）
+type AgentLoadLimits struct {
+	CPU  int
+	Mem  int
+	Disk int
+}
+
+// LoadAgentLoadLimits 读取系统设置中的 Agent 负载上限
+func LoadAgentLoadLimits(settings SettingsService) AgentLoadLimits {
+	if settings == nil {
+		return AgentLoadLimits{}
+	}
+	return AgentLoadLimits{
+		CPU:  utils.ToInt(settings.Get(constant.SectionAgent, constant.KeyAgentMaxCPUPercent), 0),
+		Mem:  utils.ToInt(settings.Get(constant.SectionAgent, constant.KeyAgentMaxMemPercent), 0),
+		Disk: utils.ToInt(settings.Get(constant.SectionAgent, constant.KeyAgentMaxDiskPercent), 0),
+	}
+}
+
+// Exceeded 返回指标超过上限的说明，未超限返回空字符串
+func (l AgentLoadLimits) Exceeded(m *models.AgentMetrics) string {
+	if m == nil {
+		return ""
+	}
+	switch {
+	case l.CPU > 0 && m.CPUPercent > float64(l.CPU):
+		return fmt.Sprintf("CPU 使用率 %.0f%% 超过上限 %d%%", m.CPUPercent, l.CPU)
+	case l.Mem > 0 && m.MemPercent > float64(l.Mem):
+		return fmt.Sprintf("内存使用率 %.0f%% 超过上限 %d%%", m.MemPercent, l.Mem)
+	case l.Disk > 0 && m.DiskPercent > float64(l.Disk):
+		return fmt.Sprintf("磁盘使用率 %.0f%% 超过上限 %d%%", m.DiskPercent, l.Disk)
+	}
+	return ""
+}
+
+// checkAgentLoad 按最近一次心跳上报的指标检查 Agent 是否超过负载上限
+func (es *ExecutorService) checkAgentLoad(agent *models.Agent) error {
+	if es.agentWSManager == nil {
+		return nil
+	}
+	if reason := LoadAgentLoadLimits(es.settingsService).Exceeded(es.agentWSManager.GetMetrics(agent.ID)); reason != "" {
+		return fmt.Errorf("Agent %s (#%s) 负载过高: %s", agent.Name, agent.ID, reason)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package tasks
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+// agentPlacement 记录服务端派发到各 Agent 且仍在运行的任务数，以及同负载时的轮询游标
+type agentPlacement struct {
+	mu      sync.Mutex
+	running map[string]int
+	cursor  int
+}
+
+func (p *agentPlacement) acquire(agentID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running == nil {
+		p.running = make(map[string]int)
+	}
+	p.running[agentID]++
+}
+
+func (p *agentPlacement) release(agentID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running[agentID] <= 1 {
+		delete(p.running, agentID)
+		return
+	}
+	p.running[agentID]--
+}
+
+// pick 从候选 Agent 中选出运行任务最少的一个，负载相同时轮询
+func (p *agentPlacement) pick(candidates []models.Agent) *models.Agent {
+	if len(candidates) == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(candidates)
+	best := -1
+	for i := 0; i < n; i++ {
+		idx := (p.cursor + i) % n
+		if best < 0 || p.running[candidates[idx].ID] < p.running[candidates[best].ID] {
+			best = idx
+		}
+	}
+	p.cursor = best + 1
+	return &candidates[best]
+}
+
+// PickAgent 按标签选择器挑选一个已启用且在线的 Agent
+func (es *ExecutorService) PickAgent(selector string) (*models.Agent, error) {
+	if len(models.SplitLabels(selector)) == 0 {
+		return nil, fmt.Errorf("Agent 标签选择器为空")
+	}
+
+	var agents []models.Agent
+	database.DB.Where("enabled = ?", true).Find(&agents)
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+
+	matched, overloaded := 0, 0
+	candidates := make([]models.Agent, 0, len(agents))
+	for i := range agents {
+		agent := agents[i]
+		if !agent.MatchLabels(selector) {
+			continue
+		}
+		matched++
+		if es.agentWSManager == nil || !es.agentWSManager.IsAgentOnline(agent.ID) {
+			continue
+		}
+		if es.checkAgentLoad(&agent) != nil {
+			overloaded++
+			continue
+		}
+		candidates = append(candidates, agent)
+	}
+
+	if matched == 0 {
+		return nil, fmt.Errorf("没有 Agent 匹配标签选择器 [%s]", selector)
+	}
+	agent := es.placement.pick(candidates)
+	if agent == nil {
+		if overloaded > 0 {
+			return nil, fmt.Errorf("匹配标签选择器 [%s] 的 %d 个 Agent 中，%d 个负载过高，其余不在线", selector, matched, overloaded)
+		}
+		return nil, fmt.Errorf("匹配标签选择器 [%s] 的 %d 个 Agent 均不在线", selector, matched)
+	}
+	return agent, nil
+}
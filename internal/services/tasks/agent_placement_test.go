@@ -0,0 +1,55 @@
+package tasks
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+func TestAgentPlacement_PickLeastBusyThenRoundRobin(t *testing.T) {
+	var p agentPlacement
+	candidates := []models.Agent{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+
+	// 负载相同时轮询
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, p.pick(candidates).ID)
+	}
+	if want := []string{"a", "b", "c", "a"}; !slices.Equal(got, want) {
+		t.Errorf("round robin = %v, want %v", got, want)
+	}
+
+	// 优先选择运行任务最少的 Agent
+	p.acquire("a")
+	p.acquire("b")
+	if id := p.pick(candidates).ID; id != "c" {
+		t.Errorf("least busy = %s, want c", id)
+	}
+	p.release("a")
+	p.release("b")
+	if len(p.running) != 0 {
+		t.Errorf("running not released: %v", p.running)
+	}
+
+	if p.pick(nil) != nil {
+		t.Error("pick on empty candidates should return nil")
+	}
+}
+
+func TestAgent_MatchLabels(t *testing.T) {
+	agent := &models.Agent{Labels: "gpu, region=cn ,edge"}
+	cases := map[string]bool{
+		"gpu":           true,
+		"gpu,region=cn": true,
+		" edge , gpu ":  true,
+		"gpu,region=us": false,
+		"":              false,
+		" , ":           false,
+	}
+	for selector, want := range cases {
+		if got := agent.MatchLabels(selector); got != want {
+			t.Errorf("MatchLabels(%q) = %v, want %v", selector, got, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package tasks
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -28,6 +29,9 @@ type AgentWSManager interface {
 	UnregisterRemoteWaiter(logID string)
 	SendToAgent(agentID string, msgType string, data interface{}) error
 	IsAgentOnline(agentID string) bool
+	BroadcastTasks(agentID string)
+	GetMetrics(agentID string) *models.AgentMetrics
+	SyncTaskScripts(agentID, workDir, command string) error
 }
 
 // SettingsService 接口定义（避免循环依赖）
@@ -43,6 +47,9 @@ type EnvService interface {
 	GetAllEnvVarsAndSecrets() ([]string, []string)
 }
 
+// agentResultTimeoutError 面板等待 Agent 结果超时时写入执行记录的错误信息
+const agentResultTimeoutError = "等待 Agent 结果超时"
+
 type ExecutorService struct {
 	taskService     *TaskService
 	taskLogService  *TaskLogService
@@ -55,6 +62,7 @@ type ExecutorService struct {
 	mu              sync.RWMutex
 	resultsMu       sync.RWMutex
 	stopCh          chan struct{}
+	placement       agentPlacement
 }
 
 func (es *ExecutorService) GetScheduler() *executor.Scheduler {
@@ -87,6 +95,10 @@ func NewExecutorService(
 	// 2. 初始化计划任务管理器
 	es.cronManager = executor.NewCronManager(es.scheduler)
 
+	// 3. Agent 上下线时接管或交还配置了故障转移的定时任务
+	eventbus.DefaultBus.Subscribe(constant.EventAgentOnline, "executor.failover", es.handleAgentStatus)
+	eventbus.DefaultBus.Subscribe(constant.EventAgentOffline, "executor.failover", es.handleAgentStatus)
+
 	return es
 }
 
@@ -117,6 +129,17 @@ type ServerSchedulerHandler struct {
 
 func (h *ServerSchedulerHandler) OnTaskScheduled(req *executor.ExecutionRequest) {
 	// 任务入队事件，可以在此处更新数据库状态为 "pending"
+	if req.TaskID == "" {
+		return
+	}
+	eventbus.DefaultBus.Publish(eventbus.Event{
+		Type: constant.EventTaskScheduled,
+		Payload: map[string]interface{}{
+			"task_id":     req.TaskID,
+			"task_name":   req.Name,
+			"retry_index": req.Metadata.RetryIndex,
+		},
+	})
 }
 
 func (h *ServerSchedulerHandler) OnTaskExecuting(req *executor.ExecutionRequest) (io.Writer, io.Writer, error) {
@@ -129,7 +152,7 @@ func (h *ServerSchedulerHandler) OnTaskExecuting(req *executor.ExecutionRequest)
 	}
 
 	// 1. 创建初始日志记录
-	taskLog, err := h.es.taskLogService.CreateEmptyLog(task.ID, req.Command)
+	taskLog, err := h.es.taskLogService.CreateEmptyLog(task.ID, req.Command, req.Metadata.RetryIndex)
 	if err != nil {
 		return nil, nil, fmt.Errorf("创建初始日志失败: %v", err)
 	}
@@ -167,6 +190,16 @@ func (h *ServerSchedulerHandler) OnTaskExecuting(req *executor.ExecutionRequest)
 		tl.Write([]byte(fmt.Sprintf("\n[System] 此为任务失败后的第 %d 次重试执行...\n\n", req.Metadata.RetryIndex)))
 	}
 
+	eventbus.DefaultBus.Publish(eventbus.Event{
+		Type: constant.EventTaskStarted,
+		Payload: map[string]interface{}{
+			"task_id":     task.ID,
+			"task_name":   task.Name,
+			"log_id":      taskLog.ID,
+			"retry_index": req.Metadata.RetryIndex,
+		},
+	})
+
 	// 对于本地任务，Scheduler 会通过返回的 Writer 写入日志
 	// 对于远程任务，Scheduler 不会写入任何内容（由 Agent 推送至此 TL）
 	return tl, tl, nil
@@ -233,9 +266,9 @@ func (h *ServerSchedulerHandler) OnTaskCompleted(req *executor.ExecutionRequest,
 		EndTime:   &endTime,
 	}
 
-	// 如果有 AgentID，也记录下来
-	if task.AgentID != nil && *task.AgentID != "" {
-		agentID := *task.AgentID
+	// 如果有 AgentID，也记录下来（按标签选择时为实际派发的 Agent）
+	if req.Metadata.AgentID != "" {
+		agentID := req.Metadata.AgentID
 		taskLog.AgentID = &agentID
 	}
 
@@ -251,7 +284,7 @@ func (h *ServerSchedulerHandler) OnTaskCompleted(req *executor.ExecutionRequest,
 	h.es.UpdateResult(*result)
 
 	// ======= 重试逻辑 =======
-	h.es.HandleTaskRetry(task, req, result.Success, result.Status, result.ExitCode)
+	retrying := h.es.HandleTaskRetry(task, req, result.Success, result.Status, result.ExitCode)
 
 	// ======= 通知触发 =======
 	// ======= 通知触发 =======
@@ -265,18 +298,42 @@ func (h *ServerSchedulerHandler) OnTaskCompleted(req *executor.ExecutionRequest,
 		case constant.TaskStatusTimeout:
 			eventType = constant.EventTaskTimeout
 		}
-		if eventType != "" {
+		// 还会重试时不发布失败事件，重试耗尽后才算一次失败的执行
+		if eventType == "" || (eventType != constant.EventTaskSuccess && retrying) {
+			return
+		}
+
+		// 根据历史日志计算连续失败次数，供通知绑定条件使用
+		previousFailures := h.es.taskLogService.CountConsecutiveFailures(task.ID, taskLog.ID, req.Metadata.RetryIndex)
+		newPayload := func() map[string]interface{} {
+			payload := map[string]interface{}{
+				"task_id":     task.ID,
+				"task_name":   task.Name,
+				"status":      result.Status,
+				"start_time":  result.StartTime.Format("2006-01-02 15:04:05"),
+				"duration":    result.Duration,
+				"output":      result.Output,
+				"error":       result.Error,
+				"retry_index": req.Metadata.RetryIndex,
+			}
+			if eventType == constant.EventTaskSuccess {
+				payload["previous_failures"] = previousFailures
+			} else {
+				payload["consecutive_failures"] = previousFailures + 1
+			}
+			return payload
+		}
+
+		eventbus.DefaultBus.Publish(eventbus.Event{
+			Type:    eventType,
+			Payload: newPayload(),
+		})
+
+		// 此前处于失败状态，本次成功则额外发布恢复事件
+		if eventType == constant.EventTaskSuccess && previousFailures > 0 {
 			eventbus.DefaultBus.Publish(eventbus.Event{
-				Type: eventType,
-				Payload: map[string]interface{}{
-					"task_id":    task.ID,
-					"task_name":  task.Name,
-					"status":     result.Status,
-					"start_time": result.StartTime.Format("2006-01-02 15:04:05"),
-					"duration":   result.Duration,
-					"output":     result.Output,
-					"error":      result.Error,
-				},
+				Type:    constant.EventTaskRecovered,
+				Payload: newPayload(),
 			})
 		}
 	}()
@@ -320,7 +377,10 @@ func (h *ServerSchedulerHandler) OnTaskFailed(req *executor.ExecutionRequest, er
 
 	// 补充 AgentID
 	task := h.es.taskService.GetTaskByID(taskID)
-	if task != nil && task.AgentID != nil && *task.AgentID != "" {
+	if req.Metadata.AgentID != "" {
+		agentID := req.Metadata.AgentID
+		taskLog.AgentID = &agentID
+	} else if task != nil && task.HasFixedAgent() {
 		agentID := *task.AgentID
 		taskLog.AgentID = &agentID
 	}
@@ -338,7 +398,10 @@ func (h *ServerSchedulerHandler) OnTaskFailed(req *executor.ExecutionRequest, er
 	})
 
 	// ======= 重试逻辑 =======
-	h.es.HandleTaskRetry(task, req, false, constant.TaskStatusFailed, 1)
+	if h.es.HandleTaskRetry(task, req, false, constant.TaskStatusFailed, 1) {
+		// 还会重试，重试耗尽后才发布失败事件
+		return
+	}
 
 	// ======= 通知触发 =======
 	// ======= 通知触发 =======
@@ -350,19 +413,21 @@ func (h *ServerSchedulerHandler) OnTaskFailed(req *executor.ExecutionRequest, er
 		eventbus.DefaultBus.Publish(eventbus.Event{
 			Type: constant.EventTaskFailed,
 			Payload: map[string]interface{}{
-				"task_id":   taskID,
-				"task_name": taskName,
-				"error":     err.Error(),
-				"output":    output,
+				"task_id":              taskID,
+				"task_name":            taskName,
+				"error":                err.Error(),
+				"output":               output,
+				"retry_index":          req.Metadata.RetryIndex,
+				"consecutive_failures": h.es.taskLogService.CountConsecutiveFailures(taskID, req.LogID, req.Metadata.RetryIndex) + 1,
 			},
 		})
 	}()
 }
 
-// HandleTaskRetry 处理任务失败重试逻辑
-func (es *ExecutorService) HandleTaskRetry(task *models.Task, req *executor.ExecutionRequest, isSuccess bool, status string, exitCode int) {
+// HandleTaskRetry 处理任务失败重试逻辑，返回是否已安排重试
+func (es *ExecutorService) HandleTaskRetry(task *models.Task, req *executor.ExecutionRequest, isSuccess bool, status string, exitCode int) bool {
 	if task == nil {
-		return
+		return false
 	}
 
 	if !isSuccess || status == constant.TaskStatusFailed || status == constant.TaskStatusTimeout || exitCode != 0 {
@@ -395,8 +460,10 @@ func (es *ExecutorService) HandleTaskRetry(task *models.Task, req *executor.Exec
 					},
 				}
 			})
+			return true
 		}
 	}
+	return false
 }
 
 func (h *ServerSchedulerHandler) OnCronNextRun(req *executor.ExecutionRequest, nextRun time.Time) {
@@ -458,10 +525,30 @@ func (es *ExecutorService) ExecuteDispatcher(ctx context.Context, req *executor.
 		}
 	}
 
+	// 广播到多个 Agent
+	if task.IsBroadcast() && !task.HasFixedAgent() {
+		return es.executeBroadcast(ctx, task, req, stdout)
+	}
+
 	// 远程任务
-	if task.AgentID != nil && *task.AgentID != "" {
+	if task.IsRemote() {
+		if stdout == nil {
+			stdout = io.Discard
+		}
+		// 确定执行 Agent（按标签挑选在线 Agent，不可用时按故障转移策略处理）
+		agentID, err := es.acquireAgent(ctx, task, stdout)
+		if err != nil {
+			return nil, err
+		}
+		if agentID == "" {
+			return es.executeLocalFallback(ctx, task, req, stdout, stderr)
+		}
+		req.Metadata.AgentID = agentID
+		if req.LogID != "" {
+			es.taskLogService.UpdateTaskAgent(req.LogID, agentID)
+		}
 		// 将请求中已包含的环境变量（已合并）传递给 Agent
-		return es.ExecuteRemoteForScheduler(task, req.LogID, executor.FormatEnvVars(req.Envs), req.Secrets)
+		return es.ExecuteRemoteForScheduler(task, agentID, req.LogID, executor.FormatEnvVars(req.Envs), req.Secrets)
 	}
 
 	// 本地任务
@@ -499,6 +586,8 @@ func (es *ExecutorService) Stop() {
 func (es *ExecutorService) StartCron() {
 	go es.loadCronTasks()
 	es.cronManager.Start()
+	// 留出 Agent 重连的时间，之后接管仍离线 Agent 的定时任务
+	time.AfterFunc(failoverStartupGrace, func() { es.syncFailoverCrons("") })
 	// logger.Info("[Executor] 计划任务管理器已启动")
 }
 
@@ -551,8 +640,8 @@ func (es *ExecutorService) loadCronTasks() {
 				logger.Infof("[Executor] 触发开机服务启动任务 #%s: %s", t.ID, t.Name)
 				es.ExecuteTask(t.ID, nil)
 			}(task)
-		} else if task.TriggerType == constant.TriggerTypeCron && task.Schedule != "" && (task.AgentID == nil || *task.AgentID == "") {
-			// 只调度本地任务（agent_id 为空或 0）的定时任务
+		} else if task.TriggerType == constant.TriggerTypeCron && task.Schedule != "" && !task.HasFixedAgent() {
+			// 只调度本地任务及按标签选择 Agent 的任务，固定 Agent 的任务由 Agent 自行调度
 			err := es.AddCronTask(&task)
 			if err != nil {
 				continue
@@ -658,15 +747,21 @@ func (es *ExecutorService) StopTaskExecution(logID string) error {
 		return fmt.Errorf("停止失败：关联的任务信息已丢失")
 	}
 
-	// 2. 远程任务逻辑
-	if task.AgentID != nil && *task.AgentID != "" {
+	// 2. 远程任务逻辑（优先使用日志记录的实际执行 Agent）
+	agentID := ""
+	if taskLog.AgentID != nil && *taskLog.AgentID != "" {
+		agentID = *taskLog.AgentID
+	} else if task.HasFixedAgent() {
+		agentID = *task.AgentID
+	}
+	if agentID != "" {
 		// 校验 Agent 是否在线
-		if !es.agentWSManager.IsAgentOnline(*task.AgentID) {
-			return fmt.Errorf("停止失败：目标 Agent (%s) 当前离线，无法下发指令", *task.AgentID)
+		if !es.agentWSManager.IsAgentOnline(agentID) {
+			return fmt.Errorf("停止失败：目标 Agent (%s) 当前离线，无法下发指令", agentID)
 		}
 
-		logger.Infof("[Executor] 请求停止远程任务 #%s (Agent #%s, LogID: %s)", task.ID, *task.AgentID, logID)
-		err := es.agentWSManager.SendToAgent(*task.AgentID, constant.WSTypeStop, map[string]interface{}{
+		logger.Infof("[Executor] 请求停止远程任务 #%s (Agent #%s, LogID: %s)", task.ID, agentID, logID)
+		err := es.agentWSManager.SendToAgent(agentID, constant.WSTypeStop, map[string]interface{}{
 			"log_id": logID,
 		})
 		if err != nil {
@@ -902,8 +997,7 @@ func (es *ExecutorService) RemoveRunningGo(taskID string, goid int64) {
 }
 
 // ExecuteRemoteForScheduler 供 Scheduler 调用，执行远程任务并等待结果
-func (es *ExecutorService) ExecuteRemoteForScheduler(task *models.Task, logID string, envs string, secrets []string) (*executor.Result, error) {
-	agentID := *task.AgentID
+func (es *ExecutorService) ExecuteRemoteForScheduler(task *models.Task, agentID string, logID string, envs string, secrets []string) (*executor.Result, error) {
 	logger.Infof("[Executor] 远程执行任务 #%s: %s (Agent #%s, LogID: %s)", task.ID, task.Name, agentID, logID)
 
 	// 1. 检查 Agent 状态
@@ -923,18 +1017,37 @@ func (es *ExecutorService) ExecuteRemoteForScheduler(task *models.Task, logID st
 	resultChan := es.agentWSManager.RegisterRemoteWaiter(logID)
 	defer es.agentWSManager.UnregisterRemoteWaiter(logID)
 
-	// 3. 发送指令
+	// 3. 同步任务用到的脚本目录，保证 Agent 上运行的是面板中最新保存的脚本
+	// 同步失败（如 Agent 网络较慢）不影响执行，使用 Agent 上已有的文件
+	if err := es.agentWSManager.SyncTaskScripts(agentID, task.WorkDir, string(task.Command)); err != nil {
+		logger.Warnf("[Executor] 任务 #%s 执行前同步脚本到 Agent #%s 失败，使用 Agent 上已有的文件继续执行: %v", task.ID, agentID, err)
+	}
+
+	es.placement.acquire(agentID)
+	defer es.placement.release(agentID)
+
+	// 4. 发送指令（附带任务定义，按标签调度的任务不在 Agent 的任务列表中）
 	err := es.agentWSManager.SendToAgent(agentID, constant.WSTypeExecute, map[string]interface{}{
 		"task_id": task.ID,
 		"log_id":  logID,
 		"envs":    envs,
 		"secrets": secrets,
+		"task": models.AgentTask{
+			ID:          task.ID,
+			Name:        task.Name,
+			Command:     string(task.Command),
+			Timeout:     task.Timeout,
+			WorkDir:     task.WorkDir,
+			Languages:   []map[string]string(task.Languages),
+			RandomRange: task.RandomRange,
+			Enabled:     true,
+		},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("发送执行命令失败: %v", err)
 	}
 
-	// 4. 等待结果或超时
+	// 5. 等待结果或超时
 	timeout := task.Timeout
 	if timeout <= 0 {
 		timeout = 30
@@ -956,24 +1069,87 @@ func (es *ExecutorService) ExecuteRemoteForScheduler(task *models.Task, logID st
 		end := time.Now()
 		return &executor.Result{
 			Status:    constant.TaskStatusFailed,
-			Error:     "等待 Agent 结果超时",
+			Error:     agentResultTimeoutError,
 			Duration:  end.Sub(start).Milliseconds(),
 			ExitCode:  -1,
 			StartTime: start,
 			EndTime:   end,
-		}, fmt.Errorf("等待 Agent 结果超时")
+		}, errors.New(agentResultTimeoutError)
 	}
 }
 
-// HandleAgentResult 处理来自 Agent 的异步结果
+// HandleAgentResult 处理没有等待者的 Agent 结果（Agent 本地定时执行、断线补传或面板重启后），按 log_id 去重
 func (es *ExecutorService) HandleAgentResult(result *models.AgentTaskResult) error {
+	exists, replacing := false, false
+	if result.LogID != "" {
+		var existing models.TaskLog
+		res := database.DB.Select("id, status, error").Where("id = ?", result.LogID).Limit(1).Find(&existing)
+		if res.Error != nil {
+			return res.Error
+		}
+		// 已结束或仍由面板执行中的记录视为重复上报；面板等待超时的记录由迟到的真实结果覆盖
+		if res.RowsAffected > 0 && (!awaitingAgentResult(&existing) || GetActiveLog(result.LogID) != nil ||
+			(existing.Status == constant.TaskStatusTimeout && result.Status == constant.TaskStatusTimeout)) {
+			logger.Infof("[Executor] 执行记录 #%s 已存在，忽略重复上报的结果", result.LogID)
+			return nil
+		}
+		exists = res.RowsAffected > 0
+		// 面板等待超时时已按超时完成过一次（统计、清理），迟到的结果只替换日志内容
+		replacing = exists && existing.Status != constant.TaskStatusRunning
+	}
+
 	taskLog, err := es.taskLogService.CreateTaskLogFromAgentResult(result)
 	if err != nil {
 		return err
 	}
+	if exists {
+		// 补全面板重启前遗留的运行中记录或替换超时记录，保留其创建时间
+		taskLog.CreatedAt = models.LocalTime{}
+		// 结构体更新会跳过零值，空错误与退出码 0 需显式写入
+		if err := database.DB.Model(&models.TaskLog{}).Where("id = ?", taskLog.ID).Updates(map[string]interface{}{
+			"error":     taskLog.Error,
+			"exit_code": taskLog.ExitCode,
+		}).Error; err != nil {
+			return err
+		}
+	} else if err := database.DB.Create(taskLog).Error; err != nil {
+		return err
+	}
+	if replacing {
+		return es.taskLogService.SaveTaskLog(taskLog)
+	}
 	return es.taskLogService.ProcessTaskCompletion(taskLog)
 }
 
+// HandleAgentLog 处理没有活动 TinyLog 的日志片段（断线补传或面板重启后），追加到仍在等待结果的执行记录
+func (es *ExecutorService) HandleAgentLog(logID, content string) error {
+	var existing models.TaskLog
+	res := database.DB.Select("id, status, error, output").Where("id = ?", logID).Limit(1).Find(&existing)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 || !awaitingAgentResult(&existing) {
+		logger.Warnf("[Executor] 执行记录 #%s 不存在或已结束，丢弃补传的日志片段 (%d 字节)", logID, len(content))
+		return nil
+	}
+
+	output, err := utils.DecompressFromBase64(string(existing.Output))
+	if err != nil {
+		return err
+	}
+	compressed, err := utils.CompressToBase64(utils.TrimLog(output+content, constant.MaxLogSize))
+	if err != nil {
+		return err
+	}
+	return database.DB.Model(&models.TaskLog{}).Where("id = ?", logID).Update("output", compressed).Error
+}
+
+// awaitingAgentResult 执行记录是否仍在等待 Agent 的最终结果：运行中，或面板等待结果超时后结束
+func awaitingAgentResult(log *models.TaskLog) bool {
+	return log.Status == constant.TaskStatusRunning || log.Status == constant.TaskStatusTimeout ||
+		string(log.Error) == agentResultTimeoutError
+}
+
 // BuildRepoCommand 构建仓库同步任务的命令
 func (es *ExecutorService) BuildRepoCommand(task *models.Task) (string, string) {
 	var config models.RepoConfig
@@ -0,0 +1,55 @@
+package tasks
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+type countingStats struct{ calls []string }
+
+func (s *countingStats) IncrementStats(taskID string, status string) error {
+	s.calls = append(s.calls, status)
+	return nil
+}
+
+// 迟到的 Agent 结果替换面板等待超时的记录时只更新日志，统计在超时时已计入
+func TestHandleAgentResultReplacesTimeoutWithoutRecount(t *testing.T) {
+	if err := database.Init(&database.Config{Type: "sqlite", Path: filepath.Join(t.TempDir(), "test.db")}); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.AutoMigrate(&models.Task{}, &models.TaskLog{}); err != nil {
+		t.Fatal(err)
+	}
+
+	timedOut := &models.TaskLog{ID: "timeout", TaskID: "t1", Status: constant.TaskStatusFailed, Error: agentResultTimeoutError, ExitCode: -1}
+	if err := database.DB.Create(timedOut).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stats := &countingStats{}
+	es := &ExecutorService{taskLogService: NewTaskLogService(stats)}
+	now := time.Now().Unix()
+	result := &models.AgentTaskResult{TaskID: "t1", LogID: "timeout", Status: constant.TaskStatusSuccess, StartTime: now, EndTime: now}
+	if err := es.HandleAgentResult(result); err != nil {
+		t.Fatal(err)
+	}
+	var replaced models.TaskLog
+	database.DB.Where("id = ?", "timeout").First(&replaced)
+	if replaced.Status != constant.TaskStatusSuccess || replaced.Error != "" || replaced.ExitCode != 0 {
+		t.Fatalf("timeout record not replaced: %+v", replaced)
+	}
+	if len(stats.calls) != 0 {
+		t.Fatalf("stats recounted for replaced record: %v", stats.calls)
+	}
+}
@@ -35,24 +35,44 @@ type CleanConfig struct {
 	Keep int    `json:"keep"` // 保留天数或条数
 }
 
-// CreateEmptyLog 创建一个空的日志记录（任务开始时调用）
-func (s *TaskLogService) CreateEmptyLog(taskID string, command string) (*models.TaskLog, error) {
+// CreateEmptyLog 创建一个空的日志记录（任务开始时调用），retryIndex 为失败重试的序号
+func (s *TaskLogService) CreateEmptyLog(taskID string, command string, retryIndex int) (*models.TaskLog, error) {
+	startTime := models.Now()
+	taskLog := &models.TaskLog{
+		ID:         utils.GenerateID(),
+		TaskID:     taskID,
+		Command:    models.BigText(command),
+		Status:     "running",
+		RetryIndex: retryIndex,
+		StartTime:  &startTime,
+		CreatedAt:  models.Now(),
+	}
+	if err := database.DB.Create(taskLog).Error; err != nil {
+		return nil, err
+	}
+
+	// 任务开始时即更新任务的 last_run 为启动时间
+	database.DB.Model(&models.Task{}).Where("id = ?", taskID).Update("last_run", startTime)
+
+	return taskLog, nil
+}
+
+// CreateChildLog 创建广播执行在单个 Agent 上的子日志记录
+func (s *TaskLogService) CreateChildLog(taskID, parentID, agentID, command string) (*models.TaskLog, error) {
 	startTime := models.Now()
 	taskLog := &models.TaskLog{
 		ID:        utils.GenerateID(),
 		TaskID:    taskID,
+		AgentID:   &agentID,
+		ParentID:  parentID,
 		Command:   models.BigText(command),
-		Status:    "running",
+		Status:    constant.TaskStatusRunning,
 		StartTime: &startTime,
 		CreatedAt: models.Now(),
 	}
 	if err := database.DB.Create(taskLog).Error; err != nil {
 		return nil, err
 	}
-
-	// 任务开始时即更新任务的 last_run 为启动时间
-	database.DB.Model(&models.Task{}).Where("id = ?", taskID).Update("last_run", startTime)
-
 	return taskLog, nil
 }
 
@@ -89,6 +109,11 @@ func (s *TaskLogService) UpdateTaskDuration(logID string, duration int64) error
 	return database.DB.Model(&models.TaskLog{}).Where("id = ?", logID).Update("duration", duration).Error
 }
 
+// UpdateTaskAgent 记录实际执行的 Agent
+func (s *TaskLogService) UpdateTaskAgent(logID string, agentID string) error {
+	return database.DB.Model(&models.TaskLog{}).Where("id = ?", logID).Update("agent_id", agentID).Error
+}
+
 // UpdateTaskStats 更新任务统计
 func (s *TaskLogService) UpdateTaskStats(taskID string, status string) {
 	if s.sendStatsService == nil {
@@ -132,7 +157,8 @@ func (s *TaskLogService) CleanTaskLogs(taskID string) {
 		deleted = result.RowsAffected
 	case "count":
 		var boundaryLog models.TaskLog
-		res := database.DB.Where("task_id = ?", taskID).Order("id DESC").Offset(config.Keep - 1).Limit(1).Find(&boundaryLog)
+		// 只按顶层记录计数，广播子日志随父记录一并清理
+		res := database.DB.Where("task_id = ? AND parent_id = ''", taskID).Order("id DESC").Offset(config.Keep - 1).Limit(1).Find(&boundaryLog)
 		if res.Error == nil && res.RowsAffected > 0 {
 			result := database.DB.Where("task_id = ? AND id < ?", taskID, boundaryLog.ID).Delete(&models.TaskLog{})
 			deleted = result.RowsAffected
@@ -144,6 +170,37 @@ func (s *TaskLogService) CleanTaskLogs(taskID string) {
 	}
 }
 
+// CountConsecutiveFailures 统计指定日志之前任务连续失败（失败/超时）的执行次数
+// 一次执行连同它的失败重试只计一次：遇到成功记录即停止，重试记录不单独计数，取消、运行中等状态的记录不影响计数。
+// retryIndex > 0 表示指定日志本身是一次重试，本次执行中先前失败的尝试不计入
+func (s *TaskLogService) CountConsecutiveFailures(taskID, beforeLogID string, retryIndex int) int {
+	var logs []models.TaskLog
+	database.DB.Select("status", "retry_index").
+		Where("task_id = ? AND id < ? AND parent_id = ''", taskID, beforeLogID).
+		Order("id DESC").Limit(200).Find(&logs)
+
+	count := 0
+	inCurrentRun := retryIndex > 0
+	for _, l := range logs {
+		if inCurrentRun {
+			// 跳过本次执行之前的尝试，直到本次执行的首次尝试
+			if l.RetryIndex == 0 {
+				inCurrentRun = false
+			}
+			continue
+		}
+		switch l.Status {
+		case constant.TaskStatusFailed, constant.TaskStatusTimeout:
+			if l.RetryIndex == 0 {
+				count++
+			}
+		case constant.TaskStatusSuccess:
+			return count
+		}
+	}
+	return count
+}
+
 // ProcessTaskCompletion 处理任务完成后的所有操作（保存日志、更新统计、清理旧日志）
 func (s *TaskLogService) ProcessTaskCompletion(taskLog *models.TaskLog) error {
 	// 1. 保存/更新日志
@@ -170,8 +227,14 @@ func (s *TaskLogService) CreateTaskLogFromAgentResult(result *models.AgentTaskRe
 		compressed = ""
 	}
 
+	// 沿用 Agent 上报的日志 ID，便于去重
+	logID := result.LogID
+	if logID == "" {
+		logID = utils.GenerateID()
+	}
+
 	taskLog := &models.TaskLog{
-		ID:        utils.GenerateID(),
+		ID:        logID,
 		TaskID:    result.TaskID,
 		AgentID:   &result.AgentID,
 		Command:   models.BigText(result.Command),
@@ -0,0 +1,60 @@
+package tasks
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/models"
+)
+
+// 一次执行连同它的失败重试只算一次连续失败
+func TestCountConsecutiveFailuresIgnoresRetryAttempts(t *testing.T) {
+	if err := database.Init(&database.Config{Type: "sqlite", Path: filepath.Join(t.TempDir(), "test.db")}); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.AutoMigrate(&models.TaskLog{}); err != nil {
+		t.Fatal(err)
+	}
+
+	failed, timeout, success := constant.TaskStatusFailed, constant.TaskStatusTimeout, constant.TaskStatusSuccess
+	logs := []models.TaskLog{
+		{ID: "01", Status: failed},
+		{ID: "02", Status: success, RetryIndex: 1}, // 重试后成功
+		{ID: "03", Status: failed},
+		{ID: "04", Status: failed, RetryIndex: 1}, // 第 1 次执行重试耗尽
+		{ID: "05", Status: failed},
+		{ID: "06", Status: timeout, RetryIndex: 1}, // 第 2 次执行重试耗尽
+		{ID: "07", Status: failed},                 // 第 3 次执行的首次尝试
+	}
+	for i := range logs {
+		logs[i].TaskID = "t1"
+		if err := database.DB.Create(&logs[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewTaskLogService(nil)
+	cases := []struct {
+		before     string
+		retryIndex int
+		want       int
+	}{
+		{"07", 0, 2}, // 第 3 次执行的首次尝试：此前连续失败 2 次执行
+		{"08", 1, 2}, // 第 3 次执行的重试：本次执行先前的尝试不计入
+		{"08", 0, 3},
+		{"03", 0, 0}, // 上一次执行重试后成功
+	}
+	for _, c := range cases {
+		if got := s.CountConsecutiveFailures("t1", c.before, c.retryIndex); got != c.want {
+			t.Errorf("before %s retry %d: got %d, want %d", c.before, c.retryIndex, got, c.want)
+		}
+	}
+}
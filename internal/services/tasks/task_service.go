@@ -24,7 +24,7 @@ func (ts *TaskService) GetTaskBySourceID(sourceID string) *models.Task {
 	return &task
 }
 
-func (ts *TaskService) CreateTask(name, command, schedule string, timeout int, workDir, cleanConfig, envs, taskType, config string, agentID *string, languages models.TaskLanguages, triggerType string, tags string, retryCount int, retryInterval int, randomRange int, sourceID string, pinType string) *models.Task {
+func (ts *TaskService) CreateTask(name, command, schedule string, timeout int, workDir, cleanConfig, envs, taskType, config string, agentID *string, placement models.TaskPlacement, languages models.TaskLanguages, triggerType string, tags string, retryCount int, retryInterval int, randomRange int, sourceID string, pinType string) *models.Task {
 	if taskType == "" {
 		taskType = "task"
 	}
@@ -50,6 +50,7 @@ func (ts *TaskService) CreateTask(name, command, schedule string, timeout int, w
 		Envs:          models.BigText(envs),
 		Languages:     languages,
 		AgentID:       agentID,
+		TaskPlacement: placement,
 		Enabled:       utils.BoolPtr(true),
 		RetryCount:    retryCount,
 		RetryInterval: retryInterval,
@@ -122,7 +123,7 @@ func (ts *TaskService) GetTaskByID(id string) *models.Task {
 	return &task
 }
 
-func (ts *TaskService) UpdateTask(id string, name, command, schedule string, timeout int, workDir, cleanConfig, envs string, enabled bool, taskType, config string, agentID *string, languages models.TaskLanguages, triggerType string, tags string, retryCount int, retryInterval int, randomRange int, sourceID string, pinType string) *models.Task {
+func (ts *TaskService) UpdateTask(id string, name, command, schedule string, timeout int, workDir, cleanConfig, envs string, enabled bool, taskType, config string, agentID *string, placement models.TaskPlacement, languages models.TaskLanguages, triggerType string, tags string, retryCount int, retryInterval int, randomRange int, sourceID string, pinType string) *models.Task {
 	var task models.Task
 	res := database.DB.Where("id = ?", id).Limit(1).Find(&task)
 	if res.Error != nil || res.RowsAffected == 0 {
@@ -139,6 +140,7 @@ func (ts *TaskService) UpdateTask(id string, name, command, schedule string, tim
 	task.Envs = models.BigText(envs)
 	task.Enabled = &enabled
 	task.AgentID = agentID
+	task.TaskPlacement = placement
 	task.Languages = languages
 	task.Config = models.BigText(config)
 	task.RetryCount = retryCount
@@ -156,7 +158,8 @@ func (ts *TaskService) UpdateTask(id string, name, command, schedule string, tim
 
 	database.DB.Model(&task).Select(
 		"Name", "Command", "Tags", "Schedule", "Timeout", "WorkDir",
-		"CleanConfig", "Envs", "Enabled", "AgentID", "Languages",
+		"CleanConfig", "Envs", "Enabled", "AgentID", "AgentSelector", "AgentMode",
+		"AgentIDs", "BroadcastPolicy", "FailoverMode", "FailoverAgentID", "FailoverWait", "Languages",
 		"RetryCount", "RetryInterval", "RandomRange", "Type",
 		"TriggerType", "Config", "SourceID", "PinType",
 	).Updates(&task)
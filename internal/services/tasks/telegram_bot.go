@@ -0,0 +1,383 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/sdk/message"
+	"github.com/engigu/baihu-panel/internal/sdk/messenger"
+	"github.com/engigu/baihu-panel/internal/systime"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+const (
+	telegramBotPollTimeout  = 20               // getUpdates 长轮询挂起秒数（需小于客户端 30 秒超时）
+	telegramBotIdleInterval = 15 * time.Second // 未启用或出错时的重试间隔
+	telegramBotReplyLimit   = 3500             // 回复内容上限（Telegram 单条消息最多 4096 字符）
+	telegramBotLogLines     = 30               // /log 返回的输出行数
+	telegramBotListLimit    = 50               // /tasks 最多列出的任务数
+)
+
+const telegramBotHelp = `可用命令:
+/tasks - 任务列表
+/run <任务名或ID> - 立即执行任务
+/stop <日志ID> - 停止正在执行的任务
+/log <任务名或ID> - 查看最近一次执行的输出
+/pause <任务名或ID> - 暂停任务的定时调度
+/resume <任务名或ID> - 恢复任务的定时调度
+/status - 面板运行状态`
+
+// TelegramBot 复用 Telegram 渠道的 Bot，通过长轮询接收白名单会话的命令来控制任务
+type TelegramBot struct {
+	executorService *ExecutorService
+	settingsService SettingsService
+}
+
+func NewTelegramBot(executorService *ExecutorService, settingsService SettingsService) *TelegramBot {
+	return &TelegramBot{executorService: executorService, settingsService: settingsService}
+}
+
+// Start 启动长轮询，未配置渠道时空闲等待，设置修改后无需重启即可生效
+func (b *TelegramBot) Start() {
+	var offset int64
+	var token string
+
+	for {
+		cli := b.client()
+		if cli == nil {
+			token = ""
+			time.Sleep(telegramBotIdleInterval)
+			continue
+		}
+
+		// 启用或更换 Bot 时跳过积压的历史消息，避免执行离线期间的旧命令
+		if cli.BotToken != token {
+			next, err := b.skipPending(cli)
+			if err != nil {
+				logger.Warnf("[TelegramBot] 连接失败: %v", err)
+				time.Sleep(telegramBotIdleInterval)
+				continue
+			}
+			offset, token = next, cli.BotToken
+			logger.Info("[TelegramBot] 已开始接收命令")
+		}
+
+		updates, err := cli.GetUpdates(offset, telegramBotPollTimeout)
+		if err != nil {
+			logger.Warnf("[TelegramBot] 获取消息失败: %v", err)
+			time.Sleep(telegramBotIdleInterval)
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			b.handle(cli, u)
+		}
+	}
+}
+
+// client 根据设置加载 Bot 所属的 Telegram 渠道，未启用时返回 nil
+func (b *TelegramBot) client() *message.Telegram {
+	wayID := b.settingsService.Get(constant.SectionNotify, constant.KeyTelegramBotWayID)
+	if wayID == "" {
+		return nil
+	}
+
+	var way models.NotifyWay
+	res := database.DB.Where("id = ? AND type = ?", wayID, messenger.ChannelTelegram).Limit(1).Find(&way)
+	if res.RowsAffected == 0 || !utils.DerefBool(way.Enabled, true) {
+		return nil
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(way.Config), &config); err != nil {
+		return nil
+	}
+	str := func(key string) string {
+		v, _ := config[key].(string)
+		return v
+	}
+	if str("bot_token") == "" {
+		return nil
+	}
+	return &message.Telegram{BotToken: str("bot_token"), ApiHost: str("api_host"), ProxyURL: str("proxy_url")}
+}
+
+// skipPending 返回最新一条消息之后的 offset
+func (b *TelegramBot) skipPending(cli *message.Telegram) (int64, error) {
+	updates, err := cli.GetUpdates(-1, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	return updates[len(updates)-1].UpdateID + 1, nil
+}
+
+func (b *TelegramBot) handle(cli *message.Telegram, u message.TelegramUpdate) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("[TelegramBot] 处理命令异常: %v", r)
+		}
+	}()
+
+	if u.Message == nil || !strings.HasPrefix(u.Message.Text, "/") {
+		return
+	}
+	text := strings.TrimSpace(u.Message.Text)
+	chatID := strconv.FormatInt(u.Message.Chat.ID, 10)
+	user := chatID
+	if u.Message.From != nil && u.Message.From.Username != "" {
+		user = "@" + u.Message.From.Username
+	}
+
+	var reply string
+	var err error
+	if b.allowed(chatID) {
+		reply, err = b.execute(text)
+	} else {
+		err = fmt.Errorf("会话 %s 不在白名单中", chatID)
+		reply = fmt.Sprintf("未授权：请在面板中将 chat_id %s 加入 Bot 白名单", chatID)
+	}
+	if err != nil && reply == "" {
+		reply = "执行失败: " + err.Error()
+	}
+
+	logger.Infof("[TelegramBot] %s 执行命令: %s", user, text)
+	b.audit(chatID, user, text, reply, err)
+
+	cli.ChatID = chatID
+	if _, sendErr := cli.SendMessageText(truncateTail(reply, telegramBotReplyLimit)); sendErr != nil {
+		logger.Warnf("[TelegramBot] 回复消息失败: %v", sendErr)
+	}
+}
+
+// allowed 校验会话是否在白名单中，未配置白名单时拒绝所有命令
+func (b *TelegramBot) allowed(chatID string) bool {
+	for _, id := range strings.Split(b.settingsService.Get(constant.SectionNotify, constant.KeyTelegramBotChatIDs), ",") {
+		if strings.TrimSpace(id) == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// audit 将命令写入应用日志
+func (b *TelegramBot) audit(chatID, user, command, reply string, err error) {
+	if len(command) > 255 {
+		command = command[:255]
+	}
+	log := &models.AppLog{
+		ID:       utils.GenerateID(),
+		Category: constant.LogCategoryBotLog,
+		Title:    command,
+		Content:  models.BigText(fmt.Sprintf("用户: %s\n\n%s", user, reply)),
+		Level:    constant.LogLevelInfo,
+		Status:   constant.LogStatusSuccess,
+		RefID:    chatID,
+	}
+	if err != nil {
+		log.Level = constant.LogLevelWarning
+		log.Status = constant.LogStatusFailed
+		log.ErrorMsg = models.BigText(err.Error())
+	}
+	if dbErr := database.DB.Create(log).Error; dbErr != nil {
+		logger.Errorf("[TelegramBot] 写入命令记录失败: %v", dbErr)
+	}
+}
+
+// execute 解析并执行命令，返回回复内容
+func (b *TelegramBot) execute(text string) (string, error) {
+	fields := strings.Fields(text)
+	// 群组中的命令形如 /run@BotName
+	cmd := strings.ToLower(strings.SplitN(fields[0], "@", 2)[0])
+	arg := strings.TrimSpace(strings.TrimPrefix(text, fields[0]))
+
+	switch cmd {
+	case "/start", "/help":
+		return telegramBotHelp, nil
+	case "/tasks":
+		return b.listTasks(), nil
+	case "/status":
+		return b.status(), nil
+	case "/stop":
+		if arg == "" {
+			return "", fmt.Errorf("用法: /stop <日志ID>")
+		}
+		if err := b.executorService.StopTaskExecution(arg); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("已发送停止指令 (日志 #%s)", arg), nil
+	case "/run", "/log", "/pause", "/resume":
+	default:
+		return "", fmt.Errorf("未知命令 %s，发送 /help 查看可用命令", cmd)
+	}
+
+	if arg == "" {
+		return "", fmt.Errorf("用法: %s <任务名或ID>", cmd)
+	}
+	task, err := b.findTask(arg)
+	if err != nil {
+		return "", err
+	}
+
+	switch cmd {
+	case "/run":
+		res := b.executorService.ExecuteTask(task.ID, nil)
+		if !res.Success {
+			return "", fmt.Errorf("%s", res.Error)
+		}
+		return fmt.Sprintf("任务 %s 已加入执行队列，可发送 /log %s 查看输出", task.Name, task.ID), nil
+	case "/log":
+		return b.lastLog(task)
+	case "/pause":
+		return b.setEnabled(task, false)
+	default:
+		return b.setEnabled(task, true)
+	}
+}
+
+// findTask 按ID或名称查找任务，名称重复时要求使用ID
+func (b *TelegramBot) findTask(key string) (*models.Task, error) {
+	if task := b.executorService.taskService.GetTaskByID(key); task != nil {
+		return task, nil
+	}
+
+	var tasks []models.Task
+	database.DB.Where("name = ?", key).Limit(2).Find(&tasks)
+	switch len(tasks) {
+	case 0:
+		return nil, fmt.Errorf("任务 %s 不存在", key)
+	case 1:
+		return &tasks[0], nil
+	default:
+		return nil, fmt.Errorf("存在多个名为 %s 的任务，请使用任务ID", key)
+	}
+}
+
+func (b *TelegramBot) listTasks() string {
+	var total int64
+	var tasks []models.Task
+	database.DB.Model(&models.Task{}).Count(&total)
+	database.DB.Order("id ASC").Limit(telegramBotListLimit).Find(&tasks)
+	if total == 0 {
+		return "暂无任务"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "任务列表 (共 %d 个)\n", total)
+	for _, t := range tasks {
+		state := "启用"
+		if !utils.DerefBool(t.Enabled, true) {
+			state = "已暂停"
+		}
+		if t.IsRunning() {
+			state = "运行中"
+		}
+		fmt.Fprintf(&sb, "\n#%s %s [%s]", t.ID, t.Name, state)
+	}
+	if total > int64(len(tasks)) {
+		fmt.Fprintf(&sb, "\n\n仅显示前 %d 个", len(tasks))
+	}
+	return sb.String()
+}
+
+func (b *TelegramBot) lastLog(task *models.Task) (string, error) {
+	var log models.TaskLog
+	res := database.DB.Where("task_id = ? AND parent_id = ''", task.ID).Order("id DESC").Limit(1).Find(&log)
+	if res.RowsAffected == 0 {
+		return fmt.Sprintf("任务 %s 暂无执行记录", task.Name), nil
+	}
+
+	var output string
+	if active := GetActiveLog(log.ID); active != nil {
+		data, _ := active.ReadLastLines(telegramBotLogLines)
+		output = string(data)
+	} else if log.Output != "" {
+		content, err := utils.DecompressFromBase64(string(log.Output))
+		if err != nil {
+			return "", fmt.Errorf("读取日志失败: %v", err)
+		}
+		output = lastLines(content, telegramBotLogLines)
+	}
+	if log.Error != "" {
+		output = strings.TrimRight(output, "\n") + "\n" + string(log.Error)
+	}
+	if strings.TrimSpace(output) == "" {
+		output = "(无输出)"
+	}
+
+	header := fmt.Sprintf("任务 %s 最近一次执行\n日志ID: %s\n状态: %s\n耗时: %dms\n\n", task.Name, log.ID, log.Status, log.Duration)
+	return header + truncateTail(output, telegramBotReplyLimit-len([]rune(header))), nil
+}
+
+// setEnabled 暂停或恢复任务的定时调度，与在面板中切换任务启用状态一致
+func (b *TelegramBot) setEnabled(task *models.Task, enabled bool) (string, error) {
+	action := "恢复"
+	if !enabled {
+		action = "暂停"
+	}
+	if utils.DerefBool(task.Enabled, true) == enabled {
+		return fmt.Sprintf("任务 %s 已是%s状态", task.Name, map[bool]string{true: "启用", false: "暂停"}[enabled]), nil
+	}
+
+	if err := database.DB.Model(&models.Task{}).Where("id = ?", task.ID).Update("enabled", enabled).Error; err != nil {
+		return "", err
+	}
+	task.Enabled = &enabled
+
+	if task.AgentID != nil && *task.AgentID != "" {
+		b.executorService.agentWSManager.BroadcastTasks(*task.AgentID)
+		b.executorService.SyncAgentTaskCron(task)
+	} else if enabled {
+		if err := b.executorService.AddCronTask(task); err != nil {
+			return "", fmt.Errorf("任务已启用，但加入调度失败: %v", err)
+		}
+	} else {
+		b.executorService.RemoveCronTask(task.ID)
+	}
+	return fmt.Sprintf("已%s任务 %s", action, task.Name), nil
+}
+
+func (b *TelegramBot) status() string {
+	var taskTotal, taskEnabled, agentOnline, todaySuccess, todayFailed int64
+	database.DB.Model(&models.Task{}).Count(&taskTotal)
+	database.DB.Model(&models.Task{}).Where("enabled = ?", true).Count(&taskEnabled)
+	database.DB.Model(&models.Agent{}).Where("status = ?", constant.AgentStatusOnline).Count(&agentOnline)
+
+	now := systime.InCST(time.Now())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	database.DB.Model(&models.TaskLog{}).Where("created_at >= ? AND status = ? AND parent_id = ''", today, constant.TaskStatusSuccess).Count(&todaySuccess)
+	database.DB.Model(&models.TaskLog{}).Where("created_at >= ? AND status IN ? AND parent_id = ''", today,
+		[]string{constant.TaskStatusFailed, constant.TaskStatusTimeout}).Count(&todayFailed)
+
+	return fmt.Sprintf("面板运行状态\n任务: %d 个（启用 %d）\n计划任务: %d 个\n运行中: %d，排队: %d\n在线 Agent: %d\n今日执行: 成功 %d，失败 %d",
+		taskTotal, taskEnabled, b.executorService.GetScheduledCount(),
+		b.executorService.GetRunningCount(), b.executorService.scheduler.GetQueueSize(),
+		agentOnline, todaySuccess, todayFailed)
+}
+
+// lastLines 返回文本的最后 n 行
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateTail 超出长度时保留末尾内容
+func truncateTail(s string, limit int) string {
+	r := []rune(s)
+	if limit <= 0 || len(r) <= limit {
+		return s
+	}
+	return "..." + string(r[len(r)-limit:])
+}
@@ -0,0 +1,457 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/eventbus"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+const (
+	// webhookDeliveryKeepCount 每个 Webhook 保留的投递记录条数
+	webhookDeliveryKeepCount = 500
+	// webhookResponseLimit 保存的响应体最大长度
+	webhookResponseLimit = 4 * 1024
+	// webhookMaxRetries 允许配置的最大重试次数
+	webhookMaxRetries = 10
+	// webhookMaxBackoff 重试退避的最大间隔
+	webhookMaxBackoff = 10 * time.Minute
+
+	// Webhook 请求头
+	WebhookHeaderEvent     = "X-Baihu-Event"
+	WebhookHeaderDelivery  = "X-Baihu-Delivery"
+	WebhookHeaderTimestamp = "X-Baihu-Timestamp"
+	WebhookHeaderSignature = "X-Baihu-Signature"
+
+	// WebhookEventPing 测试投递使用的事件类型
+	WebhookEventPing = "ping"
+)
+
+// WebhookEvents 可订阅的事件类型
+var WebhookEvents = []map[string]string{
+	{"type": eventbus.AllEvents, "label": "全部事件"},
+	{"type": constant.EventUserLogin, "label": "用户登录"},
+	{"type": constant.EventBruteForceLogin, "label": "密码多次错误"},
+	{"type": constant.EventPasswordChanged, "label": "密码修改"},
+	{"type": constant.EventTaskScheduled, "label": "任务入队"},
+	{"type": constant.EventTaskStarted, "label": "任务开始"},
+	{"type": constant.EventTaskSuccess, "label": "任务成功"},
+	{"type": constant.EventTaskFailed, "label": "任务失败"},
+	{"type": constant.EventTaskTimeout, "label": "任务超时"},
+	{"type": constant.EventTaskRecovered, "label": "任务恢复"},
+	{"type": constant.EventAgentOnline, "label": "Agent 上线"},
+	{"type": constant.EventAgentOffline, "label": "Agent 离线"},
+	{"type": constant.EventAgentOverloaded, "label": "Agent 负载过高"},
+	{"type": constant.EventCheckDown, "label": "心跳异常"},
+	{"type": constant.EventCheckUp, "label": "心跳恢复"},
+	{"type": constant.EventDiskLow, "label": "磁盘空间不足"},
+	{"type": constant.EventBackupSuccess, "label": "备份完成"},
+	{"type": constant.EventBackupFailed, "label": "备份失败"},
+	{"type": constant.EventDependencyFailed, "label": "依赖安装失败"},
+	{"type": constant.EventPanelStarted, "label": "面板启动"},
+	{"type": constant.EventSystemNotice, "label": "系统通知"},
+	{"type": constant.EventNotifySent, "label": "通知发送"},
+}
+
+// WebhookPayload 投递的请求体
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+type WebhookService struct {
+	client *http.Client
+}
+
+func NewWebhookService() *WebhookService {
+	return &WebhookService{
+		client: &http.Client{},
+	}
+}
+
+// List 获取所有 Webhook
+func (s *WebhookService) List() []models.Webhook {
+	var hooks []models.Webhook
+	database.DB.Order("id DESC").Find(&hooks)
+	return hooks
+}
+
+// GetByID 根据 ID 获取 Webhook
+func (s *WebhookService) GetByID(id string) *models.Webhook {
+	var hook models.Webhook
+	res := database.DB.Where("id = ?", id).Limit(1).Find(&hook)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil
+	}
+	return &hook
+}
+
+// Save 创建或更新 Webhook，secret 为空时新建会自动生成，更新则保留原值
+func (s *WebhookService) Save(hook *models.Webhook) error {
+	if !strings.HasPrefix(hook.URL, "http://") && !strings.HasPrefix(hook.URL, "https://") {
+		return fmt.Errorf("Webhook 地址必须以 http:// 或 https:// 开头")
+	}
+	hook.Events = normalizeWebhookEvents(hook.Events)
+	if hook.Events == "" {
+		return fmt.Errorf("至少需要订阅一个事件")
+	}
+	if hook.MaxRetries < 0 {
+		hook.MaxRetries = 0
+	}
+	if hook.MaxRetries > webhookMaxRetries {
+		hook.MaxRetries = webhookMaxRetries
+	}
+	if hook.Timeout <= 0 || hook.Timeout > 60 {
+		hook.Timeout = 10
+	}
+	if hook.Enabled == nil {
+		hook.Enabled = utils.BoolPtr(true)
+	}
+
+	if hook.ID == "" {
+		hook.ID = utils.GenerateID()
+		if hook.Secret == "" {
+			hook.Secret = utils.RandomString(32)
+		}
+		return database.DB.Create(hook).Error
+	}
+
+	existing := s.GetByID(hook.ID)
+	if existing == nil {
+		return fmt.Errorf("Webhook 不存在")
+	}
+	// 列表返回的是脱敏后的密钥，原样提交时保留原值
+	if hook.Secret == "" || hook.Secret == utils.MaskString(existing.Secret) {
+		hook.Secret = existing.Secret
+	}
+	updates := map[string]interface{}{
+		"name":        hook.Name,
+		"url":         hook.URL,
+		"secret":      hook.Secret,
+		"events":      hook.Events,
+		"max_retries": hook.MaxRetries,
+		"timeout":     hook.Timeout,
+		"enabled":     hook.Enabled,
+	}
+	return database.DB.Model(&models.Webhook{}).Where("id = ?", hook.ID).Updates(updates).Error
+}
+
+// Delete 删除 Webhook 及其投递记录
+func (s *WebhookService) Delete(id string) error {
+	if err := database.DB.Where("id = ?", id).Delete(&models.Webhook{}).Error; err != nil {
+		return err
+	}
+	database.DB.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{})
+	return nil
+}
+
+// ListDeliveries 分页获取投递记录
+func (s *WebhookService) ListDeliveries(webhookID, status string, page, pageSize int) ([]models.WebhookDelivery, int64) {
+	var deliveries []models.WebhookDelivery
+	var total int64
+
+	query := database.DB.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query.Count(&total)
+	query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&deliveries)
+	return deliveries, total
+}
+
+// GetDelivery 获取单条投递记录
+func (s *WebhookService) GetDelivery(id string) *models.WebhookDelivery {
+	var delivery models.WebhookDelivery
+	res := database.DB.Where("id = ?", id).Limit(1).Find(&delivery)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil
+	}
+	return &delivery
+}
+
+// Redeliver 使用原始请求体重新投递一次（生成新的投递记录，仅尝试一次）
+func (s *WebhookService) Redeliver(deliveryID string) (*models.WebhookDelivery, error) {
+	origin := s.GetDelivery(deliveryID)
+	if origin == nil {
+		return nil, fmt.Errorf("投递记录不存在")
+	}
+	hook := s.GetByID(origin.WebhookID)
+	if hook == nil {
+		return nil, fmt.Errorf("Webhook 不存在")
+	}
+
+	// 请求体保持不变，接收端可通过 body 中的 id 做幂等去重
+	delivery, err := s.createDelivery(hook, utils.GenerateID(), origin.Event, []byte(origin.RequestBody), origin.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.attempt(hook, delivery, 0)
+	return s.GetDelivery(delivery.ID), nil
+}
+
+// Test 发送一条 ping 事件用于验证接收端配置
+func (s *WebhookService) Test(id string) (*models.WebhookDelivery, error) {
+	hook := s.GetByID(id)
+	if hook == nil {
+		return nil, fmt.Errorf("Webhook 不存在")
+	}
+
+	delivery, err := s.newDelivery(hook, WebhookEventPing, map[string]interface{}{
+		"webhook_id": hook.ID,
+		"message":    "白虎面板 Webhook 测试",
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+	s.attempt(hook, delivery, 0)
+	return s.GetDelivery(delivery.ID), nil
+}
+
+// SubscribeEvents 订阅总线全部事件并分发到匹配的 Webhook
+func (s *WebhookService) SubscribeEvents(bus *eventbus.EventBus) {
+	go s.resumePendingDeliveries()
+
+	// 投递本身在后台按 Webhook 的重试策略执行，结果记录在投递记录中；
+	// 查询 Webhook 或创建投递记录失败时返回错误，交给总线记录并可重放
+	bus.SubscribeWithError(eventbus.AllEvents, "webhook", func(e eventbus.Event) error {
+		var hooks []models.Webhook
+		if err := database.DB.Where("enabled = ?", true).Find(&hooks).Error; err != nil {
+			return err
+		}
+
+		var errs []error
+		for i := range hooks {
+			hook := hooks[i]
+			if !webhookMatches(hook.Events, e.Type) {
+				continue
+			}
+			delivery, err := s.newDelivery(&hook, e.Type, e.Payload, "")
+			if err != nil {
+				logger.Warnf("[Webhook] 创建投递记录失败 (%s): %v", hook.Name, err)
+				errs = append(errs, fmt.Errorf("%s: %w", hook.Name, err))
+				continue
+			}
+			go s.attempt(&hook, delivery, hook.MaxRetries)
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// newDelivery 序列化事件并创建投递记录
+func (s *WebhookService) newDelivery(hook *models.Webhook, event string, data interface{}, redeliveryOf string) (*models.WebhookDelivery, error) {
+	id := utils.GenerateID()
+	body, err := json.Marshal(WebhookPayload{
+		ID:        id,
+		Event:     event,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.createDelivery(hook, id, event, body, redeliveryOf)
+}
+
+func (s *WebhookService) createDelivery(hook *models.Webhook, id, event string, body []byte, redeliveryOf string) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{
+		ID:           id,
+		WebhookID:    hook.ID,
+		Event:        event,
+		Status:       constant.WebhookStatusPending,
+		RequestURL:   hook.URL,
+		RequestBody:  models.BigText(body),
+		RedeliveryOf: redeliveryOf,
+	}
+	if err := database.DB.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	s.trimDeliveries(hook.ID)
+	return delivery, nil
+}
+
+// attempt 执行一次投递，失败时按指数退避安排下一次重试
+func (s *WebhookService) attempt(hook *models.Webhook, delivery *models.WebhookDelivery, retriesLeft int) {
+	delivery.Attempts++
+	headers, code, respBody, latency, err := s.send(hook, delivery)
+
+	headerJSON, _ := json.Marshal(headers)
+	updates := map[string]interface{}{
+		"attempts":        delivery.Attempts,
+		"request_headers": models.BigText(headerJSON),
+		"response_code":   code,
+		"response_body":   models.BigText(respBody),
+		"latency":         latency,
+		"error":           models.BigText(""),
+	}
+
+	if err == nil {
+		updates["status"] = constant.WebhookStatusSuccess
+		database.DB.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates)
+		return
+	}
+
+	updates["error"] = models.BigText(err.Error())
+	if retriesLeft <= 0 {
+		updates["status"] = constant.WebhookStatusFailed
+		database.DB.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates)
+		logger.Warnf("[Webhook] 投递 %s 到 %s 失败 (已尝试 %d 次): %v", delivery.Event, hook.Name, delivery.Attempts, err)
+		return
+	}
+
+	database.DB.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates)
+	backoff := webhookBackoff(delivery.Attempts)
+	logger.Debugf("[Webhook] 投递 %s 到 %s 失败，%v 后重试: %v", delivery.Event, hook.Name, backoff, err)
+	time.AfterFunc(backoff, func() {
+		// 重试前重新加载配置，Webhook 被删除或禁用后不再重试
+		latest := s.GetByID(hook.ID)
+		if latest == nil || !utils.DerefBool(latest.Enabled, true) {
+			database.DB.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Update("status", constant.WebhookStatusFailed)
+			return
+		}
+		s.attempt(latest, delivery, retriesLeft-1)
+	})
+}
+
+// resumePendingDeliveries 重启后继续投递仍处于 pending 的记录（重试计划只保存在内存中）
+func (s *WebhookService) resumePendingDeliveries() {
+	var deliveries []models.WebhookDelivery
+	database.DB.Where("status = ?", constant.WebhookStatusPending).Order("created_at ASC").Find(&deliveries)
+	if len(deliveries) == 0 {
+		return
+	}
+	logger.Infof("[Webhook] 继续投递重启前未完成的 %d 条记录", len(deliveries))
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		hook := s.GetByID(delivery.WebhookID)
+		if hook == nil || !utils.DerefBool(hook.Enabled, true) {
+			database.DB.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Update("status", constant.WebhookStatusFailed)
+			continue
+		}
+		retriesLeft := hook.MaxRetries - delivery.Attempts
+		if delivery.RedeliveryOf != "" || retriesLeft < 0 {
+			retriesLeft = 0
+		}
+		s.attempt(hook, delivery, retriesLeft)
+	}
+}
+
+// send 发送 HTTP 请求，2xx 视为成功
+func (s *WebhookService) send(hook *models.Webhook, delivery *models.WebhookDelivery) (map[string]string, int, string, int64, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	body := []byte(delivery.RequestBody)
+
+	headers := map[string]string{
+		"Content-Type":         "application/json",
+		"User-Agent":           "Baihu-Webhook/" + constant.Version,
+		WebhookHeaderEvent:     delivery.Event,
+		WebhookHeaderDelivery:  delivery.ID,
+		WebhookHeaderTimestamp: timestamp,
+	}
+	if hook.Secret != "" {
+		headers[WebhookHeaderSignature] = "sha256=" + SignWebhookPayload(hook.Secret, timestamp, body)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return headers, 0, "", 0, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	timeout := time.Duration(hook.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	client := *s.client
+	client.Timeout = timeout
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		return headers, 0, "", latency, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return headers, resp.StatusCode, string(respBody), latency, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return headers, resp.StatusCode, string(respBody), latency, nil
+}
+
+// trimDeliveries 仅保留最近 webhookDeliveryKeepCount 条投递记录
+func (s *WebhookService) trimDeliveries(webhookID string) {
+	var ids []string
+	database.DB.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID).
+		Order("id DESC").Offset(webhookDeliveryKeepCount).Limit(1000).Pluck("id", &ids)
+	if len(ids) > 0 {
+		database.DB.Where("id IN ?", ids).Delete(&models.WebhookDelivery{})
+	}
+}
+
+// SignWebhookPayload 计算签名: hex(HMAC-SHA256(secret, timestamp + "." + body))
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff 第 n 次失败后的等待时间：10s、30s、90s ... 最长 10 分钟
+func webhookBackoff(attempts int) time.Duration {
+	d := 10 * time.Second
+	for i := 1; i < attempts; i++ {
+		d *= 3
+		if d >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return d
+}
+
+func webhookMatches(events, eventType string) bool {
+	for _, e := range strings.Split(events, ",") {
+		if e == eventbus.AllEvents || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeWebhookEvents(events string) string {
+	seen := make(map[string]bool)
+	result := make([]string, 0)
+	for _, e := range strings.Split(events, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" || seen[e] {
+			continue
+		}
+		if e == eventbus.AllEvents {
+			return eventbus.AllEvents
+		}
+		seen[e] = true
+		result = append(result, e)
+	}
+	return strings.Join(result, ",")
+}
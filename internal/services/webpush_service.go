@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/engigu/baihu-panel/internal/constant"
+	"github.com/engigu/baihu-panel/internal/database"
+	"github.com/engigu/baihu-panel/internal/logger"
+	"github.com/engigu/baihu-panel/internal/models"
+	"github.com/engigu/baihu-panel/internal/sdk/message"
+	"github.com/engigu/baihu-panel/internal/sdk/messenger"
+	"github.com/engigu/baihu-panel/internal/utils"
+)
+
+// webPushBodyLimit 推送正文最大字符数，避免超过推送服务的负载限制
+const webPushBodyLimit = 1000
+
+// defaultWebPushSubject 未配置联系方式时使用的 VAPID subject
+const defaultWebPushSubject = "mailto:admin@localhost"
+
+func init() {
+	// Web Push 依赖数据库中的订阅信息，因此在业务层注册为普通渠道，事件绑定无需区别对待
+	messenger.RegisterChannel(messenger.ChannelWebPush, func() messenger.Channel {
+		return &webPushChannel{
+			BaseChannel: messenger.NewBaseChannel(messenger.ChannelWebPush, []string{messenger.FormatTypeText}),
+			service:     NewWebPushService(),
+		}
+	})
+}
+
+// WebPushService 管理 VAPID 密钥与浏览器订阅
+type WebPushService struct {
+	settingsService *SettingsService
+}
+
+var vapidKeyMu sync.Mutex
+
+func NewWebPushService() *WebPushService {
+	return &WebPushService{settingsService: NewSettingsService()}
+}
+
+// GetPublicKey 获取 VAPID 公钥，首次调用时生成密钥对并保存到设置中
+func (s *WebPushService) GetPublicKey() (string, error) {
+	vapidKeyMu.Lock()
+	defer vapidKeyMu.Unlock()
+
+	if key := s.settingsService.Get(constant.SectionNotify, constant.KeyWebPushPublicKey); key != "" {
+		return key, nil
+	}
+	publicKey, privateKey, err := message.GenerateVAPIDKeys()
+	if err != nil {
+		return "", err
+	}
+	if err := s.settingsService.Set(constant.SectionNotify, constant.KeyWebPushPrivateKey, privateKey); err != nil {
+		return "", err
+	}
+	if err := s.settingsService.Set(constant.SectionNotify, constant.KeyWebPushPublicKey, publicKey); err != nil {
+		return "", err
+	}
+	logger.Info("[WebPush] 已生成 VAPID 密钥")
+	return publicKey, nil
+}
+
+// Subscribe 保存浏览器订阅，同一 endpoint 重复订阅时更新密钥与所属用户
+func (s *WebPushService) Subscribe(userID, endpoint, p256dh, auth, userAgent string) (*models.WebPushSubscription, error) {
+	if endpoint == "" || p256dh == "" || auth == "" {
+		return nil, fmt.Errorf("订阅信息不完整")
+	}
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	var sub models.WebPushSubscription
+	res := database.DB.Where("endpoint = ?", endpoint).Limit(1).Find(&sub)
+	if res.RowsAffected > 0 {
+		sub.UserID, sub.P256dh, sub.Auth, sub.UserAgent = userID, p256dh, auth, userAgent
+		return &sub, database.DB.Save(&sub).Error
+	}
+
+	sub = models.WebPushSubscription{
+		ID:        utils.GenerateID(),
+		UserID:    userID,
+		Endpoint:  endpoint,
+		P256dh:    p256dh,
+		Auth:      auth,
+		UserAgent: userAgent,
+	}
+	return &sub, database.DB.Create(&sub).Error
+}
+
+// Unsubscribe 删除浏览器订阅
+func (s *WebPushService) Unsubscribe(endpoint string) error {
+	return database.DB.Where("endpoint = ?", endpoint).Delete(&models.WebPushSubscription{}).Error
+}
+
+// DeleteSubscription 按ID删除订阅
+func (s *WebPushService) DeleteSubscription(id string) error {
+	return database.DB.Where("id = ?", id).Delete(&models.WebPushSubscription{}).Error
+}
+
+// ListSubscriptions 获取订阅列表，userID 为空时返回全部
+func (s *WebPushService) ListSubscriptions(userID string) []models.WebPushSubscription {
+	var subs []models.WebPushSubscription
+	query := database.DB.Order("id DESC")
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	query.Find(&subs)
+	return subs
+}
+
+// Send 向订阅推送消息，返回成功送达的数量；已失效（404/410）的订阅会被自动删除
+func (s *WebPushService) Send(userID string, msg *messenger.Message, ttl int, urgency string) (int, error) {
+	subs := s.ListSubscriptions(userID)
+	if len(subs) == 0 {
+		return 0, fmt.Errorf("没有可用的浏览器订阅")
+	}
+	if _, err := s.GetPublicKey(); err != nil {
+		return 0, err
+	}
+
+	body := msg.Text
+	if r := []rune(body); len(r) > webPushBodyLimit {
+		body = string(r[:webPushBodyLimit]) + "..."
+	}
+	url := msg.URL
+	if url == "" {
+		url = s.settingsService.Get(constant.SectionNotify, constant.KeyNotifyPublicURL)
+	}
+	payload, _ := json.Marshal(map[string]string{
+		"title": msg.Title,
+		"body":  body,
+		"url":   url,
+		"image": msg.ImageURL,
+	})
+
+	subject := s.settingsService.Get(constant.SectionNotify, constant.KeyWebPushSubject)
+	if subject == "" {
+		subject = defaultWebPushSubject
+	}
+	cli := message.WebPush{
+		PublicKey:  s.settingsService.Get(constant.SectionNotify, constant.KeyWebPushPublicKey),
+		PrivateKey: s.settingsService.Get(constant.SectionNotify, constant.KeyWebPushPrivateKey),
+		Subject:    subject,
+		TTL:        ttl,
+		Urgency:    urgency,
+	}
+
+	delivered := 0
+	var lastErr error
+	for _, sub := range subs {
+		status, _, err := cli.Send(message.WebPushSubscription{Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth}, payload)
+		if status == 404 || status == 410 {
+			logger.Infof("[WebPush] 订阅已失效，删除: %s", sub.ID)
+			s.DeleteSubscription(sub.ID)
+			continue
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		delivered++
+		now := models.Now()
+		database.DB.Model(&models.WebPushSubscription{}).Where("id = ?", sub.ID).Update("last_used_at", &now)
+	}
+
+	if delivered == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("全部订阅均已失效")
+		}
+		return 0, lastErr
+	}
+	return delivered, nil
+}
+
+// webPushChannel Web Push 渠道，配置项均为可选：user_id 仅推送给指定用户的浏览器，ttl、urgency 为推送参数
+type webPushChannel struct {
+	*messenger.BaseChannel
+	service *WebPushService
+}
+
+func (c *webPushChannel) Send(config messenger.ChannelConfig, msg *messenger.Message) (*messenger.Result, error) {
+	ttl, _ := strconv.Atoi(config.GetString("ttl"))
+	delivered, err := c.service.Send(config.GetString("user_id"), msg, ttl, config.GetString("urgency"))
+	if err != nil {
+		return messenger.ErrorResult("", err), nil
+	}
+	return messenger.SuccessResult(fmt.Sprintf("已推送到 %d 个浏览器", delivered)), nil
+}
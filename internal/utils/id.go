@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"fmt"
+
 	"github.com/rs/xid"
 )
 
@@ -17,3 +20,12 @@ func IsNumeric(s string) bool {
 	}
 	return s != ""
 }
+
+// GenerateUUID 生成一个随机的 UUID v4 字符串
+func GenerateUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
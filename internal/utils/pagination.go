@@ -18,6 +18,21 @@ func ToInt(s string, defaultVal int) int {
 	return val
 }
 
+// ToIntValue 将任意数值类型（含 JSON 反序列化得到的 float64）转换为整数，无法转换时返回 0
+func ToIntValue(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case string:
+		return ToInt(n, 0)
+	}
+	return 0
+}
+
 // ParseInt 解析字符串为整数
 func ParseInt(s string) (int, error) {
 	return strconv.Atoi(s)
@@ -137,6 +137,16 @@ export const api = {
     sendStats: (days?: number) => request<DailyStats[]>(`/sendstats${days ? `?days=${days}` : ''}`),
     taskStats: (days?: number) => request<TaskStatsItem[]>(`/taskstats${days ? `?days=${days}` : ''}`)
   },
+  checks: {
+    list: (params?: { page?: number; page_size?: number; name?: string; status?: string }) => {
+      const query = new URLSearchParams()
+      if (params?.page) query.set('page', String(params.page))
+      if (params?.page_size) query.set('page_size', String(params.page_size))
+      if (params?.name) query.set('name', params.name)
+      if (params?.status) query.set('status', params.status)
+      return request<CheckListResponse>(`/checks?${query}`)
+    }
+  },
   settings: {
     changePassword: (data: { old_username?: string; username?: string; old_password: string; new_password?: string }) =>
       request('/settings/password', { method: 'POST', body: JSON.stringify(data) }),
@@ -314,7 +324,16 @@ export const api = {
       request('/notify/bindings/batch', { method: 'POST', body: JSON.stringify(data) }),
     deleteBinding: (id: string) => request('/notify/bindings/' + id, { method: 'DELETE' }),
     send: (data: { channel_id: string; title: string; text: string }) =>
-      request<NotifyResult>('/notify/send', { method: 'POST', body: JSON.stringify(data) })
+      request<NotifyResult>('/notify/send', { method: 'POST', body: JSON.stringify(data) }),
+
+    webPush: {
+      getPublicKey: () => request<{ public_key: string }>('/notify/webpush/key'),
+      subscribe: (data: PushSubscriptionJSON) =>
+        request('/notify/webpush/subscribe', { method: 'POST', body: JSON.stringify(data) }),
+      unsubscribe: (endpoint: string) =>
+        request('/notify/webpush/unsubscribe', { method: 'POST', body: JSON.stringify({ endpoint }) }),
+      test: () => request<{ delivered: number }>('/notify/webpush/test', { method: 'POST' })
+    }
   },
   appLogs: {
     list: (params?: { page?: number; page_size?: number; category?: string; status?: string; level?: string; keyword?: string }) => {
@@ -438,6 +457,8 @@ export interface Stats {
   logs: number
   scheduled: number
   running: number
+  checks: number
+  checks_down: number
 }
 
 
@@ -538,9 +559,36 @@ export interface DailyStats {
 export interface TaskStatsItem {
   task_id: string
   task_name: string
+  type: 'task' | 'check'
   count: number
 }
 
+export interface Check {
+  id: string
+  name: string
+  remark: string
+  tags: string
+  ping_key: string
+  ping_path: string
+  period: number
+  grace: number
+  status: 'new' | 'up' | 'down' | 'started' | 'paused'
+  last_ping: string | null
+  last_start: string | null
+  last_duration: number
+  next_deadline: string | null
+  enabled: boolean
+  created_at: string
+  updated_at: string
+}
+
+export interface CheckListResponse {
+  data: Check[]
+  total: number
+  page: number
+  page_size: number
+}
+
 export interface Dependency {
   id: string
   name: string
@@ -106,7 +106,9 @@ export default defineConfig({
         ]
       },
       workbox: {
-        maximumFileSizeToCacheInBytes: 10 * 1024 * 1024 // 10MB to allow monaco-editor files
+        maximumFileSizeToCacheInBytes: 10 * 1024 * 1024, // 10MB to allow monaco-editor files
+        // 浏览器推送的 push / notificationclick 处理由后端提供
+        importScripts: ['push-sw.js']
       },
       devOptions: {
         enabled: true